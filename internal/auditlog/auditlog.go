@@ -5,6 +5,7 @@ package auditlog
 
 import (
 	"context"
+	"strings"
 	"sync"
 
 	audit "github.com/soulteary/audit-kit"
@@ -48,8 +49,9 @@ func Stop() error {
 	return nil
 }
 
-// LogUserQuery records a user query event
-func LogUserQuery(ctx context.Context, userID, identifier, identifierType, ip string, success bool, reason string) {
+// LogUserQuery records a user query event.
+// source names the data source(s) the user was loaded from (comma-separated); empty when unknown.
+func LogUserQuery(ctx context.Context, userID, identifier, identifierType, ip string, success bool, reason, source string) {
 	l := GetLogger()
 	if l == nil {
 		return
@@ -60,12 +62,16 @@ func LogUserQuery(ctx context.Context, userID, identifier, identifierType, ip st
 		result = audit.ResultFailure
 	}
 
-	l.LogAccess(ctx, audit.EventCustom, userID, "user_query", result,
+	opts := []audit.RecordOption{
 		audit.WithRecordIP(ip),
 		audit.WithRecordReason(reason),
 		audit.WithRecordMetadata("identifier", identifier),
 		audit.WithRecordMetadata("identifier_type", identifierType),
-	)
+	}
+	if source != "" {
+		opts = append(opts, audit.WithRecordMetadata("source", source))
+	}
+	l.LogAccess(ctx, audit.EventCustom, userID, "user_query", result, opts...)
 }
 
 // LogUserCreate records a user creation event
@@ -146,3 +152,28 @@ func LogAccessGranted(ctx context.Context, userID, resource, ip string) {
 		audit.WithRecordIP(ip),
 	)
 }
+
+// LogIdentityConflict records an identity conflict found while merging sources: a user_id or mail (value)
+// shared by the records identified by keys. action is the conflict policy applied; dropped lists the keys
+// of the records removed from the dataset.
+func LogIdentityConflict(ctx context.Context, field, value, action string, keys, dropped []string) {
+	l := GetLogger()
+	if l == nil {
+		return
+	}
+
+	result := audit.ResultSuccess
+	if len(dropped) > 0 {
+		result = audit.ResultFailure
+	}
+	record := audit.NewRecord(audit.EventCustom, result).
+		WithResource("identity:"+field).
+		WithReason("identity_conflict").
+		WithMetadata("field", field).
+		WithMetadata("value", value).
+		WithMetadata("action", action).
+		WithMetadata("keys", strings.Join(keys, ",")).
+		WithMetadata("dropped", strings.Join(dropped, ","))
+
+	l.Log(ctx, record)
+}
@@ -28,11 +28,11 @@ func TestAuditLogFunctions(t *testing.T) {
 
 	// Test all logging functions (should not panic)
 	t.Run("LogUserQuery Success", func(t *testing.T) {
-		LogUserQuery(ctx, "user1", "test@example.com", "email", "127.0.0.1", true, "")
+		LogUserQuery(ctx, "user1", "test@example.com", "email", "127.0.0.1", true, "", "/data/data.json")
 	})
 
 	t.Run("LogUserQuery Failure", func(t *testing.T) {
-		LogUserQuery(ctx, "", "unknown@example.com", "email", "127.0.0.1", false, "user_not_found")
+		LogUserQuery(ctx, "", "unknown@example.com", "email", "127.0.0.1", false, "user_not_found", "")
 	})
 
 	t.Run("LogUserCreate", func(t *testing.T) {
@@ -59,6 +59,10 @@ func TestAuditLogFunctions(t *testing.T) {
 		LogAccessGranted(ctx, "user1", "/api/users", "127.0.0.1")
 	})
 
+	t.Run("LogIdentityConflict", func(t *testing.T) {
+		LogIdentityConflict(ctx, "user_id", "u1", "prefer", []string{"13800138000", "13900139000"}, []string{"13800138000"})
+	})
+
 	// Test Stop
 	err := Stop()
 	assert.NoError(t, err)
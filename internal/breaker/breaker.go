@@ -0,0 +1,192 @@
+// Package breaker provides a circuit breaker for data sources that fail repeatedly.
+//
+// After Options.Threshold consecutive failures the breaker opens and requests are rejected without
+// contacting the source. Once the open period has elapsed a single probe is let through (half-open):
+// success closes the breaker, failure re-opens it for twice as long, up to Options.MaxDelay.
+// Open periods are jittered so that instances sharing a source do not probe it in lockstep.
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultThreshold is the number of consecutive failures that opens the breaker.
+	DefaultThreshold = 3
+	// DefaultBaseDelay is the first open period.
+	DefaultBaseDelay = 10 * time.Second
+	// DefaultMaxDelay caps the open period.
+	DefaultMaxDelay = 5 * time.Minute
+)
+
+// ErrOpen is returned by Allow while the breaker is open.
+var ErrOpen = errors.New("circuit breaker open")
+
+// State is the breaker state.
+type State int
+
+const (
+	// Closed lets every request through.
+	Closed State = iota
+	// HalfOpen lets one probe through after the open period.
+	HalfOpen
+	// Open rejects requests until the open period has elapsed.
+	Open
+)
+
+// String returns "closed", "half_open" or "open".
+func (s State) String() string {
+	switch s {
+	case HalfOpen:
+		return "half_open"
+	case Open:
+		return "open"
+	}
+	return "closed"
+}
+
+// Options configures a Breaker. Zero values use the defaults.
+type Options struct {
+	Threshold int           // consecutive failures that open the breaker (default DefaultThreshold)
+	BaseDelay time.Duration // first open period, doubled on every failed probe (default DefaultBaseDelay)
+	MaxDelay  time.Duration // maximum open period (default DefaultMaxDelay)
+}
+
+// Validate checks the options.
+func (o *Options) Validate() error {
+	if o.Threshold < 0 {
+		return fmt.Errorf("threshold %d is negative", o.Threshold)
+	}
+	if o.BaseDelay < 0 || o.MaxDelay < 0 {
+		return errors.New("delays must not be negative")
+	}
+	if o.BaseDelay > 0 && o.MaxDelay > 0 && o.BaseDelay > o.MaxDelay {
+		return fmt.Errorf("base delay %s exceeds max delay %s", o.BaseDelay, o.MaxDelay)
+	}
+	return nil
+}
+
+// Status is a snapshot of a Breaker.
+type Status struct {
+	State    State
+	Failures int       // consecutive failures
+	RetryAt  time.Time // end of the open period (zero unless open)
+}
+
+// Breaker is a circuit breaker. Methods are safe for concurrent use.
+type Breaker struct {
+	mu        sync.Mutex
+	threshold int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	state     State
+	failures  int
+	opens     int // consecutive open periods, for the backoff
+	retryAt   time.Time
+	probing   bool // a half-open probe is in flight
+
+	now    func() time.Time
+	jitter func(d time.Duration) time.Duration
+}
+
+// New creates a closed Breaker.
+func New(o Options) *Breaker {
+	b := &Breaker{
+		threshold: o.Threshold,
+		baseDelay: o.BaseDelay,
+		maxDelay:  o.MaxDelay,
+		now:       time.Now,
+		jitter:    equalJitter,
+	}
+	if b.threshold <= 0 {
+		b.threshold = DefaultThreshold
+	}
+	if b.baseDelay <= 0 {
+		b.baseDelay = DefaultBaseDelay
+	}
+	if b.maxDelay <= 0 {
+		b.maxDelay = DefaultMaxDelay
+	}
+	b.maxDelay = max(b.maxDelay, b.baseDelay)
+	return b
+}
+
+// equalJitter returns a random duration in [d/2, d).
+func equalJitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(half) // #nosec G404 -- jitter does not need a secure source
+}
+
+// Allow reports whether a request may be made. probe is true for the single half-open request, which the
+// caller should keep cheap (e.g. without retries). While open, Allow returns an error wrapping ErrOpen.
+// Every allowed request must be followed by Success or Failure.
+func (b *Breaker) Allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case Open:
+		if b.now().Before(b.retryAt) {
+			return false, fmt.Errorf("%w, retry at %s", ErrOpen, b.retryAt.Format(time.RFC3339))
+		}
+		b.state = HalfOpen
+		b.probing = true
+		return true, nil
+	case HalfOpen:
+		if b.probing {
+			return false, fmt.Errorf("%w, probe in progress", ErrOpen)
+		}
+		b.probing = true
+		return true, nil
+	}
+	return false, nil
+}
+
+// Success records a successful request and closes the breaker.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = Closed
+	b.failures = 0
+	b.opens = 0
+	b.retryAt = time.Time{}
+	b.probing = false
+}
+
+// Failure records a failed request. It reports whether the breaker opened because of it.
+func (b *Breaker) Failure() (opened bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	// A request allowed before the breaker opened does not extend the open period
+	if b.state == Open || (b.state == Closed && b.failures < b.threshold) {
+		return false
+	}
+	delay := b.baseDelay
+	for i := 0; i < b.opens && delay < b.maxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, b.maxDelay)
+	b.opens++
+	b.state = Open
+	b.retryAt = b.now().Add(b.jitter(delay))
+	return true
+}
+
+// Status returns a snapshot of the breaker.
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := Status{State: b.state, Failures: b.failures}
+	if b.state == Open {
+		s.RetryAt = b.retryAt
+	}
+	return s
+}
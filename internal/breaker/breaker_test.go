@@ -0,0 +1,115 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestBreaker returns a Breaker with a controllable clock and no jitter.
+func newTestBreaker(o Options) (*Breaker, *time.Time) {
+	now := time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+	b := New(o)
+	b.now = func() time.Time { return now }
+	b.jitter = func(d time.Duration) time.Duration { return d }
+	return b, &now
+}
+
+func TestBreaker_OpensAfterThreshold(t *testing.T) {
+	b, now := newTestBreaker(Options{Threshold: 2, BaseDelay: 10 * time.Second, MaxDelay: 30 * time.Second})
+
+	probe, err := b.Allow()
+	require.NoError(t, err)
+	assert.False(t, probe)
+	assert.False(t, b.Failure())
+	assert.Equal(t, Closed, b.Status().State)
+	assert.True(t, b.Failure(), "second consecutive failure opens")
+
+	st := b.Status()
+	assert.Equal(t, Open, st.State)
+	assert.Equal(t, 2, st.Failures)
+	assert.Equal(t, now.Add(10*time.Second), st.RetryAt)
+	_, err = b.Allow()
+	assert.ErrorIs(t, err, ErrOpen)
+
+	// After the open period one probe is let through.
+	*now = now.Add(10 * time.Second)
+	probe, err = b.Allow()
+	require.NoError(t, err)
+	assert.True(t, probe)
+	assert.Equal(t, HalfOpen, b.Status().State)
+	_, err = b.Allow()
+	assert.ErrorIs(t, err, ErrOpen, "only one probe at a time")
+
+	// A failed probe re-opens for twice as long, capped at MaxDelay.
+	assert.True(t, b.Failure())
+	assert.Equal(t, now.Add(20*time.Second), b.Status().RetryAt)
+	*now = now.Add(20 * time.Second)
+	_, err = b.Allow()
+	require.NoError(t, err)
+	b.Failure()
+	assert.Equal(t, now.Add(30*time.Second), b.Status().RetryAt)
+
+	// A successful probe closes the breaker and resets the backoff.
+	*now = now.Add(30 * time.Second)
+	probe, err = b.Allow()
+	require.NoError(t, err)
+	assert.True(t, probe)
+	b.Success()
+	assert.Equal(t, Status{State: Closed}, b.Status())
+	b.Failure()
+	assert.True(t, b.Failure())
+	assert.Equal(t, now.Add(10*time.Second), b.Status().RetryAt)
+}
+
+func TestBreaker_SuccessResetsFailures(t *testing.T) {
+	b, _ := newTestBreaker(Options{Threshold: 2})
+	b.Failure()
+	b.Success()
+	assert.False(t, b.Failure(), "failures must be consecutive")
+	assert.Equal(t, Closed, b.Status().State)
+}
+
+func TestBreaker_LateFailureDoesNotExtendOpenPeriod(t *testing.T) {
+	b, now := newTestBreaker(Options{Threshold: 1, BaseDelay: time.Second})
+	assert.True(t, b.Failure())
+	retryAt := b.Status().RetryAt
+	assert.False(t, b.Failure())
+	assert.Equal(t, retryAt, b.Status().RetryAt)
+	assert.Equal(t, now.Add(time.Second), retryAt)
+}
+
+func TestNew_Defaults(t *testing.T) {
+	b := New(Options{})
+	assert.Equal(t, DefaultThreshold, b.threshold)
+	assert.Equal(t, DefaultBaseDelay, b.baseDelay)
+	assert.Equal(t, DefaultMaxDelay, b.maxDelay)
+
+	b = New(Options{BaseDelay: 10 * time.Minute})
+	assert.Equal(t, 10*time.Minute, b.maxDelay, "max delay is at least the base delay")
+}
+
+func TestEqualJitter(t *testing.T) {
+	for range 100 {
+		d := equalJitter(10 * time.Second)
+		assert.GreaterOrEqual(t, d, 5*time.Second)
+		assert.Less(t, d, 10*time.Second)
+	}
+	assert.Equal(t, time.Duration(1), equalJitter(1))
+}
+
+func TestOptions_Validate(t *testing.T) {
+	assert.NoError(t, (&Options{}).Validate())
+	assert.NoError(t, (&Options{Threshold: 5, BaseDelay: time.Second, MaxDelay: time.Minute}).Validate())
+	assert.Error(t, (&Options{Threshold: -1}).Validate())
+	assert.Error(t, (&Options{BaseDelay: -time.Second}).Validate())
+	assert.Error(t, (&Options{BaseDelay: time.Minute, MaxDelay: time.Second}).Validate())
+}
+
+func TestState_String(t *testing.T) {
+	assert.Equal(t, "closed", Closed.String())
+	assert.Equal(t, "half_open", HalfOpen.String())
+	assert.Equal(t, "open", Open.String())
+}
@@ -14,6 +14,7 @@ import (
 //nolint:govet // fieldalignment: field order has been optimized, but not further adjusted to maintain API compatibility
 type Locker struct {
 	Cache      *redis.Client       // Redis client, if nil then use local lock
+	Namespace  Namespace           // prefix of the lock keys, so deployments sharing a Redis do not block each other
 	hybridLock rediskitlock.Locker // Hybrid locker from redis-kit (auto-fallback to local lock)
 }
 
@@ -23,7 +24,7 @@ type Locker struct {
 // if Redis is unavailable. Lock default expiration time is DefaultLockTime seconds.
 //
 // Parameters:
-//   - key: lock key name, used to identify different locks
+//   - key: lock key name, used to identify different locks; stored below the Namespace
 //
 // Returns:
 //   - success: true means successfully acquired lock, false means lock is held by another process
@@ -37,7 +38,7 @@ func (s *Locker) Lock(key string) (success bool, err error) {
 	if s.hybridLock == nil {
 		s.hybridLock = rediskitlock.NewHybridLocker(s.Cache)
 	}
-	return s.hybridLock.Lock(key)
+	return s.hybridLock.Lock(s.Namespace.Key(key))
 }
 
 // Unlock releases distributed lock
@@ -59,5 +60,5 @@ func (s *Locker) Unlock(key string) error {
 	if s.hybridLock == nil {
 		s.hybridLock = rediskitlock.NewHybridLocker(s.Cache)
 	}
-	return s.hybridLock.Unlock(key)
+	return s.hybridLock.Unlock(s.Namespace.Key(key))
 }
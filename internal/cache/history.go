@@ -0,0 +1,334 @@
+package cache
+
+import (
+	// Standard library
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	// Third-party libraries
+	"github.com/redis/go-redis/v9"
+
+	// Internal packages
+	"github.com/soulteary/warden/internal/define"
+)
+
+const (
+	// REDIS_DATASET_PREFIX prefix of the dataset history keys below the namespace: <prefix>:seq issues
+	// version numbers, <prefix>:index lists the kept datasets, <prefix>:<version> holds the users of one
+	// version and <prefix>:pinned the pinned version
+	REDIS_DATASET_PREFIX = "datasets"
+
+	// DefaultDatasetHistory is the default number of applied datasets kept for rollback
+	DefaultDatasetHistory = 5
+	// MaxDatasetHistory is the largest accepted history; every kept dataset is a full (encoded) copy of
+	// the user list
+	MaxDatasetHistory = 100
+)
+
+// ErrDatasetNotFound is returned when a dataset version is not (or no longer) in the history.
+var ErrDatasetNotFound = errors.New("dataset version not found")
+
+// Dataset describes one applied dataset.
+type Dataset struct {
+	Version   int64     `json:"version"`
+	Hash      string    `json:"hash"`
+	Users     int       `json:"users"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// DatasetHistory keeps the last applied datasets so that a previous one can be applied again, and the
+// version pinned by a rollback. Datasets are stored in the payload encoding of the Redis cache. With Redis
+// the history and the pin are shared by all instances (the leader records, any instance may roll back) and
+// only the metadata of the datasets is kept in memory, their users are read on Get; without Redis the
+// encoded datasets live in memory.
+type DatasetHistory struct {
+	client   redis.UniversalClient
+	ns       Namespace
+	size     int
+	encoding string
+
+	mu       sync.Mutex
+	datasets []Dataset        // newest first
+	payloads map[int64][]byte // encoded users by version, without Redis only
+	seq      int64
+	pinned   int64
+}
+
+// NewDatasetHistory creates a history of size datasets (0 = DefaultDatasetHistory) kept in the namespace
+// ns and stored in encoding (see ValidatePayloadEncoding); client may be nil. Returns nil when size is
+// negative (history disabled).
+func NewDatasetHistory(client redis.UniversalClient, ns Namespace, size int, encoding string) *DatasetHistory {
+	if size < 0 {
+		return nil
+	}
+	if size == 0 {
+		size = DefaultDatasetHistory
+	}
+	return &DatasetHistory{
+		client:   client,
+		ns:       ns,
+		size:     size,
+		encoding: normalizePayloadEncoding(encoding),
+		payloads: make(map[int64][]byte),
+	}
+}
+
+func (h *DatasetHistory) key(suffix string) string {
+	return h.ns.Key(REDIS_DATASET_PREFIX + ":" + suffix)
+}
+
+// Record adds users (whose SafeUserCache hash is hash) as the newest dataset and drops the oldest ones
+// beyond the history size. A dataset with the hash of the newest one is not recorded again; the newest
+// one is returned instead.
+func (h *DatasetHistory) Record(ctx context.Context, users []define.AllowListUser, hash string) (Dataset, error) {
+	return h.RecordFenced(ctx, users, hash, 0)
+}
+
+// RecordFenced is Record for the leader with fencing token token (see LeaderElector.Fence): the version
+// and the index are only written if no leader with a higher token has written them; ErrFenced otherwise.
+// A token of 0 writes unfenced.
+func (h *DatasetHistory) RecordFenced(ctx context.Context, users []define.AllowListUser, hash string, token int64) (Dataset, error) {
+	datasets, err := h.List(ctx)
+	if err != nil {
+		return Dataset{}, err
+	}
+	if len(datasets) > 0 && datasets[0].Hash == hash {
+		return datasets[0], nil
+	}
+	payload, err := encodeUsers(users, h.encoding)
+	if err != nil {
+		return Dataset{}, fmt.Errorf("dataset history: encode users: %w", err)
+	}
+
+	ds := Dataset{Hash: hash, Users: len(users), AppliedAt: time.Now().UTC()}
+	if h.client != nil {
+		if ds.Version, err = h.nextVersion(ctx, token); err != nil {
+			return Dataset{}, err
+		}
+		if err := h.client.Set(ctx, h.usersKey(ds.Version), payload, 0).Err(); err != nil {
+			return Dataset{}, fmt.Errorf("dataset history: write users: %w", err)
+		}
+	} else {
+		h.mu.Lock()
+		h.seq++
+		ds.Version = h.seq
+		h.mu.Unlock()
+	}
+
+	datasets = append([]Dataset{ds}, datasets...)
+	var dropped []Dataset
+	if len(datasets) > h.size {
+		datasets, dropped = datasets[:h.size], datasets[h.size:]
+	}
+	if h.client != nil {
+		if err := h.writeIndex(ctx, datasets, token); err != nil {
+			// The users of the unlisted version would be orphaned
+			_ = h.client.Del(ctx, h.usersKey(ds.Version)).Err() //nolint:errcheck // best effort
+			return Dataset{}, err
+		}
+		for _, old := range dropped {
+			// A failed delete leaves an orphaned key behind; it is no longer listed
+			_ = h.client.Del(ctx, h.usersKey(old.Version)).Err() //nolint:errcheck // best effort
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.datasets = datasets
+	if h.client == nil {
+		h.payloads[ds.Version] = payload
+		for v := range h.payloads {
+			if !containsVersion(datasets, v) {
+				delete(h.payloads, v)
+			}
+		}
+	}
+	return ds, nil
+}
+
+func (h *DatasetHistory) usersKey(version int64) string {
+	return h.key(strconv.FormatInt(version, 10))
+}
+
+// nextVersion allocates a version number in Redis, fenced with token when it is not 0.
+func (h *DatasetHistory) nextVersion(ctx context.Context, token int64) (int64, error) {
+	key := h.key("seq")
+	if token == 0 {
+		version, err := h.client.Incr(ctx, key).Result()
+		if err != nil {
+			return 0, fmt.Errorf("dataset history: allocate version: %w", err)
+		}
+		return version, nil
+	}
+	version, err := fencedIncrScript.Run(ctx, h.client, []string{key, fenceKey(key)}, token).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("dataset history: allocate version: %w", err)
+	}
+	if version < 0 {
+		return 0, ErrFenced
+	}
+	return version, nil
+}
+
+// writeIndex stores the list of kept datasets in Redis, fenced with token when it is not 0.
+func (h *DatasetHistory) writeIndex(ctx context.Context, datasets []Dataset, token int64) error {
+	index, err := json.Marshal(datasets)
+	if err != nil {
+		return fmt.Errorf("dataset history: encode index: %w", err)
+	}
+	key := h.key("index")
+	if token == 0 {
+		if err := h.client.Set(ctx, key, index, 0).Err(); err != nil {
+			return fmt.Errorf("dataset history: write index: %w", err)
+		}
+		return nil
+	}
+	written, err := fencedSetScript.Run(ctx, h.client, []string{key, fenceKey(key)}, token, index, 0).Int64()
+	if err != nil {
+		return fmt.Errorf("dataset history: write index: %w", err)
+	}
+	if written == 0 {
+		return ErrFenced
+	}
+	return nil
+}
+
+// List returns the kept datasets, newest first. With Redis the shared history is read; the in-memory
+// copy is returned when Redis fails.
+func (h *DatasetHistory) List(ctx context.Context) ([]Dataset, error) {
+	if h.client != nil {
+		raw, err := h.client.Get(ctx, h.key("index")).Bytes()
+		switch {
+		case errors.Is(err, redis.Nil):
+			return []Dataset{}, nil
+		case err == nil:
+			var datasets []Dataset
+			if err := json.Unmarshal(raw, &datasets); err != nil {
+				return nil, fmt.Errorf("dataset history: decode index: %w", err)
+			}
+			return datasets, nil
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Dataset{}, h.datasets...), nil
+}
+
+// Get returns a kept dataset and its users, or ErrDatasetNotFound. With Redis the users are read from it.
+func (h *DatasetHistory) Get(ctx context.Context, version int64) (Dataset, []define.AllowListUser, error) {
+	datasets, err := h.List(ctx)
+	if err != nil {
+		return Dataset{}, nil, err
+	}
+	var ds Dataset
+	for _, d := range datasets {
+		if d.Version == version {
+			ds = d
+			break
+		}
+	}
+	if ds.Version == 0 {
+		return Dataset{}, nil, ErrDatasetNotFound
+	}
+
+	var payload []byte
+	if h.client != nil {
+		payload, err = h.client.Get(ctx, h.usersKey(version)).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return Dataset{}, nil, ErrDatasetNotFound
+		}
+		if err != nil {
+			return Dataset{}, nil, fmt.Errorf("dataset history: read users: %w", err)
+		}
+	} else {
+		h.mu.Lock()
+		payload = h.payloads[version]
+		h.mu.Unlock()
+		if payload == nil {
+			return Dataset{}, nil, ErrDatasetNotFound
+		}
+	}
+	users, err := decodeUsers(payload)
+	if err != nil {
+		return Dataset{}, nil, fmt.Errorf("dataset history: decode users: %w", err)
+	}
+	return ds, users, nil
+}
+
+// VersionOf returns the version of the newest kept dataset with hash, 0 when none has it.
+func (h *DatasetHistory) VersionOf(ctx context.Context, hash string) int64 {
+	datasets, err := h.List(ctx)
+	if err != nil || hash == "" {
+		return 0
+	}
+	for _, d := range datasets {
+		if d.Hash == hash {
+			return d.Version
+		}
+	}
+	return 0
+}
+
+// Pin marks a kept dataset as pinned: it stays applied, and the sources are not loaded, until Unpin.
+func (h *DatasetHistory) Pin(ctx context.Context, version int64) error {
+	if _, _, err := h.Get(ctx, version); err != nil {
+		return err
+	}
+	if h.client != nil {
+		if err := h.client.Set(ctx, h.key("pinned"), version, 0).Err(); err != nil {
+			return fmt.Errorf("dataset history: pin: %w", err)
+		}
+	}
+	h.mu.Lock()
+	h.pinned = version
+	h.mu.Unlock()
+	return nil
+}
+
+// Unpin removes the pin.
+func (h *DatasetHistory) Unpin(ctx context.Context) error {
+	if h.client != nil {
+		if err := h.client.Del(ctx, h.key("pinned")).Err(); err != nil {
+			return fmt.Errorf("dataset history: unpin: %w", err)
+		}
+	}
+	h.mu.Lock()
+	h.pinned = 0
+	h.mu.Unlock()
+	return nil
+}
+
+// Pinned returns the pinned version, 0 when none is. With Redis the shared pin is read; the last known
+// pin of this instance is returned when Redis fails.
+func (h *DatasetHistory) Pinned(ctx context.Context) int64 {
+	if h.client != nil {
+		version, err := h.client.Get(ctx, h.key("pinned")).Int64()
+		switch {
+		case errors.Is(err, redis.Nil):
+			version = 0
+			fallthrough
+		case err == nil:
+			h.mu.Lock()
+			h.pinned = version
+			h.mu.Unlock()
+			return version
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.pinned
+}
+
+func containsVersion(datasets []Dataset, version int64) bool {
+	for _, d := range datasets {
+		if d.Version == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/internal/testutil"
+)
+
+func datasetUsers(phones ...string) []define.AllowListUser {
+	users := make([]define.AllowListUser, len(phones))
+	for i, phone := range phones {
+		users[i] = define.AllowListUser{Phone: phone}
+	}
+	return users
+}
+
+func TestNewDatasetHistory_Disabled(t *testing.T) {
+	assert.Nil(t, NewDatasetHistory(nil, "", -1, ""))
+	assert.Equal(t, DefaultDatasetHistory, NewDatasetHistory(nil, "", 0, "").size)
+}
+
+func TestDatasetHistory_Memory(t *testing.T) {
+	ctx := context.Background()
+	h := NewDatasetHistory(nil, "", 2, "")
+
+	first, err := h.Record(ctx, datasetUsers("13800138000"), "h1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), first.Version)
+	assert.Equal(t, 1, first.Users)
+
+	// The same content is not recorded twice
+	again, err := h.Record(ctx, datasetUsers("13800138000"), "h1")
+	require.NoError(t, err)
+	assert.Equal(t, first, again)
+
+	_, err = h.Record(ctx, datasetUsers("13800138001"), "h2")
+	require.NoError(t, err)
+	third, err := h.Record(ctx, datasetUsers("13800138002"), "h3")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), third.Version)
+
+	datasets, err := h.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, datasets, 2)
+	assert.Equal(t, []int64{3, 2}, []int64{datasets[0].Version, datasets[1].Version})
+
+	_, _, err = h.Get(ctx, 1)
+	assert.ErrorIs(t, err, ErrDatasetNotFound)
+	ds, users, err := h.Get(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "h2", ds.Hash)
+	assert.Equal(t, "13800138001", users[0].Phone)
+
+	assert.Equal(t, int64(2), h.VersionOf(ctx, "h2"))
+	assert.Zero(t, h.VersionOf(ctx, "h1"))
+
+	assert.ErrorIs(t, h.Pin(ctx, 1), ErrDatasetNotFound)
+	require.NoError(t, h.Pin(ctx, 2))
+	assert.Equal(t, int64(2), h.Pinned(ctx))
+	require.NoError(t, h.Unpin(ctx))
+	assert.Zero(t, h.Pinned(ctx))
+}
+
+func TestDatasetHistory_Redis(t *testing.T) {
+	ctx := context.Background()
+	client, server := newFakeRedisClientServer(t)
+	leader := NewDatasetHistory(client, "", 2, PayloadZstd)
+
+	for i, phone := range []string{"13800138000", "13800138001", "13800138002"} {
+		ds, err := leader.Record(ctx, datasetUsers(phone), "h"+phone)
+		require.NoError(t, err)
+		assert.Equal(t, int64(i+1), ds.Version)
+	}
+
+	// The dropped dataset is deleted from Redis, the kept ones are stored in the payload encoding and
+	// only their metadata is kept in memory
+	server.Mu.Lock()
+	kept, ok := server.Data[leader.key("3")]
+	_, dropped := server.Data[leader.key("1")]
+	server.Mu.Unlock()
+	require.True(t, ok)
+	assert.Equal(t, []byte{payloadMarker, payloadZstdByte}, []byte(kept[:2]))
+	assert.False(t, dropped)
+	assert.Empty(t, leader.payloads)
+
+	// Another instance sees the shared history, reads users from Redis and shares the pin
+	other := NewDatasetHistory(client, "", 2, "")
+	datasets, err := other.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, datasets, 2)
+	ds, users, err := other.Get(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "h13800138001", ds.Hash)
+	require.Len(t, users, 1)
+	assert.Equal(t, "13800138001", users[0].Phone)
+
+	require.NoError(t, other.Pin(ctx, 2))
+	assert.Equal(t, int64(2), leader.Pinned(ctx))
+	require.NoError(t, leader.Unpin(ctx))
+	assert.Zero(t, other.Pinned(ctx))
+}
+
+func TestDatasetHistory_RedisUnreachable(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newFakeRedisClientServer(t)
+	h := NewDatasetHistory(client, "", 2, "")
+	_, err := h.Record(ctx, datasetUsers("13800138000"), "h1")
+	require.NoError(t, err)
+	require.NoError(t, h.Pin(ctx, 1))
+
+	unreachable := redis.NewClient(&redis.Options{
+		Addr:       "unreachable",
+		MaxRetries: -1,
+		Dialer: func(context.Context, string, string) (net.Conn, error) {
+			return nil, errors.New("connection refused")
+		},
+	})
+	t.Cleanup(func() { _ = unreachable.Close() }) //nolint:errcheck // test cleanup
+	h.client = unreachable
+
+	// The metadata and the pin answer from memory, the users are only kept in Redis
+	datasets, err := h.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, datasets, 1)
+	assert.Equal(t, int64(1), h.Pinned(ctx))
+	_, _, err = h.Get(ctx, 1)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrDatasetNotFound)
+}
+
+func TestDatasetHistory_RecordFenced(t *testing.T) {
+	ctx := context.Background()
+	client, server := testutil.NewMiniRedisClient(t)
+	h := NewDatasetHistory(client, "", 2, "")
+
+	ds, err := h.RecordFenced(ctx, datasetUsers("13800138000"), "h1", 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), ds.Version)
+
+	// A superseded leader neither allocates a version nor changes the index
+	_, err = h.RecordFenced(ctx, datasetUsers("13800138001"), "h2", 1)
+	require.ErrorIs(t, err, ErrFenced)
+	seq, err := server.Get(h.key("seq"))
+	require.NoError(t, err)
+	assert.Equal(t, "1", seq)
+	datasets, err := h.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, datasets, 1)
+	assert.Equal(t, "h1", datasets[0].Hash)
+
+	// The current leader records, and a write of the index with the older token is still rejected
+	ds, err = h.RecordFenced(ctx, datasetUsers("13800138001"), "h2", 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), ds.Version)
+	require.ErrorIs(t, h.writeIndex(ctx, nil, 1), ErrFenced)
+	assert.True(t, server.Exists(h.key("2")))
+}
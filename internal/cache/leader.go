@@ -0,0 +1,265 @@
+package cache
+
+import (
+	// Standard library
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	// Third-party libraries
+	"github.com/redis/go-redis/v9"
+
+	// Internal packages
+	"github.com/soulteary/warden/internal/prommetrics"
+)
+
+const (
+	// Leader keys, below the namespace (see Namespace.Key)
+
+	// REDIS_LEADER_KEY Redis key holding the id of the leader; expires unless renewed
+	REDIS_LEADER_KEY = "leader"
+	// REDIS_LEADER_TOKEN_KEY Redis counter issuing a fencing token to every new leader
+	REDIS_LEADER_TOKEN_KEY = "leader:token"
+
+	// DefaultLeaderLease is how long leadership lasts without renewal; it is renewed every third of it
+	DefaultLeaderLease = 15 * time.Second
+	// MinLeaderLease is the shortest accepted lease; shorter ones leave no room for a renewal round trip
+	MinLeaderLease = time.Second
+)
+
+var (
+	// ErrNotLeader is returned by Fence when this instance does not hold the leadership.
+	ErrNotLeader = errors.New("not the leader")
+	// ErrFenced is returned by fenced writes (and Fence) when a newer leader has already written to Redis.
+	ErrFenced = errors.New("fencing token superseded by a newer leader")
+)
+
+// Lua scripts run atomically in Redis.
+const (
+	// renewLeaseLua extends the lease if it is still held by ARGV[1]
+	renewLeaseLua = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`
+	// releaseLeaseLua deletes the lease if it is still held by ARGV[1]
+	releaseLeaseLua = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`
+	// fencedSetLua sets KEYS[1] to ARGV[2] with a TTL of ARGV[3] ms (0 = none), unless the fence KEYS[2]
+	// holds a token higher than ARGV[1], which it then records; returns 0 when fenced
+	fencedSetLua = `local current = tonumber(redis.call("GET", KEYS[2]) or "0")
+if current > tonumber(ARGV[1]) then
+	return 0
+end
+redis.call("SET", KEYS[2], ARGV[1])
+if tonumber(ARGV[3]) > 0 then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+else
+	redis.call("SET", KEYS[1], ARGV[2])
+end
+return 1`
+	// fencedSwitchLua is GETSET KEYS[1] ARGV[2] for an integer key, fenced like fencedSetLua; returns the
+	// previous value (0 when unset), or -1 when fenced
+	fencedSwitchLua = `local current = tonumber(redis.call("GET", KEYS[2]) or "0")
+if current > tonumber(ARGV[1]) then
+	return -1
+end
+redis.call("SET", KEYS[2], ARGV[1])
+return tonumber(redis.call("GETSET", KEYS[1], ARGV[2]) or "0")`
+	// fencedIncrLua is INCR KEYS[1], fenced like fencedSetLua; returns the new value, or -1 when fenced
+	fencedIncrLua = `local current = tonumber(redis.call("GET", KEYS[2]) or "0")
+if current > tonumber(ARGV[1]) then
+	return -1
+end
+redis.call("SET", KEYS[2], ARGV[1])
+return redis.call("INCR", KEYS[1])`
+)
+
+var (
+	renewLeaseScript   = redis.NewScript(renewLeaseLua)
+	releaseLeaseScript = redis.NewScript(releaseLeaseLua)
+	fencedSetScript    = redis.NewScript(fencedSetLua)
+	fencedSwitchScript = redis.NewScript(fencedSwitchLua)
+	fencedIncrScript   = redis.NewScript(fencedIncrLua)
+)
+
+// LeaderElector elects one instance among those sharing a Redis as the leader, which alone loads the
+// sources and writes the user cache. Leadership is a Redis key set with NX and a lease that the leader
+// renews every lease/3; when the leader stops renewing, another instance takes over after the lease
+// expires. Every new leader gets a fencing token from a Redis counter, and the writes run through Fence
+// are rejected once a leader with a higher token has written, e.g. after a pause longer than the lease.
+type LeaderElector struct {
+	client   redis.UniversalClient
+	ns       Namespace
+	instance string
+	lease    time.Duration
+	onChange func(leader bool)
+
+	mu        sync.RWMutex
+	leader    bool
+	token     int64
+	reachable bool // whether the last election round reached Redis
+}
+
+// NewLeaderElector creates an elector for the instance with the given id (see DefaultInstanceID); each
+// namespace elects its own leader. lease <= 0 uses DefaultLeaderLease.
+func NewLeaderElector(client redis.UniversalClient, ns Namespace, instance string, lease time.Duration) *LeaderElector {
+	if lease <= 0 {
+		lease = DefaultLeaderLease
+	}
+	return &LeaderElector{client: client, ns: ns, instance: instance, lease: lease}
+}
+
+// OnChange sets a function called when this instance gains (true) or loses (false) the leadership.
+// It must be set before Campaign or Run is called.
+func (e *LeaderElector) OnChange(fn func(leader bool)) {
+	e.onChange = fn
+}
+
+// Instance returns the id of this instance.
+func (e *LeaderElector) Instance() string {
+	return e.instance
+}
+
+// IsLeader reports whether this instance currently holds the leadership.
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// Token returns the fencing token of the current leadership (0 when not leader).
+func (e *LeaderElector) Token() int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.token
+}
+
+// Reachable reports whether the last election round reached Redis.
+func (e *LeaderElector) Reachable() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.reachable
+}
+
+// Leader returns the id of the instance holding the leadership ("" when there is none).
+func (e *LeaderElector) Leader(ctx context.Context) (string, error) {
+	id, err := e.client.Get(ctx, e.ns.Key(REDIS_LEADER_KEY)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return id, err
+}
+
+// Campaign runs one election round: the leader renews its lease, other instances try to acquire it.
+// A leader that cannot renew (lease lost or Redis error) steps down at once.
+func (e *LeaderElector) Campaign(ctx context.Context) {
+	if e.IsLeader() {
+		renewed, err := renewLeaseScript.Run(ctx, e.client, []string{e.ns.Key(REDIS_LEADER_KEY)}, e.instance, e.lease.Milliseconds()).Int64()
+		e.setReachable(err == nil)
+		if err != nil || renewed == 0 {
+			if err != nil {
+				log.Warn().Err(err).Str("instance", e.instance).Msg("Failed to renew leader lease")
+			}
+			e.setLeader(false, 0)
+		}
+		return
+	}
+
+	acquired, err := e.client.SetNX(ctx, e.ns.Key(REDIS_LEADER_KEY), e.instance, e.lease).Result()
+	e.setReachable(err == nil)
+	if err != nil || !acquired {
+		return
+	}
+	token, err := e.client.Incr(ctx, e.ns.Key(REDIS_LEADER_TOKEN_KEY)).Result()
+	if err != nil {
+		// Without a token the leadership cannot be fenced; give it up for the next round
+		log.Warn().Err(err).Str("instance", e.instance).Msg("Failed to obtain fencing token")
+		e.release(ctx)
+		return
+	}
+	e.setLeader(true, token)
+}
+
+// Run campaigns every lease/3 until ctx is done, then releases the leadership so another instance can
+// take over without waiting for the lease to expire.
+func (e *LeaderElector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.lease / 3)
+	defer ticker.Stop()
+	for {
+		roundCtx, cancel := context.WithTimeout(ctx, REDIS_OPERATION_TIMEOUT)
+		e.Campaign(roundCtx)
+		cancel()
+		select {
+		case <-ctx.Done():
+			releaseCtx, cancel := context.WithTimeout(context.Background(), REDIS_OPERATION_TIMEOUT)
+			e.Resign(releaseCtx)
+			cancel()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Resign gives up the leadership, if held.
+func (e *LeaderElector) Resign(ctx context.Context) {
+	if !e.IsLeader() {
+		return
+	}
+	e.release(ctx)
+	e.setLeader(false, 0)
+}
+
+// release deletes the lease if this instance still holds it.
+func (e *LeaderElector) release(ctx context.Context) {
+	if err := releaseLeaseScript.Run(ctx, e.client, []string{e.ns.Key(REDIS_LEADER_KEY)}, e.instance).Err(); err != nil {
+		log.Debug().Err(err).Str("instance", e.instance).Msg("Failed to release leader lease")
+	}
+}
+
+// Fence runs write, which must be fenced with the given token of this leadership (see
+// RedisUserCache.SetFenced, RedisUserStore.SetFenced and DatasetHistory.RecordFenced). When write fails with ErrFenced, a newer leader
+// has already written and this instance steps down. Returns ErrNotLeader without calling write when not
+// leader.
+func (e *LeaderElector) Fence(write func(token int64) error) error {
+	e.mu.RLock()
+	leader, token := e.leader, e.token
+	e.mu.RUnlock()
+	if !leader {
+		return ErrNotLeader
+	}
+	err := write(token)
+	if errors.Is(err, ErrFenced) {
+		e.setLeader(false, 0)
+	}
+	return err
+}
+
+// fenceKey returns the key holding the highest fencing token that wrote key. Its hash tag puts it in the
+// cluster slot of key, so that the fence check and the write run in one script.
+func fenceKey(key string) string {
+	return "{" + key + "}:fence"
+}
+
+func (e *LeaderElector) setReachable(reachable bool) {
+	e.mu.Lock()
+	e.reachable = reachable
+	e.mu.Unlock()
+}
+
+// setLeader records a leadership change and calls onChange when the role changed.
+func (e *LeaderElector) setLeader(leader bool, token int64) {
+	e.mu.Lock()
+	changed := e.leader != leader
+	e.leader, e.token = leader, token
+	e.mu.Unlock()
+	if !changed {
+		return
+	}
+	prommetrics.RecordLeadership(leader)
+	if e.onChange != nil {
+		e.onChange(leader)
+	}
+}
@@ -0,0 +1,267 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/internal/testutil"
+)
+
+func TestLeaderElector_Campaign(t *testing.T) {
+	client, server := testutil.NewMiniRedisClient(t)
+	ctx := context.Background()
+	a := NewLeaderElector(client, "", "a", 0)
+	b := NewLeaderElector(client, "", "b", time.Second)
+	assert.Equal(t, DefaultLeaderLease, a.lease)
+	assert.Equal(t, "a", a.Instance())
+	leaseKey := DefaultNamespace.Key(REDIS_LEADER_KEY)
+
+	var changes []bool
+	a.OnChange(func(leader bool) { changes = append(changes, leader) })
+
+	a.Campaign(ctx)
+	b.Campaign(ctx)
+	assert.True(t, a.IsLeader())
+	assert.True(t, a.Reachable())
+	assert.Equal(t, int64(1), a.Token())
+	assert.False(t, b.IsLeader())
+	assert.True(t, b.Reachable())
+	assert.Equal(t, int64(0), b.Token())
+	holder, err := b.Leader(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "a", holder)
+
+	// The leader renews its lease; the follower keeps waiting
+	server.FastForward(DefaultLeaderLease / 2)
+	a.Campaign(ctx)
+	b.Campaign(ctx)
+	assert.True(t, a.IsLeader())
+	assert.False(t, b.IsLeader())
+	assert.Equal(t, DefaultLeaderLease, server.TTL(leaseKey))
+
+	// The lease expires and b takes over with a higher token; a steps down on its next renewal
+	server.FastForward(DefaultLeaderLease)
+	b.Campaign(ctx)
+	a.Campaign(ctx)
+	assert.True(t, b.IsLeader())
+	assert.Equal(t, int64(2), b.Token())
+	assert.False(t, a.IsLeader())
+	assert.Equal(t, []bool{true, false}, changes)
+	holder, err = a.Leader(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "b", holder, "the renewal of a did not touch the lease of b")
+	assert.Equal(t, time.Second, server.TTL(leaseKey))
+
+	// Resign releases the lease for the next candidate
+	b.Resign(ctx)
+	assert.False(t, b.IsLeader())
+	holder, err = a.Leader(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, holder)
+	a.Campaign(ctx)
+	assert.True(t, a.IsLeader())
+	assert.Equal(t, int64(3), a.Token())
+}
+
+// TestLeaderElector_LeaseScripts tests that only the holder of the lease renews or releases it
+func TestLeaderElector_LeaseScripts(t *testing.T) {
+	client, server := testutil.NewMiniRedisClient(t)
+	ctx := context.Background()
+	key := DefaultNamespace.Key(REDIS_LEADER_KEY)
+	require.NoError(t, client.Set(ctx, key, "a", time.Second).Err())
+
+	renewed, err := renewLeaseScript.Run(ctx, client, []string{key}, "b", 60000).Int64()
+	require.NoError(t, err)
+	assert.Zero(t, renewed)
+	assert.Equal(t, time.Second, server.TTL(key))
+	released, err := releaseLeaseScript.Run(ctx, client, []string{key}, "b").Int64()
+	require.NoError(t, err)
+	assert.Zero(t, released)
+	assert.True(t, server.Exists(key))
+
+	renewed, err = renewLeaseScript.Run(ctx, client, []string{key}, "a", 60000).Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), renewed)
+	assert.Equal(t, time.Minute, server.TTL(key))
+	released, err = releaseLeaseScript.Run(ctx, client, []string{key}, "a").Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), released)
+	assert.False(t, server.Exists(key))
+
+	// Nothing to renew once the lease expired
+	renewed, err = renewLeaseScript.Run(ctx, client, []string{key}, "a", 60000).Int64()
+	require.NoError(t, err)
+	assert.Zero(t, renewed)
+	assert.False(t, server.Exists(key))
+}
+
+// TestLeaderElector_FenceScripts tests that the fenced writes reject a token lower than the highest one
+// that wrote, and leave the data untouched then
+func TestLeaderElector_FenceScripts(t *testing.T) {
+	client, server := testutil.NewMiniRedisClient(t)
+	ctx := context.Background()
+	key := DefaultNamespace.Key(REDIS_CACHE_KEY)
+	fence := fenceKey(key)
+
+	written, err := fencedSetScript.Run(ctx, client, []string{key, fence}, 2, "second", 60000).Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), written)
+	assert.Equal(t, time.Minute, server.TTL(key))
+	written, err = fencedSetScript.Run(ctx, client, []string{key, fence}, 1, "stale", 60000).Int64()
+	require.NoError(t, err)
+	assert.Zero(t, written, "a stale token is rejected")
+	value, err := server.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, "second", value)
+	written, err = fencedSetScript.Run(ctx, client, []string{key, fence}, 2, "again", 60000).Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), written, "the current leader writes again")
+	fenceValue, err := server.Get(fence)
+	require.NoError(t, err)
+	assert.Equal(t, "2", fenceValue)
+
+	gen := DefaultNamespace.Key("users:store:gen")
+	genFence := fenceKey(gen)
+	previous, err := fencedSwitchScript.Run(ctx, client, []string{gen, genFence}, 3, 7).Int64()
+	require.NoError(t, err)
+	assert.Zero(t, previous, "no generation before")
+	previous, err = fencedSwitchScript.Run(ctx, client, []string{gen, genFence}, 3, 8).Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), previous)
+	previous, err = fencedSwitchScript.Run(ctx, client, []string{gen, genFence}, 2, 9).Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), previous, "a stale token does not switch the generation")
+	value, err = server.Get(gen)
+	require.NoError(t, err)
+	assert.Equal(t, "8", value)
+}
+
+func TestLeaderElector_Namespaces(t *testing.T) {
+	client, _ := testutil.NewMiniRedisClient(t)
+	ctx := context.Background()
+	a := NewLeaderElector(client, "team-a", "a", 0)
+	b := NewLeaderElector(client, "team-b", "b", 0)
+
+	// Each namespace elects its own leader
+	a.Campaign(ctx)
+	b.Campaign(ctx)
+	assert.True(t, a.IsLeader())
+	assert.True(t, b.IsLeader())
+	holder, err := b.Leader(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "b", holder)
+}
+
+func TestLeaderElector_Fence(t *testing.T) {
+	client, server := testutil.NewMiniRedisClient(t)
+	ctx := context.Background()
+	a := NewLeaderElector(client, "", "a", 0)
+	b := NewLeaderElector(client, "", "b", 0)
+	blob := NewRedisUserCache(client, "", 0, "")
+	store := NewRedisUserStore(client, "", 0, 0, nil)
+	users := []define.AllowListUser{{Phone: "13800138000"}}
+	writeBoth := func(token int64) error {
+		if err := blob.SetFenced(users, token); err != nil {
+			return err
+		}
+		return store.SetFenced(users, token)
+	}
+
+	require.ErrorIs(t, a.Fence(func(int64) error {
+		t.Fatal("write without leadership")
+		return nil
+	}), ErrNotLeader)
+
+	a.Campaign(ctx)
+	require.NoError(t, a.Fence(writeBoth))
+	require.NoError(t, a.Fence(writeBoth))
+	version, err := blob.GetVersion()
+	require.NoError(t, err)
+	gen, err := store.GetVersion()
+	require.NoError(t, err)
+
+	// a is paused beyond its lease while b takes over and writes
+	server.FastForward(DefaultLeaderLease + time.Second)
+	b.Campaign(ctx)
+	require.True(t, b.IsLeader())
+	users = []define.AllowListUser{{Phone: "13900139000"}}
+	require.NoError(t, b.Fence(writeBoth))
+
+	// a still believes it leads, but its token is stale: neither layout is written
+	assert.True(t, a.IsLeader())
+	users = []define.AllowListUser{{Phone: "13700137000"}}
+	require.ErrorIs(t, a.Fence(func(token int64) error { return blob.SetFenced(users, token) }), ErrFenced)
+	assert.False(t, a.IsLeader())
+	a.setLeader(true, 1)
+	require.ErrorIs(t, a.Fence(func(token int64) error { return store.SetFenced(users, token) }), ErrFenced)
+	assert.False(t, a.IsLeader())
+
+	got, err := blob.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "13900139000", got[0].Phone)
+	newVersion, err := blob.GetVersion()
+	require.NoError(t, err)
+	assert.Equal(t, version+1, newVersion, "the fenced write does not bump the version")
+	_, ok := store.GetByPhone("13900139000")
+	assert.True(t, ok)
+	newGen, err := store.GetVersion()
+	require.NoError(t, err)
+	assert.Greater(t, newGen, gen)
+	for _, key := range server.Keys() {
+		assert.NotContains(t, key, "13700137000", "the fenced generation is deleted")
+	}
+
+	// The fence keys share the hash tag of the keys they guard
+	assert.Equal(t, "{warden:users:cache}:fence", fenceKey(DefaultNamespace.Key(REDIS_CACHE_KEY)))
+}
+
+func TestLeaderElector_Unreachable(t *testing.T) {
+	client := redis.NewClient(&redis.Options{
+		Addr:       "unreachable",
+		MaxRetries: -1,
+		Dialer: func(context.Context, string, string) (net.Conn, error) {
+			return nil, errors.New("connection refused")
+		},
+	})
+	t.Cleanup(func() { _ = client.Close() }) //nolint:errcheck // test cleanup
+	e := NewLeaderElector(client, "", "a", 0)
+
+	e.Campaign(context.Background())
+	assert.False(t, e.IsLeader())
+	assert.False(t, e.Reachable())
+}
+
+func TestLeaderElector_RunResignsOnCancel(t *testing.T) {
+	client, _ := testutil.NewMiniRedisClient(t)
+	e := NewLeaderElector(client, "", "a", 300*time.Millisecond)
+	var leader atomic.Bool
+	e.OnChange(leader.Store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.Run(ctx)
+	}()
+	require.Eventually(t, leader.Load, 2*time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+	assert.False(t, leader.Load())
+	holder, err := e.Leader(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, holder)
+}
@@ -0,0 +1,133 @@
+package cache
+
+import (
+	// Standard library
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	// Third-party libraries
+	"github.com/redis/go-redis/v9"
+
+	// Internal packages
+	"github.com/soulteary/warden/internal/define"
+)
+
+const (
+	// Loader state keys, below the namespace (see Namespace.Key)
+
+	// REDIS_LOADER_STATE_KEY Redis key holding the state of the leader's last load (JSON)
+	REDIS_LOADER_STATE_KEY = "loader:state"
+	// REDIS_LOADER_PROVENANCE_KEY prefix of the hashes mapping user keys to source lists, one per state
+	REDIS_LOADER_PROVENANCE_KEY = "loader:provenance"
+)
+
+// ErrLoaderStateMissing is returned by LoaderStateStore.Get when no leader has published a state yet.
+var ErrLoaderStateMissing = errors.New("loader state not found in Redis")
+
+// LoaderStateStore shares the state of the leader's last load (define.LoaderState) with the followers,
+// which do not load the sources. The state is one JSON value; the source list of each user is a field of
+// a hash written under a new key for every state and read one user at a time, so followers never read
+// the provenance of the whole list.
+type LoaderStateStore struct {
+	client redis.UniversalClient
+	ns     Namespace
+}
+
+// storedLoaderState is the JSON value of REDIS_LOADER_STATE_KEY.
+type storedLoaderState struct {
+	define.LoaderState
+	Provenance string `json:"provenance"` // key of the provenance hash
+}
+
+// NewLoaderStateStore creates a loader state store in the namespace ns.
+func NewLoaderStateStore(client redis.UniversalClient, ns Namespace) *LoaderStateStore {
+	return &LoaderStateStore{client: client, ns: ns}
+}
+
+// Publish replaces the state with state; provenance maps user keys to indexes in state.SourceSets.
+func (s *LoaderStateStore) Publish(state define.LoaderState, provenance map[string]int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), REDIS_OPERATION_TIMEOUT+time.Duration(len(provenance)/userStoreBatch)*time.Second)
+	defer cancel()
+
+	seq, err := s.client.Incr(ctx, s.ns.Key(REDIS_LOADER_PROVENANCE_KEY+":seq")).Result()
+	if err != nil {
+		return fmt.Errorf("loader state: allocate provenance key: %w", err)
+	}
+	key := s.ns.Key(REDIS_LOADER_PROVENANCE_KEY + ":" + strconv.FormatInt(seq, 10))
+	fields := make([]interface{}, 0, 2*userStoreBatch)
+	flush := func() error {
+		if len(fields) == 0 {
+			return nil
+		}
+		err := s.client.HSet(ctx, key, fields...).Err()
+		fields = fields[:0]
+		return err
+	}
+	for userKey, i := range provenance {
+		fields = append(fields, userKey, i)
+		if len(fields) == 2*userStoreBatch {
+			if err := flush(); err != nil {
+				s.client.Del(ctx, key)
+				return fmt.Errorf("loader state: write provenance: %w", err)
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		s.client.Del(ctx, key)
+		return fmt.Errorf("loader state: write provenance: %w", err)
+	}
+
+	data, err := json.Marshal(storedLoaderState{LoaderState: state, Provenance: key})
+	if err != nil {
+		s.client.Del(ctx, key)
+		return fmt.Errorf("loader state: encode: %w", err)
+	}
+	old, err := s.client.GetSet(ctx, s.ns.Key(REDIS_LOADER_STATE_KEY), data).Bytes()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("loader state: write: %w", err)
+	}
+	var previous storedLoaderState
+	if len(old) > 0 && json.Unmarshal(old, &previous) == nil && previous.Provenance != "" && previous.Provenance != key {
+		if err := s.client.Del(ctx, previous.Provenance).Err(); err != nil {
+			log.Warn().Err(err).Str("key", previous.Provenance).Msg("Failed to delete previous loader provenance")
+		}
+	}
+	return nil
+}
+
+// Get returns the published state and a function resolving the index in SourceSets of the source list
+// of a user key, which reads Redis (false when the user is unknown or Redis fails).
+// ErrLoaderStateMissing when no state was published.
+func (s *LoaderStateStore) Get() (define.LoaderState, func(key string) (int, bool), error) {
+	ctx, cancel := context.WithTimeout(context.Background(), REDIS_OPERATION_TIMEOUT)
+	defer cancel()
+
+	data, err := s.client.Get(ctx, s.ns.Key(REDIS_LOADER_STATE_KEY)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return define.LoaderState{}, nil, ErrLoaderStateMissing
+	}
+	if err != nil {
+		return define.LoaderState{}, nil, fmt.Errorf("loader state: read: %w", err)
+	}
+	var stored storedLoaderState
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return define.LoaderState{}, nil, fmt.Errorf("loader state: decode: %w", err)
+	}
+	index := func(userKey string) (int, bool) {
+		ctx, cancel := context.WithTimeout(context.Background(), REDIS_OPERATION_TIMEOUT)
+		defer cancel()
+		i, err := s.client.HGet(ctx, stored.Provenance, userKey).Int()
+		if err != nil {
+			if !errors.Is(err, redis.Nil) {
+				log.Debug().Err(err).Msg("Failed to read loader provenance")
+			}
+			return 0, false
+		}
+		return i, true
+	}
+	return stored.LoaderState, index, nil
+}
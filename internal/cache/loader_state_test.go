@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/define"
+)
+
+func TestLoaderStateStore_PublishAndGet(t *testing.T) {
+	client, server := newFakeRedisClientServer(t)
+	store := NewLoaderStateStore(client, "")
+
+	_, _, err := store.Get()
+	require.ErrorIs(t, err, ErrLoaderStateMissing)
+
+	state := define.LoaderState{
+		LoadedAt:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Version:    "abc",
+		Sources:    []define.SourceStatus{{Name: "data.json", Type: "file", Records: 2}},
+		Report:     &define.LoadReport{Total: 2, Accepted: 2},
+		SourceSets: [][]define.ProvenanceSource{{{Name: "data.json", Type: "file"}}},
+	}
+	// More users than one HSET batch
+	provenance := make(map[string]int, 2*userStoreBatch+1)
+	for i := range 2 * userStoreBatch {
+		provenance["user_id:"+strconv.Itoa(i)] = 0
+	}
+	provenance["phone:13800138000"] = 0
+	require.NoError(t, store.Publish(state, provenance))
+
+	got, index, err := store.Get()
+	require.NoError(t, err)
+	assert.Equal(t, state, got)
+	i, ok := index("phone:13800138000")
+	require.True(t, ok)
+	assert.Equal(t, 0, i)
+	_, ok = index("phone:13900139000")
+	assert.False(t, ok)
+
+	// A new state replaces the provenance of the previous one
+	require.NoError(t, store.Publish(define.LoaderState{}, map[string]int{"phone:13900139000": 0}))
+	_, ok = index("phone:13800138000")
+	assert.False(t, ok, "the previous provenance is deleted")
+	_, index, err = store.Get()
+	require.NoError(t, err)
+	_, ok = index("phone:13900139000")
+	assert.True(t, ok)
+	server.Mu.Lock()
+	assert.Len(t, server.Hashes, 1)
+	server.Mu.Unlock()
+}
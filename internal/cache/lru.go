@@ -0,0 +1,98 @@
+package cache
+
+import (
+	// Standard library
+	"container/list"
+	"sync"
+	"time"
+
+	// Internal packages
+	"github.com/soulteary/warden/internal/define"
+)
+
+// lruEntry is a cached lookup result; found=false caches a miss.
+type lruEntry struct {
+	key     string
+	user    define.AllowListUser
+	found   bool
+	expires time.Time
+}
+
+// lruCache is a fixed-size, thread-safe LRU of lookup results whose entries expire after ttl.
+type lruCache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	order *list.List // front = most recently used
+	items map[string]*list.Element
+	now   func() time.Time
+}
+
+// newLRUCache creates an LRU holding at most size entries; size <= 0 disables caching.
+func newLRUCache(size int, ttl time.Duration) *lruCache {
+	return &lruCache{
+		size:  size,
+		ttl:   ttl,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+		now:   time.Now,
+	}
+}
+
+// get returns the cached result for key; ok is false when it is not cached or has expired.
+func (c *lruCache) get(key string) (user define.AllowListUser, found, ok bool) {
+	if c.size <= 0 {
+		return define.AllowListUser{}, false, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, hit := c.items[key]
+	if !hit {
+		return define.AllowListUser{}, false, false
+	}
+	e := el.Value.(*lruEntry) //nolint:errcheck // only *lruEntry is stored
+	if !c.now().Before(e.expires) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return define.AllowListUser{}, false, false
+	}
+	c.order.MoveToFront(el)
+	return e.user, e.found, true
+}
+
+// add caches a lookup result, evicting the least recently used entry when full.
+func (c *lruCache) add(key string, user define.AllowListUser, found bool) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := c.now().Add(c.ttl)
+	if el, hit := c.items[key]; hit {
+		e := el.Value.(*lruEntry) //nolint:errcheck // only *lruEntry is stored
+		e.user, e.found, e.expires = user, found, expires
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, user: user, found: found, expires: expires})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key) //nolint:errcheck // only *lruEntry is stored
+	}
+}
+
+// purge removes all entries.
+func (c *lruCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+// count returns the number of cached entries (including expired ones not yet evicted).
+func (c *lruCache) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
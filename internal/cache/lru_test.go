@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/soulteary/warden/internal/define"
+)
+
+func TestLRUCache(t *testing.T) {
+	c := newLRUCache(2, time.Minute)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.add("a", define.AllowListUser{UserID: "a"}, true)
+	c.add("b", define.AllowListUser{}, false)
+	user, found, ok := c.get("a")
+	assert.True(t, ok)
+	assert.True(t, found)
+	assert.Equal(t, "a", user.UserID)
+	_, found, ok = c.get("b")
+	assert.True(t, ok, "misses are cached")
+	assert.False(t, found)
+
+	// "a" was used after "b", so "b" is evicted
+	_, _, _ = c.get("a")
+	c.add("c", define.AllowListUser{UserID: "c"}, true)
+	assert.Equal(t, 2, c.count())
+	_, _, ok = c.get("b")
+	assert.False(t, ok)
+
+	now = now.Add(time.Minute)
+	_, _, ok = c.get("a")
+	assert.False(t, ok, "expired")
+
+	c.purge()
+	assert.Equal(t, 0, c.count())
+
+	disabled := newLRUCache(-1, time.Minute)
+	disabled.add("a", define.AllowListUser{}, true)
+	_, _, ok = disabled.get("a")
+	assert.False(t, ok)
+}
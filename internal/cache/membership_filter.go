@@ -0,0 +1,60 @@
+package cache
+
+import (
+	// Internal packages
+	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/internal/prommetrics"
+	"github.com/soulteary/warden/pkg/warden"
+)
+
+// MembershipFilterSource provides the membership filter of the current list, nil when there is none.
+// Implemented by SafeUserCache and RedisUserStore.
+type MembershipFilterSource interface {
+	MembershipFilter() *warden.MembershipFilter
+}
+
+// filteredLookup answers lookups for identifiers that the membership filter of users rules out as misses,
+// without asking lookup (the memory cache or the per-user Redis store).
+type filteredLookup struct {
+	lookup UserLookup
+	users  MembershipFilterSource
+}
+
+// NewFilteredLookup wraps lookup with the membership filter of users (see SafeUserCache.EnableMembershipFilter
+// and RedisUserStore.EnableMembershipFilter). While users has no filter every lookup goes to lookup.
+func NewFilteredLookup(lookup UserLookup, users MembershipFilterSource) UserLookup {
+	return &filteredLookup{lookup: lookup, users: users}
+}
+
+// GetByPhone implements UserLookup.
+func (f *filteredLookup) GetByPhone(phone string) (define.AllowListUser, bool) {
+	if !f.mayContain(warden.IdentifierPhone, IndexPhone, phone) {
+		return define.AllowListUser{}, false
+	}
+	return f.lookup.GetByPhone(phone)
+}
+
+// GetByMail implements UserLookup.
+func (f *filteredLookup) GetByMail(mail string) (define.AllowListUser, bool) {
+	if !f.mayContain(warden.IdentifierMail, IndexMail, mail) {
+		return define.AllowListUser{}, false
+	}
+	return f.lookup.GetByMail(mail)
+}
+
+// GetByUserID implements UserLookup.
+func (f *filteredLookup) GetByUserID(userID string) (define.AllowListUser, bool) {
+	if !f.mayContain(warden.IdentifierUserID, IndexUserID, userID) {
+		return define.AllowListUser{}, false
+	}
+	return f.lookup.GetByUserID(userID)
+}
+
+func (f *filteredLookup) mayContain(kind, index, value string) bool {
+	filter := f.users.MembershipFilter()
+	if filter == nil || filter.MayContain(kind, value) {
+		return true
+	}
+	prommetrics.RecordMembershipFilterReject(index)
+	return false
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/pkg/warden"
+)
+
+// countingLookup counts the lookups that reach it.
+type countingLookup struct {
+	UserLookup
+	calls int
+}
+
+func (c *countingLookup) GetByPhone(phone string) (define.AllowListUser, bool) {
+	c.calls++
+	return c.UserLookup.GetByPhone(phone)
+}
+
+func (c *countingLookup) GetByMail(mail string) (define.AllowListUser, bool) {
+	c.calls++
+	return c.UserLookup.GetByMail(mail)
+}
+
+func (c *countingLookup) GetByUserID(userID string) (define.AllowListUser, bool) {
+	c.calls++
+	return c.UserLookup.GetByUserID(userID)
+}
+
+func TestSafeUserCache_MembershipFilter(t *testing.T) {
+	c := NewSafeUserCache()
+	c.Set([]define.AllowListUser{{Phone: "13800138000", Mail: "a@example.com", UserID: "ua"}})
+	assert.Nil(t, c.MembershipFilter(), "disabled by default")
+
+	c.EnableMembershipFilter(0.001)
+	f := c.MembershipFilter()
+	require.NotNil(t, f, "enabling builds the filter of the current list")
+	assert.True(t, f.MayContain(warden.IdentifierPhone, "13800138000"))
+	assert.True(t, f.MayContain(warden.IdentifierMail, "A@example.com"))
+	assert.True(t, f.MayContain(warden.IdentifierUserID, "ua"))
+
+	// Set rebuilds it
+	c.Set([]define.AllowListUser{{Mail: "b@example.com"}})
+	f = c.MembershipFilter()
+	assert.False(t, f.MayContain(warden.IdentifierMail, "a@example.com"))
+	assert.True(t, f.MayContain(warden.IdentifierMail, "b@example.com"))
+	assert.True(t, f.MayContain(warden.IdentifierPhone, "b@example.com"), "mail-only users are found by GetByPhone via their primary key")
+
+	// Upserts extend a copy; deletes keep it
+	require.NoError(t, c.Upsert(define.AllowListUser{Phone: "13900139000"}))
+	assert.True(t, c.MembershipFilter().MayContain(warden.IdentifierPhone, "13900139000"))
+	assert.False(t, f.MayContain(warden.IdentifierPhone, "13900139000"), "published filters are never modified")
+	assert.True(t, c.Delete("13900139000"))
+	assert.True(t, c.MembershipFilter().MayContain(warden.IdentifierPhone, "13900139000"))
+
+	c.EnableMembershipFilter(0)
+	assert.Nil(t, c.MembershipFilter())
+}
+
+func TestFilteredLookup(t *testing.T) {
+	users := NewSafeUserCache()
+	users.EnableMembershipFilter(0.0001)
+	users.Set([]define.AllowListUser{{Phone: "13800138000", Mail: "a@example.com", UserID: "ua"}})
+	inner := &countingLookup{UserLookup: users}
+	lookup := NewFilteredLookup(inner, users)
+
+	u, ok := lookup.GetByPhone("13800138000")
+	assert.True(t, ok)
+	assert.Equal(t, "ua", u.UserID)
+	_, ok = lookup.GetByMail("A@Example.com")
+	assert.True(t, ok)
+	_, ok = lookup.GetByUserID("ua")
+	assert.True(t, ok)
+	assert.Equal(t, 3, inner.calls)
+
+	_, ok = lookup.GetByPhone("13900139000")
+	assert.False(t, ok)
+	_, ok = lookup.GetByMail("nobody@example.com")
+	assert.False(t, ok)
+	_, ok = lookup.GetByUserID("nobody")
+	assert.False(t, ok)
+	assert.Equal(t, 3, inner.calls, "definite misses must not reach the wrapped lookup")
+
+	// Without a filter every lookup goes through
+	users.EnableMembershipFilter(0)
+	_, ok = lookup.GetByPhone("13900139000")
+	assert.False(t, ok)
+	assert.Equal(t, 4, inner.calls)
+}
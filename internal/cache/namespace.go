@@ -0,0 +1,41 @@
+package cache
+
+import (
+	// Standard library
+	"fmt"
+	"strings"
+)
+
+// DefaultNamespace is the key prefix used when none is configured; keys then read warden:users:cache etc.
+const DefaultNamespace Namespace = "warden"
+
+// Namespace prefixes every Redis key, channel and lock of one Warden deployment, so that several
+// deployments can share a Redis (and database) without overwriting each other's data.
+type Namespace string
+
+// Key returns suffix below the namespace: "<namespace>:<suffix>". The empty namespace is DefaultNamespace.
+func (n Namespace) Key(suffix string) string {
+	if n == "" {
+		n = DefaultNamespace
+	}
+	return string(n) + ":" + suffix
+}
+
+// ValidateNamespace checks a REDIS_KEY_PREFIX value ("" means DefaultNamespace). Letters, digits and
+// "-_.:" are accepted; glob characters would break the SCAN patterns built from the namespace.
+func ValidateNamespace(ns string) error {
+	if ns == "" {
+		return nil
+	}
+	if strings.HasSuffix(ns, ":") {
+		return fmt.Errorf("key prefix %q must not end with ':'", ns)
+	}
+	for _, r := range ns {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("-_.:", r):
+		default:
+			return fmt.Errorf("key prefix %q contains %q (allowed: letters, digits, '-', '_', '.', ':')", ns, r)
+		}
+	}
+	return nil
+}
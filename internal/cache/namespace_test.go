@@ -0,0 +1,21 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespace_Key(t *testing.T) {
+	assert.Equal(t, "warden:users:cache", Namespace("").Key(REDIS_CACHE_KEY))
+	assert.Equal(t, "prod.eu:leader", Namespace("prod.eu").Key(REDIS_LEADER_KEY))
+}
+
+func TestValidateNamespace(t *testing.T) {
+	for _, ns := range []string{"", "warden", "team-a", "prod.eu:warden_2"} {
+		assert.NoError(t, ValidateNamespace(ns), ns)
+	}
+	for _, ns := range []string{"warden:", "team a", "warden*", "users[1]"} {
+		assert.Error(t, ValidateNamespace(ns), ns)
+	}
+}
@@ -0,0 +1,147 @@
+package cache
+
+import (
+	// Standard library
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	// Third-party libraries
+	"github.com/redis/go-redis/v9"
+
+	// Internal packages
+	"github.com/soulteary/warden/internal/define"
+)
+
+const (
+	// REDIS_CACHE_CHANNEL Redis pub/sub channel announcing user cache updates (below the namespace)
+	REDIS_CACHE_CHANNEL = "users:cache:updates"
+	// REDIS_INSTANCES_KEY Redis hash of instance id -> InstanceStatus (JSON) (below the namespace)
+	REDIS_INSTANCES_KEY = "users:cache:instances"
+
+	// InstanceHeartbeat is how often an instance reports its status
+	InstanceHeartbeat = 30 * time.Second
+	// InstanceExpiry is how long a report is kept without being renewed
+	InstanceExpiry = 3 * InstanceHeartbeat
+)
+
+// CacheUpdate announces that an instance wrote a new dataset to Redis.
+type CacheUpdate struct {
+	Instance string `json:"instance"` // writing instance
+	Version  int64  `json:"version"`  // Redis cache version or user store generation after the write
+	Hash     string `json:"hash"`     // content hash of the written user list
+}
+
+// Notifier publishes and receives user cache updates over Redis pub/sub and shares the status of every
+// instance through a Redis hash, so replicas converge on the data written by whichever one synced.
+type Notifier struct {
+	client   redis.UniversalClient
+	ns       Namespace
+	instance string
+	now      func() time.Time
+}
+
+// NewNotifier creates a Notifier for the instance with the given id (see DefaultInstanceID); only
+// instances of the same namespace see each other.
+func NewNotifier(client redis.UniversalClient, ns Namespace, instance string) *Notifier {
+	return &Notifier{client: client, ns: ns, instance: instance, now: time.Now}
+}
+
+// DefaultInstanceID returns hostname-pid, which is unique per process on a host and per pod in Kubernetes.
+func DefaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "warden"
+	}
+	return host + "-" + strconv.Itoa(os.Getpid())
+}
+
+// Instance returns the id of this instance.
+func (n *Notifier) Instance() string {
+	return n.instance
+}
+
+// Publish announces a write of this instance.
+func (n *Notifier) Publish(ctx context.Context, version int64, hash string) error {
+	payload, err := json.Marshal(CacheUpdate{Instance: n.instance, Version: version, Hash: hash})
+	if err != nil {
+		return err
+	}
+	if err := n.client.Publish(ctx, n.ns.Key(REDIS_CACHE_CHANNEL), payload).Err(); err != nil {
+		return fmt.Errorf("publish cache update: %w", err)
+	}
+	return nil
+}
+
+// Subscribe calls fn for every update published by other instances until ctx is done. The subscription
+// is re-established automatically after connection errors; updates published meanwhile are missed and
+// picked up by the next scheduled load.
+func (n *Notifier) Subscribe(ctx context.Context, fn func(CacheUpdate)) {
+	sub := n.client.Subscribe(ctx, n.ns.Key(REDIS_CACHE_CHANNEL))
+	defer func() { _ = sub.Close() }() //nolint:errcheck // #nosec G104 -- best effort on shutdown
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var u CacheUpdate
+			if err := json.Unmarshal([]byte(msg.Payload), &u); err != nil {
+				log.Warn().Err(err).Msg("Ignoring malformed cache update notification")
+				continue
+			}
+			if u.Instance == n.instance {
+				continue
+			}
+			fn(u)
+		}
+	}
+}
+
+// Report stores the status of this instance (Instance and UpdatedAt are set here).
+func (n *Notifier) Report(ctx context.Context, st define.InstanceStatus) error {
+	st.Instance = n.instance
+	st.UpdatedAt = n.now()
+	payload, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	if err := n.client.HSet(ctx, n.ns.Key(REDIS_INSTANCES_KEY), n.instance, payload).Err(); err != nil {
+		return fmt.Errorf("report instance status: %w", err)
+	}
+	return nil
+}
+
+// Instances returns the reported status of every live instance sorted by id. Reports older than
+// InstanceExpiry are removed.
+func (n *Notifier) Instances(ctx context.Context) ([]define.InstanceStatus, error) {
+	reports, err := n.client.HGetAll(ctx, n.ns.Key(REDIS_INSTANCES_KEY)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("read instance status: %w", err)
+	}
+	cutoff := n.now().Add(-InstanceExpiry)
+	statuses := make([]define.InstanceStatus, 0, len(reports))
+	var stale []string
+	for id, payload := range reports {
+		var st define.InstanceStatus
+		if err := json.Unmarshal([]byte(payload), &st); err != nil || st.UpdatedAt.Before(cutoff) {
+			stale = append(stale, id)
+			continue
+		}
+		statuses = append(statuses, st)
+	}
+	if len(stale) > 0 {
+		if err := n.client.HDel(ctx, n.ns.Key(REDIS_INSTANCES_KEY), stale...).Err(); err != nil {
+			log.Debug().Err(err).Msg("Failed to remove stale instance status")
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Instance < statuses[j].Instance })
+	return statuses, nil
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/define"
+)
+
+func TestDefaultInstanceID(t *testing.T) {
+	assert.NotEmpty(t, DefaultInstanceID())
+	assert.Equal(t, DefaultInstanceID(), DefaultInstanceID())
+}
+
+func TestNotifier_PublishSubscribe(t *testing.T) {
+	client, server := newFakeRedisClientServer(t)
+	self := NewNotifier(client, "", "a")
+	other := NewNotifier(client, "", "b")
+	assert.Equal(t, "a", self.Instance())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	updates := make(chan CacheUpdate, 4)
+	go func() {
+		defer close(done)
+		self.Subscribe(ctx, func(u CacheUpdate) { updates <- u })
+	}()
+	require.Eventually(t, func() bool { return server.Subscribers(DefaultNamespace.Key(REDIS_CACHE_CHANNEL)) == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	// Own updates and malformed payloads are ignored
+	require.NoError(t, self.Publish(ctx, 1, "h1"))
+	require.NoError(t, client.Publish(ctx, DefaultNamespace.Key(REDIS_CACHE_CHANNEL), "not json").Err())
+	require.NoError(t, other.Publish(ctx, 2, "h2"))
+
+	select {
+	case u := <-updates:
+		assert.Equal(t, CacheUpdate{Instance: "b", Version: 2, Hash: "h2"}, u)
+	case <-time.After(2 * time.Second):
+		t.Fatal("cache update not received")
+	}
+	assert.Empty(t, updates)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Subscribe did not return after cancel")
+	}
+}
+
+func TestNotifier_ReportInstances(t *testing.T) {
+	client, server := newFakeRedisClientServer(t)
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	a := NewNotifier(client, "", "a")
+	a.now = func() time.Time { return now }
+	b := NewNotifier(client, "", "b")
+	b.now = a.now
+	ctx := context.Background()
+
+	require.NoError(t, b.Report(ctx, define.InstanceStatus{Instance: "ignored", Version: 3, Hash: "h3", Users: 2}))
+	require.NoError(t, a.Report(ctx, define.InstanceStatus{Version: 3, Hash: "h3", Users: 2}))
+
+	stale, err := json.Marshal(define.InstanceStatus{Instance: "gone", UpdatedAt: now.Add(-InstanceExpiry - time.Second)})
+	require.NoError(t, err)
+	require.NoError(t, client.HSet(ctx, DefaultNamespace.Key(REDIS_INSTANCES_KEY), "gone", stale, "broken", "{").Err())
+
+	statuses, err := a.Instances(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "a", statuses[0].Instance)
+	assert.Equal(t, "b", statuses[1].Instance)
+	assert.Equal(t, int64(3), statuses[1].Version)
+	assert.Equal(t, "h3", statuses[1].Hash)
+	assert.True(t, statuses[1].UpdatedAt.Equal(now))
+
+	// Expired and malformed reports are removed
+	server.Mu.Lock()
+	assert.Len(t, server.Hashes[DefaultNamespace.Key(REDIS_INSTANCES_KEY)], 2)
+	server.Mu.Unlock()
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	// Standard library
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	// Third-party libraries
+	"github.com/klauspost/compress/zstd"
+
+	// Internal packages
+	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/internal/prommetrics"
+)
+
+const (
+	// PayloadJSON stores the user list as plain JSON, readable by every Warden version (default)
+	PayloadJSON = "json"
+	// PayloadZstd stores the user list as zstd-compressed JSON behind a format marker
+	PayloadZstd = "zstd"
+)
+
+// Encoded payloads start with payloadMarker followed by one format byte. Plain JSON never starts with a NUL
+// byte, so payloads written by older versions (and with PayloadJSON) are read as they are.
+const (
+	payloadMarker   = 0x00
+	payloadZstdByte = 0x01
+)
+
+var (
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+		return zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	})
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+		return zstd.NewReader(nil)
+	})
+)
+
+// ValidatePayloadEncoding checks a REDIS_PAYLOAD_ENCODING value ("" means json).
+func ValidatePayloadEncoding(encoding string) error {
+	switch normalizePayloadEncoding(encoding) {
+	case PayloadJSON, PayloadZstd:
+		return nil
+	default:
+		return fmt.Errorf("unknown payload encoding %q (want %s or %s)", encoding, PayloadJSON, PayloadZstd)
+	}
+}
+
+func normalizePayloadEncoding(encoding string) string {
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+	if encoding == "" {
+		return PayloadJSON
+	}
+	return encoding
+}
+
+// encodeUsers serializes users in the given encoding and records the payload size.
+func encodeUsers(users []define.AllowListUser, encoding string) ([]byte, error) {
+	data, err := json.Marshal(users)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal values: %w", err)
+	}
+	if encoding == PayloadZstd {
+		enc, err := zstdEncoder()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+		}
+		data = enc.EncodeAll(data, []byte{payloadMarker, payloadZstdByte})
+	}
+	prommetrics.RecordRedisPayload("write", encoding, len(data), 0)
+	return data, nil
+}
+
+// decodeUsers reads a payload of any encoding, recognized by its marker, and records its size and decode time.
+func decodeUsers(data []byte) ([]define.AllowListUser, error) {
+	start := time.Now()
+	size, encoding := len(data), PayloadJSON
+	if len(data) >= 2 && data[0] == payloadMarker {
+		if data[1] != payloadZstdByte {
+			return nil, fmt.Errorf("unknown payload format 0x%02x (written by a newer version?)", data[1])
+		}
+		dec, err := zstdDecoder()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+		}
+		if data, err = dec.DecodeAll(data[2:], nil); err != nil {
+			return nil, fmt.Errorf("failed to decompress values: %w", err)
+		}
+		encoding = PayloadZstd
+	}
+
+	var users []define.AllowListUser
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal values: %w", err)
+	}
+	prommetrics.RecordRedisPayload("read", encoding, size, time.Since(start))
+	return users, nil
+}
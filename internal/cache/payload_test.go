@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/define"
+)
+
+func payloadUsers(n int) []define.AllowListUser {
+	users := make([]define.AllowListUser, n)
+	for i := range users {
+		users[i] = define.AllowListUser{
+			Phone:  "138" + strconv.Itoa(10000000+i),
+			Mail:   "user" + strconv.Itoa(i) + "@example.com",
+			Status: "active",
+			Scope:  []string{"read"},
+		}
+	}
+	return users
+}
+
+func TestValidatePayloadEncoding(t *testing.T) {
+	for _, encoding := range []string{"", "json", "ZSTD"} {
+		assert.NoError(t, ValidatePayloadEncoding(encoding), encoding)
+	}
+	assert.Error(t, ValidatePayloadEncoding("msgpack"))
+}
+
+func TestPayload_RoundTrip(t *testing.T) {
+	users := payloadUsers(1000)
+
+	plain, err := encodeUsers(users, PayloadJSON)
+	require.NoError(t, err)
+	assert.Equal(t, byte('['), plain[0], "json payloads stay readable by older versions")
+
+	compressed, err := encodeUsers(users, PayloadZstd)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{payloadMarker, payloadZstdByte}, compressed[:2])
+	assert.Less(t, len(compressed), len(plain)/4)
+
+	for _, data := range [][]byte{plain, compressed} {
+		got, err := decodeUsers(data)
+		require.NoError(t, err)
+		assert.Equal(t, users, got)
+	}
+}
+
+func TestPayload_DecodeErrors(t *testing.T) {
+	_, err := decodeUsers([]byte{payloadMarker, 0x7f, '['})
+	assert.ErrorContains(t, err, "unknown payload format")
+
+	_, err = decodeUsers([]byte{payloadMarker, payloadZstdByte, 'x'})
+	assert.ErrorContains(t, err, "decompress")
+}
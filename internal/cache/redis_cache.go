@@ -6,66 +6,156 @@ package cache
 
 import (
 	// Standard library
+	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	// Third-party libraries
 	"github.com/redis/go-redis/v9"
-	cache "github.com/soulteary/cache-kit"
 
 	// Internal packages
 	"github.com/soulteary/warden/internal/define"
 )
 
 const (
-	// REDIS_CACHE_KEY Redis key for storing user data
-	REDIS_CACHE_KEY = "warden:users:cache"
-	// REDIS_CACHE_VERSION_KEY Redis key for storing cache version
-	REDIS_CACHE_VERSION_KEY = "warden:users:cache:version"
-	// REDIS_CACHE_TTL Redis cache expiration time (1 hour)
+	// REDIS_CACHE_KEY Redis key for storing user data (below the namespace)
+	REDIS_CACHE_KEY = "users:cache"
+	// REDIS_CACHE_VERSION_KEY Redis key for storing cache version (below the namespace)
+	REDIS_CACHE_VERSION_KEY = "users:cache:version"
+	// REDIS_CACHE_TTL default Redis cache expiration time (1 hour), see cache.ttl
 	REDIS_CACHE_TTL = 1 * time.Hour
 	// REDIS_OPERATION_TIMEOUT Redis operation timeout
 	REDIS_OPERATION_TIMEOUT = 5 * time.Second
 )
 
-// RedisUserCache provides Redis-based user cache using cache-kit
+// ErrUserCacheMissing is returned by RedisUserCache.Get when no list is stored (never written, expired
+// or cleared), as opposed to an empty list that was written.
+var ErrUserCacheMissing = errors.New("user cache not found in Redis")
+
+// RedisUserCache stores the user list in Redis as one value (JSON, optionally zstd-compressed) next to a
+// version counter that every Set increments, both below the namespace of the deployment. It works with
+// every topology of NewRedisTopology; the two keys are written in one pipeline, which a cluster client
+// splits per node (a fenced write sets the list in a script first).
 type RedisUserCache struct {
-	cache *cache.RedisCache[define.AllowListUser]
+	client   redis.UniversalClient
+	ns       Namespace
+	ttl      time.Duration
+	encoding string
 }
 
-// NewRedisUserCache creates a new Redis user cache
-func NewRedisUserCache(client *redis.Client) *RedisUserCache {
-	config := cache.DefaultRedisConfig().
-		WithKeyPrefix(""). // We use custom key name directly
-		WithTTL(REDIS_CACHE_TTL).
-		WithOperationTimeout(REDIS_OPERATION_TIMEOUT).
-		WithVersionKeySuffix(":version")
-
-	return &RedisUserCache{
-		cache: cache.NewRedisCacheWithKey[define.AllowListUser](client, REDIS_CACHE_KEY, config),
+// NewRedisUserCache creates a new Redis user cache in the namespace ns; the list expires ttl after the
+// last Set or Touch (ttl <= 0 uses REDIS_CACHE_TTL). The version does not expire, so a list written again
+// after it expired gets a version no reader has seen. Set writes the list in encoding (PayloadJSON or
+// PayloadZstd, "" = PayloadJSON); Get reads either.
+func NewRedisUserCache(client redis.UniversalClient, ns Namespace, ttl time.Duration, encoding string) *RedisUserCache {
+	if ttl <= 0 {
+		ttl = REDIS_CACHE_TTL
 	}
+	return &RedisUserCache{client: client, ns: ns, ttl: ttl, encoding: normalizePayloadEncoding(encoding)}
 }
 
 // Set stores user list to Redis and updates version number
 func (c *RedisUserCache) Set(users []define.AllowListUser) error {
-	return c.cache.Set(users)
+	return c.SetFenced(users, 0)
 }
 
-// Get gets user list from Redis
+// SetFenced is Set for the leader with fencing token token (see LeaderElector.Fence): the list is only
+// written if no leader with a higher token has written it, checked and written in one script; ErrFenced
+// otherwise. A token of 0 writes unfenced.
+func (c *RedisUserCache) SetFenced(users []define.AllowListUser, token int64) error {
+	data, err := encodeUsers(users, c.encoding)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), REDIS_OPERATION_TIMEOUT)
+	defer cancel()
+
+	key := c.ns.Key(REDIS_CACHE_KEY)
+	pipe := c.client.Pipeline()
+	if token > 0 {
+		written, err := fencedSetScript.Run(ctx, c.client, []string{key, fenceKey(key)}, token, data, c.ttl.Milliseconds()).Int64()
+		if err != nil {
+			return fmt.Errorf("failed to set cache: %w", err)
+		}
+		if written == 0 {
+			return ErrFenced
+		}
+	} else {
+		pipe.Set(ctx, key, data, c.ttl)
+	}
+	pipe.Incr(ctx, c.ns.Key(REDIS_CACHE_VERSION_KEY))
+	pipe.Persist(ctx, c.ns.Key(REDIS_CACHE_VERSION_KEY)) // written with a TTL before
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set cache: %w", err)
+	}
+	return nil
+}
+
+// Touch restarts the expiry of the stored list without writing it; false when no list is stored (never
+// written or expired).
+func (c *RedisUserCache) Touch() (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), REDIS_OPERATION_TIMEOUT)
+	defer cancel()
+
+	ok, err := c.client.Expire(ctx, c.ns.Key(REDIS_CACHE_KEY), c.ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to touch cache: %w", err)
+	}
+	return ok, nil
+}
+
+// Get gets user list from Redis (ErrUserCacheMissing when the key does not exist)
 func (c *RedisUserCache) Get() ([]define.AllowListUser, error) {
-	return c.cache.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), REDIS_OPERATION_TIMEOUT)
+	defer cancel()
+
+	data, err := c.client.Get(ctx, c.ns.Key(REDIS_CACHE_KEY)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrUserCacheMissing
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cache: %w", err)
+	}
+	return decodeUsers(data)
 }
 
 // Exists checks if cache exists
 func (c *RedisUserCache) Exists() (bool, error) {
-	return c.cache.Exists()
+	ctx, cancel := context.WithTimeout(context.Background(), REDIS_OPERATION_TIMEOUT)
+	defer cancel()
+
+	n, err := c.client.Exists(ctx, c.ns.Key(REDIS_CACHE_KEY)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check existence: %w", err)
+	}
+	return n > 0, nil
 }
 
-// GetVersion gets cache version number
+// GetVersion gets cache version number (0 when no version was written)
 func (c *RedisUserCache) GetVersion() (int64, error) {
-	return c.cache.GetVersion()
+	ctx, cancel := context.WithTimeout(context.Background(), REDIS_OPERATION_TIMEOUT)
+	defer cancel()
+
+	version, err := c.client.Get(ctx, c.ns.Key(REDIS_CACHE_VERSION_KEY)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get version: %w", err)
+	}
+	return version, nil
 }
 
 // Clear clears cache
 func (c *RedisUserCache) Clear() error {
-	return c.cache.Clear()
+	ctx, cancel := context.WithTimeout(context.Background(), REDIS_OPERATION_TIMEOUT)
+	defer cancel()
+
+	// One DEL per key: the keys may live on different cluster nodes
+	pipe := c.client.Pipeline()
+	pipe.Del(ctx, c.ns.Key(REDIS_CACHE_KEY))
+	pipe.Del(ctx, c.ns.Key(REDIS_CACHE_VERSION_KEY))
+	_, err := pipe.Exec(ctx)
+	return err
 }
@@ -0,0 +1,292 @@
+package cache
+
+import (
+	// Standard library
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	// Third-party libraries
+	"github.com/redis/go-redis/v9"
+	rediskitclient "github.com/soulteary/redis-kit/client"
+)
+
+const (
+	// RedisModeStandalone connects to a single Redis server (default)
+	RedisModeStandalone = "standalone"
+	// RedisModeSentinel connects to the master of a Sentinel-managed deployment and follows failovers
+	RedisModeSentinel = "sentinel"
+	// RedisModeCluster connects to a Redis Cluster
+	RedisModeCluster = "cluster"
+)
+
+// RedisOptions configures the connection of NewRedisTopology.
+type RedisOptions struct {
+	Mode             string   // standalone (default), sentinel or cluster
+	Addr             string   // standalone: host:port
+	Addrs            []string // sentinel: Sentinel addresses; cluster: seed nodes
+	MasterName       string   // sentinel: name of the monitored master
+	Password         string   // password of the data nodes
+	DB               int      // standalone, sentinel: database (a cluster only has database 0)
+	SentinelPassword string   // sentinel: password of the Sentinels (optional)
+
+	// Dialer replaces the network dialer (tests)
+	Dialer func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// ValidateRedisMode checks a REDIS_MODE value ("" means standalone).
+func ValidateRedisMode(mode string) error {
+	switch normalizeRedisMode(mode) {
+	case RedisModeStandalone, RedisModeSentinel, RedisModeCluster:
+		return nil
+	default:
+		return fmt.Errorf("unknown Redis mode %q (want %s, %s or %s)", mode, RedisModeStandalone, RedisModeSentinel, RedisModeCluster)
+	}
+}
+
+func normalizeRedisMode(mode string) string {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode == "" {
+		return RedisModeStandalone
+	}
+	return mode
+}
+
+// RedisTopology is a Redis connection in one of the supported topologies. Client serves the user cache,
+// the user store, notifications and leader election alike; Status reports the state of the deployment.
+type RedisTopology struct {
+	Client redis.UniversalClient
+
+	mode       string
+	masterName string
+	sentinels  []*redis.SentinelClient // sentinel: one client per Sentinel, used by Status
+}
+
+// NewRedisTopology connects to Redis as configured by opts and pings it. Pool size and timeouts are
+// the redis-kit defaults in every mode.
+func NewRedisTopology(opts RedisOptions) (*RedisTopology, error) {
+	if err := ValidateRedisMode(opts.Mode); err != nil {
+		return nil, err
+	}
+	defaults := rediskitclient.DefaultConfig()
+	t := &RedisTopology{mode: normalizeRedisMode(opts.Mode), masterName: opts.MasterName}
+
+	switch t.mode {
+	case RedisModeSentinel:
+		if opts.MasterName == "" || len(opts.Addrs) == 0 {
+			return nil, fmt.Errorf("redis sentinel: master name and Sentinel addresses are required")
+		}
+		t.Client = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       opts.MasterName,
+			SentinelAddrs:    opts.Addrs,
+			SentinelPassword: opts.SentinelPassword,
+			Password:         opts.Password,
+			DB:               opts.DB,
+			Dialer:           opts.Dialer,
+			PoolSize:         defaults.PoolSize,
+			MinIdleConns:     defaults.MinIdleConns,
+			DialTimeout:      defaults.DialTimeout,
+			ReadTimeout:      defaults.ReadTimeout,
+			WriteTimeout:     defaults.WriteTimeout,
+			MaxRetries:       defaults.MaxRetries,
+			PoolTimeout:      defaults.PoolTimeout,
+		})
+		for _, addr := range opts.Addrs {
+			t.sentinels = append(t.sentinels, redis.NewSentinelClient(&redis.Options{
+				Addr:        addr,
+				Password:    opts.SentinelPassword,
+				Dialer:      opts.Dialer,
+				DialTimeout: defaults.DialTimeout,
+				ReadTimeout: defaults.ReadTimeout,
+				MaxRetries:  -1,
+			}))
+		}
+	case RedisModeCluster:
+		if len(opts.Addrs) == 0 {
+			return nil, fmt.Errorf("redis cluster: node addresses are required")
+		}
+		if opts.DB != 0 {
+			return nil, fmt.Errorf("redis cluster: only database 0 is available")
+		}
+		t.Client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        opts.Addrs,
+			Password:     opts.Password,
+			Dialer:       opts.Dialer,
+			PoolSize:     defaults.PoolSize,
+			MinIdleConns: defaults.MinIdleConns,
+			DialTimeout:  defaults.DialTimeout,
+			ReadTimeout:  defaults.ReadTimeout,
+			WriteTimeout: defaults.WriteTimeout,
+			MaxRetries:   defaults.MaxRetries,
+			PoolTimeout:  defaults.PoolTimeout,
+		})
+	default:
+		cfg := defaults.WithAddr(opts.Addr).WithPassword(opts.Password)
+		cfg.DB = opts.DB
+		cfg.Dialer = opts.Dialer
+		client, err := rediskitclient.NewClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+		t.Client = client
+		return t, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaults.DialTimeout)
+	defer cancel()
+	if err := t.Client.Ping(ctx).Err(); err != nil {
+		_ = t.Close() //nolint:errcheck // already failing
+		return nil, fmt.Errorf("failed to connect to Redis (%s): %w", t.mode, err)
+	}
+	return t, nil
+}
+
+// Mode returns the topology: standalone, sentinel or cluster.
+func (t *RedisTopology) Mode() string {
+	return t.mode
+}
+
+// Close closes the client and the Sentinel connections.
+func (t *RedisTopology) Close() error {
+	for _, s := range t.sentinels {
+		_ = s.Close() //nolint:errcheck // best effort
+	}
+	return t.Client.Close()
+}
+
+// RedisTopologyStatus is the state of the Redis deployment, reported by the redis health check.
+type RedisTopologyStatus struct {
+	Mode string
+	// Role of the node the client writes to ("master" expected) and its connected replicas (standalone, sentinel)
+	Role     string
+	Replicas int
+	// Master address reported by the Sentinels and how many of them answered (sentinel)
+	Master    string
+	Sentinels int
+	// State is cluster_state ("ok" when every slot is served); Nodes counts known nodes and SlotsOK served slots (cluster)
+	State   string
+	Nodes   int
+	SlotsOK int
+}
+
+// Metadata returns the status as health check metadata, leaving out fields that do not apply to the mode.
+func (s RedisTopologyStatus) Metadata() map[string]any {
+	md := map[string]any{"mode": s.Mode}
+	switch s.Mode {
+	case RedisModeCluster:
+		md["cluster_state"] = s.State
+		md["known_nodes"] = s.Nodes
+		md["slots_ok"] = s.SlotsOK
+	case RedisModeSentinel:
+		md["master"] = s.Master
+		md["sentinels"] = s.Sentinels
+		fallthrough
+	default:
+		md["role"] = s.Role
+		md["replicas"] = s.Replicas
+	}
+	return md
+}
+
+// Status pings Redis and reads the state of the deployment. An error means the deployment cannot serve
+// writes: Redis is unreachable, the client is connected to a replica, or the cluster is not "ok".
+func (t *RedisTopology) Status(ctx context.Context) (RedisTopologyStatus, error) {
+	st := RedisTopologyStatus{Mode: t.mode}
+	if err := t.Client.Ping(ctx).Err(); err != nil {
+		return st, err
+	}
+
+	if cluster, ok := t.Client.(*redis.ClusterClient); ok {
+		raw, err := cluster.ClusterInfo(ctx).Result()
+		if err != nil {
+			return st, fmt.Errorf("cluster info: %w", err)
+		}
+		info := parseRedisInfo(raw)
+		st.State = info["cluster_state"]
+		st.Nodes, _ = strconv.Atoi(info["cluster_known_nodes"]) //nolint:errcheck // 0 when missing
+		st.SlotsOK, _ = strconv.Atoi(info["cluster_slots_ok"])  //nolint:errcheck // 0 when missing
+		if st.State != "ok" {
+			return st, fmt.Errorf("cluster state is %q", st.State)
+		}
+		return st, nil
+	}
+
+	raw, err := t.Client.Info(ctx, "replication").Result()
+	if err != nil {
+		return st, fmt.Errorf("replication info: %w", err)
+	}
+	info := parseRedisInfo(raw)
+	st.Role = info["role"]
+	st.Replicas, _ = strconv.Atoi(info["connected_slaves"]) //nolint:errcheck // 0 when missing
+
+	for _, s := range t.sentinels {
+		sctx, cancel := context.WithTimeout(ctx, time.Second)
+		addr, err := s.GetMasterAddrByName(sctx, t.masterName).Result()
+		cancel()
+		if err != nil || len(addr) != 2 {
+			continue
+		}
+		st.Sentinels++
+		if st.Master == "" {
+			st.Master = net.JoinHostPort(addr[0], addr[1])
+		}
+	}
+	if st.Role != "" && st.Role != "master" {
+		return st, fmt.Errorf("connected to a %s, not the master", st.Role)
+	}
+	return st, nil
+}
+
+// parseRedisInfo parses the "key:value" lines of INFO and CLUSTER INFO replies.
+func parseRedisInfo(raw string) map[string]string {
+	info := make(map[string]string)
+	for _, line := range strings.Split(raw, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if ok && !strings.HasPrefix(key, "#") {
+			info[key] = value
+		}
+	}
+	return info
+}
+
+// scanKeys calls fn with the keys matching pattern, batch by batch. A cluster client scans every master,
+// since SCAN only covers the node it is sent to; fn is then called from one goroutine per master.
+func scanKeys(ctx context.Context, client redis.UniversalClient, pattern string, count int64, fn func(keys []string) error) error {
+	scan := func(ctx context.Context, c redis.Cmdable) error {
+		var cursor uint64
+		for {
+			keys, next, err := c.Scan(ctx, cursor, pattern, count).Result()
+			if err != nil {
+				return err
+			}
+			if len(keys) > 0 {
+				if err := fn(keys); err != nil {
+					return err
+				}
+			}
+			if cursor = next; cursor == 0 {
+				return nil
+			}
+		}
+	}
+	if cluster, ok := client.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return scan(ctx, node)
+		})
+	}
+	return scan(ctx, client)
+}
+
+// deleteKeys deletes keys with one DEL per key in a pipeline, so keys of different cluster slots can be
+// deleted together (a multi-key DEL fails with CROSSSLOT on a cluster).
+func deleteKeys(ctx context.Context, client redis.UniversalClient, keys []string) error {
+	pipe := client.Pipeline()
+	for _, key := range keys {
+		pipe.Del(ctx, key)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
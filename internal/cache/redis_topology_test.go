@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/testutil"
+)
+
+func TestValidateRedisMode(t *testing.T) {
+	for _, mode := range []string{"", "standalone", "Sentinel", " cluster "} {
+		assert.NoError(t, ValidateRedisMode(mode), mode)
+	}
+	assert.Error(t, ValidateRedisMode("replica"))
+}
+
+func TestNewRedisTopology_InvalidOptions(t *testing.T) {
+	_, err := NewRedisTopology(RedisOptions{Mode: "replica"})
+	assert.Error(t, err)
+	_, err = NewRedisTopology(RedisOptions{Mode: RedisModeSentinel, Addrs: []string{"127.0.0.1:26379"}})
+	assert.Error(t, err, "sentinel needs a master name")
+	_, err = NewRedisTopology(RedisOptions{Mode: RedisModeCluster})
+	assert.Error(t, err, "cluster needs node addresses")
+}
+
+func TestRedisTopology_StandaloneStatus(t *testing.T) {
+	server := testutil.NewFakeRedis()
+	topology, err := NewRedisTopology(RedisOptions{Addr: "fake", Dialer: server.Dialer})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = topology.Close() }) //nolint:errcheck // test cleanup
+	assert.Equal(t, RedisModeStandalone, topology.Mode())
+
+	st, err := topology.Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"mode": RedisModeStandalone, "role": "master", "replicas": 1}, st.Metadata())
+
+	// A client connected to a replica cannot serve writes
+	server.Mu.Lock()
+	server.Role = "slave"
+	server.Mu.Unlock()
+	st, err = topology.Status(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, "slave", st.Role)
+}
+
+func TestRedisTopologyStatus_Metadata(t *testing.T) {
+	sentinel := RedisTopologyStatus{Mode: RedisModeSentinel, Role: "master", Master: "10.0.0.1:6379", Sentinels: 3}
+	assert.Equal(t, map[string]any{"mode": RedisModeSentinel, "role": "master", "replicas": 0, "master": "10.0.0.1:6379", "sentinels": 3}, sentinel.Metadata())
+
+	cluster := RedisTopologyStatus{Mode: RedisModeCluster, State: "ok", Nodes: 6, SlotsOK: 16384}
+	assert.Equal(t, map[string]any{"mode": RedisModeCluster, "cluster_state": "ok", "known_nodes": 6, "slots_ok": 16384}, cluster.Metadata())
+}
+
+func TestParseRedisInfo(t *testing.T) {
+	info := parseRedisInfo("# Replication\r\nrole:master\r\nconnected_slaves:2\r\nslave0:ip=10.0.0.2,port=6379\r\n")
+	assert.Equal(t, "master", info["role"])
+	assert.Equal(t, "2", info["connected_slaves"])
+	assert.Equal(t, "ip=10.0.0.2,port=6379", info["slave0"])
+	assert.NotContains(t, info, "# Replication")
+}
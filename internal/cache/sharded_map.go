@@ -0,0 +1,94 @@
+package cache
+
+import (
+	// Standard library
+	"iter"
+	"maps"
+)
+
+// mapShards is the number of shards of a shardedMap. A write to a copy costs one copy of the shard table
+// plus one copy of each shard it changes: mapShards + n/mapShards entries, about 2000 for a million.
+const mapShards = 1024
+
+// shardedMap is a string-keyed map that snapshots share. clone returns a copy that shares every shard
+// with the original and copies a shard the first time it changes it, so a change of a few keys does not
+// copy the whole map. A map must not be changed once readers can see it (see userSnapshot).
+type shardedMap[V any] struct {
+	shards *[mapShards]map[string]V
+	owned  *[mapShards]bool // shards this copy has copied (or created) and may change
+	n      int
+	hint   int // expected size, to allocate new shards
+}
+
+// newShardedMap returns an empty map for about size keys.
+func newShardedMap[V any](size int) shardedMap[V] {
+	return shardedMap[V]{shards: new([mapShards]map[string]V), owned: new([mapShards]bool), hint: size}
+}
+
+// shardOf returns the shard of key (FNV-1a).
+func shardOf(key string) int {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return int(h % mapShards)
+}
+
+// clone returns a copy of m that shares its shards until it changes them.
+func (m *shardedMap[V]) clone() shardedMap[V] {
+	shards := *m.shards
+	return shardedMap[V]{shards: &shards, owned: new([mapShards]bool), n: m.n, hint: m.hint}
+}
+
+func (m *shardedMap[V]) get(key string) (V, bool) {
+	v, ok := m.shards[shardOf(key)][key]
+	return v, ok
+}
+
+func (m *shardedMap[V]) len() int {
+	return m.n
+}
+
+// shard returns shard i for writing, copying it if it is still shared.
+func (m *shardedMap[V]) shard(i int) map[string]V {
+	if !m.owned[i] {
+		if m.shards[i] == nil {
+			m.shards[i] = make(map[string]V, m.hint/mapShards)
+		} else {
+			m.shards[i] = maps.Clone(m.shards[i])
+		}
+		m.owned[i] = true
+	}
+	return m.shards[i]
+}
+
+func (m *shardedMap[V]) set(key string, v V) {
+	shard := m.shard(shardOf(key))
+	if _, ok := shard[key]; !ok {
+		m.n++
+	}
+	shard[key] = v
+}
+
+func (m *shardedMap[V]) delete(key string) {
+	i := shardOf(key)
+	if _, ok := m.shards[i][key]; !ok {
+		return
+	}
+	delete(m.shard(i), key)
+	m.n--
+}
+
+// all returns the entries of m in no particular order.
+func (m *shardedMap[V]) all() iter.Seq2[string, V] {
+	return func(yield func(string, V) bool) {
+		for _, shard := range m.shards {
+			for k, v := range shard {
+				if !yield(k, v) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -4,18 +4,27 @@ package cache
 
 import (
 	// Standard library
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
 	"errors"
-	"sort"
+	"fmt"
+	"iter"
+	"math/bits"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	// External packages
-	cache "github.com/soulteary/cache-kit"
 	"github.com/soulteary/cli-kit/validator"
 	secure "github.com/soulteary/secure-kit"
 
 	// Internal packages
 	"github.com/soulteary/warden/internal/define"
 	"github.com/soulteary/warden/internal/logger"
+	"github.com/soulteary/warden/internal/prommetrics"
+	"github.com/soulteary/warden/pkg/warden"
 )
 
 var log = logger.GetLoggerKit()
@@ -33,7 +42,7 @@ const (
 // primaryKeyForUser returns the dedup/primary key: phone if non-empty, else normalized mail.
 // Matches loader's allowListUserKey so cache and merge strategy stay consistent.
 //
-//nolint:gocritic // hugeParam: signature must match cache-kit PrimaryKeyFunc[T](T)(string)
+//nolint:gocritic // hugeParam: kept by value like the loader's key function
 func primaryKeyForUser(u define.AllowListUser) string {
 	k := strings.TrimSpace(u.Phone)
 	if k == "" {
@@ -42,178 +51,531 @@ func primaryKeyForUser(u define.AllowListUser) string {
 	return k
 }
 
-// SafeUserCache provides thread-safe user cache using cache-kit MultiIndexCache
-// Maintains multiple indexes to support fast queries by phone, mail, user_id
+// SafeUserCache provides a thread-safe user cache with O(1) lookups by phone (primary key), mail and user_id.
+// Readers never lock: they load an immutable snapshot, which writers replace as a whole. Set rebuilds the
+// snapshot from a full list; Upsert, Delete and ApplyDiff copy the current one and only normalize, validate
+// and hash the changed users, since the content hash is a sum of per-user digests (see HashUserList).
 type SafeUserCache struct {
-	cache *cache.MemoryCache[define.AllowListUser]
+	mu         sync.Mutex // serializes writers
+	snap       atomic.Pointer[userSnapshot]
+	report     atomic.Pointer[define.LoadReport] // validation report of the latest Set
+	filterRate float64                           // false positive rate of the membership filter, 0 = none (guarded by mu)
+}
+
+// userSnapshot is one immutable state of SafeUserCache. Index keys are lowercase and trimmed. The maps
+// are shared with the next snapshot, which copies the shards it changes (see shardedMap).
+type userSnapshot struct {
+	users  shardedMap[userEntry]    // primary key -> user
+	order  []string                 // primary keys in insertion order, with stale entries (see userEntry)
+	mail   shardedMap[string]       // mail -> primary key
+	userID shardedMap[string]       // user_id -> primary key
+	sum    listDigest               // sum of the digests of users
+	hash   string                   // "" until the first write
+	gen    uint64                   // bumped by every write that replaces the snapshot
+	filter *warden.MembershipFilter // identifiers of users, nil unless enabled (never modified once stored)
+}
+
+// userEntry is a user and its position in userSnapshot.order. A deleted user leaves its key in order
+// behind; an entry of order is only current when the user under its key points back to it.
+type userEntry struct {
+	user *define.AllowListUser // never modified once stored
+	pos  int
+}
+
+// newUserSnapshot returns an empty snapshot for about size users.
+func newUserSnapshot(size int) *userSnapshot {
+	return &userSnapshot{
+		users:  newShardedMap[userEntry](size),
+		order:  make([]string, 0, size),
+		mail:   newShardedMap[string](size),
+		userID: newShardedMap[string](size),
+	}
+}
+
+// UserDiff is a batch of changes for ApplyDiff: Upserts are applied in order, then Deletes.
+type UserDiff struct {
+	Upserts []define.AllowListUser
+	Deletes []string // primary keys: the phone, or the mail of users without phone
+}
+
+// DiffResult reports what ApplyDiff changed. Rejected lists the upserts that failed validation, with
+// Index pointing into UserDiff.Upserts.
+type DiffResult struct {
+	Upserted int
+	Deleted  int
+	Rejected []define.LoadIssue
 }
 
 // NewSafeUserCache creates a new thread-safe user cache
 func NewSafeUserCache() *SafeUserCache {
-	// Primary key: phone if non-empty, else normalized mail (supports email-only users)
-	config := cache.DefaultConfig[define.AllowListUser]().
-		WithPrimaryKey(primaryKeyForUser).
-		WithValidateFunc(validateUser).
-		WithNormalizeFunc(normalizeUser).
-		WithHashFunc(hashUsers)
-
-	c := cache.NewMultiIndexCache(config)
+	c := &SafeUserCache{}
+	c.snap.Store(newUserSnapshot(0))
+	return c
+}
 
-	// Add indexes for mail and user_id lookups
-	c.AddIndex(IndexMail, func(u define.AllowListUser) string {
-		return strings.ToLower(strings.TrimSpace(u.Mail))
-	})
-	c.AddIndex(IndexUserID, func(u define.AllowListUser) string {
-		return u.UserID
-	})
+// indexKey normalizes a mail or user_id index key.
+func indexKey(key string) string {
+	return strings.ToLower(strings.TrimSpace(key))
+}
 
-	return &SafeUserCache{
-		cache: c,
+// checkUser validates user using cli-kit validator and returns the load report reason when it is invalid.
+// At least one of phone or mail must be non-empty (email-only users are supported).
+func checkUser(user *define.AllowListUser) (reason string, err error) {
+	if strings.TrimSpace(user.Phone) == "" && strings.TrimSpace(user.Mail) == "" {
+		return define.ReasonMissingIdentifier, errBothIdentifierEmpty
+	}
+	if err := validator.ValidatePhone(user.Phone, &validator.PhoneOptions{AllowEmpty: true}); err != nil {
+		return define.ReasonInvalidPhone, err
 	}
+	if err := validator.ValidateEmail(user.Mail, &validator.EmailOptions{AllowEmpty: true}); err != nil {
+		return define.ReasonInvalidMail, err
+	}
+	return "", nil
 }
 
-// validateUser validates user data using cli-kit validator.
-// At least one of phone or mail must be non-empty (email-only users are supported).
-//
-//nolint:gocritic // hugeParam: function signature must match cache.ValidateFunc interface
-func validateUser(user define.AllowListUser) error {
-	phoneTrim := strings.TrimSpace(user.Phone)
-	mailTrim := strings.TrimSpace(user.Mail)
-	if phoneTrim == "" && mailTrim == "" {
-		log.Warn().Msg("Skipping user with both phone and mail empty")
-		return errBothIdentifierEmpty
-	}
-	phoneOpts := &validator.PhoneOptions{AllowEmpty: true}
-	emailOpts := &validator.EmailOptions{AllowEmpty: true}
-
-	if err := validator.ValidatePhone(user.Phone, phoneOpts); err != nil {
-		log.Warn().
-			Err(err).
-			Str("phone", user.Phone).
-			Str("mail", user.Mail).
-			Str("field", "phone").
-			Msg("Skipping invalid user data")
-		return err
-	}
-	if err := validator.ValidateEmail(user.Mail, emailOpts); err != nil {
-		log.Warn().
-			Err(err).
-			Str("phone", user.Phone).
-			Str("mail", user.Mail).
-			Str("field", "email").
-			Msg("Skipping invalid user data")
-		return err
+// buildLoadReport checks users the way the cache stores them: invalid records are dropped, a record with
+// the primary key of an earlier one replaces it, and user_id lookups resolve to the last record with that id.
+// Accepted is left to the caller.
+func buildLoadReport(users []define.AllowListUser) *define.LoadReport {
+	type owner struct {
+		index int
+		key   string
 	}
-	return nil
+	report := &define.LoadReport{
+		GeneratedAt: time.Now(),
+		Total:       len(users),
+		Reasons:     map[string]int{},
+		Issues:      []define.LoadIssue{},
+	}
+	keys := make(map[string]int, len(users))
+	ids := make(map[string]owner, len(users))
+	for i := range users {
+		u := normalizeUser(users[i])
+		issue := define.LoadIssue{Index: i, Phone: u.Phone, Mail: u.Mail, UserID: u.UserID}
+		if reason, err := checkUser(&u); err != nil {
+			issue.Reason, issue.Detail = reason, err.Error()
+			report.Rejected++
+			report.Add(issue)
+			continue
+		}
+		key := primaryKeyForUser(u)
+		if j, ok := keys[key]; ok {
+			issue.Reason, issue.Detail = define.ReasonDuplicateKey, fmt.Sprintf("replaces record %d", j)
+			report.Add(issue)
+		}
+		keys[key] = i
+		if u.UserID == "" {
+			continue
+		}
+		if prev, ok := ids[u.UserID]; ok && prev.key != key {
+			issue.Reason, issue.Detail = define.ReasonConflictingUserID, fmt.Sprintf("user_id also used by record %d", prev.index)
+			report.Add(issue)
+		}
+		ids[u.UserID] = owner{index: i, key: key}
+	}
+	return report
 }
 
 // normalizeUser normalizes user data
 //
-//nolint:gocritic // hugeParam: function signature must match cache.NormalizeFunc interface
+//nolint:gocritic // hugeParam: returns the normalized copy
 func normalizeUser(user define.AllowListUser) define.AllowListUser {
 	user.Normalize()
 	return user
 }
 
-// HashUserList computes SHA256 hash of user list for change detection.
-// Sorts by primary key (phone or mail), normalizes each user, then hashes.
-// Used by SafeUserCache internally and by main for backgroundTask comparison.
+// HashUserList computes the content hash of a user list for change detection: the SHA256 digests of the
+// normalized users are summed (mod 2^256), so the hash does not depend on order and SafeUserCache can update
+// it per changed user. Used by SafeUserCache internally and by main for backgroundTask comparison.
 func HashUserList(users []define.AllowListUser) string {
 	if len(users) == 0 {
 		return secure.GetSHA256Hash("empty")
 	}
-	sorted := make([]define.AllowListUser, len(users))
-	copy(sorted, users)
-	for i := range sorted {
-		sorted[i].Normalize()
+	var sum listDigest
+	for i := range users {
+		u := normalizeUser(users[i])
+		sum.add(userDigest(&u))
 	}
-	sort.Slice(sorted, func(i, j int) bool {
-		ki, kj := primaryKeyForUser(sorted[i]), primaryKeyForUser(sorted[j])
-		return ki < kj
-	})
-	var sb strings.Builder
-	for i := range sorted {
-		scopeStr := strings.Join(sorted[i].Scope, ",")
-		sb.WriteString(sorted[i].Phone + ":" + sorted[i].Mail + ":" + sorted[i].UserID + ":" + sorted[i].Status + ":" + scopeStr + ":" + sorted[i].Role + "\n")
+	return sum.String()
+}
+
+// listDigest is a 256-bit sum of user digests.
+type listDigest [4]uint64
+
+// userDigest returns the SHA256 digest of the hashed fields of a normalized user.
+func userDigest(u *define.AllowListUser) listDigest {
+	sum := sha256.Sum256([]byte(u.Phone + ":" + u.Mail + ":" + u.UserID + ":" + u.Status + ":" + strings.Join(u.Scope, ",") + ":" + u.Role + "\n"))
+	var d listDigest
+	for i := range d {
+		d[i] = binary.BigEndian.Uint64(sum[i*8:])
 	}
-	return secure.GetSHA256Hash(sb.String())
+	return d
 }
 
-// hashUsers calculates hash value of user data for change detection (cache-kit callback)
-func hashUsers(users []define.AllowListUser) string {
-	return HashUserList(users)
+func (d *listDigest) add(o listDigest) {
+	var carry uint64
+	for i := len(d) - 1; i >= 0; i-- {
+		d[i], carry = bits.Add64(d[i], o[i], carry)
+	}
+}
+
+func (d *listDigest) sub(o listDigest) {
+	var borrow uint64
+	for i := len(d) - 1; i >= 0; i-- {
+		d[i], borrow = bits.Sub64(d[i], o[i], borrow)
+	}
+}
+
+// String returns the digest as 64 hex characters.
+func (d listDigest) String() string {
+	var b [32]byte
+	for i := range d {
+		binary.BigEndian.PutUint64(b[i*8:], d[i])
+	}
+	return hex.EncodeToString(b[:])
 }
 
 // Get gets a copy of user list (thread-safe)
 // Returns slice format to maintain API compatibility
 // Return order matches the order when Set was called
 func (c *SafeUserCache) Get() []define.AllowListUser {
-	return c.cache.GetAll()
+	return c.snap.Load().list()
+}
+
+// GetWithGeneration returns a copy of the user list together with the generation of the snapshot it was
+// read from, so callers can cache data derived from the list (see Generation).
+func (c *SafeUserCache) GetWithGeneration() ([]define.AllowListUser, uint64) {
+	s := c.snap.Load()
+	return s.list(), s.gen
+}
+
+// Generation returns a counter that changes whenever the cached list changes, including reorders that
+// keep the content hash. It starts at 0 for an empty cache.
+func (c *SafeUserCache) Generation() uint64 {
+	return c.snap.Load().gen
+}
+
+// EnableMembershipFilter keeps a membership filter (Bloom filter) of the phones, mails and user_ids in the
+// cache with the false positive rate fpRate, rebuilt by every Set and extended by upserts. 0 disables it.
+func (c *SafeUserCache) EnableMembershipFilter(fpRate float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.filterRate = fpRate
+	next := *c.snap.Load()
+	next.filter = next.buildFilter(fpRate)
+	c.snap.Store(&next)
+}
+
+// MembershipFilter returns the membership filter of the current list, or nil when it is not enabled.
+// The filter is shared and must not be modified.
+func (c *SafeUserCache) MembershipFilter() *warden.MembershipFilter {
+	return c.snap.Load().filter
+}
+
+// buildFilter returns a membership filter of the users of s, or nil when fpRate is 0.
+func (s *userSnapshot) buildFilter(fpRate float64) *warden.MembershipFilter {
+	if fpRate <= 0 {
+		return nil
+	}
+	n := 0
+	for _, e := range s.users.all() {
+		n += filterIdentifiers(e.user)
+	}
+	f := warden.NewMembershipFilter(n, fpRate)
+	for _, e := range s.users.all() {
+		addToFilter(f, e.user)
+	}
+	return f
+}
+
+// filterIdentifiers returns the number of identifiers of u that addToFilter adds.
+func filterIdentifiers(u *define.AllowListUser) int {
+	n := 0
+	for _, v := range []string{u.Phone, u.Mail, u.UserID} {
+		if strings.TrimSpace(v) != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// addToFilter adds the phone, mail and user_id of u to f. The phone entry is the primary key, which is the
+// mail of users without phone, since GetByPhone looks users up by primary key.
+func addToFilter(f *warden.MembershipFilter, u *define.AllowListUser) {
+	f.Add(warden.IdentifierPhone, primaryKeyForUser(*u))
+	f.Add(warden.IdentifierMail, u.Mail)
+	f.Add(warden.IdentifierUserID, u.UserID)
+}
+
+// list copies the users of s in insertion order.
+func (s *userSnapshot) list() []define.AllowListUser {
+	users := make([]define.AllowListUser, 0, s.users.len())
+	for u := range s.each() {
+		users = append(users, *u)
+	}
+	return users
+}
+
+// each returns the users of s in insertion order, skipping the stale entries of order.
+func (s *userSnapshot) each() iter.Seq[*define.AllowListUser] {
+	return func(yield func(*define.AllowListUser) bool) {
+		for i, key := range s.order {
+			if e, ok := s.users.get(key); ok && e.pos == i && !yield(e.user) {
+				return
+			}
+		}
+	}
 }
 
 // Set sets user list (thread-safe)
-// Accepts slice format, internally converts to map for storage
-// Preserves input order. Keeps users with at least one of phone or mail (email-only users supported).
+// Preserves input order. Keeps users with at least one of phone or mail (email-only users supported);
+// invalid users are skipped and a user with the primary key of an earlier one replaces it in place.
+// Every call replaces the load report (see Report) and counts its issues by reason in prommetrics.
 func (c *SafeUserCache) Set(users []define.AllowListUser) {
-	// Filter out users with both phone and mail empty
-	validUsers := make([]define.AllowListUser, 0, len(users))
+	report := buildLoadReport(users)
+
+	next := newUserSnapshot(len(users))
 	for i := range users {
-		if primaryKeyForUser(users[i]) != "" {
-			validUsers = append(validUsers, users[i])
+		u := normalizeUser(users[i])
+		if _, err := checkUser(&u); err != nil {
+			continue
 		}
+		next.put(&u)
 	}
+	next.rehash()
 
-	// Track statistics for logging
-	invalidCount := len(users) - len(validUsers)
-	beforeLen := len(validUsers)
+	c.mu.Lock()
+	next.gen = c.snap.Load().gen + 1
+	next.filter = next.buildFilter(c.filterRate)
+	c.snap.Store(next)
+	c.mu.Unlock()
 
-	c.cache.Set(validUsers)
+	report.Accepted = next.users.len()
+	c.report.Store(report)
+	prommetrics.RecordLoadIssues(report.Reasons)
 
-	afterLen := c.cache.Len()
-	duplicateCount := beforeLen - afterLen - invalidCount
-	if duplicateCount < 0 {
-		duplicateCount = 0
-	}
-
-	// The cache internally handles validation and deduplication
 	// Log summary if there were any issues
-	if invalidCount > 0 || duplicateCount > 0 {
+	if len(report.Reasons) > 0 {
 		log.Info().
-			Int("total", len(users)).
-			Int("valid", afterLen).
-			Int("invalid", invalidCount).
-			Int("duplicates", duplicateCount).
+			Int("total", report.Total).
+			Int("valid", report.Accepted).
+			Int("invalid", report.Rejected).
+			Interface("reasons", report.Reasons).
 			Msg("Data validation completed")
 	}
 }
 
+// Upsert adds a user or replaces the user with the same primary key, keeping its position.
+// Returns the validation error when the user is rejected (the cache is then unchanged).
+//
+//nolint:gocritic // hugeParam: same value semantics as Set
+func (c *SafeUserCache) Upsert(user define.AllowListUser) error {
+	res := c.ApplyDiff(UserDiff{Upserts: []define.AllowListUser{user}})
+	if len(res.Rejected) > 0 {
+		return errors.New(res.Rejected[0].Detail)
+	}
+	return nil
+}
+
+// Delete removes the user with the given primary key (phone, or mail for users without phone).
+// Returns false when there is no such user.
+func (c *SafeUserCache) Delete(key string) bool {
+	return c.ApplyDiff(UserDiff{Deletes: []string{key}}).Deleted > 0
+}
+
+// ApplyDiff applies a batch of changes as one step: readers see either none or all of them. Only the
+// changed users are normalized, validated and hashed, and only the shards of the maps that hold them are
+// copied, so a change costs about mapShards + n/mapShards copied entries rather than n (see shardedMap);
+// the list order is compacted once deletes have left it half stale.
+// An index entry shared by several users (e.g. a duplicate user_id) follows the last write, as in Set.
+// The load report of the latest Set is left unchanged; rejected upserts are counted in prommetrics.
+func (c *SafeUserCache) ApplyDiff(diff UserDiff) DiffResult {
+	var res DiffResult
+	if len(diff.Upserts) == 0 && len(diff.Deletes) == 0 {
+		return res
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cur := c.snap.Load()
+	next := &userSnapshot{
+		users:  cur.users.clone(),
+		order:  cur.order, // appends only write past the length visible to readers of cur
+		mail:   cur.mail.clone(),
+		userID: cur.userID.clone(),
+		sum:    cur.sum,
+	}
+
+	reasons := map[string]int{}
+	var upserted []*define.AllowListUser
+	for i := range diff.Upserts {
+		u := normalizeUser(diff.Upserts[i])
+		if reason, err := checkUser(&u); err != nil {
+			res.Rejected = append(res.Rejected, define.LoadIssue{Index: i, Phone: u.Phone, Mail: u.Mail, UserID: u.UserID, Reason: reason, Detail: err.Error()})
+			reasons[reason]++
+			continue
+		}
+		next.put(&u)
+		upserted = append(upserted, &u)
+		res.Upserted++
+	}
+
+	for _, key := range diff.Deletes {
+		key = strings.TrimSpace(key)
+		if _, ok := next.users.get(key); !ok {
+			key = strings.ToLower(key)
+		}
+		if next.remove(key) {
+			res.Deleted++
+		}
+	}
+	if len(next.order) > 2*next.users.len()+mapShards {
+		next.compact()
+	}
+
+	if res.Upserted > 0 || res.Deleted > 0 {
+		next.rehash()
+		next.gen = cur.gen + 1
+		// Deleted users stay in the filter (a false positive at worst) until the next Set rebuilds it
+		next.filter = cur.filter
+		if next.filter != nil && len(upserted) > 0 {
+			next.filter = cur.filter.Clone()
+			for _, u := range upserted {
+				addToFilter(next.filter, u)
+			}
+		}
+		c.snap.Store(next)
+	}
+	if len(reasons) > 0 {
+		prommetrics.RecordLoadIssues(reasons)
+	}
+	return res
+}
+
+// put stores a normalized, valid user, replacing the user with the same primary key in place.
+func (s *userSnapshot) put(u *define.AllowListUser) {
+	key := primaryKeyForUser(*u)
+	pos := len(s.order)
+	if old, ok := s.users.get(key); ok {
+		s.unindex(key, old.user)
+		s.sum.sub(userDigest(old.user))
+		pos = old.pos
+	} else {
+		s.order = append(s.order, key)
+	}
+	s.users.set(key, userEntry{user: u, pos: pos})
+	s.sum.add(userDigest(u))
+	if k := indexKey(u.Mail); k != "" {
+		s.mail.set(k, key)
+	}
+	if k := indexKey(u.UserID); k != "" {
+		s.userID.set(k, key)
+	}
+}
+
+// remove deletes the user with the given primary key; its entry in order becomes stale.
+func (s *userSnapshot) remove(key string) bool {
+	old, ok := s.users.get(key)
+	if !ok {
+		return false
+	}
+	s.unindex(key, old.user)
+	s.sum.sub(userDigest(old.user))
+	s.users.delete(key)
+	return true
+}
+
+// compact drops the stale entries of order, which moves every user: O(n), once per about n deletes.
+func (s *userSnapshot) compact() {
+	order := make([]string, 0, s.users.len())
+	for u := range s.each() {
+		key := primaryKeyForUser(*u)
+		s.users.set(key, userEntry{user: u, pos: len(order)})
+		order = append(order, key)
+	}
+	s.order = order
+}
+
+// unindex drops the index entries of old that still point to key.
+func (s *userSnapshot) unindex(key string, old *define.AllowListUser) {
+	if k := indexKey(old.Mail); k != "" {
+		if pk, ok := s.mail.get(k); ok && pk == key {
+			s.mail.delete(k)
+		}
+	}
+	if k := indexKey(old.UserID); k != "" {
+		if pk, ok := s.userID.get(k); ok && pk == key {
+			s.userID.delete(k)
+		}
+	}
+}
+
+// rehash derives the content hash from the digest sum.
+func (s *userSnapshot) rehash() {
+	if s.users.len() == 0 {
+		s.sum = listDigest{}
+		s.hash = secure.GetSHA256Hash("empty")
+		return
+	}
+	s.hash = s.sum.String()
+}
+
+// Report returns the validation report of the latest Set; false before the first one.
+func (c *SafeUserCache) Report() (define.LoadReport, bool) {
+	r := c.report.Load()
+	if r == nil {
+		return define.LoadReport{}, false
+	}
+	return *r, true
+}
+
 // Len gets user count (thread-safe)
 func (c *SafeUserCache) Len() int {
-	return c.cache.Len()
+	return c.snap.Load().users.len()
 }
 
 // GetByPhone gets user by phone number (thread-safe, O(1) lookup)
 func (c *SafeUserCache) GetByPhone(phone string) (define.AllowListUser, bool) {
 	// Primary key is trimmed phone (when user has phone), so normalize lookup
-	return c.cache.Get(strings.TrimSpace(phone))
+	if e, ok := c.snap.Load().users.get(strings.TrimSpace(phone)); ok {
+		return *e.user, true
+	}
+	return define.AllowListUser{}, false
 }
 
 // GetByMail gets user by email (thread-safe, O(1) lookup)
 func (c *SafeUserCache) GetByMail(mail string) (define.AllowListUser, bool) {
-	// Index uses normalized mail (lowercase, trimmed)
-	return c.cache.GetByIndex(IndexMail, strings.ToLower(strings.TrimSpace(mail)))
+	s := c.snap.Load()
+	return s.lookup(&s.mail, mail)
 }
 
 // GetByUserID gets user by user ID (thread-safe, O(1) lookup)
 func (c *SafeUserCache) GetByUserID(userID string) (define.AllowListUser, bool) {
-	return c.cache.GetByIndex(IndexUserID, userID)
+	s := c.snap.Load()
+	return s.lookup(&s.userID, userID)
+}
+
+// lookup resolves an index key (lowercase, trimmed) to its user.
+func (s *userSnapshot) lookup(index *shardedMap[string], key string) (define.AllowListUser, bool) {
+	pk, ok := index.get(indexKey(key))
+	if !ok {
+		return define.AllowListUser{}, false
+	}
+	if e, ok := s.users.get(pk); ok {
+		return *e.user, true
+	}
+	return define.AllowListUser{}, false
 }
 
 // Iterate iterates all users, avoiding copying entire slice (thread-safe)
 // Callback function receives user data in insertion order
 // If callback function returns false, iteration will stop
 func (c *SafeUserCache) Iterate(fn func(user define.AllowListUser) bool) {
-	c.cache.Iterate(fn)
+	for u := range c.snap.Load().each() {
+		if !fn(*u) {
+			return
+		}
+	}
 }
 
 // GetReadOnly gets read-only view (actually returns copy, but semantically represents read-only)
@@ -226,5 +588,5 @@ func (c *SafeUserCache) GetReadOnly() []define.AllowListUser {
 // If hash value is not calculated, returns empty string
 // Using cached hash value can avoid redundant calculations and improve performance
 func (c *SafeUserCache) GetHash() string {
-	return c.cache.GetHash()
+	return c.snap.Load().hash
 }
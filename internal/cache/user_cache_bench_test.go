@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"fmt"
+	"strconv"
 	"sync"
 	"testing"
 
@@ -134,3 +136,30 @@ func BenchmarkSafeUserCache_ConcurrentReadWrite(b *testing.B) {
 
 	wg.Wait()
 }
+
+// BenchmarkSafeUserCache_Upsert tests performance of changing one user in a cache of a million users;
+// the cost must not grow with the size of the cache
+func BenchmarkSafeUserCache_Upsert(b *testing.B) {
+	cache := NewSafeUserCache()
+	users := make([]define.AllowListUser, 1000000)
+	for i := range users {
+		users[i] = define.AllowListUser{Phone: fmt.Sprintf("138%08d", i), UserID: fmt.Sprintf("u%d", i)}
+	}
+	cache.Set(users)
+
+	b.Run("replace", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = cache.Upsert(define.AllowListUser{Phone: "13800000042", UserID: "u42", Role: strconv.Itoa(i)})
+		}
+	})
+	b.Run("insert", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = cache.Upsert(define.AllowListUser{Phone: fmt.Sprintf("139%08d", i)})
+		}
+	})
+	b.Run("delete", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = cache.Delete(fmt.Sprintf("138%08d", i))
+		}
+	})
+}
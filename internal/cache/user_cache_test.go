@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"fmt"
 	"sync"
 	"testing"
 
@@ -222,6 +223,30 @@ func TestSafeUserCache_GetHash(t *testing.T) {
 	assert.NotEmpty(t, h2, "空列表也有 hash 值")
 }
 
+func TestSafeUserCache_Generation(t *testing.T) {
+	cache := NewSafeUserCache()
+	assert.Equal(t, uint64(0), cache.Generation())
+
+	a := define.AllowListUser{Phone: "13800138000"}
+	b := define.AllowListUser{Phone: "13800138001"}
+	cache.Set([]define.AllowListUser{a, b})
+	users, gen := cache.GetWithGeneration()
+	assert.Equal(t, uint64(1), gen)
+	assert.Len(t, users, 2)
+
+	// Same content in another order: hash unchanged, generation bumped
+	hash := cache.GetHash()
+	cache.Set([]define.AllowListUser{b, a})
+	assert.Equal(t, hash, cache.GetHash())
+	assert.Equal(t, uint64(2), cache.Generation())
+
+	// Diffs that change nothing keep the generation
+	assert.False(t, cache.Delete("13900000000"))
+	assert.Equal(t, uint64(2), cache.Generation())
+	require.NoError(t, cache.Upsert(define.AllowListUser{Phone: "13900000000"}))
+	assert.Equal(t, uint64(3), cache.Generation())
+}
+
 func TestSafeUserCache_InvalidPhoneSkipped(t *testing.T) {
 	cache := NewSafeUserCache()
 
@@ -248,3 +273,213 @@ func TestSafeUserCache_InvalidEmailSkipped(t *testing.T) {
 	result := cache.Get()
 	assert.GreaterOrEqual(t, len(result), 1, "无效邮箱用户应被跳过或保留有效项")
 }
+
+func TestSafeUserCache_Report(t *testing.T) {
+	c := NewSafeUserCache()
+	_, ok := c.Report()
+	assert.False(t, ok)
+
+	c.Set([]define.AllowListUser{
+		{Phone: "13800138000", Mail: "a@example.com", UserID: "u1"},
+		{Phone: "abc", Mail: "b@example.com"},
+		{Mail: "not-a-mail"},
+		{},
+		{Phone: "13800138000", Mail: "a2@example.com", UserID: "u1"},
+		{Phone: "13900139000", UserID: "u1"},
+	})
+	report, ok := c.Report()
+	require.True(t, ok)
+	assert.Equal(t, 6, report.Total)
+	assert.Equal(t, 2, report.Accepted)
+	assert.Equal(t, 3, report.Rejected)
+	assert.Equal(t, map[string]int{
+		define.ReasonInvalidPhone:      1,
+		define.ReasonInvalidMail:       1,
+		define.ReasonMissingIdentifier: 1,
+		define.ReasonDuplicateKey:      1,
+		define.ReasonConflictingUserID: 1,
+	}, report.Reasons)
+	require.Len(t, report.Issues, 5)
+	assert.Equal(t, define.LoadIssue{Index: 4, Phone: "13800138000", Mail: "a2@example.com", UserID: "u1", Reason: define.ReasonDuplicateKey, Detail: "replaces record 0"}, report.Issues[3])
+	assert.Equal(t, 5, report.Issues[4].Index)
+	assert.Equal(t, "user_id also used by record 4", report.Issues[4].Detail)
+
+	// A clean dataset replaces the report
+	c.Set([]define.AllowListUser{{Phone: "13800138000"}})
+	report, _ = c.Report()
+	assert.Empty(t, report.Issues)
+	assert.Equal(t, 1, report.Accepted)
+}
+
+func TestSafeUserCache_Upsert(t *testing.T) {
+	c := NewSafeUserCache()
+	c.Set([]define.AllowListUser{
+		{Phone: "13800138000", Mail: "a@example.com", UserID: "u1"},
+		{Phone: "13900139000", Mail: "b@example.com", UserID: "u2"},
+	})
+
+	// Replacing keeps the position and moves the indexes
+	require.NoError(t, c.Upsert(define.AllowListUser{Phone: "13800138000", Mail: "a2@example.com", UserID: "U3", Role: "admin"}))
+	users := c.Get()
+	require.Len(t, users, 2)
+	assert.Equal(t, "admin", users[0].Role)
+	_, ok := c.GetByMail("a@example.com")
+	assert.False(t, ok, "旧邮箱索引应被移除")
+	_, ok = c.GetByUserID("u1")
+	assert.False(t, ok, "旧 user_id 索引应被移除")
+	user, ok := c.GetByUserID("u3")
+	require.True(t, ok)
+	assert.Equal(t, "13800138000", user.Phone)
+
+	// New users are appended and normalized
+	require.NoError(t, c.Upsert(define.AllowListUser{Mail: " Only@Example.com "}))
+	users = c.Get()
+	require.Len(t, users, 3)
+	assert.Equal(t, "active", users[2].Status)
+	_, ok = c.GetByMail("only@example.com")
+	assert.True(t, ok)
+
+	require.Error(t, c.Upsert(define.AllowListUser{Phone: "abc"}))
+	assert.Equal(t, 3, c.Len())
+
+	assert.Equal(t, HashUserList(c.Get()), c.GetHash(), "增量哈希应与完整计算一致")
+}
+
+func TestSafeUserCache_Delete(t *testing.T) {
+	c := NewSafeUserCache()
+	c.Set([]define.AllowListUser{
+		{Phone: "13800138000", Mail: "a@example.com", UserID: "u1"},
+		{Mail: "B@example.com", UserID: "u2"},
+		{Phone: "13700137000"},
+	})
+	before := c.GetHash()
+
+	assert.True(t, c.Delete(" b@example.com "), "无手机号的用户按邮箱删除")
+	assert.False(t, c.Delete("b@example.com"))
+	assert.False(t, c.Delete("13600136000"))
+	_, ok := c.GetByUserID("u2")
+	assert.False(t, ok)
+	users := c.Get()
+	require.Len(t, users, 2)
+	assert.Equal(t, "13700137000", users[1].Phone)
+	assert.Equal(t, HashUserList(users), c.GetHash())
+
+	// Re-adding the user restores the previous hash
+	require.NoError(t, c.Upsert(define.AllowListUser{Mail: "B@example.com", UserID: "u2"}))
+	assert.Equal(t, before, c.GetHash())
+
+	assert.True(t, c.Delete("13800138000"))
+	assert.True(t, c.Delete("13700137000"))
+	assert.True(t, c.Delete("b@example.com"))
+	assert.Equal(t, HashUserList(nil), c.GetHash())
+	assert.Empty(t, c.Get())
+}
+
+func TestSafeUserCache_ApplyDiff(t *testing.T) {
+	c := NewSafeUserCache()
+	assert.Equal(t, DiffResult{}, c.ApplyDiff(UserDiff{}))
+	assert.Empty(t, c.GetHash(), "空差异不应计算哈希")
+
+	c.Set([]define.AllowListUser{{Phone: "13800138000"}, {Phone: "13900139000"}})
+	report, _ := c.Report()
+
+	res := c.ApplyDiff(UserDiff{
+		Upserts: []define.AllowListUser{
+			{Phone: "13700137000", UserID: "new"},
+			{Mail: "not-a-mail"},
+			{Phone: "13800138000", Role: "admin"},
+		},
+		Deletes: []string{"13900139000", "13600136000"},
+	})
+	assert.Equal(t, 2, res.Upserted)
+	assert.Equal(t, 1, res.Deleted)
+	require.Len(t, res.Rejected, 1)
+	assert.Equal(t, 1, res.Rejected[0].Index)
+	assert.Equal(t, define.ReasonInvalidMail, res.Rejected[0].Reason)
+
+	var phones []string
+	c.Iterate(func(u define.AllowListUser) bool {
+		phones = append(phones, u.Phone)
+		return true
+	})
+	assert.Equal(t, []string{"13800138000", "13700137000"}, phones)
+	assert.Equal(t, HashUserList(c.Get()), c.GetHash())
+
+	after, _ := c.Report()
+	assert.Equal(t, report, after, "差异不应替换加载报告")
+
+	// The same list loaded with Set has the same hash
+	other := NewSafeUserCache()
+	other.Set(c.Get())
+	assert.Equal(t, other.GetHash(), c.GetHash())
+}
+
+// TestSafeUserCache_ApplyDiffOrder tests the list order across deletes, re-inserts and the compaction of
+// the order, and that earlier snapshots are not changed by later diffs
+func TestSafeUserCache_ApplyDiffOrder(t *testing.T) {
+	c := NewSafeUserCache()
+	users := make([]define.AllowListUser, 3000)
+	for i := range users {
+		users[i] = define.AllowListUser{Phone: fmt.Sprintf("138%08d", i), UserID: fmt.Sprintf("u%d", i)}
+	}
+	c.Set(users)
+	before := c.snap.Load()
+	want := c.Get()
+	first := want
+
+	// Delete every other user and add each back at the end, several times over
+	for round := 0; round < 3; round++ {
+		var deletes []string
+		var upserts, kept, moved []define.AllowListUser
+		for i, u := range want {
+			if i%2 == 0 {
+				deletes = append(deletes, u.Phone)
+				moved = append(moved, u)
+			} else {
+				kept = append(kept, u)
+			}
+		}
+		for _, u := range moved {
+			require.Equal(t, 1, c.ApplyDiff(UserDiff{Deletes: []string{u.Phone}}).Deleted)
+			upserts = append(upserts, u)
+		}
+		require.Equal(t, len(upserts), c.ApplyDiff(UserDiff{Upserts: upserts}).Upserted)
+		want = append(kept, moved...)
+		require.Equal(t, want, c.Get(), "round %d", round)
+		require.Len(t, deletes, len(moved))
+	}
+	assert.LessOrEqual(t, len(c.snap.Load().order), 2*len(users)+mapShards, "the order is compacted")
+	assert.Equal(t, HashUserList(c.Get()), c.GetHash())
+	for _, u := range want[:10] {
+		got, ok := c.GetByUserID(u.UserID)
+		require.True(t, ok)
+		assert.Equal(t, u.Phone, got.Phone)
+	}
+
+	assert.Equal(t, first, before.list(), "an earlier snapshot keeps its users and order")
+	_, ok := before.lookup(&before.userID, "u0")
+	assert.True(t, ok)
+}
+
+func TestSafeUserCache_ReadersSeeWholeDiffs(t *testing.T) {
+	c := NewSafeUserCache()
+	c.Set([]define.AllowListUser{{Phone: "13800138000"}, {Phone: "13900139000"}})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			c.ApplyDiff(UserDiff{Deletes: []string{"13800138000", "13900139000"}})
+			c.ApplyDiff(UserDiff{Upserts: []define.AllowListUser{{Phone: "13800138000"}, {Phone: "13900139000"}}})
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		n := len(c.Get())
+		assert.True(t, n == 0 || n == 2, "读取到部分应用的差异: %d", n)
+	}
+}
@@ -0,0 +1,595 @@
+package cache
+
+import (
+	// Standard library
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	// Third-party libraries
+	"github.com/redis/go-redis/v9"
+
+	// Internal packages
+	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/internal/prommetrics"
+	"github.com/soulteary/warden/pkg/warden"
+)
+
+const (
+	// UserStoreBlob stores the whole user list as one JSON value (RedisUserCache, default)
+	UserStoreBlob = "blob"
+	// UserStoreHash stores one Redis hash per user with mail and user_id index keys (RedisUserStore)
+	UserStoreHash = "hash"
+
+	// DefaultUserStoreLRUSize is the default number of lookup results cached in front of Redis
+	DefaultUserStoreLRUSize = 10000
+	// DefaultUserStoreLRUTTL is how long a cached lookup result is used before Redis is asked again
+	DefaultUserStoreLRUTTL = 30 * time.Second
+
+	// REDIS_USER_STORE_PREFIX prefix of the per-user keys below the namespace. The current generation is
+	// stored under <prefix>:gen; users, indexes and the meta keys (user count, content hash, key order,
+	// membership filter) of a generation under <prefix>:<gen>:...
+	REDIS_USER_STORE_PREFIX = "users:store"
+
+	// userStoreBatch is the number of users written per pipeline, and read per page when streaming
+	userStoreBatch = 1000
+)
+
+// ErrUserStoreEmpty is returned when no dataset has been written to the store yet.
+var ErrUserStoreEmpty = errors.New("user store is empty")
+
+// UserStoreInfo describes the generation a RedisUserStore serves.
+type UserStoreInfo struct {
+	Generation int64
+	Users      int
+	Hash       string // content hash, as SafeUserCache.GetHash of the same users
+}
+
+// storeInfo is the generation known to a store, with its membership filter (nil unless enabled).
+type storeInfo struct {
+	UserStoreInfo
+	filter *warden.MembershipFilter
+}
+
+// UserLookup finds a user by identifier. Implemented by SafeUserCache and RedisUserStore.
+type UserLookup interface {
+	GetByPhone(phone string) (define.AllowListUser, bool)
+	GetByMail(mail string) (define.AllowListUser, bool)
+	GetByUserID(userID string) (define.AllowListUser, bool)
+}
+
+// ValidateUserStoreMode checks a REDIS_USER_STORE value ("" means blob).
+func ValidateUserStoreMode(mode string) error {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", UserStoreBlob, UserStoreHash:
+		return nil
+	default:
+		return fmt.Errorf("unknown user store %q (want %s or %s)", mode, UserStoreBlob, UserStoreHash)
+	}
+}
+
+// RedisUserStore keeps the user list in Redis as one hash per user, keyed like SafeUserCache (phone, else
+// lowercase mail), with string keys indexing mail and user_id. Lookups read single users from Redis, so the
+// list never has to be deserialized as a whole; a small LRU of recent results (including misses) sits in front.
+//
+// Every Set writes a new generation and then switches the generation pointer, so readers never see a
+// partially written dataset; the previous generation is deleted afterwards. Lookups fall back to the
+// fallback cache while the store is empty or Redis fails. The list itself is read page by page (Page,
+// Each) in the order of the keys Set wrote, so no instance has to hold it in memory.
+type RedisUserStore struct {
+	client     redis.UniversalClient
+	ns         Namespace
+	lru        *lruCache
+	fallback   UserLookup
+	filterRate float64 // false positive rate of the membership filter written by Set, 0 = none
+
+	mu        sync.Mutex
+	gen       int64     // generation used for lookups
+	genExpiry time.Time // when gen is read from Redis again
+
+	info atomic.Pointer[storeInfo] // generation last written or loaded by Refresh
+}
+
+// NewRedisUserStore creates a per-user Redis store in the namespace ns. lruSize (0 = DefaultUserStoreLRUSize, < 0 = no LRU) and
+// lruTTL (0 = DefaultUserStoreLRUTTL) configure the local cache; fallback (optional) answers lookups while
+// the store is empty or unreachable.
+func NewRedisUserStore(client redis.UniversalClient, ns Namespace, lruSize int, lruTTL time.Duration, fallback UserLookup) *RedisUserStore {
+	if lruSize == 0 {
+		lruSize = DefaultUserStoreLRUSize
+	}
+	if lruTTL <= 0 {
+		lruTTL = DefaultUserStoreLRUTTL
+	}
+	return &RedisUserStore{
+		client:   client,
+		ns:       ns,
+		lru:      newLRUCache(lruSize, lruTTL),
+		fallback: fallback,
+	}
+}
+
+// key returns <namespace>:<prefix>:<suffix>.
+func (s *RedisUserStore) key(suffix string) string {
+	return s.ns.Key(REDIS_USER_STORE_PREFIX + ":" + suffix)
+}
+
+func (s *RedisUserStore) userKey(gen int64, kind, id string) string {
+	return s.key(strconv.FormatInt(gen, 10) + ":" + kind + ":" + id)
+}
+
+// EnableMembershipFilter makes Set write a membership filter of the users with the given false positive
+// rate (see SafeUserCache.EnableMembershipFilter); every instance loads it with Refresh. Call it before
+// the store is used.
+func (s *RedisUserStore) EnableMembershipFilter(fpRate float64) {
+	s.filterRate = fpRate
+}
+
+// Set validates, normalizes and deduplicates users the way SafeUserCache does and writes them as a new
+// generation. Later records replace earlier ones with the same key; mail and user_id resolve to the last
+// record that has them.
+func (s *RedisUserStore) Set(users []define.AllowListUser) error {
+	return s.SetFenced(users, 0)
+}
+
+// SetFenced is Set for the leader with fencing token token (see LeaderElector.Fence): the new generation
+// only becomes current if no leader with a higher token has switched the generation, checked and
+// switched in one script; otherwise it is deleted and ErrFenced returned. A token of 0 writes unfenced.
+func (s *RedisUserStore) SetFenced(users []define.AllowListUser, token int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), REDIS_OPERATION_TIMEOUT+time.Duration(len(users)/userStoreBatch)*time.Second)
+	defer cancel()
+
+	snap := newUserSnapshot(len(users))
+	for i := range users {
+		u := normalizeUser(users[i])
+		if _, err := checkUser(&u); err != nil {
+			continue
+		}
+		snap.put(&u)
+	}
+	snap.rehash()
+
+	gen, err := s.client.Incr(ctx, s.key("seq")).Result()
+	if err != nil {
+		return fmt.Errorf("user store: allocate generation: %w", err)
+	}
+	for start := 0; start < len(snap.order); start += userStoreBatch {
+		batch := snap.order[start:min(start+userStoreBatch, len(snap.order))]
+		pipe := s.client.Pipeline()
+		order := make([]interface{}, 0, len(batch))
+		for _, key := range batch {
+			e, _ := snap.users.get(key) // a new snapshot has no stale keys in order
+			u := e.user
+			scope, err := json.Marshal(u.Scope)
+			if err != nil {
+				return fmt.Errorf("user store: encode scope: %w", err)
+			}
+			pipe.HSet(ctx, s.userKey(gen, "user", key),
+				"phone", u.Phone, "mail", u.Mail, "user_id", u.UserID, "status", u.Status,
+				"scope", string(scope), "role", u.Role, "name", u.Name, "dingtalk_userid", u.DingtalkUserID)
+			order = append(order, key)
+		}
+		pipe.RPush(ctx, s.userKey(gen, "meta", "order"), order...)
+		if _, err := pipe.Exec(ctx); err != nil {
+			s.deleteGeneration(ctx, gen)
+			return fmt.Errorf("user store: write users: %w", err)
+		}
+	}
+	if err := s.writeIndex(ctx, gen, IndexMail, &snap.mail); err != nil {
+		s.deleteGeneration(ctx, gen)
+		return err
+	}
+	if err := s.writeIndex(ctx, gen, IndexUserID, &snap.userID); err != nil {
+		s.deleteGeneration(ctx, gen)
+		return err
+	}
+	info := &storeInfo{
+		UserStoreInfo: UserStoreInfo{Generation: gen, Users: snap.users.len(), Hash: snap.hash},
+		filter:        snap.buildFilter(s.filterRate),
+	}
+	if err := s.writeMeta(ctx, info); err != nil {
+		s.deleteGeneration(ctx, gen)
+		return err
+	}
+
+	previous, err := s.switchGeneration(ctx, gen, token)
+	if errors.Is(err, ErrFenced) {
+		s.deleteGeneration(ctx, gen)
+	}
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.gen, s.genExpiry = gen, time.Now().Add(s.lru.ttl)
+	s.mu.Unlock()
+	s.lru.purge()
+	s.info.Store(info)
+	if previous > 0 && previous != gen {
+		s.deleteGeneration(ctx, previous)
+	}
+	return nil
+}
+
+// switchGeneration makes gen the current generation, fenced with token unless it is 0, and returns the
+// previous one (0 without).
+func (s *RedisUserStore) switchGeneration(ctx context.Context, gen, token int64) (int64, error) {
+	if token == 0 {
+		previous, err := s.client.GetSet(ctx, s.key("gen"), gen).Int64()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return 0, fmt.Errorf("user store: switch generation: %w", err)
+		}
+		return previous, nil
+	}
+	key := s.key("gen")
+	previous, err := fencedSwitchScript.Run(ctx, s.client, []string{key, fenceKey(key)}, token, gen).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("user store: switch generation: %w", err)
+	}
+	if previous < 0 {
+		return 0, ErrFenced
+	}
+	return previous, nil
+}
+
+// writeMeta writes the user count, the content hash and the membership filter of a generation.
+func (s *RedisUserStore) writeMeta(ctx context.Context, info *storeInfo) error {
+	pipe := s.client.Pipeline()
+	pipe.Set(ctx, s.userKey(info.Generation, "meta", "count"), info.Users, 0)
+	pipe.Set(ctx, s.userKey(info.Generation, "meta", "hash"), info.Hash, 0)
+	if info.filter != nil {
+		data, err := info.filter.MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("user store: encode membership filter: %w", err)
+		}
+		pipe.Set(ctx, s.userKey(info.Generation, "meta", "filter"), data, 0)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("user store: write meta: %w", err)
+	}
+	return nil
+}
+
+// writeIndex writes the index keys of one index (normalized value -> user key).
+func (s *RedisUserStore) writeIndex(ctx context.Context, gen int64, index string, entries *shardedMap[string]) error {
+	pipe := s.client.Pipeline()
+	n := 0
+	for value, key := range entries.all() {
+		pipe.Set(ctx, s.userKey(gen, index, value), key, 0)
+		if n++; n%userStoreBatch == 0 {
+			if _, err := pipe.Exec(ctx); err != nil {
+				return fmt.Errorf("user store: write %s index: %w", index, err)
+			}
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("user store: write %s index: %w", index, err)
+	}
+	return nil
+}
+
+// deleteGeneration removes all keys of a generation. Failures leave orphaned keys behind but do not affect lookups.
+func (s *RedisUserStore) deleteGeneration(ctx context.Context, gen int64) {
+	pattern := s.key(strconv.FormatInt(gen, 10) + ":*")
+	err := scanKeys(ctx, s.client, pattern, userStoreBatch, func(keys []string) error {
+		return deleteKeys(ctx, s.client, keys)
+	})
+	if err != nil {
+		log.Warn().Err(err).Int64("generation", gen).Msg("Failed to delete user store generation")
+	}
+}
+
+// Len returns the number of users in the current generation.
+func (s *RedisUserStore) Len() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), REDIS_OPERATION_TIMEOUT)
+	defer cancel()
+	gen, err := s.generation(ctx, true)
+	if err != nil {
+		return 0, err
+	}
+	return s.client.Get(ctx, s.userKey(gen, "meta", "count")).Int()
+}
+
+// GetVersion returns the current generation (0 while the store is empty).
+func (s *RedisUserStore) GetVersion() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), REDIS_OPERATION_TIMEOUT)
+	defer cancel()
+	gen, err := s.generation(ctx, true)
+	if errors.Is(err, ErrUserStoreEmpty) {
+		return 0, nil
+	}
+	return gen, err
+}
+
+// Info returns the generation last written by Set or loaded by Refresh; false before either succeeded.
+func (s *RedisUserStore) Info() (UserStoreInfo, bool) {
+	info := s.info.Load()
+	if info == nil {
+		return UserStoreInfo{}, false
+	}
+	return info.UserStoreInfo, true
+}
+
+// MembershipFilter returns the membership filter of the generation last written or loaded, or nil when
+// it is not enabled or not known yet. The filter is shared and must not be modified.
+func (s *RedisUserStore) MembershipFilter() *warden.MembershipFilter {
+	if info := s.info.Load(); info != nil {
+		return info.filter
+	}
+	return nil
+}
+
+// Refresh picks up the current generation, e.g. after another instance wrote one: it drops the local
+// LRU and loads the user count, content hash and membership filter of the generation (the users stay
+// in Redis). Returns ErrUserStoreEmpty while no generation has been written.
+func (s *RedisUserStore) Refresh() error {
+	s.Invalidate()
+	ctx, cancel := context.WithTimeout(context.Background(), REDIS_OPERATION_TIMEOUT)
+	defer cancel()
+	gen, err := s.generation(ctx, true)
+	if err != nil {
+		return err
+	}
+	pipe := s.client.Pipeline()
+	count := pipe.Get(ctx, s.userKey(gen, "meta", "count"))
+	hash := pipe.Get(ctx, s.userKey(gen, "meta", "hash"))
+	var filter *redis.StringCmd
+	if s.filterRate > 0 {
+		filter = pipe.Get(ctx, s.userKey(gen, "meta", "filter"))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("user store: read meta: %w", err)
+	}
+	users, err := count.Int()
+	if err != nil {
+		return fmt.Errorf("user store: read count of generation %d: %w", gen, err)
+	}
+	info := &storeInfo{UserStoreInfo: UserStoreInfo{Generation: gen, Users: users, Hash: hash.Val()}}
+	if filter != nil {
+		if data, err := filter.Bytes(); err == nil {
+			info.filter = &warden.MembershipFilter{}
+			if err := info.filter.UnmarshalBinary(data); err != nil {
+				return fmt.Errorf("user store: decode membership filter: %w", err)
+			}
+		}
+	}
+	s.info.Store(info)
+	return nil
+}
+
+// Page reads up to limit users starting at offset, in the order Set wrote them, and returns them with
+// the number of users of the generation. A generation switched by another instance meanwhile is
+// followed once, like a lookup miss.
+func (s *RedisUserStore) Page(offset, limit int) ([]define.AllowListUser, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), REDIS_OPERATION_TIMEOUT)
+	defer cancel()
+	gen, err := s.generation(ctx, false)
+	if err != nil {
+		return nil, 0, err
+	}
+	users, total, err := s.page(ctx, gen, offset, limit)
+	if !errors.Is(err, redis.Nil) {
+		return users, total, err
+	}
+	if gen, err = s.generation(ctx, true); err != nil {
+		return nil, 0, err
+	}
+	users, total, err = s.page(ctx, gen, offset, limit)
+	if errors.Is(err, redis.Nil) {
+		err = fmt.Errorf("user store: generation %d was replaced while reading", gen)
+	}
+	return users, total, err
+}
+
+// Each calls fn with the users of the current generation in pages of at most userStoreBatch, in the
+// order Set wrote them, until fn fails. Only one page is held in memory. The generation is fixed at the
+// start; Each fails when another instance deletes it before the last page was read.
+func (s *RedisUserStore) Each(fn func(users []define.AllowListUser) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), REDIS_OPERATION_TIMEOUT)
+	gen, err := s.generation(ctx, true)
+	cancel()
+	if err != nil {
+		return err
+	}
+	for offset := 0; ; offset += userStoreBatch {
+		ctx, cancel := context.WithTimeout(context.Background(), REDIS_OPERATION_TIMEOUT)
+		users, total, err := s.page(ctx, gen, offset, userStoreBatch)
+		cancel()
+		if errors.Is(err, redis.Nil) {
+			return fmt.Errorf("user store: generation %d was replaced while reading", gen)
+		}
+		if err != nil {
+			return err
+		}
+		if len(users) > 0 {
+			if err := fn(users); err != nil {
+				return err
+			}
+		}
+		if offset+userStoreBatch >= total {
+			return nil
+		}
+	}
+}
+
+// page reads the users of generation gen at offset. It returns redis.Nil when the generation, or one of
+// its users, no longer exists.
+func (s *RedisUserStore) page(ctx context.Context, gen int64, offset, limit int) ([]define.AllowListUser, int, error) {
+	total, err := s.client.Get(ctx, s.userKey(gen, "meta", "count")).Int()
+	if err != nil {
+		return nil, 0, err
+	}
+	if limit <= 0 || offset >= total {
+		return []define.AllowListUser{}, total, nil
+	}
+	keys, err := s.client.LRange(ctx, s.userKey(gen, "meta", "order"), int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(keys) < min(limit, total-offset) {
+		return nil, 0, redis.Nil
+	}
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, 0, len(keys))
+	for _, key := range keys {
+		cmds = append(cmds, pipe.HGetAll(ctx, s.userKey(gen, "user", key)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, 0, err
+	}
+	users := make([]define.AllowListUser, 0, len(keys))
+	for _, cmd := range cmds {
+		if len(cmd.Val()) == 0 {
+			return nil, 0, redis.Nil
+		}
+		user, err := decodeStoreUser(cmd.Val())
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+	return users, total, nil
+}
+
+// Invalidate drops the local LRU and the cached generation, e.g. after another instance wrote a new generation.
+func (s *RedisUserStore) Invalidate() {
+	s.mu.Lock()
+	s.gen, s.genExpiry = 0, time.Time{}
+	s.mu.Unlock()
+	s.lru.purge()
+}
+
+// generation returns the current generation, read from Redis when refresh is set or the cached value is older than the LRU TTL.
+func (s *RedisUserStore) generation(ctx context.Context, refresh bool) (int64, error) {
+	s.mu.Lock()
+	gen, expiry := s.gen, s.genExpiry
+	s.mu.Unlock()
+	if gen > 0 && !refresh && time.Now().Before(expiry) {
+		return gen, nil
+	}
+	gen, err := s.client.Get(ctx, s.key("gen")).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, ErrUserStoreEmpty
+	}
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	s.gen, s.genExpiry = gen, time.Now().Add(s.lru.ttl)
+	s.mu.Unlock()
+	return gen, nil
+}
+
+// GetByPhone gets user by phone number
+func (s *RedisUserStore) GetByPhone(phone string) (define.AllowListUser, bool) {
+	return s.lookup("user", strings.TrimSpace(phone), func(f UserLookup) (define.AllowListUser, bool) { return f.GetByPhone(phone) })
+}
+
+// GetByMail gets user by email (case-insensitive)
+func (s *RedisUserStore) GetByMail(mail string) (define.AllowListUser, bool) {
+	return s.lookup(IndexMail, indexKey(mail), func(f UserLookup) (define.AllowListUser, bool) { return f.GetByMail(mail) })
+}
+
+// GetByUserID gets user by user ID (case-insensitive, like SafeUserCache)
+func (s *RedisUserStore) GetByUserID(userID string) (define.AllowListUser, bool) {
+	return s.lookup(IndexUserID, indexKey(userID), func(f UserLookup) (define.AllowListUser, bool) { return f.GetByUserID(userID) })
+}
+
+// lookup resolves id of the given kind ("user" = primary key, or an index) through the LRU and Redis,
+// using fallback while the store is empty or Redis fails.
+func (s *RedisUserStore) lookup(kind, id string, fallback func(UserLookup) (define.AllowListUser, bool)) (define.AllowListUser, bool) {
+	if id == "" {
+		return define.AllowListUser{}, false
+	}
+	cacheKey := kind + ":" + id
+	if user, found, ok := s.lru.get(cacheKey); ok {
+		prommetrics.RecordUserStoreLookup("local")
+		return user, found
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), REDIS_OPERATION_TIMEOUT)
+	defer cancel()
+	user, found, err := s.fetchCurrent(ctx, kind, id)
+	if err != nil {
+		if !errors.Is(err, ErrUserStoreEmpty) {
+			prommetrics.RecordUserStoreLookup("error")
+			log.Warn().Err(err).Str("index", kind).Msg("User store lookup failed, using memory cache")
+		}
+		if s.fallback == nil {
+			return define.AllowListUser{}, false
+		}
+		return fallback(s.fallback)
+	}
+	prommetrics.RecordUserStoreLookup("redis")
+	s.lru.add(cacheKey, user, found)
+	return user, found
+}
+
+// fetchCurrent reads a user from the current generation. A miss is retried once with a freshly read
+// generation, in case another instance switched generations and deleted the cached one.
+func (s *RedisUserStore) fetchCurrent(ctx context.Context, kind, id string) (define.AllowListUser, bool, error) {
+	gen, err := s.generation(ctx, false)
+	if err != nil {
+		return define.AllowListUser{}, false, err
+	}
+	user, found, err := s.fetch(ctx, gen, kind, id)
+	if err != nil || found {
+		return user, found, err
+	}
+	latest, err := s.generation(ctx, true)
+	if err != nil || latest == gen {
+		return define.AllowListUser{}, false, err
+	}
+	return s.fetch(ctx, latest, kind, id)
+}
+
+// fetch reads a user of generation gen, resolving index keys first.
+func (s *RedisUserStore) fetch(ctx context.Context, gen int64, kind, id string) (define.AllowListUser, bool, error) {
+	key := id
+	if kind != "user" {
+		var err error
+		key, err = s.client.Get(ctx, s.userKey(gen, kind, id)).Result()
+		if errors.Is(err, redis.Nil) {
+			return define.AllowListUser{}, false, nil
+		}
+		if err != nil {
+			return define.AllowListUser{}, false, err
+		}
+	}
+	fields, err := s.client.HGetAll(ctx, s.userKey(gen, "user", key)).Result()
+	if err != nil {
+		return define.AllowListUser{}, false, err
+	}
+	if len(fields) == 0 {
+		return define.AllowListUser{}, false, nil
+	}
+	user, err := decodeStoreUser(fields)
+	if err != nil {
+		return define.AllowListUser{}, false, err
+	}
+	return user, true, nil
+}
+
+// decodeStoreUser builds a user from the fields of its hash.
+func decodeStoreUser(fields map[string]string) (define.AllowListUser, error) {
+	user := define.AllowListUser{
+		Phone:          fields["phone"],
+		Mail:           fields["mail"],
+		UserID:         fields["user_id"],
+		Status:         fields["status"],
+		Role:           fields["role"],
+		Name:           fields["name"],
+		DingtalkUserID: fields["dingtalk_userid"],
+		Scope:          []string{},
+	}
+	if scope := fields["scope"]; scope != "" {
+		if err := json.Unmarshal([]byte(scope), &user.Scope); err != nil {
+			return define.AllowListUser{}, fmt.Errorf("user store: decode scope: %w", err)
+		}
+	}
+	return user, nil
+}
@@ -0,0 +1,270 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/pkg/warden"
+)
+
+// TestRedisUserStore_UserIDCaseInsensitive checks that user_id is matched the way SafeUserCache does it
+// (trimmed, case-insensitive), so the answer does not depend on REDIS_USER_STORE.
+func TestRedisUserStore_UserIDCaseInsensitive(t *testing.T) {
+	client, _ := newFakeRedisClientServer(t)
+	store := NewRedisUserStore(client, "", 0, 0, nil)
+	memory := NewSafeUserCache()
+	users := []define.AllowListUser{{Phone: "13700137000", UserID: "U123"}}
+	memory.Set(users)
+	require.NoError(t, store.Set(users))
+
+	for _, id := range []string{"U123", "u123", " u123 "} {
+		user, ok := store.GetByUserID(id)
+		require.True(t, ok, id)
+		assert.Equal(t, "U123", user.UserID)
+		_, ok = memory.GetByUserID(id)
+		assert.True(t, ok, id)
+	}
+}
+
+func TestRedisUserStore_SetAndLookup(t *testing.T) {
+	client, server := newFakeRedisClientServer(t)
+	store := NewRedisUserStore(client, "", 0, 0, nil)
+
+	require.NoError(t, store.Set([]define.AllowListUser{
+		{Phone: "13800138000", Mail: "User1@Example.com", UserID: "u1", Scope: []string{"read", "write"}, Name: "One"},
+		{Mail: "only-mail@example.com", UserID: "u2"},
+		{Phone: "", Mail: ""}, // invalid, dropped
+		{Phone: "13800138000", Mail: "user1@example.com", UserID: "u1", Role: "admin"}, // replaces the first record
+	}))
+
+	n, err := store.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	user, ok := store.GetByPhone(" 13800138000 ")
+	require.True(t, ok)
+	assert.Equal(t, "admin", user.Role)
+	assert.Equal(t, "active", user.Status)
+	assert.Equal(t, []string{}, user.Scope)
+
+	user, ok = store.GetByMail("USER1@example.com")
+	require.True(t, ok)
+	assert.Equal(t, "13800138000", user.Phone)
+
+	user, ok = store.GetByUserID("u2")
+	require.True(t, ok)
+	assert.Equal(t, "only-mail@example.com", user.Mail)
+
+	_, ok = store.GetByPhone("13900139000")
+	assert.False(t, ok)
+	_, ok = store.GetByUserID("")
+	assert.False(t, ok)
+
+	// A new generation replaces the previous one, whose keys are deleted
+	require.NoError(t, store.Set([]define.AllowListUser{{Phone: "13900139000", Scope: []string{"read"}}}))
+	_, ok = store.GetByPhone("13800138000")
+	assert.False(t, ok)
+	user, ok = store.GetByPhone("13900139000")
+	require.True(t, ok)
+	assert.Equal(t, []string{"read"}, user.Scope)
+
+	server.Mu.Lock()
+	defer server.Mu.Unlock()
+	for key := range server.Hashes {
+		assert.True(t, strings.HasPrefix(key, DefaultNamespace.Key(REDIS_USER_STORE_PREFIX+":2:")), key)
+	}
+}
+
+func TestRedisUserStore_FollowsGenerationOfOtherInstance(t *testing.T) {
+	client := newFakeRedisClient(t)
+	writer := NewRedisUserStore(client, "", 0, 0, nil)
+	reader := NewRedisUserStore(client, "", 0, time.Hour, nil)
+
+	require.NoError(t, writer.Set([]define.AllowListUser{{Phone: "13800138000", UserID: "a"}}))
+	user, ok := reader.GetByPhone("13800138000")
+	require.True(t, ok)
+	assert.Equal(t, "a", user.UserID)
+
+	// The reader's cached generation is deleted by the writer; a miss re-reads the generation
+	require.NoError(t, writer.Set([]define.AllowListUser{{Phone: "13900139000", UserID: "b"}}))
+	user, ok = reader.GetByUserID("b")
+	require.True(t, ok)
+	assert.Equal(t, "13900139000", user.Phone)
+
+	// Results are served from the LRU until they expire
+	user, ok = reader.GetByPhone("13800138000")
+	require.True(t, ok, "cached result")
+	assert.Equal(t, "a", user.UserID)
+
+	// Invalidate drops the LRU and the cached generation
+	reader.Invalidate()
+	_, ok = reader.GetByPhone("13800138000")
+	assert.False(t, ok)
+	version, err := reader.GetVersion()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), version)
+}
+
+func TestRedisUserStore_GetVersionEmpty(t *testing.T) {
+	store := NewRedisUserStore(newFakeRedisClient(t), "", 0, 0, nil)
+	version, err := store.GetVersion()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), version)
+}
+
+func TestRedisUserStore_PageAndEach(t *testing.T) {
+	store := NewRedisUserStore(newFakeRedisClient(t), "", 0, 0, nil)
+	_, _, err := store.Page(0, 10)
+	require.ErrorIs(t, err, ErrUserStoreEmpty)
+	require.ErrorIs(t, store.Each(func([]define.AllowListUser) error { return nil }), ErrUserStoreEmpty)
+
+	users := make([]define.AllowListUser, 0, userStoreBatch+2)
+	for i := range userStoreBatch + 2 {
+		users = append(users, define.AllowListUser{Phone: strconv.Itoa(13900000000 - i), Name: strconv.Itoa(i)})
+	}
+	users = append(users, define.AllowListUser{Phone: "13900000000", Name: "replaced", Scope: []string{"read"}})
+	require.NoError(t, store.Set(users))
+
+	// Pages follow the order of Set; a replaced user keeps its position
+	page, total, err := store.Page(0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, userStoreBatch+2, total)
+	require.Len(t, page, 2)
+	assert.Equal(t, "replaced", page[0].Name)
+	assert.Equal(t, []string{"read"}, page[0].Scope)
+	assert.Equal(t, "1", page[1].Name)
+	page, _, err = store.Page(userStoreBatch+1, 10)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, strconv.Itoa(userStoreBatch+1), page[0].Name)
+	page, _, err = store.Page(userStoreBatch+2, 10)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+
+	var pages []int
+	var names []string
+	require.NoError(t, store.Each(func(batch []define.AllowListUser) error {
+		pages = append(pages, len(batch))
+		for _, u := range batch {
+			names = append(names, u.Name)
+		}
+		return nil
+	}))
+	assert.Equal(t, []int{userStoreBatch, 2}, pages)
+	memory := NewSafeUserCache()
+	memory.Set(users)
+	want := make([]string, 0, memory.Len())
+	for _, u := range memory.Get() {
+		want = append(want, u.Name)
+	}
+	assert.Equal(t, want, names, "same order as the memory cache")
+
+	stop := errors.New("stop")
+	assert.ErrorIs(t, store.Each(func([]define.AllowListUser) error { return stop }), stop)
+
+	// An empty dataset is a generation without users
+	require.NoError(t, store.Set(nil))
+	page, total, err = store.Page(0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+	assert.Equal(t, 0, total)
+}
+
+// TestRedisUserStore_Refresh tests that another instance picks up the count, hash and membership filter
+// of a generation without reading its users.
+func TestRedisUserStore_Refresh(t *testing.T) {
+	client := newFakeRedisClient(t)
+	writer := NewRedisUserStore(client, "", 0, 0, nil)
+	writer.EnableMembershipFilter(0.01)
+	reader := NewRedisUserStore(client, "", 0, 0, nil)
+	reader.EnableMembershipFilter(0.01)
+
+	_, ok := reader.Info()
+	assert.False(t, ok)
+	require.ErrorIs(t, reader.Refresh(), ErrUserStoreEmpty)
+	assert.Nil(t, reader.MembershipFilter())
+
+	users := []define.AllowListUser{{Phone: "13800138000", Mail: "a@example.com"}, {Mail: "b@example.com", UserID: "b"}}
+	require.NoError(t, writer.Set(users))
+	require.NoError(t, reader.Refresh())
+	memory := NewSafeUserCache()
+	memory.Set(users)
+
+	written, ok := writer.Info()
+	require.True(t, ok)
+	info, ok := reader.Info()
+	require.True(t, ok)
+	assert.Equal(t, written, info)
+	assert.Equal(t, 2, info.Users)
+	assert.Equal(t, memory.GetHash(), info.Hash)
+
+	filter := reader.MembershipFilter()
+	require.NotNil(t, filter)
+	assert.True(t, filter.MayContain(warden.IdentifierMail, "b@example.com"))
+	assert.True(t, filter.MayContain(warden.IdentifierUserID, "b"))
+	assert.False(t, filter.MayContain(warden.IdentifierPhone, "13900139000"))
+}
+
+func TestRedisUserStore_Fallback(t *testing.T) {
+	memory := NewSafeUserCache()
+	memory.Set([]define.AllowListUser{{Phone: "13800138000", Mail: "a@example.com", UserID: "a"}})
+
+	// Empty store: lookups are answered by the memory cache
+	client := newFakeRedisClient(t)
+	store := NewRedisUserStore(client, "", 0, 0, memory)
+	user, ok := store.GetByMail("a@example.com")
+	require.True(t, ok)
+	assert.Equal(t, "a", user.UserID)
+	_, err := store.Len()
+	assert.ErrorIs(t, err, ErrUserStoreEmpty)
+
+	// Unreachable Redis: same
+	broken := redis.NewClient(&redis.Options{
+		Addr:        "127.0.0.1:1",
+		DialTimeout: 100 * time.Millisecond,
+		MaxRetries:  -1,
+	})
+	t.Cleanup(func() { _ = broken.Close() }) //nolint:errcheck // test cleanup
+	store = NewRedisUserStore(broken, "", -1, 0, memory)
+	user, ok = store.GetByUserID("a")
+	require.True(t, ok)
+	assert.Equal(t, "13800138000", user.Phone)
+	assert.Error(t, store.Set([]define.AllowListUser{{Phone: "13900139000"}}))
+
+	store = NewRedisUserStore(broken, "", -1, 0, nil)
+	_, ok = store.GetByPhone("13800138000")
+	assert.False(t, ok)
+}
+
+func TestValidateUserStoreMode(t *testing.T) {
+	for _, mode := range []string{"", "blob", "hash", " HASH "} {
+		assert.NoError(t, ValidateUserStoreMode(mode), mode)
+	}
+	assert.Error(t, ValidateUserStoreMode("sharded"))
+}
+
+func TestRedisUserStore_KeysAreScopedByGeneration(t *testing.T) {
+	client := newFakeRedisClient(t)
+	store := NewRedisUserStore(client, "", 0, 0, nil)
+	require.NoError(t, store.Set([]define.AllowListUser{{Phone: "13800138000", Mail: "a@example.com", UserID: "a"}}))
+
+	ctx := context.Background()
+	gen, err := client.Get(ctx, store.key("gen")).Int64()
+	require.NoError(t, err)
+	key, err := client.Get(ctx, store.userKey(gen, IndexMail, "a@example.com")).Result()
+	require.NoError(t, err)
+	assert.Equal(t, "13800138000", key)
+	fields, err := client.HGetAll(ctx, store.userKey(gen, "user", "13800138000")).Result()
+	require.NoError(t, err)
+	assert.Equal(t, "a", fields["user_id"])
+	assert.Equal(t, "[]", fields["scope"])
+}
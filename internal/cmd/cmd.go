@@ -8,6 +8,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	// External packages
 	"github.com/soulteary/cli-kit/configutil"
@@ -48,6 +49,29 @@ type Config struct {
 	TLSKeyFile              string   // env WARDEN_TLS_KEY
 	TLSCAFile               string   // env WARDEN_TLS_CA (client CA for mTLS)
 	TLSRequireClientCert    bool     // env WARDEN_TLS_REQUIRE_CLIENT_CERT
+
+	// Structured source options (YAML + env)
+	RemotePagination config.RemotePaginationConfig // env REMOTE_PAGINATION, REMOTE_PAGE_SIZE, REMOTE_MAX_PAGES, ...
+	DataWatch        config.FileWatchConfig        // env DATA_WATCH, DATA_WATCH_DEBOUNCE
+	Signature        config.SignatureConfig        // env SIGNATURE_PUBLIC_KEYS (comma-separated)
+	RemoteKeys       []config.RemoteKeyConfig      // env REMOTE_PRIVATE_KEYS ("kid=path", comma-separated)
+	DecryptLegacy    bool                          // env REMOTE_DECRYPT_LEGACY (accept application/x-warden-encrypted)
+	Merge            config.MergeConfig            // env MERGE_FIELDS ("field=strategy", comma-separated), MERGE_CONFLICTS
+	AdminAPIKey      string                        // env ADMIN_API_KEY (enables /v1/admin endpoints)
+	Exec             config.ExecSourceConfig       // env EXEC_COMMAND, EXEC_ARGS, EXEC_FORMAT, EXEC_TIMEOUT, EXEC_PASS_ENV
+	Git              config.GitSourceConfig        // env GIT_SOURCE_URL, GIT_SOURCE_REF, GIT_SOURCE_PATHS, GIT_SOURCE_DIR, GIT_SOURCE_TIMEOUT
+	CircuitBreaker   config.CircuitBreakerConfig   // env CIRCUIT_BREAKER, CIRCUIT_BREAKER_THRESHOLD, CIRCUIT_BREAKER_BASE_DELAY, CIRCUIT_BREAKER_MAX_DELAY
+	RemoteTLS        config.RemoteTLSConfig        // env REMOTE_TLS_CA_FILE, REMOTE_TLS_CERT_FILE, REMOTE_TLS_KEY_FILE, REMOTE_TLS_SERVER_NAME
+	RemoteOAuth2     config.RemoteOAuth2Config     // env REMOTE_OAUTH2_TOKEN_URL, REMOTE_OAUTH2_CLIENT_ID, REMOTE_OAUTH2_CLIENT_SECRET_FILE, REMOTE_OAUTH2_SCOPES, ...
+	RedisDB          int                           // env REDIS_DB (database of standalone and Sentinel mode)
+	RedisKeyPrefix   string                        // env REDIS_KEY_PREFIX (namespace of Redis keys, channels and locks, default "warden")
+	RedisEncoding    string                        // env REDIS_PAYLOAD_ENCODING (json, zstd; encoding of the user list in Redis)
+	CacheTTL         time.Duration                 // env CACHE_TTL (expiry of the user list in Redis, default 1h)
+	BloomFPRate      float64                       // env BLOOM_FILTER_FP_RATE (false positive rate of the membership filter, 0 = disabled)
+	RedisTopology    config.RedisTopologyConfig    // env REDIS_MODE (standalone, sentinel, cluster), REDIS_MASTER_NAME, REDIS_ADDRS, REDIS_SENTINEL_PASSWORD
+	UserStore        config.UserStoreConfig        // env REDIS_USER_STORE (blob, hash), REDIS_USER_STORE_LRU_SIZE, REDIS_USER_STORE_LRU_TTL
+	LeaderLease      time.Duration                 // env REDIS_LEADER_LEASE (leader election lease, default 15s)
+	DatasetHistory   int                           // env DATASET_HISTORY (datasets kept for rollback, default 5, -1 = none)
 }
 
 // flagValues holds parsed flag values
@@ -304,6 +328,10 @@ func processAPIKeyFromFlags(cfg *Config, fs *flag.FlagSet) {
 	if apiKeyVal := configutil.ResolveString(fs, "api-key", "API_KEY", "", true); apiKeyVal != "" {
 		cfg.APIKey = apiKeyVal
 	}
+	// The admin key is env/config only, so it does not show up in the process list
+	if v := env.GetTrimmed("ADMIN_API_KEY", ""); v != "" {
+		cfg.AdminAPIKey = v
+	}
 }
 
 // processDataFileFromFlags processes local data file path configuration
@@ -337,7 +365,8 @@ func processResponseFieldsFromEnv(cfg *Config) {
 	}
 }
 
-// processRemoteDecryptFromEnv reads REMOTE_DECRYPT_ENABLED, REMOTE_RSA_PRIVATE_KEY_FILE, REMOTE_RSA_PRIVATE_KEY from env.
+// processRemoteDecryptFromEnv reads REMOTE_DECRYPT_ENABLED, REMOTE_RSA_PRIVATE_KEY_FILE, REMOTE_RSA_PRIVATE_KEY,
+// REMOTE_PRIVATE_KEYS and REMOTE_DECRYPT_LEGACY from env.
 func processRemoteDecryptFromEnv(cfg *Config) {
 	if v := env.GetTrimmed("REMOTE_DECRYPT_ENABLED", ""); v != "" {
 		cfg.RemoteDecryptEnabled = strings.EqualFold(v, "true") || v == "1"
@@ -348,6 +377,235 @@ func processRemoteDecryptFromEnv(cfg *Config) {
 	if v := env.GetTrimmed("REMOTE_RSA_PRIVATE_KEY", ""); v != "" {
 		cfg.RemoteRSAPrivateKey = v
 	}
+	if v := env.GetTrimmed("REMOTE_PRIVATE_KEYS", ""); v != "" {
+		cfg.RemoteKeys = config.ParseRemoteKeys(v)
+	}
+	if env.Has("REMOTE_DECRYPT_LEGACY") {
+		cfg.DecryptLegacy = env.GetBool("REMOTE_DECRYPT_LEGACY", false)
+	}
+}
+
+// processRemoteTLSFromEnv reads REMOTE_TLS_CA_FILE, REMOTE_TLS_CERT_FILE, REMOTE_TLS_KEY_FILE and
+// REMOTE_TLS_SERVER_NAME from env.
+func processRemoteTLSFromEnv(cfg *Config) {
+	if v := env.GetTrimmed("REMOTE_TLS_CA_FILE", ""); v != "" {
+		cfg.RemoteTLS.CAFile = v
+	}
+	if v := env.GetTrimmed("REMOTE_TLS_CERT_FILE", ""); v != "" {
+		cfg.RemoteTLS.CertFile = v
+	}
+	if v := env.GetTrimmed("REMOTE_TLS_KEY_FILE", ""); v != "" {
+		cfg.RemoteTLS.KeyFile = v
+	}
+	if v := env.GetTrimmed("REMOTE_TLS_SERVER_NAME", ""); v != "" {
+		cfg.RemoteTLS.ServerName = v
+	}
+}
+
+// processRemoteOAuth2FromEnv reads REMOTE_OAUTH2_TOKEN_URL, REMOTE_OAUTH2_CLIENT_ID, REMOTE_OAUTH2_CLIENT_SECRET_FILE,
+// REMOTE_OAUTH2_SCOPES, REMOTE_OAUTH2_AUDIENCE and REMOTE_OAUTH2_AUTH_STYLE from env.
+func processRemoteOAuth2FromEnv(cfg *Config) {
+	if v := env.GetTrimmed("REMOTE_OAUTH2_TOKEN_URL", ""); v != "" {
+		cfg.RemoteOAuth2.TokenURL = v
+	}
+	if v := env.GetTrimmed("REMOTE_OAUTH2_CLIENT_ID", ""); v != "" {
+		cfg.RemoteOAuth2.ClientID = v
+	}
+	if v := env.GetTrimmed("REMOTE_OAUTH2_CLIENT_SECRET_FILE", ""); v != "" {
+		cfg.RemoteOAuth2.ClientSecretFile = v
+	}
+	if v := env.GetStringSlice("REMOTE_OAUTH2_SCOPES", nil, ","); len(v) > 0 {
+		cfg.RemoteOAuth2.Scopes = v
+	}
+	if v := env.GetTrimmed("REMOTE_OAUTH2_AUDIENCE", ""); v != "" {
+		cfg.RemoteOAuth2.Audience = v
+	}
+	if v := env.GetTrimmed("REMOTE_OAUTH2_AUTH_STYLE", ""); v != "" {
+		cfg.RemoteOAuth2.AuthStyle = strings.ToLower(v)
+	}
+}
+
+// processUserStoreFromEnv reads REDIS_USER_STORE, REDIS_USER_STORE_LRU_SIZE and REDIS_USER_STORE_LRU_TTL from env.
+func processUserStoreFromEnv(cfg *Config) {
+	if v := env.GetTrimmed("REDIS_USER_STORE", ""); v != "" {
+		cfg.UserStore.Mode = strings.ToLower(v)
+	}
+	if v := env.GetInt("REDIS_USER_STORE_LRU_SIZE", 0); v != 0 {
+		cfg.UserStore.LRUSize = v
+	}
+	if v := env.GetDuration("REDIS_USER_STORE_LRU_TTL", 0); v > 0 {
+		cfg.UserStore.LRUTTL = v
+	}
+}
+
+// processRedisTopologyFromEnv reads REDIS_MODE, REDIS_MASTER_NAME, REDIS_ADDRS and REDIS_SENTINEL_PASSWORD from env.
+func processRedisTopologyFromEnv(cfg *Config) {
+	if v := env.GetTrimmed("REDIS_MODE", ""); v != "" {
+		cfg.RedisTopology.Mode = strings.ToLower(v)
+	}
+	if v := env.GetTrimmed("REDIS_MASTER_NAME", ""); v != "" {
+		cfg.RedisTopology.MasterName = v
+	}
+	if v := env.GetStringSlice("REDIS_ADDRS", nil, ","); len(v) > 0 {
+		cfg.RedisTopology.Addrs = v
+	}
+	if v := env.GetTrimmed("REDIS_SENTINEL_PASSWORD", ""); v != "" {
+		cfg.RedisTopology.SentinelPassword = v
+	}
+}
+
+// processRedisKeysFromEnv reads REDIS_DB, REDIS_KEY_PREFIX and CACHE_TTL from env.
+func processRedisKeysFromEnv(cfg *Config) {
+	if v := env.GetInt("REDIS_DB", -1); v >= 0 {
+		cfg.RedisDB = v
+	}
+	if v := env.GetTrimmed("REDIS_KEY_PREFIX", ""); v != "" {
+		cfg.RedisKeyPrefix = v
+	}
+	if v := env.GetDuration("CACHE_TTL", 0); v > 0 {
+		cfg.CacheTTL = v
+	}
+}
+
+// processMembershipFilterFromEnv reads BLOOM_FILTER_FP_RATE from env.
+func processMembershipFilterFromEnv(cfg *Config) {
+	if v := env.GetFloat64("BLOOM_FILTER_FP_RATE", -1); v >= 0 {
+		cfg.BloomFPRate = v
+	}
+}
+
+// processRedisEncodingFromEnv reads REDIS_PAYLOAD_ENCODING from env.
+func processRedisEncodingFromEnv(cfg *Config) {
+	if v := env.GetTrimmed("REDIS_PAYLOAD_ENCODING", ""); v != "" {
+		cfg.RedisEncoding = strings.ToLower(v)
+	}
+}
+
+// processLeaderLeaseFromEnv reads REDIS_LEADER_LEASE from env.
+func processLeaderLeaseFromEnv(cfg *Config) {
+	if v := env.GetDuration("REDIS_LEADER_LEASE", 0); v > 0 {
+		cfg.LeaderLease = v
+	}
+}
+
+// processDatasetHistoryFromEnv reads DATASET_HISTORY from env.
+func processDatasetHistoryFromEnv(cfg *Config) {
+	if v := env.GetInt("DATASET_HISTORY", 0); v != 0 {
+		cfg.DatasetHistory = v
+	}
+}
+
+// processRemotePaginationFromEnv reads REMOTE_PAGINATION, REMOTE_PAGE_SIZE, REMOTE_MAX_PAGES,
+// REMOTE_PAGINATION_ITEMS_FIELD, REMOTE_PAGINATION_CURSOR_FIELD and REMOTE_PAGINATION_TOTAL_FIELD from env.
+func processRemotePaginationFromEnv(cfg *Config) {
+	if v := env.GetTrimmed("REMOTE_PAGINATION", ""); v != "" {
+		cfg.RemotePagination.Strategy = v
+	}
+	if v := env.GetInt("REMOTE_PAGE_SIZE", 0); v > 0 {
+		cfg.RemotePagination.PageSize = v
+	}
+	if v := env.GetInt("REMOTE_MAX_PAGES", 0); v > 0 {
+		cfg.RemotePagination.MaxPages = v
+	}
+	if v := env.GetTrimmed("REMOTE_PAGINATION_ITEMS_FIELD", ""); v != "" {
+		cfg.RemotePagination.ItemsField = v
+	}
+	if v := env.GetTrimmed("REMOTE_PAGINATION_CURSOR_FIELD", ""); v != "" {
+		cfg.RemotePagination.CursorField = v
+	}
+	if v := env.GetTrimmed("REMOTE_PAGINATION_TOTAL_FIELD", ""); v != "" {
+		cfg.RemotePagination.TotalField = v
+	}
+}
+
+// processDataWatchFromEnv reads DATA_WATCH and DATA_WATCH_DEBOUNCE from env (no CLI flags).
+func processDataWatchFromEnv(cfg *Config) {
+	if env.Has("DATA_WATCH") {
+		cfg.DataWatch.Disabled = !env.GetBool("DATA_WATCH", true)
+	}
+	if v := env.GetDuration("DATA_WATCH_DEBOUNCE", 0); v > 0 {
+		cfg.DataWatch.Debounce = v
+	}
+}
+
+// processSignatureFromEnv reads SIGNATURE_PUBLIC_KEYS (comma-separated key files or directories) from env.
+func processSignatureFromEnv(cfg *Config) {
+	if v := env.GetStringSlice("SIGNATURE_PUBLIC_KEYS", nil, ","); len(v) > 0 {
+		cfg.Signature.PublicKeys = v
+	}
+}
+
+// processMergeFromEnv reads MERGE_FIELDS (comma-separated "field=strategy") and MERGE_CONFLICTS from env.
+func processMergeFromEnv(cfg *Config) {
+	if v := env.GetTrimmed("MERGE_CONFLICTS", ""); v != "" {
+		cfg.Merge.Conflicts = strings.ToLower(v)
+	}
+	items := env.GetStringSlice("MERGE_FIELDS", nil, ",")
+	if len(items) == 0 {
+		return
+	}
+	cfg.Merge.Fields = make(map[string]string, len(items))
+	for _, item := range items {
+		if field, strategy, ok := strings.Cut(item, "="); ok {
+			cfg.Merge.Fields[strings.TrimSpace(field)] = strings.TrimSpace(strategy)
+		}
+	}
+}
+
+// processExecFromEnv reads EXEC_COMMAND, EXEC_ARGS, EXEC_FORMAT, EXEC_TIMEOUT and EXEC_PASS_ENV from env (no CLI flags,
+// so the command line never carries source arguments).
+func processExecFromEnv(cfg *Config) {
+	if v := env.GetTrimmed("EXEC_COMMAND", ""); v != "" {
+		cfg.Exec.Command = v
+	}
+	if v := env.GetStringSlice("EXEC_ARGS", nil, ","); len(v) > 0 {
+		cfg.Exec.Args = v
+	}
+	if v := env.GetTrimmed("EXEC_FORMAT", ""); v != "" {
+		cfg.Exec.Format = v
+	}
+	if v := env.GetDuration("EXEC_TIMEOUT", 0); v > 0 {
+		cfg.Exec.Timeout = v
+	}
+	if v := env.GetStringSlice("EXEC_PASS_ENV", nil, ","); len(v) > 0 {
+		cfg.Exec.PassEnv = v
+	}
+}
+
+// processGitFromEnv reads GIT_SOURCE_URL, GIT_SOURCE_REF, GIT_SOURCE_PATHS, GIT_SOURCE_DIR and GIT_SOURCE_TIMEOUT from env.
+func processGitFromEnv(cfg *Config) {
+	if v := env.GetTrimmed("GIT_SOURCE_URL", ""); v != "" {
+		cfg.Git.URL = v
+	}
+	if v := env.GetTrimmed("GIT_SOURCE_REF", ""); v != "" {
+		cfg.Git.Ref = v
+	}
+	if v := env.GetStringSlice("GIT_SOURCE_PATHS", nil, ","); len(v) > 0 {
+		cfg.Git.Paths = v
+	}
+	if v := env.GetTrimmed("GIT_SOURCE_DIR", ""); v != "" {
+		cfg.Git.Dir = v
+	}
+	if v := env.GetDuration("GIT_SOURCE_TIMEOUT", 0); v > 0 {
+		cfg.Git.Timeout = v
+	}
+}
+
+// processCircuitBreakerFromEnv reads CIRCUIT_BREAKER, CIRCUIT_BREAKER_THRESHOLD, CIRCUIT_BREAKER_BASE_DELAY and
+// CIRCUIT_BREAKER_MAX_DELAY from env.
+func processCircuitBreakerFromEnv(cfg *Config) {
+	if env.Has("CIRCUIT_BREAKER") {
+		cfg.CircuitBreaker.Disabled = !env.GetBool("CIRCUIT_BREAKER", true)
+	}
+	if v := env.GetInt("CIRCUIT_BREAKER_THRESHOLD", 0); v > 0 {
+		cfg.CircuitBreaker.Threshold = v
+	}
+	if v := env.GetDuration("CIRCUIT_BREAKER_BASE_DELAY", 0); v > 0 {
+		cfg.CircuitBreaker.BaseDelay = v
+	}
+	if v := env.GetDuration("CIRCUIT_BREAKER_MAX_DELAY", 0); v > 0 {
+		cfg.CircuitBreaker.MaxDelay = v
+	}
 }
 
 // processServiceAuthFromEnv reads service-to-service auth config from env (no CLI flags).
@@ -437,6 +695,22 @@ func getArgsFromFlags() *Config {
 	processDataDirFromEnv(cfg)
 	processResponseFieldsFromEnv(cfg)
 	processRemoteDecryptFromEnv(cfg)
+	processRemotePaginationFromEnv(cfg)
+	processDataWatchFromEnv(cfg)
+	processSignatureFromEnv(cfg)
+	processMergeFromEnv(cfg)
+	processExecFromEnv(cfg)
+	processGitFromEnv(cfg)
+	processCircuitBreakerFromEnv(cfg)
+	processRemoteTLSFromEnv(cfg)
+	processRemoteOAuth2FromEnv(cfg)
+	processRedisKeysFromEnv(cfg)
+	processRedisEncodingFromEnv(cfg)
+	processMembershipFilterFromEnv(cfg)
+	processRedisTopologyFromEnv(cfg)
+	processUserStoreFromEnv(cfg)
+	processLeaderLeaseFromEnv(cfg)
+	processDatasetHistoryFromEnv(cfg)
 	processServiceAuthFromEnv(cfg)
 
 	return cfg
@@ -469,6 +743,27 @@ func convertToConfig(cfg *config.CmdConfigData) *Config {
 		TLSKeyFile:              cfg.TLSKeyFile,
 		TLSCAFile:               cfg.TLSCAFile,
 		TLSRequireClientCert:    cfg.TLSRequireClientCert,
+		RemotePagination:        cfg.RemotePagination,
+		DataWatch:               cfg.DataWatch,
+		Signature:               cfg.Signature,
+		RemoteKeys:              cfg.RemoteKeys,
+		DecryptLegacy:           cfg.DecryptLegacy,
+		Merge:                   cfg.Merge,
+		AdminAPIKey:             cfg.AdminAPIKey,
+		Exec:                    cfg.Exec,
+		Git:                     cfg.Git,
+		CircuitBreaker:          cfg.CircuitBreaker,
+		RemoteTLS:               cfg.RemoteTLS,
+		RemoteOAuth2:            cfg.RemoteOAuth2,
+		RedisDB:                 cfg.RedisDB,
+		RedisKeyPrefix:          cfg.RedisKeyPrefix,
+		RedisEncoding:           cfg.RedisEncoding,
+		CacheTTL:                cfg.CacheTTL,
+		BloomFPRate:             cfg.BloomFPRate,
+		RedisTopology:           cfg.RedisTopology,
+		UserStore:               cfg.UserStore,
+		LeaderLease:             cfg.LeaderLease,
+		DatasetHistory:          cfg.DatasetHistory,
 	}
 }
 
@@ -504,6 +799,21 @@ func overrideWithFlags(cfg *config.CmdConfigData) {
 		HTTPTimeout:             cfg.HTTPTimeout,
 		HTTPMaxIdleConns:        cfg.HTTPMaxIdleConns,
 		HTTPInsecureTLS:         cfg.HTTPInsecureTLS,
+		AdminAPIKey:             cfg.AdminAPIKey,
+		Exec:                    cfg.Exec,
+		Git:                     cfg.Git,
+		CircuitBreaker:          cfg.CircuitBreaker,
+		RemoteTLS:               cfg.RemoteTLS,
+		RemoteOAuth2:            cfg.RemoteOAuth2,
+		RedisDB:                 cfg.RedisDB,
+		RedisKeyPrefix:          cfg.RedisKeyPrefix,
+		RedisEncoding:           cfg.RedisEncoding,
+		CacheTTL:                cfg.CacheTTL,
+		BloomFPRate:             cfg.BloomFPRate,
+		RedisTopology:           cfg.RedisTopology,
+		UserStore:               cfg.UserStore,
+		LeaderLease:             cfg.LeaderLease,
+		DatasetHistory:          cfg.DatasetHistory,
 	}
 
 	// Process each configuration item using unified processing functions
@@ -538,6 +848,21 @@ func overrideWithFlags(cfg *config.CmdConfigData) {
 	cfg.HTTPTimeout = tempCfg.HTTPTimeout
 	cfg.HTTPMaxIdleConns = tempCfg.HTTPMaxIdleConns
 	cfg.HTTPInsecureTLS = tempCfg.HTTPInsecureTLS
+	cfg.AdminAPIKey = tempCfg.AdminAPIKey
+	cfg.Exec = tempCfg.Exec
+	cfg.Git = tempCfg.Git
+	cfg.CircuitBreaker = tempCfg.CircuitBreaker
+	cfg.RemoteTLS = tempCfg.RemoteTLS
+	cfg.RemoteOAuth2 = tempCfg.RemoteOAuth2
+	cfg.RedisDB = tempCfg.RedisDB
+	cfg.RedisKeyPrefix = tempCfg.RedisKeyPrefix
+	cfg.RedisEncoding = tempCfg.RedisEncoding
+	cfg.CacheTTL = tempCfg.CacheTTL
+	cfg.BloomFPRate = tempCfg.BloomFPRate
+	cfg.RedisTopology = tempCfg.RedisTopology
+	cfg.UserStore = tempCfg.UserStore
+	cfg.LeaderLease = tempCfg.LeaderLease
+	cfg.DatasetHistory = tempCfg.DatasetHistory
 }
 
 // LoadConfig loads configuration (new interface, supports configuration file)
@@ -594,6 +919,27 @@ func overrideFromEnvInternal(cfg *config.CmdConfigData) {
 		TLSKeyFile:              cfg.TLSKeyFile,
 		TLSCAFile:               cfg.TLSCAFile,
 		TLSRequireClientCert:    cfg.TLSRequireClientCert,
+		RemotePagination:        cfg.RemotePagination,
+		DataWatch:               cfg.DataWatch,
+		Signature:               cfg.Signature,
+		RemoteKeys:              cfg.RemoteKeys,
+		DecryptLegacy:           cfg.DecryptLegacy,
+		Merge:                   cfg.Merge,
+		AdminAPIKey:             cfg.AdminAPIKey,
+		Exec:                    cfg.Exec,
+		Git:                     cfg.Git,
+		CircuitBreaker:          cfg.CircuitBreaker,
+		RemoteTLS:               cfg.RemoteTLS,
+		RemoteOAuth2:            cfg.RemoteOAuth2,
+		RedisDB:                 cfg.RedisDB,
+		RedisKeyPrefix:          cfg.RedisKeyPrefix,
+		RedisEncoding:           cfg.RedisEncoding,
+		CacheTTL:                cfg.CacheTTL,
+		BloomFPRate:             cfg.BloomFPRate,
+		RedisTopology:           cfg.RedisTopology,
+		UserStore:               cfg.UserStore,
+		LeaderLease:             cfg.LeaderLease,
+		DatasetHistory:          cfg.DatasetHistory,
 	}
 
 	// Process each configuration item using unified processing functions
@@ -609,6 +955,22 @@ func overrideFromEnvInternal(cfg *config.CmdConfigData) {
 	processDataDirFromEnv(tempCfg)
 	processResponseFieldsFromEnv(tempCfg)
 	processRemoteDecryptFromEnv(tempCfg)
+	processRemotePaginationFromEnv(tempCfg)
+	processDataWatchFromEnv(tempCfg)
+	processSignatureFromEnv(tempCfg)
+	processMergeFromEnv(tempCfg)
+	processExecFromEnv(tempCfg)
+	processGitFromEnv(tempCfg)
+	processCircuitBreakerFromEnv(tempCfg)
+	processRemoteTLSFromEnv(tempCfg)
+	processRemoteOAuth2FromEnv(tempCfg)
+	processRedisKeysFromEnv(tempCfg)
+	processRedisEncodingFromEnv(tempCfg)
+	processMembershipFilterFromEnv(tempCfg)
+	processRedisTopologyFromEnv(tempCfg)
+	processUserStoreFromEnv(tempCfg)
+	processLeaderLeaseFromEnv(tempCfg)
+	processDatasetHistoryFromEnv(tempCfg)
 	processServiceAuthFromEnv(tempCfg)
 
 	// Copy back to CmdConfigData
@@ -636,4 +998,25 @@ func overrideFromEnvInternal(cfg *config.CmdConfigData) {
 	cfg.TLSKeyFile = tempCfg.TLSKeyFile
 	cfg.TLSCAFile = tempCfg.TLSCAFile
 	cfg.TLSRequireClientCert = tempCfg.TLSRequireClientCert
+	cfg.RemotePagination = tempCfg.RemotePagination
+	cfg.DataWatch = tempCfg.DataWatch
+	cfg.Signature = tempCfg.Signature
+	cfg.RemoteKeys = tempCfg.RemoteKeys
+	cfg.DecryptLegacy = tempCfg.DecryptLegacy
+	cfg.Merge = tempCfg.Merge
+	cfg.AdminAPIKey = tempCfg.AdminAPIKey
+	cfg.Exec = tempCfg.Exec
+	cfg.Git = tempCfg.Git
+	cfg.CircuitBreaker = tempCfg.CircuitBreaker
+	cfg.RemoteTLS = tempCfg.RemoteTLS
+	cfg.RemoteOAuth2 = tempCfg.RemoteOAuth2
+	cfg.RedisDB = tempCfg.RedisDB
+	cfg.RedisKeyPrefix = tempCfg.RedisKeyPrefix
+	cfg.RedisEncoding = tempCfg.RedisEncoding
+	cfg.CacheTTL = tempCfg.CacheTTL
+	cfg.BloomFPRate = tempCfg.BloomFPRate
+	cfg.RedisTopology = tempCfg.RedisTopology
+	cfg.UserStore = tempCfg.UserStore
+	cfg.LeaderLease = tempCfg.LeaderLease
+	cfg.DatasetHistory = tempCfg.DatasetHistory
 }
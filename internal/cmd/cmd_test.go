@@ -4,9 +4,11 @@ import (
 	"os"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/soulteary/cli-kit/flagutil"
 	"github.com/soulteary/cli-kit/testutil"
+	"github.com/soulteary/warden/internal/config"
 	"github.com/soulteary/warden/internal/define"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -136,6 +138,253 @@ func TestGetArgs_DataFile(t *testing.T) {
 	assert.Equal(t, "/flag/data.json", cfg.DataFile, "--data-file should set DataFile")
 }
 
+func TestGetArgs_DataWatch(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	envMgr := testutil.NewEnvManager()
+	defer envMgr.Cleanup()
+
+	// Default: watching enabled with the watcher's default debounce
+	os.Args = []string{"test"}
+	cfg := GetArgs()
+	assert.False(t, cfg.DataWatch.Disabled)
+	assert.Zero(t, cfg.DataWatch.Debounce)
+
+	require.NoError(t, envMgr.Set("DATA_WATCH", "false"))
+	require.NoError(t, envMgr.Set("DATA_WATCH_DEBOUNCE", "1s"))
+	cfg = GetArgs()
+	assert.True(t, cfg.DataWatch.Disabled, "DATA_WATCH=false disables watching")
+	assert.Equal(t, time.Second, cfg.DataWatch.Debounce)
+}
+
+func TestGetArgs_MergeFields(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	envMgr := testutil.NewEnvManager()
+	defer envMgr.Cleanup()
+
+	os.Args = []string{"test"}
+	assert.Empty(t, GetArgs().Merge.Fields)
+
+	require.NoError(t, envMgr.Set("MERGE_FIELDS", "status=remote, scope=union"))
+	cfg := GetArgs()
+	assert.Equal(t, map[string]string{"status": "remote", "scope": "union"}, cfg.Merge.Fields)
+
+	require.NoError(t, envMgr.Set("MERGE_CONFLICTS", "Reject"))
+	assert.Equal(t, "reject", GetArgs().Merge.Conflicts)
+}
+
+func TestGetArgs_UserStore(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	envMgr := testutil.NewEnvManager()
+	defer envMgr.Cleanup()
+
+	os.Args = []string{"test"}
+	assert.Empty(t, GetArgs().UserStore.Mode)
+
+	require.NoError(t, envMgr.Set("REDIS_USER_STORE", "Hash"))
+	require.NoError(t, envMgr.Set("REDIS_USER_STORE_LRU_SIZE", "-1"))
+	require.NoError(t, envMgr.Set("REDIS_USER_STORE_LRU_TTL", "5s"))
+	cfg := GetArgs()
+	assert.Equal(t, "hash", cfg.UserStore.Mode)
+	assert.Equal(t, -1, cfg.UserStore.LRUSize)
+	assert.Equal(t, 5*time.Second, cfg.UserStore.LRUTTL)
+}
+
+func TestGetArgs_LeaderLease(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	envMgr := testutil.NewEnvManager()
+	defer envMgr.Cleanup()
+
+	os.Args = []string{"test"}
+	assert.Zero(t, GetArgs().LeaderLease)
+
+	require.NoError(t, envMgr.Set("REDIS_LEADER_LEASE", "30s"))
+	assert.Equal(t, 30*time.Second, GetArgs().LeaderLease)
+}
+
+func TestGetArgs_DatasetHistory(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	envMgr := testutil.NewEnvManager()
+	defer envMgr.Cleanup()
+
+	os.Args = []string{"test"}
+	assert.Zero(t, GetArgs().DatasetHistory)
+
+	require.NoError(t, envMgr.Set("DATASET_HISTORY", "10"))
+	assert.Equal(t, 10, GetArgs().DatasetHistory)
+}
+
+func TestGetArgs_RedisTopology(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	envMgr := testutil.NewEnvManager()
+	defer envMgr.Cleanup()
+
+	os.Args = []string{"test"}
+	assert.Empty(t, GetArgs().RedisTopology.Mode, "standalone by default")
+
+	require.NoError(t, envMgr.Set("REDIS_MODE", "Sentinel"))
+	require.NoError(t, envMgr.Set("REDIS_MASTER_NAME", "mymaster"))
+	require.NoError(t, envMgr.Set("REDIS_ADDRS", "10.0.0.1:26379, 10.0.0.2:26379"))
+	require.NoError(t, envMgr.Set("REDIS_SENTINEL_PASSWORD", "sentinel-secret"))
+	topology := GetArgs().RedisTopology
+	assert.Equal(t, "sentinel", topology.Mode)
+	assert.Equal(t, "mymaster", topology.MasterName)
+	assert.Equal(t, []string{"10.0.0.1:26379", "10.0.0.2:26379"}, topology.Addrs)
+	assert.Equal(t, "sentinel-secret", topology.SentinelPassword)
+}
+
+func TestGetArgs_RedisKeys(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	envMgr := testutil.NewEnvManager()
+	defer envMgr.Cleanup()
+
+	os.Args = []string{"test"}
+	cfg := GetArgs()
+	assert.Zero(t, cfg.RedisDB)
+	assert.Empty(t, cfg.RedisKeyPrefix)
+	assert.Zero(t, cfg.CacheTTL)
+
+	require.NoError(t, envMgr.Set("REDIS_DB", "3"))
+	require.NoError(t, envMgr.Set("REDIS_KEY_PREFIX", " team-a "))
+	require.NoError(t, envMgr.Set("CACHE_TTL", "10m"))
+	require.NoError(t, envMgr.Set("REDIS_PAYLOAD_ENCODING", "ZSTD"))
+	cfg = GetArgs()
+	assert.Equal(t, "zstd", cfg.RedisEncoding)
+	assert.Equal(t, 3, cfg.RedisDB)
+	assert.Equal(t, "team-a", cfg.RedisKeyPrefix)
+	assert.Equal(t, 10*time.Minute, cfg.CacheTTL)
+}
+
+func TestGetArgs_MembershipFilter(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	envMgr := testutil.NewEnvManager()
+	defer envMgr.Cleanup()
+
+	os.Args = []string{"test"}
+	cfg := GetArgs()
+	assert.Zero(t, cfg.BloomFPRate)
+
+	require.NoError(t, envMgr.Set("BLOOM_FILTER_FP_RATE", "0.001"))
+	cfg = GetArgs()
+	assert.InDelta(t, 0.001, cfg.BloomFPRate, 1e-12)
+}
+
+func TestGetArgs_AdminAPIKey(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	envMgr := testutil.NewEnvManager()
+	defer envMgr.Cleanup()
+
+	os.Args = []string{"test"}
+	assert.Empty(t, GetArgs().AdminAPIKey, "admin endpoints are disabled by default")
+
+	require.NoError(t, envMgr.Set("ADMIN_API_KEY", " admin-secret "))
+	assert.Equal(t, "admin-secret", GetArgs().AdminAPIKey)
+}
+
+func TestGetArgs_Exec(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	envMgr := testutil.NewEnvManager()
+	defer envMgr.Cleanup()
+
+	os.Args = []string{"test"}
+	assert.Empty(t, GetArgs().Exec.Command, "exec source is disabled by default")
+
+	require.NoError(t, envMgr.Set("EXEC_COMMAND", "/usr/local/bin/roster"))
+	require.NoError(t, envMgr.Set("EXEC_ARGS", "export, --format=ndjson"))
+	require.NoError(t, envMgr.Set("EXEC_FORMAT", "ndjson"))
+	require.NoError(t, envMgr.Set("EXEC_TIMEOUT", "10s"))
+	require.NoError(t, envMgr.Set("EXEC_PASS_ENV", "ROSTER_TOKEN"))
+	cfg := GetArgs()
+	assert.Equal(t, "/usr/local/bin/roster", cfg.Exec.Command)
+	assert.Equal(t, []string{"export", "--format=ndjson"}, cfg.Exec.Args)
+	assert.Equal(t, "ndjson", cfg.Exec.Format)
+	assert.Equal(t, 10*time.Second, cfg.Exec.Timeout)
+	assert.Equal(t, []string{"ROSTER_TOKEN"}, cfg.Exec.PassEnv)
+}
+
+func TestGetArgs_Git(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	envMgr := testutil.NewEnvManager()
+	defer envMgr.Cleanup()
+
+	os.Args = []string{"test"}
+	assert.Empty(t, GetArgs().Git.URL, "Git source is disabled by default")
+
+	require.NoError(t, envMgr.Set("GIT_SOURCE_URL", "https://git.example.com/org/users.git"))
+	require.NoError(t, envMgr.Set("GIT_SOURCE_REF", "release"))
+	require.NoError(t, envMgr.Set("GIT_SOURCE_PATHS", "users/*.json,admins.json"))
+	require.NoError(t, envMgr.Set("GIT_SOURCE_DIR", "/var/cache/warden/git"))
+	require.NoError(t, envMgr.Set("GIT_SOURCE_TIMEOUT", "2m"))
+	cfg := GetArgs()
+	assert.Equal(t, config.GitSourceConfig{
+		URL:     "https://git.example.com/org/users.git",
+		Ref:     "release",
+		Paths:   []string{"users/*.json", "admins.json"},
+		Dir:     "/var/cache/warden/git",
+		Timeout: 2 * time.Minute,
+	}, cfg.Git)
+}
+
+func TestGetArgs_CircuitBreaker(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	envMgr := testutil.NewEnvManager()
+	defer envMgr.Cleanup()
+
+	os.Args = []string{"test"}
+	assert.Equal(t, config.CircuitBreakerConfig{}, GetArgs().CircuitBreaker, "enabled with defaults")
+
+	require.NoError(t, envMgr.Set("CIRCUIT_BREAKER_THRESHOLD", "5"))
+	require.NoError(t, envMgr.Set("CIRCUIT_BREAKER_BASE_DELAY", "30s"))
+	require.NoError(t, envMgr.Set("CIRCUIT_BREAKER_MAX_DELAY", "10m"))
+	assert.Equal(t, config.CircuitBreakerConfig{Threshold: 5, BaseDelay: 30 * time.Second, MaxDelay: 10 * time.Minute}, GetArgs().CircuitBreaker)
+
+	require.NoError(t, envMgr.Set("CIRCUIT_BREAKER", "false"))
+	assert.True(t, GetArgs().CircuitBreaker.Disabled)
+}
+
+func TestGetArgs_RemoteTLS(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	envMgr := testutil.NewEnvManager()
+	defer envMgr.Cleanup()
+
+	os.Args = []string{"test"}
+	require.NoError(t, envMgr.Set("REMOTE_TLS_CA_FILE", "/etc/warden/ca.pem"))
+	require.NoError(t, envMgr.Set("REMOTE_TLS_CERT_FILE", "/etc/warden/client.crt"))
+	require.NoError(t, envMgr.Set("REMOTE_TLS_KEY_FILE", "/etc/warden/client.key"))
+	require.NoError(t, envMgr.Set("REMOTE_TLS_SERVER_NAME", "roster.internal"))
+	assert.Equal(t, config.RemoteTLSConfig{
+		CAFile:     "/etc/warden/ca.pem",
+		CertFile:   "/etc/warden/client.crt",
+		KeyFile:    "/etc/warden/client.key",
+		ServerName: "roster.internal",
+	}, GetArgs().RemoteTLS)
+}
+
 // TestReadPasswordFromFile tests ReadPasswordFromFile function
 func TestReadPasswordFromFile(t *testing.T) {
 	// Create temporary file
@@ -913,3 +1162,25 @@ func TestGetArgs_RedisPassword_Priority(t *testing.T) {
 	cfg = GetArgs()
 	assert.Equal(t, "cli-password", cfg.RedisPassword, "CLI参数应该有最低优先级")
 }
+
+func TestGetArgs_RemoteOAuth2(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	envMgr := testutil.NewEnvManager()
+	defer envMgr.Cleanup()
+
+	os.Args = []string{"test"}
+	require.NoError(t, envMgr.Set("REMOTE_OAUTH2_TOKEN_URL", "https://idp.example.com/oauth2/token"))
+	require.NoError(t, envMgr.Set("REMOTE_OAUTH2_CLIENT_ID", "warden"))
+	require.NoError(t, envMgr.Set("REMOTE_OAUTH2_CLIENT_SECRET_FILE", "/run/secrets/warden-client"))
+	require.NoError(t, envMgr.Set("REMOTE_OAUTH2_SCOPES", "roster.read, roster.list"))
+	require.NoError(t, envMgr.Set("REMOTE_OAUTH2_AUTH_STYLE", "BODY"))
+	assert.Equal(t, config.RemoteOAuth2Config{
+		TokenURL:         "https://idp.example.com/oauth2/token",
+		ClientID:         "warden",
+		ClientSecretFile: "/run/secrets/warden-client",
+		Scopes:           []string{"roster.read", "roster.list"},
+		AuthStyle:        "body",
+	}, GetArgs().RemoteOAuth2)
+}
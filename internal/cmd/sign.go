@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/soulteary/warden/internal/signature"
+)
+
+// SignCommand is the subcommand name handled by RunSign ("warden sign ...").
+const SignCommand = "sign"
+
+// RunSign implements "warden sign": it writes a detached "<file>.sig" for each data file,
+// or prints the signature (for the remote X-Warden-Signature header) with --stdout.
+// Returns the process exit code.
+func RunSign(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(SignCommand, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	keyFile := fs.String("key", "", "Ed25519 or ECDSA private key PEM file")
+	toStdout := fs.Bool("stdout", false, "print signatures to stdout (one line per file) instead of writing <file>.sig")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(stderr, "Usage: warden %s --key private.pem [--stdout] data.json [more.json ...]\n", SignCommand) //nolint:errcheck // usage output
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *keyFile == "" || fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	pemData, err := os.ReadFile(filepath.Clean(*keyFile)) // #nosec G304 -- path from CLI
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "read key: %v\n", err) //nolint:errcheck // CLI output
+		return 1
+	}
+	key, err := signature.ParsePrivateKey(pemData)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err) //nolint:errcheck // CLI output
+		return 1
+	}
+
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(filepath.Clean(path)) // #nosec G304 -- path from CLI
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "read %s: %v\n", path, err) //nolint:errcheck // CLI output
+			return 1
+		}
+		sig, err := signature.Sign(key, data)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "sign %s: %v\n", path, err) //nolint:errcheck // CLI output
+			return 1
+		}
+		if *toStdout {
+			_, _ = fmt.Fprintln(stdout, sig) //nolint:errcheck // CLI output
+			continue
+		}
+		if err := os.WriteFile(path+signature.FileSuffix, []byte(sig+"\n"), 0o644); err != nil { // #nosec G306 -- signatures are public
+			_, _ = fmt.Fprintf(stderr, "write %s%s: %v\n", path, signature.FileSuffix, err) //nolint:errcheck // CLI output
+			return 1
+		}
+		_, _ = fmt.Fprintf(stdout, "wrote %s%s\n", path, signature.FileSuffix) //nolint:errcheck // CLI output
+	}
+	return 0
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/signature"
+)
+
+func writeSigningKey(t *testing.T, dir string) (string, ed25519.PublicKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	path := filepath.Join(dir, "signing.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return path, pub
+}
+
+func TestRunSign_WritesDetachedSignature(t *testing.T) {
+	dir := t.TempDir()
+	keyPath, pub := writeSigningKey(t, dir)
+	dataPath := filepath.Join(dir, "data.json")
+	data := []byte(`[{"phone":"13800138000"}]`)
+	require.NoError(t, os.WriteFile(dataPath, data, 0o600))
+
+	var stdout, stderr bytes.Buffer
+	code := RunSign([]string{"--key", keyPath, dataPath}, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+
+	v, err := signature.NewVerifier(pub)
+	require.NoError(t, err)
+	assert.NoError(t, v.VerifyFile(dataPath, data))
+}
+
+func TestRunSign_Stdout(t *testing.T) {
+	dir := t.TempDir()
+	keyPath, pub := writeSigningKey(t, dir)
+	dataPath := filepath.Join(dir, "data.json")
+	data := []byte(`[]`)
+	require.NoError(t, os.WriteFile(dataPath, data, 0o600))
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, RunSign([]string{"--key", keyPath, "--stdout", dataPath}, &stdout, &stderr))
+	_, err := os.Stat(dataPath + signature.FileSuffix)
+	assert.True(t, os.IsNotExist(err), "--stdout does not write a .sig file")
+
+	v, err := signature.NewVerifier(pub)
+	require.NoError(t, err)
+	assert.NoError(t, v.Verify(data, strings.TrimSpace(stdout.String())))
+}
+
+func TestRunSign_Errors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, RunSign(nil, &stdout, &stderr), "missing --key and files")
+	assert.Equal(t, 1, RunSign([]string{"--key", "/nonexistent.pem", "data.json"}, &stdout, &stderr))
+
+	dir := t.TempDir()
+	keyPath, _ := writeSigningKey(t, dir)
+	assert.Equal(t, 1, RunSign([]string{"--key", keyPath, filepath.Join(dir, "missing.json")}, &stdout, &stderr))
+}
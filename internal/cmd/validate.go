@@ -4,14 +4,24 @@ import (
 	// Standard library
 	"fmt"
 	"os"
+	"os/exec"
 	"strings"
+	"time"
 
 	// External packages
 	"github.com/soulteary/cli-kit/validator"
 
 	// Internal packages
+	"github.com/soulteary/warden/internal/breaker"
+	"github.com/soulteary/warden/internal/cache"
+	"github.com/soulteary/warden/internal/config"
 	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/internal/execsource"
+	"github.com/soulteary/warden/internal/gitsource"
 	"github.com/soulteary/warden/internal/i18n"
+	"github.com/soulteary/warden/internal/merge"
+	"github.com/soulteary/warden/internal/remote"
+	"github.com/soulteary/warden/internal/signature"
 )
 
 // ValidateConfig validates configuration validity
@@ -70,10 +80,10 @@ func ValidateConfig(cfg *Config) error {
 		}
 	}
 
-	// When remote decrypt is enabled, either RSA private key file or inline PEM must be set
+	// When remote decrypt is enabled, an RSA private key file, inline PEM or REMOTE_PRIVATE_KEYS must be set
 	if cfg.RemoteDecryptEnabled {
-		if cfg.RemoteRSAPrivateKeyFile == "" && cfg.RemoteRSAPrivateKey == "" {
-			errors = append(errors, "REMOTE_DECRYPT_ENABLED is true but neither REMOTE_RSA_PRIVATE_KEY_FILE nor REMOTE_RSA_PRIVATE_KEY is set")
+		if cfg.RemoteRSAPrivateKeyFile == "" && cfg.RemoteRSAPrivateKey == "" && len(cfg.RemoteKeys) == 0 {
+			errors = append(errors, "REMOTE_DECRYPT_ENABLED is true but none of REMOTE_RSA_PRIVATE_KEY_FILE, REMOTE_RSA_PRIVATE_KEY or REMOTE_PRIVATE_KEYS is set")
 		} else if cfg.RemoteRSAPrivateKeyFile != "" {
 			info, err := os.Stat(cfg.RemoteRSAPrivateKeyFile)
 			switch {
@@ -97,6 +107,149 @@ func ValidateConfig(cfg *Config) error {
 			}
 		}
 		// When only REMOTE_RSA_PRIVATE_KEY (inline PEM) is set, key is validated at load time
+		seen := make(map[string]bool, len(cfg.RemoteKeys))
+		for _, k := range cfg.RemoteKeys {
+			if k.ID != "" {
+				if seen[k.ID] {
+					errors = append(errors, fmt.Sprintf("REMOTE_PRIVATE_KEYS: duplicate key id %q", k.ID))
+				}
+				seen[k.ID] = true
+			}
+			data, err := os.ReadFile(k.File) // #nosec G304 -- path from config
+			if err != nil {
+				errors = append(errors, fmt.Sprintf("REMOTE_PRIVATE_KEYS %q: %v", k.File, err))
+				continue
+			}
+			if _, err := remote.ParsePrivateKey(data); err != nil {
+				errors = append(errors, fmt.Sprintf("REMOTE_PRIVATE_KEYS %q: %v", k.File, err))
+			}
+		}
+	}
+
+	// Validate the remote TLS files when set (client certificate and key must be set together)
+	tlsOpts := remote.TLSOptions{CAFile: cfg.RemoteTLS.CAFile, CertFile: cfg.RemoteTLS.CertFile, KeyFile: cfg.RemoteTLS.KeyFile, ServerName: cfg.RemoteTLS.ServerName}
+	if !tlsOpts.IsZero() {
+		if err := tlsOpts.Validate(); err != nil {
+			errors = append(errors, fmt.Sprintf("REMOTE_TLS: %v", err))
+		}
+		if cfg.HTTPInsecureTLS && cfg.RemoteTLS.CAFile != "" {
+			errors = append(errors, "HTTP_INSECURE_TLS disables server verification and cannot be combined with REMOTE_TLS_CA_FILE")
+		}
+	}
+
+	// Validate the OAuth2 client credentials when set; the bearer token replaces REMOTE_KEY
+	oauth := remote.OAuth2Options{
+		TokenURL:         cfg.RemoteOAuth2.TokenURL,
+		ClientID:         cfg.RemoteOAuth2.ClientID,
+		ClientSecretFile: cfg.RemoteOAuth2.ClientSecretFile,
+		Scopes:           cfg.RemoteOAuth2.Scopes,
+		Audience:         cfg.RemoteOAuth2.Audience,
+		AuthStyle:        cfg.RemoteOAuth2.AuthStyle,
+	}
+	if !oauth.IsZero() {
+		if err := oauth.Validate(); err != nil {
+			errors = append(errors, fmt.Sprintf("REMOTE_OAUTH2: %v", err))
+		}
+		if cfg.RemoteKey != "" {
+			errors = append(errors, "REMOTE_KEY and REMOTE_OAUTH2_* cannot be used together")
+		}
+	}
+
+	// The admin key must not be usable on the regular API (and vice versa)
+	if cfg.AdminAPIKey != "" && cfg.AdminAPIKey == cfg.APIKey {
+		errors = append(errors, "ADMIN_API_KEY must differ from API_KEY")
+	}
+
+	// Validate field-level merge policy when set
+	if _, err := merge.NewPolicy(cfg.Merge.Fields); err != nil {
+		errors = append(errors, fmt.Sprintf("MERGE_FIELDS: %v", err))
+	}
+	if err := merge.ValidateConflictPolicy(cfg.Merge.Conflicts); err != nil {
+		errors = append(errors, fmt.Sprintf("MERGE_CONFLICTS: %v", err))
+	}
+
+	// Validate the Redis topology, database and key namespace
+	errors = append(errors, validateRedisTopology(cfg.RedisTopology)...)
+	if cfg.RedisDB < 0 {
+		errors = append(errors, "REDIS_DB must not be negative")
+	} else if cfg.RedisDB != 0 && strings.EqualFold(strings.TrimSpace(cfg.RedisTopology.Mode), cache.RedisModeCluster) {
+		errors = append(errors, "REDIS_DB must be 0 when REDIS_MODE is cluster (Redis Cluster has a single database)")
+	}
+	if err := cache.ValidateNamespace(cfg.RedisKeyPrefix); err != nil {
+		errors = append(errors, fmt.Sprintf("REDIS_KEY_PREFIX: %v", err))
+	}
+	if err := cache.ValidatePayloadEncoding(cfg.RedisEncoding); err != nil {
+		errors = append(errors, fmt.Sprintf("REDIS_PAYLOAD_ENCODING: %v", err))
+	}
+	if cfg.CacheTTL < 0 || (cfg.CacheTTL > 0 && cfg.CacheTTL < time.Second) {
+		errors = append(errors, "CACHE_TTL must be at least 1s")
+	} else if cfg.CacheTTL > 0 && cfg.TaskInterval > 0 && cfg.CacheTTL <= time.Duration(cfg.TaskInterval)*time.Second {
+		// The leader restarts the expiry once per task run
+		errors = append(errors, fmt.Sprintf("CACHE_TTL (%s) must be longer than the task interval (%ds)", cfg.CacheTTL, cfg.TaskInterval))
+	}
+	if cfg.BloomFPRate < 0 || cfg.BloomFPRate >= 0.5 {
+		errors = append(errors, "BLOOM_FILTER_FP_RATE must be 0 (disabled) or between 0 and 0.5")
+	}
+
+	// Validate the Redis user store layout
+	if err := cache.ValidateUserStoreMode(cfg.UserStore.Mode); err != nil {
+		errors = append(errors, fmt.Sprintf("REDIS_USER_STORE: %v", err))
+	}
+	if cfg.LeaderLease != 0 && cfg.LeaderLease < cache.MinLeaderLease {
+		errors = append(errors, fmt.Sprintf("REDIS_LEADER_LEASE must be at least %s", cache.MinLeaderLease))
+	}
+	if cfg.DatasetHistory < -1 || cfg.DatasetHistory > cache.MaxDatasetHistory {
+		errors = append(errors, fmt.Sprintf("DATASET_HISTORY must be between 1 and %d (or -1 to disable)", cache.MaxDatasetHistory))
+	}
+
+	// Validate remote pagination strategy when set
+	if s := strings.TrimSpace(cfg.RemotePagination.Strategy); s != "" {
+		validStrategies := []string{"page", "offset", "cursor", "link"}
+		if err := validator.ValidateEnum(s, validStrategies, true); err != nil {
+			errors = append(errors, fmt.Sprintf("REMOTE_PAGINATION %q is invalid (should be one of: %s)", s, strings.Join(validStrategies, ", ")))
+		}
+	}
+
+	// Validate trusted signature public keys when set
+	if len(cfg.Signature.PublicKeys) > 0 {
+		if _, err := signature.LoadVerifier(cfg.Signature.PublicKeys); err != nil {
+			errors = append(errors, fmt.Sprintf("SIGNATURE_PUBLIC_KEYS is invalid: %v", err))
+		}
+	}
+
+	// Validate the exec source when set: the command must resolve and no argument may carry a secret
+	if cfg.Exec.Command != "" {
+		opts := execsource.Options{Command: cfg.Exec.Command, Args: cfg.Exec.Args, Format: cfg.Exec.Format, Timeout: cfg.Exec.Timeout, PassEnv: cfg.Exec.PassEnv}
+		if err := opts.Validate(); err != nil {
+			errors = append(errors, fmt.Sprintf("EXEC_COMMAND: %v", err))
+		} else if err := execsource.CheckArgs(cfg.Exec.Args, cfg.APIKey, cfg.AdminAPIKey, cfg.RemoteKey, cfg.RedisPassword); err != nil {
+			errors = append(errors, fmt.Sprintf("EXEC_ARGS: %v", err))
+		}
+		if _, err := exec.LookPath(cfg.Exec.Command); err != nil {
+			errors = append(errors, fmt.Sprintf("EXEC_COMMAND %q: %v", cfg.Exec.Command, err))
+		}
+		if len(cfg.Signature.PublicKeys) > 0 {
+			errors = append(errors, "EXEC_COMMAND cannot be used with SIGNATURE_PUBLIC_KEYS: command output is not signed")
+		}
+	}
+
+	// Validate the Git source when set: git must be installed and the ref / patterns well-formed
+	if cfg.Git.URL != "" {
+		opts := gitsource.Options{URL: cfg.Git.URL, Ref: cfg.Git.Ref, Paths: cfg.Git.Paths, Dir: cfg.Git.Dir, Timeout: cfg.Git.Timeout}
+		if err := opts.Validate(); err != nil {
+			errors = append(errors, fmt.Sprintf("GIT_SOURCE: %v", err))
+		}
+		if _, err := exec.LookPath("git"); err != nil {
+			errors = append(errors, fmt.Sprintf("GIT_SOURCE_URL is set but git is not installed: %v", err))
+		}
+	}
+
+	// Validate the circuit breaker settings (zero values use the defaults)
+	if !cfg.CircuitBreaker.Disabled {
+		opts := breaker.Options{Threshold: cfg.CircuitBreaker.Threshold, BaseDelay: cfg.CircuitBreaker.BaseDelay, MaxDelay: cfg.CircuitBreaker.MaxDelay}
+		if err := opts.Validate(); err != nil {
+			errors = append(errors, fmt.Sprintf("CIRCUIT_BREAKER: %v", err))
+		}
 	}
 
 	if len(errors) > 0 {
@@ -105,3 +258,30 @@ func ValidateConfig(cfg *Config) error {
 
 	return nil
 }
+
+// validateRedisTopology checks REDIS_MODE and the addresses it needs.
+func validateRedisTopology(t config.RedisTopologyConfig) []string {
+	if err := cache.ValidateRedisMode(t.Mode); err != nil {
+		return []string{fmt.Sprintf("REDIS_MODE: %v", err)}
+	}
+	var errs []string
+	switch strings.ToLower(strings.TrimSpace(t.Mode)) {
+	case cache.RedisModeSentinel:
+		if strings.TrimSpace(t.MasterName) == "" {
+			errs = append(errs, "REDIS_MASTER_NAME is required when REDIS_MODE is sentinel")
+		}
+		if len(t.Addrs) == 0 {
+			errs = append(errs, "REDIS_ADDRS (Sentinel addresses) is required when REDIS_MODE is sentinel")
+		}
+	case cache.RedisModeCluster:
+		if len(t.Addrs) == 0 {
+			errs = append(errs, "REDIS_ADDRS (cluster nodes) is required when REDIS_MODE is cluster")
+		}
+	}
+	for _, addr := range t.Addrs {
+		if _, _, err := validator.ValidateHostPort(addr); err != nil {
+			errs = append(errs, fmt.Sprintf("Invalid REDIS_ADDRS entry %q (should be host:port): %v", addr, err))
+		}
+	}
+	return errs
+}
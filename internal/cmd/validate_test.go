@@ -1,9 +1,15 @@
 package cmd
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/config"
 )
 
 func TestValidateConfig_ValidConfig(t *testing.T) {
@@ -140,3 +146,337 @@ func TestValidateConfig_RemoteDecryptEnabled_KeyFileNotExist(t *testing.T) {
 	assert.Error(t, err, "启用远程解密但私钥文件不存在应返回错误")
 	assert.Contains(t, err.Error(), "does not exist")
 }
+
+func TestValidateConfig_RemotePagination(t *testing.T) {
+	cfg := &Config{
+		Port:         "8081",
+		TaskInterval: 5,
+		Mode:         "DEFAULT",
+	}
+	cfg.RemotePagination.Strategy = "link"
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.RemotePagination.Strategy = "bogus"
+	err := ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "REMOTE_PAGINATION")
+}
+
+func TestValidateConfig_SignaturePublicKeys(t *testing.T) {
+	cfg := &Config{
+		Port:         "8081",
+		TaskInterval: 5,
+		Mode:         "DEFAULT",
+	}
+	cfg.Signature.PublicKeys = []string{"/nonexistent/trusted.pem"}
+	err := ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "SIGNATURE_PUBLIC_KEYS")
+}
+
+func TestValidateConfig_RemotePrivateKeys(t *testing.T) {
+	cfg := &Config{
+		Port:                 "8081",
+		TaskInterval:         5,
+		Mode:                 "DEFAULT",
+		RemoteDecryptEnabled: true,
+	}
+	cfg.RemoteKeys = []config.RemoteKeyConfig{{ID: "k1", File: "/nonexistent/a.pem"}, {ID: "k1", File: "/nonexistent/b.pem"}}
+	err := ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "REMOTE_PRIVATE_KEYS")
+	assert.Contains(t, err.Error(), `duplicate key id "k1"`)
+	assert.NotContains(t, err.Error(), "none of", "REMOTE_PRIVATE_KEYS alone satisfies REMOTE_DECRYPT_ENABLED")
+}
+
+func TestValidateConfig_MergeFields(t *testing.T) {
+	cfg := &Config{
+		Port:         "8081",
+		TaskInterval: 5,
+		Mode:         "DEFAULT",
+	}
+	cfg.Merge.Fields = map[string]string{"status": "remote", "scope": "union"}
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.Merge.Fields = map[string]string{"name": "union"}
+	err := ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "MERGE_FIELDS")
+
+	cfg.Merge.Fields = nil
+	cfg.Merge.Conflicts = "prefer"
+	assert.NoError(t, ValidateConfig(cfg))
+	cfg.Merge.Conflicts = "drop"
+	err = ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "MERGE_CONFLICTS")
+}
+
+func TestValidateConfig_UserStore(t *testing.T) {
+	cfg := &Config{
+		Port:         "8081",
+		TaskInterval: 5,
+		Mode:         "DEFAULT",
+	}
+	cfg.UserStore.Mode = "hash"
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.UserStore.Mode = "sharded"
+	err := ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "REDIS_USER_STORE")
+}
+
+func TestValidateConfig_LeaderLease(t *testing.T) {
+	cfg := &Config{
+		Port:         "8081",
+		TaskInterval: 5,
+		Mode:         "DEFAULT",
+		LeaderLease:  10 * time.Second,
+	}
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.LeaderLease = 100 * time.Millisecond
+	err := ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "REDIS_LEADER_LEASE")
+}
+
+func TestValidateConfig_DatasetHistory(t *testing.T) {
+	cfg := &Config{
+		Port:           "8081",
+		TaskInterval:   5,
+		Mode:           "DEFAULT",
+		DatasetHistory: -1,
+	}
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.DatasetHistory = -2
+	err := ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "DATASET_HISTORY")
+}
+
+func TestValidateConfig_RedisTopology(t *testing.T) {
+	cfg := &Config{
+		Port:         "8081",
+		TaskInterval: 5,
+		Mode:         "DEFAULT",
+		RedisTopology: config.RedisTopologyConfig{
+			Mode:       "sentinel",
+			MasterName: "mymaster",
+			Addrs:      []string{"10.0.0.1:26379", "10.0.0.2:26379"},
+		},
+	}
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.RedisTopology.MasterName = ""
+	err := ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "REDIS_MASTER_NAME")
+
+	cfg.RedisTopology = config.RedisTopologyConfig{Mode: "cluster"}
+	err = ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "REDIS_ADDRS")
+
+	cfg.RedisTopology.Addrs = []string{"10.0.0.1"}
+	err = ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "REDIS_ADDRS")
+
+	cfg.RedisTopology = config.RedisTopologyConfig{Mode: "replica"}
+	err = ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "REDIS_MODE")
+}
+
+func TestValidateConfig_RedisKeys(t *testing.T) {
+	cfg := &Config{
+		Port:           "8081",
+		TaskInterval:   5,
+		Mode:           "DEFAULT",
+		RedisDB:        2,
+		RedisKeyPrefix: "team-a",
+		CacheTTL:       10 * time.Minute,
+	}
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.RedisKeyPrefix = "team a"
+	err := ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "REDIS_KEY_PREFIX")
+
+	cfg.RedisKeyPrefix = ""
+	cfg.RedisEncoding = "msgpack"
+	err = ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "REDIS_PAYLOAD_ENCODING")
+
+	cfg.RedisEncoding = "zstd"
+	cfg.CacheTTL = time.Millisecond
+	err = ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "CACHE_TTL")
+
+	cfg.CacheTTL = time.Duration(cfg.TaskInterval) * time.Second
+	err = ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "longer than the task interval")
+
+	cfg.CacheTTL = 0
+	cfg.RedisTopology = config.RedisTopologyConfig{Mode: "cluster", Addrs: []string{"10.0.0.1:7000"}}
+	err = ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "REDIS_DB")
+}
+
+func TestValidateConfig_MembershipFilter(t *testing.T) {
+	cfg := &Config{
+		Port:         "8081",
+		TaskInterval: 5,
+		Mode:         "DEFAULT",
+		BloomFPRate:  0.01,
+	}
+	assert.NoError(t, ValidateConfig(cfg))
+
+	for _, rate := range []float64{-0.1, 0.5, 1} {
+		cfg.BloomFPRate = rate
+		err := ValidateConfig(cfg)
+		require.Error(t, err, rate)
+		assert.Contains(t, err.Error(), "BLOOM_FILTER_FP_RATE")
+	}
+}
+
+func TestValidateConfig_AdminAPIKey(t *testing.T) {
+	cfg := &Config{
+		Port:         "8081",
+		TaskInterval: 5,
+		Mode:         "DEFAULT",
+		APIKey:       "api-secret",
+		AdminAPIKey:  "admin-secret",
+	}
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.AdminAPIKey = cfg.APIKey
+	err := ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ADMIN_API_KEY")
+}
+
+func TestValidateConfig_Exec(t *testing.T) {
+	t.Setenv("ROSTER_TOKEN", "roster-token-value")
+	cfg := &Config{
+		Port:         "8081",
+		TaskInterval: 5,
+		Mode:         "DEFAULT",
+		APIKey:       "api-secret-value",
+	}
+	cfg.Exec.Command = "sh"
+	cfg.Exec.Args = []string{"-c", "cat /data/users.json"}
+	cfg.Exec.PassEnv = []string{"ROSTER_TOKEN"}
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.Exec.Args = []string{"--token", "roster-token-value"}
+	err := ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "EXEC_COMMAND")
+	assert.Contains(t, err.Error(), "pass_env")
+
+	cfg.Exec.Args = []string{"--key=api-secret-value"}
+	err = ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "EXEC_ARGS")
+
+	cfg.Exec.Args = nil
+	cfg.Exec.Format = "csv"
+	cfg.Exec.Command = "/nonexistent/roster"
+	err = ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "format")
+	assert.Contains(t, err.Error(), "/nonexistent/roster")
+
+	// Command output is not signed, so it cannot be combined with signature verification
+	cfg.Exec = config.ExecSourceConfig{Command: "sh"}
+	cfg.Signature.PublicKeys = []string{"/etc/warden/trusted.pem"}
+	err = ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "EXEC_COMMAND cannot be used with SIGNATURE_PUBLIC_KEYS")
+}
+
+func TestValidateConfig_Git(t *testing.T) {
+	cfg := &Config{
+		Port:         "8081",
+		TaskInterval: 5,
+		Mode:         "DEFAULT",
+	}
+	cfg.Git.URL = "/srv/git/users.git"
+	cfg.Git.Ref = "main"
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.Git.Ref = "--upload-pack=touch"
+	err := ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "GIT_SOURCE")
+}
+
+func TestValidateConfig_CircuitBreaker(t *testing.T) {
+	cfg := &Config{
+		Port:         "8081",
+		TaskInterval: 5,
+		Mode:         "DEFAULT",
+	}
+	cfg.CircuitBreaker.BaseDelay = time.Minute
+	cfg.CircuitBreaker.MaxDelay = time.Second
+	err := ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "CIRCUIT_BREAKER")
+
+	cfg.CircuitBreaker.Disabled = true
+	assert.NoError(t, ValidateConfig(cfg))
+}
+
+func TestValidateConfig_RemoteTLS(t *testing.T) {
+	cfg := &Config{
+		Port:         "8081",
+		TaskInterval: 5,
+		Mode:         "DEFAULT",
+	}
+	cfg.RemoteTLS.CertFile = "/nonexistent/client.crt"
+	err := ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "REMOTE_TLS")
+	assert.Contains(t, err.Error(), "set together")
+
+	cfg.RemoteTLS.KeyFile = "/nonexistent/client.key"
+	err = ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "client certificate")
+}
+
+func TestValidateConfig_RemoteOAuth2(t *testing.T) {
+	cfg := &Config{
+		Port:         "8081",
+		TaskInterval: 5,
+		Mode:         "DEFAULT",
+	}
+	cfg.RemoteOAuth2.TokenURL = "https://idp.example.com/oauth2/token"
+	err := ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "REMOTE_OAUTH2")
+	assert.Contains(t, err.Error(), "client id")
+
+	cfg.RemoteOAuth2.ClientID = "warden"
+	cfg.RemoteOAuth2.ClientSecretFile = "/nonexistent/secret"
+	err = ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "client secret")
+
+	secret := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(secret, []byte("s3cret"), 0o600))
+	cfg.RemoteOAuth2.ClientSecretFile = secret
+	cfg.RemoteKey = "Bearer static"
+	err = ValidateConfig(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "REMOTE_KEY and REMOTE_OAUTH2_*")
+}
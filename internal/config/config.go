@@ -24,15 +24,21 @@ import (
 //
 //nolint:govet // fieldalignment: field order is affected by YAML serialization tags, optimization may break configuration file compatibility
 type Config struct {
-	Server    ServerConfig    `yaml:"server"`
-	Redis     RedisConfig     `yaml:"redis"`
-	Remote    RemoteConfig    `yaml:"remote"`
-	HTTP      HTTPConfig      `yaml:"http"`
-	Cache     CacheConfig     `yaml:"cache"`
-	RateLimit RateLimitConfig `yaml:"rate_limit"`
-	App       AppConfig       `yaml:"app"`
-	Task      TaskConfig      `yaml:"task"`
-	Tracing   TracingConfig   `yaml:"tracing"`
+	Server    ServerConfig     `yaml:"server"`
+	Redis     RedisConfig      `yaml:"redis"`
+	Remote    RemoteConfig     `yaml:"remote"`
+	HTTP      HTTPConfig       `yaml:"http"`
+	Cache     CacheConfig      `yaml:"cache"`
+	RateLimit RateLimitConfig  `yaml:"rate_limit"`
+	App       AppConfig        `yaml:"app"`
+	Task      TaskConfig       `yaml:"task"`
+	Tracing   TracingConfig    `yaml:"tracing"`
+	Signature SignatureConfig  `yaml:"signature"`
+	Merge     MergeConfig      `yaml:"merge"`
+	Exec      ExecSourceConfig `yaml:"exec"`
+	Git       GitSourceConfig  `yaml:"git"`
+
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker"`
 }
 
 // ServerConfig server configuration
@@ -51,12 +57,37 @@ type RedisConfig struct {
 	Password     string `yaml:"password"`      // 16 bytes
 	PasswordFile string `yaml:"password_file"` // 16 bytes
 	DB           int    `yaml:"db"`            // 8 bytes
+	KeyPrefix    string `yaml:"key_prefix"`    // namespace of all Redis keys, channels and locks (default "warden")
+	Encoding     string `yaml:"encoding"`      // encoding of the user list payload: json (default) or zstd
+
+	Topology    RedisTopologyConfig `yaml:",inline"`      // standalone (addr), sentinel or cluster
+	UserStore   UserStoreConfig     `yaml:"user_store"`   // storage layout of the user list in Redis
+	LeaderLease time.Duration       `yaml:"leader_lease"` // lease of the leader that loads the sources (default 15s)
+}
+
+// RedisTopologyConfig selects the Redis deployment. Standalone connects to addr; sentinel asks the Sentinels
+// at addrs for the master named master_name and follows failovers; cluster uses addrs as seed nodes.
+type RedisTopologyConfig struct {
+	Mode             string   `yaml:"mode"`              // standalone (default), sentinel or cluster
+	MasterName       string   `yaml:"master_name"`       // sentinel: name of the monitored master
+	Addrs            []string `yaml:"addrs"`             // sentinel: Sentinel addresses; cluster: seed nodes
+	SentinelPassword string   `yaml:"sentinel_password"` // sentinel: password of the Sentinels (optional)
+}
+
+// UserStoreConfig storage layout of the user list in Redis. The hash layout stores one hash per user with
+// mail and user_id index keys, and /user and /v1/lookup read single users from Redis through a local LRU.
+type UserStoreConfig struct {
+	Mode    string        `yaml:"mode"`     // blob (default, the whole list as one value) or hash
+	LRUSize int           `yaml:"lru_size"` // hash: lookup results cached locally (default 10000, -1 = none)
+	LRUTTL  time.Duration `yaml:"lru_ttl"`  // hash: how long a cached lookup result is used (default 30s)
 }
 
 // CacheConfig cache configuration
 type CacheConfig struct {
-	TTL            time.Duration `yaml:"ttl"`
+	TTL            time.Duration `yaml:"ttl"` // expiry of the user list in Redis (default 1h)
 	UpdateInterval time.Duration `yaml:"update_interval"`
+	History        int           `yaml:"history"`       // applied datasets kept for rollback (default 5, -1 disables)
+	BloomFPRate    float64       `yaml:"bloom_fp_rate"` // false positive rate of the membership filter (0 = disabled)
 }
 
 // RateLimitConfig rate limit configuration
@@ -81,22 +112,134 @@ type RemoteConfig struct {
 	Mode              string `yaml:"mode"`
 	RSAPrivateKeyFile string `yaml:"rsa_private_key_file"` // path to PEM file (preferred over key in env)
 	DecryptEnabled    bool   `yaml:"decrypt_enabled"`      // decrypt response with RSA private key
+
+	Pagination RemotePaginationConfig `yaml:"pagination"` // multi-page fetching (empty strategy = single request)
+
+	PrivateKeys      []RemoteKeyConfig `yaml:"private_keys"`      // RSA / X25519 keys for the envelope format, selected by key id
+	LegacyEncryption bool              `yaml:"legacy_encryption"` // also accept the unauthenticated application/x-warden-encrypted format
+
+	TLS    RemoteTLSConfig    `yaml:"tls"`    // CA bundle and client certificate for upstreams that require mutual TLS
+	OAuth2 RemoteOAuth2Config `yaml:"oauth2"` // client credentials grant; the bearer token replaces key
+}
+
+// RemoteOAuth2Config OAuth2 client credentials settings of the remote source. Tokens are cached and
+// renewed shortly before they expire.
+type RemoteOAuth2Config struct {
+	TokenURL         string   `yaml:"token_url"`          // IdP token endpoint
+	ClientID         string   `yaml:"client_id"`          // client id
+	ClientSecretFile string   `yaml:"client_secret_file"` // file containing the client secret (re-read on every token request)
+	Scopes           []string `yaml:"scopes"`             // requested scopes
+	Audience         string   `yaml:"audience"`           // audience parameter required by some IdPs
+	AuthStyle        string   `yaml:"auth_style"`         // basic (default, HTTP Basic) or body (form parameters)
+}
+
+// RemoteTLSConfig TLS settings of the remote source. Files are PEM and are re-read on every fetch.
+type RemoteTLSConfig struct {
+	CAFile     string `yaml:"ca_file"`     // CA bundle trusted instead of the system roots
+	CertFile   string `yaml:"cert_file"`   // client certificate (requires key_file)
+	KeyFile    string `yaml:"key_file"`    // client private key (requires cert_file)
+	ServerName string `yaml:"server_name"` // expected server name when it differs from the URL host
+}
+
+// RemoteKeyConfig is a private key used to decrypt remote envelopes. ID matches the envelope "kid";
+// a key without ID is tried for every envelope.
+type RemoteKeyConfig struct {
+	ID   string `yaml:"id"`
+	File string `yaml:"file"` // PEM file: RSA (PKCS#1/PKCS#8, >= 2048 bits) or X25519 (PKCS#8)
+}
+
+// RemotePaginationConfig remote pagination configuration. Empty fields use the remote package defaults.
+//
+//nolint:govet // fieldalignment: field order is affected by YAML serialization tags
+type RemotePaginationConfig struct {
+	Strategy    string `yaml:"strategy"`     // "", page, offset, cursor, link
+	PageSize    int    `yaml:"page_size"`    // records per page (default 500)
+	MaxPages    int    `yaml:"max_pages"`    // request cap per fetch (default 100)
+	StartPage   int    `yaml:"start_page"`   // page strategy: first page number (default 1)
+	PageParam   string `yaml:"page_param"`   // page strategy: page query parameter (default "page")
+	SizeParam   string `yaml:"size_param"`   // page strategy: size query parameter (default "page_size")
+	OffsetParam string `yaml:"offset_param"` // offset strategy: offset query parameter (default "offset")
+	LimitParam  string `yaml:"limit_param"`  // offset strategy: limit query parameter (default "limit")
+	CursorParam string `yaml:"cursor_param"` // cursor strategy: cursor query parameter (default "cursor")
+	CursorField string `yaml:"cursor_field"` // cursor strategy: next cursor path in body (default "next_cursor")
+	ItemsField  string `yaml:"items_field"`  // user array path when body is an object (default "data")
+	TotalField  string `yaml:"total_field"`  // page/offset strategies: total record count path in body (default "total")
 }
 
 // TaskConfig task configuration
 type TaskConfig struct {
-	Interval time.Duration `yaml:"interval"`
+	Interval time.Duration   `yaml:"interval"`
+	Watch    FileWatchConfig `yaml:"watch"` // reload immediately when data_file / data_dir change
+}
+
+// FileWatchConfig local data file watching configuration (polling by task interval is always kept as fallback)
+type FileWatchConfig struct {
+	Debounce time.Duration `yaml:"debounce"` // quiet period after the last change before reloading (default 500ms)
+	Disabled bool          `yaml:"disabled"` // disable watching, rely on task interval polling only
 }
 
 // AppConfig application configuration
 type AppConfig struct {
 	Mode           string   `yaml:"mode"`
 	APIKey         string   `yaml:"api_key"`         // API Key for authentication (recommend env)
+	AdminAPIKey    string   `yaml:"admin_api_key"`   // API Key for /v1/admin endpoints (recommend env; admin endpoints disabled when empty)
 	DataFile       string   `yaml:"data_file"`       // Local user data file path
 	DataDir        string   `yaml:"data_dir"`        // Local user data directory (merge all *.json files; can be used with data_file)
 	ResponseFields []string `yaml:"response_fields"` // API response field whitelist (empty = all fields); e.g. ["phone","mail","user_id","status","scope","role","name"]
 }
 
+// SignatureConfig user data signature verification configuration.
+// When public keys are configured, every local file needs a valid detached "<file>.sig"
+// and every remote response a valid X-Warden-Signature header.
+type SignatureConfig struct {
+	PublicKeys []string `yaml:"public_keys"` // trusted Ed25519/ECDSA PEM public key files or directories of *.pem
+}
+
+// MergeConfig field-level merge of the same user across sources.
+// Fields maps a user field (JSON name) to a strategy: "remote", "local" or "union" (scope only).
+// When empty, a higher-priority source replaces the whole record.
+// Conflicts is the policy for a user_id or mail shared by records with different keys: "warn" (default),
+// "reject" or "prefer" (the record of the higher-priority source is kept).
+type MergeConfig struct {
+	Fields    map[string]string `yaml:"fields"`    // e.g. {status: remote, scope: union, name: local}
+	Conflicts string            `yaml:"conflicts"` // identity conflict policy: warn, reject or prefer
+}
+
+// ExecSourceConfig external command source: the command prints users to stdout (JSON array or NDJSON).
+// It is treated like the remote source (skipped on failure unless MODE=ONLY_REMOTE, ignored in ONLY_LOCAL).
+// Secrets must be passed via PassEnv, never Args.
+//
+//nolint:govet // fieldalignment: field order is affected by YAML serialization tags
+type ExecSourceConfig struct {
+	Command string            `yaml:"command"`  // executable path; empty = disabled
+	Args    []string          `yaml:"args"`     // arguments (no shell)
+	Format  string            `yaml:"format"`   // "json", "ndjson" or "" (detect)
+	Timeout time.Duration     `yaml:"timeout"`  // default 30s
+	Env     map[string]string `yaml:"env"`      // extra non-secret environment variables
+	PassEnv []string          `yaml:"pass_env"` // Warden environment variables passed to the command (e.g. tokens)
+}
+
+// GitSourceConfig Git repository source: data files are read from the commit at Ref, fetched on every load.
+// Like the exec source it is treated as a remote source (skipped on failure unless MODE=ONLY_REMOTE).
+//
+//nolint:govet // fieldalignment: field order is affected by YAML serialization tags
+type GitSourceConfig struct {
+	URL     string        `yaml:"url"`     // repository URL or local path; empty = disabled
+	Ref     string        `yaml:"ref"`     // branch, tag or full commit SHA (default HEAD)
+	Paths   []string      `yaml:"paths"`   // data file patterns relative to the repository root (default ["*.json"])
+	Dir     string        `yaml:"dir"`     // local cache directory (default under the system temp directory)
+	Timeout time.Duration `yaml:"timeout"` // per git command (default 60s)
+}
+
+// CircuitBreakerConfig per-source circuit breaker for the remote, Git and exec sources: after Threshold
+// consecutive failures the source is skipped for an exponentially growing, jittered period, then probed once.
+type CircuitBreakerConfig struct {
+	Threshold int           `yaml:"threshold"`  // consecutive failures that open the breaker (default 3)
+	BaseDelay time.Duration `yaml:"base_delay"` // first open period, doubled on every failed probe (default 10s)
+	MaxDelay  time.Duration `yaml:"max_delay"`  // maximum open period (default 5m)
+	Disabled  bool          `yaml:"disabled"`   // retry failing sources on every load
+}
+
 // TracingConfig OpenTelemetry tracing configuration
 type TracingConfig struct {
 	Endpoint string `yaml:"endpoint"` // OTLP endpoint (e.g., "http://localhost:4318")
@@ -279,6 +422,11 @@ func applyDefaults(cfg *Config) {
 
 // parseResponseFields parses comma-separated field names (e.g. "phone,mail,user_id") into a slice.
 func parseResponseFields(s string) []string {
+	return parseList(s)
+}
+
+// parseList parses a comma-separated list (e.g. "a.pem,b.pem") into a slice, dropping empty items.
+func parseList(s string) []string {
 	parts := strings.Split(s, ",")
 	out := make([]string, 0, len(parts))
 	for _, p := range parts {
@@ -289,6 +437,33 @@ func parseResponseFields(s string) []string {
 	return out
 }
 
+// ParseRemoteKeys parses a comma-separated "kid=path" list (e.g. "2026-01=/keys/a.pem,/keys/b.pem").
+// Items without "=" get an empty key id.
+func ParseRemoteKeys(s string) []RemoteKeyConfig {
+	items := parseList(s)
+	out := make([]RemoteKeyConfig, 0, len(items))
+	for _, item := range items {
+		id, file, ok := strings.Cut(item, "=")
+		if !ok {
+			id, file = "", item
+		}
+		out = append(out, RemoteKeyConfig{ID: strings.TrimSpace(id), File: strings.TrimSpace(file)})
+	}
+	return out
+}
+
+// parseMergeFields parses a comma-separated "field=strategy" list (e.g. "status=remote,scope=union").
+// Items without "=" are ignored.
+func parseMergeFields(s string) map[string]string {
+	out := make(map[string]string)
+	for _, item := range parseList(s) {
+		if field, strategy, ok := strings.Cut(item, "="); ok {
+			out[strings.TrimSpace(field)] = strings.TrimSpace(strategy)
+		}
+	}
+	return out
+}
+
 // overrideFromEnv overrides configuration from environment variables
 func overrideFromEnv(cfg *Config) {
 	// Server
@@ -309,6 +484,39 @@ func overrideFromEnv(cfg *Config) {
 	} else if redisPasswordFile != "" {
 		cfg.Redis.PasswordFile = redisPasswordFile
 	}
+	if v := strings.TrimSpace(os.Getenv("REDIS_DB")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.Redis.DB = n
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("REDIS_KEY_PREFIX")); v != "" {
+		cfg.Redis.KeyPrefix = v
+	}
+	if v := strings.TrimSpace(os.Getenv("REDIS_PAYLOAD_ENCODING")); v != "" {
+		cfg.Redis.Encoding = strings.ToLower(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("CACHE_TTL")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.Cache.TTL = d
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("BLOOM_FILTER_FP_RATE")); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 {
+			cfg.Cache.BloomFPRate = f
+		}
+	}
+	overrideRedisTopologyFromEnv(&cfg.Redis.Topology)
+	overrideUserStoreFromEnv(&cfg.Redis.UserStore)
+	if v := strings.TrimSpace(os.Getenv("REDIS_LEADER_LEASE")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.Redis.LeaderLease = d
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("DATASET_HISTORY")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n != 0 {
+			cfg.Cache.History = n
+		}
+	}
 
 	// Remote
 	if config := os.Getenv("CONFIG"); config != "" {
@@ -341,6 +549,9 @@ func overrideFromEnv(cfg *Config) {
 	if apiKey := os.Getenv("API_KEY"); apiKey != "" {
 		cfg.App.APIKey = apiKey
 	}
+	if adminKey := os.Getenv("ADMIN_API_KEY"); adminKey != "" {
+		cfg.App.AdminAPIKey = adminKey
+	}
 	if dataFile := os.Getenv("DATA_FILE"); dataFile != "" {
 		cfg.App.DataFile = dataFile
 	}
@@ -353,9 +564,26 @@ func overrideFromEnv(cfg *Config) {
 	if v := os.Getenv("REMOTE_RSA_PRIVATE_KEY_FILE"); v != "" {
 		cfg.Remote.RSAPrivateKeyFile = v
 	}
+	overrideRemoteKeysFromEnv(&cfg.Remote)
+	overrideRemoteTLSFromEnv(&cfg.Remote.TLS)
+	overrideRemoteOAuth2FromEnv(&cfg.Remote.OAuth2)
 	if responseFields := os.Getenv("RESPONSE_FIELDS"); responseFields != "" {
 		cfg.App.ResponseFields = parseResponseFields(responseFields)
 	}
+	overridePaginationFromEnv(&cfg.Remote.Pagination)
+	overrideWatchFromEnv(&cfg.Task.Watch)
+	if v := strings.TrimSpace(os.Getenv("SIGNATURE_PUBLIC_KEYS")); v != "" {
+		cfg.Signature.PublicKeys = parseList(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("MERGE_FIELDS")); v != "" {
+		cfg.Merge.Fields = parseMergeFields(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("MERGE_CONFLICTS")); v != "" {
+		cfg.Merge.Conflicts = strings.ToLower(v)
+	}
+	overrideExecFromEnv(&cfg.Exec)
+	overrideGitFromEnv(&cfg.Git)
+	overrideCircuitBreakerFromEnv(&cfg.CircuitBreaker)
 
 	// Tracing
 	if otlpEnabled := os.Getenv("OTLP_ENABLED"); otlpEnabled != "" {
@@ -366,6 +594,197 @@ func overrideFromEnv(cfg *Config) {
 	}
 }
 
+// overridePaginationFromEnv overrides remote pagination settings from REMOTE_PAGINATION* environment variables
+func overridePaginationFromEnv(p *RemotePaginationConfig) {
+	if v := strings.TrimSpace(os.Getenv("REMOTE_PAGINATION")); v != "" {
+		p.Strategy = v
+	}
+	if v := strings.TrimSpace(os.Getenv("REMOTE_PAGE_SIZE")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			p.PageSize = n
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("REMOTE_MAX_PAGES")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			p.MaxPages = n
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("REMOTE_PAGINATION_ITEMS_FIELD")); v != "" {
+		p.ItemsField = v
+	}
+	if v := strings.TrimSpace(os.Getenv("REMOTE_PAGINATION_CURSOR_FIELD")); v != "" {
+		p.CursorField = v
+	}
+	if v := strings.TrimSpace(os.Getenv("REMOTE_PAGINATION_TOTAL_FIELD")); v != "" {
+		p.TotalField = v
+	}
+}
+
+// overrideRemoteKeysFromEnv overrides envelope decryption keys from REMOTE_PRIVATE_KEYS / REMOTE_DECRYPT_LEGACY environment variables
+func overrideRemoteKeysFromEnv(r *RemoteConfig) {
+	if v := strings.TrimSpace(os.Getenv("REMOTE_PRIVATE_KEYS")); v != "" {
+		r.PrivateKeys = ParseRemoteKeys(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("REMOTE_DECRYPT_LEGACY")); v != "" {
+		r.LegacyEncryption = strings.EqualFold(v, "true") || v == "1"
+	}
+}
+
+// overrideRemoteTLSFromEnv overrides the remote TLS settings from REMOTE_TLS_CA_FILE, REMOTE_TLS_CERT_FILE,
+// REMOTE_TLS_KEY_FILE and REMOTE_TLS_SERVER_NAME environment variables
+func overrideRemoteTLSFromEnv(t *RemoteTLSConfig) {
+	if v := strings.TrimSpace(os.Getenv("REMOTE_TLS_CA_FILE")); v != "" {
+		t.CAFile = v
+	}
+	if v := strings.TrimSpace(os.Getenv("REMOTE_TLS_CERT_FILE")); v != "" {
+		t.CertFile = v
+	}
+	if v := strings.TrimSpace(os.Getenv("REMOTE_TLS_KEY_FILE")); v != "" {
+		t.KeyFile = v
+	}
+	if v := strings.TrimSpace(os.Getenv("REMOTE_TLS_SERVER_NAME")); v != "" {
+		t.ServerName = v
+	}
+}
+
+// overrideRemoteOAuth2FromEnv overrides the remote OAuth2 settings from REMOTE_OAUTH2_TOKEN_URL,
+// REMOTE_OAUTH2_CLIENT_ID, REMOTE_OAUTH2_CLIENT_SECRET_FILE, REMOTE_OAUTH2_SCOPES (comma-separated),
+// REMOTE_OAUTH2_AUDIENCE and REMOTE_OAUTH2_AUTH_STYLE environment variables
+func overrideRemoteOAuth2FromEnv(o *RemoteOAuth2Config) {
+	if v := strings.TrimSpace(os.Getenv("REMOTE_OAUTH2_TOKEN_URL")); v != "" {
+		o.TokenURL = v
+	}
+	if v := strings.TrimSpace(os.Getenv("REMOTE_OAUTH2_CLIENT_ID")); v != "" {
+		o.ClientID = v
+	}
+	if v := strings.TrimSpace(os.Getenv("REMOTE_OAUTH2_CLIENT_SECRET_FILE")); v != "" {
+		o.ClientSecretFile = v
+	}
+	if v := strings.TrimSpace(os.Getenv("REMOTE_OAUTH2_SCOPES")); v != "" {
+		o.Scopes = parseList(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("REMOTE_OAUTH2_AUDIENCE")); v != "" {
+		o.Audience = v
+	}
+	if v := strings.TrimSpace(os.Getenv("REMOTE_OAUTH2_AUTH_STYLE")); v != "" {
+		o.AuthStyle = strings.ToLower(v)
+	}
+}
+
+// overrideRedisTopologyFromEnv overrides the Redis topology from REDIS_MODE, REDIS_MASTER_NAME, REDIS_ADDRS
+// and REDIS_SENTINEL_PASSWORD environment variables
+func overrideRedisTopologyFromEnv(t *RedisTopologyConfig) {
+	if v := strings.TrimSpace(os.Getenv("REDIS_MODE")); v != "" {
+		t.Mode = strings.ToLower(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("REDIS_MASTER_NAME")); v != "" {
+		t.MasterName = v
+	}
+	if v := strings.TrimSpace(os.Getenv("REDIS_ADDRS")); v != "" {
+		t.Addrs = parseList(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("REDIS_SENTINEL_PASSWORD")); v != "" {
+		t.SentinelPassword = v
+	}
+}
+
+// overrideUserStoreFromEnv overrides the Redis user store from REDIS_USER_STORE, REDIS_USER_STORE_LRU_SIZE
+// and REDIS_USER_STORE_LRU_TTL environment variables
+func overrideUserStoreFromEnv(u *UserStoreConfig) {
+	if v := strings.TrimSpace(os.Getenv("REDIS_USER_STORE")); v != "" {
+		u.Mode = strings.ToLower(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("REDIS_USER_STORE_LRU_SIZE")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n != 0 {
+			u.LRUSize = n
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("REDIS_USER_STORE_LRU_TTL")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			u.LRUTTL = d
+		}
+	}
+}
+
+// overrideWatchFromEnv overrides data file watching settings from DATA_WATCH / DATA_WATCH_DEBOUNCE environment variables
+func overrideWatchFromEnv(w *FileWatchConfig) {
+	if v := strings.TrimSpace(os.Getenv("DATA_WATCH")); v != "" {
+		w.Disabled = !(strings.EqualFold(v, "true") || v == "1")
+	}
+	if v := strings.TrimSpace(os.Getenv("DATA_WATCH_DEBOUNCE")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			w.Debounce = d
+		}
+	}
+}
+
+// overrideExecFromEnv overrides the exec source from EXEC_COMMAND, EXEC_ARGS, EXEC_FORMAT, EXEC_TIMEOUT
+// and EXEC_PASS_ENV environment variables (EXEC_ARGS and EXEC_PASS_ENV are comma-separated)
+func overrideExecFromEnv(e *ExecSourceConfig) {
+	if v := strings.TrimSpace(os.Getenv("EXEC_COMMAND")); v != "" {
+		e.Command = v
+	}
+	if v := strings.TrimSpace(os.Getenv("EXEC_ARGS")); v != "" {
+		e.Args = parseList(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("EXEC_FORMAT")); v != "" {
+		e.Format = v
+	}
+	if v := strings.TrimSpace(os.Getenv("EXEC_TIMEOUT")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			e.Timeout = d
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("EXEC_PASS_ENV")); v != "" {
+		e.PassEnv = parseList(v)
+	}
+}
+
+// overrideCircuitBreakerFromEnv overrides the circuit breaker from CIRCUIT_BREAKER, CIRCUIT_BREAKER_THRESHOLD,
+// CIRCUIT_BREAKER_BASE_DELAY and CIRCUIT_BREAKER_MAX_DELAY environment variables
+func overrideCircuitBreakerFromEnv(c *CircuitBreakerConfig) {
+	if v := strings.TrimSpace(os.Getenv("CIRCUIT_BREAKER")); v != "" {
+		c.Disabled = !(strings.EqualFold(v, "true") || v == "1")
+	}
+	if v := strings.TrimSpace(os.Getenv("CIRCUIT_BREAKER_THRESHOLD")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			c.Threshold = n
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("CIRCUIT_BREAKER_BASE_DELAY")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			c.BaseDelay = d
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("CIRCUIT_BREAKER_MAX_DELAY")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			c.MaxDelay = d
+		}
+	}
+}
+
+// overrideGitFromEnv overrides the Git source from GIT_SOURCE_URL, GIT_SOURCE_REF, GIT_SOURCE_PATHS (comma-separated),
+// GIT_SOURCE_DIR and GIT_SOURCE_TIMEOUT environment variables
+func overrideGitFromEnv(g *GitSourceConfig) {
+	if v := strings.TrimSpace(os.Getenv("GIT_SOURCE_URL")); v != "" {
+		g.URL = v
+	}
+	if v := strings.TrimSpace(os.Getenv("GIT_SOURCE_REF")); v != "" {
+		g.Ref = v
+	}
+	if v := strings.TrimSpace(os.Getenv("GIT_SOURCE_PATHS")); v != "" {
+		g.Paths = parseList(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("GIT_SOURCE_DIR")); v != "" {
+		g.Dir = v
+	}
+	if v := strings.TrimSpace(os.Getenv("GIT_SOURCE_TIMEOUT")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			g.Timeout = d
+		}
+	}
+}
+
 // validate validates configuration
 func validate(cfg *Config) error {
 	var errs []string
@@ -525,6 +944,29 @@ type CmdConfigData struct {
 	TLSKeyFile              string   // WARDEN_TLS_KEY
 	TLSCAFile               string   // WARDEN_TLS_CA
 	TLSRequireClientCert    bool     // WARDEN_TLS_REQUIRE_CLIENT_CERT
+
+	// Structured source options (YAML + env)
+	RemotePagination RemotePaginationConfig // REMOTE_PAGINATION*
+	DataWatch        FileWatchConfig        // DATA_WATCH, DATA_WATCH_DEBOUNCE
+	Signature        SignatureConfig        // SIGNATURE_PUBLIC_KEYS
+	RemoteKeys       []RemoteKeyConfig      // REMOTE_PRIVATE_KEYS
+	DecryptLegacy    bool                   // REMOTE_DECRYPT_LEGACY
+	Merge            MergeConfig            // MERGE_FIELDS, MERGE_CONFLICTS
+	AdminAPIKey      string                 // ADMIN_API_KEY
+	Exec             ExecSourceConfig       // EXEC_COMMAND, EXEC_ARGS, EXEC_FORMAT, EXEC_TIMEOUT, EXEC_PASS_ENV
+	Git              GitSourceConfig        // GIT_SOURCE_URL, GIT_SOURCE_REF, GIT_SOURCE_PATHS, GIT_SOURCE_DIR, GIT_SOURCE_TIMEOUT
+	CircuitBreaker   CircuitBreakerConfig   // CIRCUIT_BREAKER, CIRCUIT_BREAKER_THRESHOLD, CIRCUIT_BREAKER_BASE_DELAY, CIRCUIT_BREAKER_MAX_DELAY
+	RemoteTLS        RemoteTLSConfig        // REMOTE_TLS_CA_FILE, REMOTE_TLS_CERT_FILE, REMOTE_TLS_KEY_FILE, REMOTE_TLS_SERVER_NAME
+	RemoteOAuth2     RemoteOAuth2Config     // REMOTE_OAUTH2_TOKEN_URL, REMOTE_OAUTH2_CLIENT_ID, REMOTE_OAUTH2_CLIENT_SECRET_FILE, REMOTE_OAUTH2_SCOPES, ...
+	RedisDB          int                    // REDIS_DB
+	RedisKeyPrefix   string                 // REDIS_KEY_PREFIX
+	RedisEncoding    string                 // REDIS_PAYLOAD_ENCODING
+	CacheTTL         time.Duration          // CACHE_TTL
+	BloomFPRate      float64                // BLOOM_FILTER_FP_RATE
+	RedisTopology    RedisTopologyConfig    // REDIS_MODE, REDIS_MASTER_NAME, REDIS_ADDRS, REDIS_SENTINEL_PASSWORD
+	UserStore        UserStoreConfig        // REDIS_USER_STORE, REDIS_USER_STORE_LRU_SIZE, REDIS_USER_STORE_LRU_TTL
+	LeaderLease      time.Duration          // REDIS_LEADER_LEASE
+	DatasetHistory   int                    // DATASET_HISTORY
 }
 
 // ToCmdConfig converts to cmd.Config format
@@ -582,6 +1024,35 @@ func (c *Config) ToCmdConfig() *CmdConfigData {
 		rsaKeyFile = v
 	}
 	rsaKeyPEM := strings.TrimSpace(os.Getenv("REMOTE_RSA_PRIVATE_KEY"))
+	pagination := c.Remote.Pagination
+	overridePaginationFromEnv(&pagination)
+	watch := c.Task.Watch
+	overrideWatchFromEnv(&watch)
+	sig := c.Signature
+	if v := strings.TrimSpace(os.Getenv("SIGNATURE_PUBLIC_KEYS")); v != "" {
+		sig.PublicKeys = parseList(v)
+	}
+	remoteCfg := c.Remote
+	overrideRemoteKeysFromEnv(&remoteCfg)
+	overrideRemoteTLSFromEnv(&remoteCfg.TLS)
+	overrideRemoteOAuth2FromEnv(&remoteCfg.OAuth2)
+	mergeCfg := c.Merge
+	if v := strings.TrimSpace(os.Getenv("MERGE_FIELDS")); v != "" {
+		mergeCfg.Fields = parseMergeFields(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("MERGE_CONFLICTS")); v != "" {
+		mergeCfg.Conflicts = strings.ToLower(v)
+	}
+	execCfg := c.Exec
+	overrideExecFromEnv(&execCfg)
+	gitCfg := c.Git
+	overrideGitFromEnv(&gitCfg)
+	breakerCfg := c.CircuitBreaker
+	overrideCircuitBreakerFromEnv(&breakerCfg)
+	topologyCfg := c.Redis.Topology
+	overrideRedisTopologyFromEnv(&topologyCfg)
+	userStoreCfg := c.Redis.UserStore
+	overrideUserStoreFromEnv(&userStoreCfg)
 	return &CmdConfigData{
 		Port:                    c.Server.Port,
 		Redis:                   c.Redis.Addr,
@@ -607,5 +1078,26 @@ func (c *Config) ToCmdConfig() *CmdConfigData {
 		TLSKeyFile:              strings.TrimSpace(os.Getenv("WARDEN_TLS_KEY")),
 		TLSCAFile:               strings.TrimSpace(os.Getenv("WARDEN_TLS_CA")),
 		TLSRequireClientCert:    tlsRequire,
+		RemotePagination:        pagination,
+		DataWatch:               watch,
+		Signature:               sig,
+		RemoteKeys:              remoteCfg.PrivateKeys,
+		DecryptLegacy:           remoteCfg.LegacyEncryption,
+		Merge:                   mergeCfg,
+		AdminAPIKey:             c.App.AdminAPIKey,
+		Exec:                    execCfg,
+		Git:                     gitCfg,
+		CircuitBreaker:          breakerCfg,
+		RemoteTLS:               remoteCfg.TLS,
+		RemoteOAuth2:            remoteCfg.OAuth2,
+		RedisDB:                 c.Redis.DB,
+		RedisKeyPrefix:          c.Redis.KeyPrefix,
+		RedisEncoding:           c.Redis.Encoding,
+		CacheTTL:                c.Cache.TTL,
+		BloomFPRate:             c.Cache.BloomFPRate,
+		RedisTopology:           topologyCfg,
+		UserStore:               userStoreCfg,
+		LeaderLease:             c.Redis.LeaderLease,
+		DatasetHistory:          c.Cache.History,
 	}
 }
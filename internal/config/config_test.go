@@ -488,3 +488,272 @@ func TestToLegacyConfig(t *testing.T) {
 	assert.Equal(t, 60, legacy.TaskInterval)
 	assert.Equal(t, "development", legacy.Mode)
 }
+
+// TestOverrideFromEnv_Pagination tests REMOTE_PAGINATION* environment variable overrides
+func TestOverrideFromEnv_Pagination(t *testing.T) {
+	t.Setenv("REMOTE_PAGINATION", "cursor")
+	t.Setenv("REMOTE_PAGE_SIZE", "250")
+	t.Setenv("REMOTE_MAX_PAGES", "20")
+	t.Setenv("REMOTE_PAGINATION_ITEMS_FIELD", "items")
+	t.Setenv("REMOTE_PAGINATION_CURSOR_FIELD", "meta.next")
+	t.Setenv("REMOTE_PAGINATION_TOTAL_FIELD", "meta.total")
+
+	cfg := &Config{Remote: RemoteConfig{Pagination: RemotePaginationConfig{Strategy: "page", PageParam: "p"}}}
+	overrideFromEnv(cfg)
+
+	assert.Equal(t, "cursor", cfg.Remote.Pagination.Strategy)
+	assert.Equal(t, 250, cfg.Remote.Pagination.PageSize)
+	assert.Equal(t, 20, cfg.Remote.Pagination.MaxPages)
+	assert.Equal(t, "items", cfg.Remote.Pagination.ItemsField)
+	assert.Equal(t, "meta.next", cfg.Remote.Pagination.CursorField)
+	assert.Equal(t, "meta.total", cfg.Remote.Pagination.TotalField)
+	assert.Equal(t, "p", cfg.Remote.Pagination.PageParam, "YAML-only fields are kept")
+
+	data := cfg.ToCmdConfig()
+	assert.Equal(t, "cursor", data.RemotePagination.Strategy)
+}
+
+func TestOverrideFromEnv_DataWatch(t *testing.T) {
+	t.Setenv("DATA_WATCH", "false")
+	t.Setenv("DATA_WATCH_DEBOUNCE", "2s")
+
+	cfg := &Config{}
+	overrideFromEnv(cfg)
+	assert.True(t, cfg.Task.Watch.Disabled)
+	assert.Equal(t, 2*time.Second, cfg.Task.Watch.Debounce)
+
+	t.Setenv("DATA_WATCH", "true")
+	data := cfg.ToCmdConfig()
+	assert.False(t, data.DataWatch.Disabled, "env re-enables watching over YAML")
+}
+
+func TestOverrideFromEnv_SignaturePublicKeys(t *testing.T) {
+	t.Setenv("SIGNATURE_PUBLIC_KEYS", "/keys/a.pem, /keys/dir")
+
+	cfg := &Config{}
+	overrideFromEnv(cfg)
+	assert.Equal(t, []string{"/keys/a.pem", "/keys/dir"}, cfg.Signature.PublicKeys)
+	assert.Equal(t, cfg.Signature.PublicKeys, cfg.ToCmdConfig().Signature.PublicKeys)
+}
+
+func TestOverrideFromEnv_RemotePrivateKeys(t *testing.T) {
+	t.Setenv("REMOTE_PRIVATE_KEYS", "2026-01=/keys/a.pem, /keys/b.pem")
+	t.Setenv("REMOTE_DECRYPT_LEGACY", "1")
+
+	cfg := &Config{}
+	overrideFromEnv(cfg)
+	want := []RemoteKeyConfig{{ID: "2026-01", File: "/keys/a.pem"}, {File: "/keys/b.pem"}}
+	assert.Equal(t, want, cfg.Remote.PrivateKeys)
+	assert.True(t, cfg.Remote.LegacyEncryption)
+
+	data := cfg.ToCmdConfig()
+	assert.Equal(t, want, data.RemoteKeys)
+	assert.True(t, data.DecryptLegacy)
+}
+
+func TestOverrideFromEnv_MergeFields(t *testing.T) {
+	t.Setenv("MERGE_FIELDS", "status=remote, scope=union,bogus")
+
+	cfg := &Config{}
+	overrideFromEnv(cfg)
+	want := map[string]string{"status": "remote", "scope": "union"}
+	assert.Equal(t, want, cfg.Merge.Fields)
+	assert.Equal(t, want, cfg.ToCmdConfig().Merge.Fields)
+
+	t.Setenv("MERGE_CONFLICTS", "Prefer")
+	assert.Equal(t, "prefer", cfg.ToCmdConfig().Merge.Conflicts)
+}
+
+func TestOverrideFromEnv_UserStore(t *testing.T) {
+	t.Setenv("REDIS_USER_STORE", "HASH")
+	t.Setenv("REDIS_USER_STORE_LRU_SIZE", "500")
+	t.Setenv("REDIS_USER_STORE_LRU_TTL", "bogus")
+
+	cfg := &Config{Redis: RedisConfig{UserStore: UserStoreConfig{LRUTTL: time.Minute}}}
+	overrideFromEnv(cfg)
+	want := UserStoreConfig{Mode: "hash", LRUSize: 500, LRUTTL: time.Minute}
+	assert.Equal(t, want, cfg.Redis.UserStore)
+	assert.Equal(t, want, cfg.ToCmdConfig().UserStore)
+}
+
+func TestOverrideFromEnv_LeaderLease(t *testing.T) {
+	t.Setenv("REDIS_LEADER_LEASE", "45s")
+
+	cfg := &Config{Redis: RedisConfig{LeaderLease: 20 * time.Second}}
+	overrideFromEnv(cfg)
+	assert.Equal(t, 45*time.Second, cfg.Redis.LeaderLease)
+	assert.Equal(t, 45*time.Second, cfg.ToCmdConfig().LeaderLease)
+
+	t.Setenv("REDIS_LEADER_LEASE", "-1s")
+	cfg = &Config{Redis: RedisConfig{LeaderLease: 20 * time.Second}}
+	overrideFromEnv(cfg)
+	assert.Equal(t, 20*time.Second, cfg.Redis.LeaderLease)
+}
+
+func TestOverrideFromEnv_DatasetHistory(t *testing.T) {
+	t.Setenv("DATASET_HISTORY", "8")
+
+	cfg := &Config{Cache: CacheConfig{History: 3}}
+	overrideFromEnv(cfg)
+	assert.Equal(t, 8, cfg.Cache.History)
+	assert.Equal(t, 8, cfg.ToCmdConfig().DatasetHistory)
+
+	t.Setenv("DATASET_HISTORY", "many")
+	cfg = &Config{Cache: CacheConfig{History: 3}}
+	overrideFromEnv(cfg)
+	assert.Equal(t, 3, cfg.Cache.History)
+}
+
+func TestOverrideFromEnv_RedisTopology(t *testing.T) {
+	t.Setenv("REDIS_MODE", "CLUSTER")
+	t.Setenv("REDIS_ADDRS", "10.0.0.1:7000, 10.0.0.2:7000")
+
+	cfg := &Config{Redis: RedisConfig{Topology: RedisTopologyConfig{Mode: "sentinel", MasterName: "mymaster"}}}
+	overrideFromEnv(cfg)
+	assert.Equal(t, "cluster", cfg.Redis.Topology.Mode)
+	assert.Equal(t, "mymaster", cfg.Redis.Topology.MasterName)
+	assert.Equal(t, []string{"10.0.0.1:7000", "10.0.0.2:7000"}, cfg.Redis.Topology.Addrs)
+	assert.Equal(t, cfg.Redis.Topology, cfg.ToCmdConfig().RedisTopology)
+}
+
+func TestOverrideFromEnv_RedisKeys(t *testing.T) {
+	t.Setenv("REDIS_DB", "2")
+	t.Setenv("REDIS_KEY_PREFIX", "team-a")
+	t.Setenv("CACHE_TTL", "10m")
+	t.Setenv("REDIS_PAYLOAD_ENCODING", "zstd")
+
+	cfg := &Config{Redis: RedisConfig{KeyPrefix: "warden"}, Cache: CacheConfig{TTL: time.Hour}}
+	overrideFromEnv(cfg)
+	assert.Equal(t, 2, cfg.Redis.DB)
+	assert.Equal(t, "team-a", cfg.Redis.KeyPrefix)
+	assert.Equal(t, 10*time.Minute, cfg.Cache.TTL)
+
+	cmdCfg := cfg.ToCmdConfig()
+	assert.Equal(t, 2, cmdCfg.RedisDB)
+	assert.Equal(t, "team-a", cmdCfg.RedisKeyPrefix)
+	assert.Equal(t, 10*time.Minute, cmdCfg.CacheTTL)
+	assert.Equal(t, "zstd", cmdCfg.RedisEncoding)
+}
+
+func TestOverrideFromEnv_MembershipFilter(t *testing.T) {
+	t.Setenv("BLOOM_FILTER_FP_RATE", "0.001")
+
+	cfg := &Config{Cache: CacheConfig{BloomFPRate: 0.01}}
+	overrideFromEnv(cfg)
+	assert.InDelta(t, 0.001, cfg.Cache.BloomFPRate, 1e-12)
+	assert.InDelta(t, 0.001, cfg.ToCmdConfig().BloomFPRate, 1e-12)
+
+	t.Setenv("BLOOM_FILTER_FP_RATE", "often")
+	cfg = &Config{Cache: CacheConfig{BloomFPRate: 0.01}}
+	overrideFromEnv(cfg)
+	assert.InDelta(t, 0.01, cfg.Cache.BloomFPRate, 1e-12)
+}
+
+func TestLoadFromFile_RedisSentinel(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	yamlContent := `
+redis:
+  mode: "sentinel"
+  master_name: "mymaster"
+  addrs: ["10.0.0.1:26379", "10.0.0.2:26379"]
+  sentinel_password: "sentinel-secret"
+`
+	require.NoError(t, os.WriteFile(configFile, []byte(yamlContent), 0o600))
+
+	cfg, err := LoadFromFile(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, RedisTopologyConfig{
+		Mode:             "sentinel",
+		MasterName:       "mymaster",
+		Addrs:            []string{"10.0.0.1:26379", "10.0.0.2:26379"},
+		SentinelPassword: "sentinel-secret",
+	}, cfg.Redis.Topology)
+}
+
+func TestOverrideFromEnv_Exec(t *testing.T) {
+	t.Setenv("EXEC_COMMAND", "/usr/local/bin/roster")
+	t.Setenv("EXEC_ARGS", "export,--format=json")
+	t.Setenv("EXEC_TIMEOUT", "15s")
+	t.Setenv("EXEC_PASS_ENV", "ROSTER_TOKEN, ROSTER_REGION")
+
+	cfg := &Config{Exec: ExecSourceConfig{Format: "json", Env: map[string]string{"LANG": "C"}}}
+	overrideFromEnv(cfg)
+	want := ExecSourceConfig{
+		Command: "/usr/local/bin/roster",
+		Args:    []string{"export", "--format=json"},
+		Format:  "json",
+		Timeout: 15 * time.Second,
+		Env:     map[string]string{"LANG": "C"},
+		PassEnv: []string{"ROSTER_TOKEN", "ROSTER_REGION"},
+	}
+	assert.Equal(t, want, cfg.Exec)
+	assert.Equal(t, want, cfg.ToCmdConfig().Exec)
+}
+
+func TestOverrideFromEnv_Git(t *testing.T) {
+	t.Setenv("GIT_SOURCE_URL", "/srv/git/users.git")
+	t.Setenv("GIT_SOURCE_PATHS", "users/*.json, admins.json")
+	t.Setenv("GIT_SOURCE_TIMEOUT", "90s")
+
+	cfg := &Config{Git: GitSourceConfig{Ref: "main"}}
+	overrideFromEnv(cfg)
+	want := GitSourceConfig{
+		URL:     "/srv/git/users.git",
+		Ref:     "main",
+		Paths:   []string{"users/*.json", "admins.json"},
+		Timeout: 90 * time.Second,
+	}
+	assert.Equal(t, want, cfg.Git)
+	assert.Equal(t, want, cfg.ToCmdConfig().Git)
+}
+
+func TestOverrideFromEnv_CircuitBreaker(t *testing.T) {
+	t.Setenv("CIRCUIT_BREAKER_THRESHOLD", "5")
+	t.Setenv("CIRCUIT_BREAKER_MAX_DELAY", "10m")
+
+	cfg := &Config{CircuitBreaker: CircuitBreakerConfig{BaseDelay: 30 * time.Second}}
+	overrideFromEnv(cfg)
+	want := CircuitBreakerConfig{Threshold: 5, BaseDelay: 30 * time.Second, MaxDelay: 10 * time.Minute}
+	assert.Equal(t, want, cfg.CircuitBreaker)
+	assert.Equal(t, want, cfg.ToCmdConfig().CircuitBreaker)
+
+	t.Setenv("CIRCUIT_BREAKER", "0")
+	overrideFromEnv(cfg)
+	assert.True(t, cfg.CircuitBreaker.Disabled)
+}
+
+func TestOverrideFromEnv_RemoteTLS(t *testing.T) {
+	t.Setenv("REMOTE_TLS_CERT_FILE", "/etc/warden/client.crt")
+	t.Setenv("REMOTE_TLS_KEY_FILE", "/etc/warden/client.key")
+
+	cfg := &Config{Remote: RemoteConfig{TLS: RemoteTLSConfig{CAFile: "/etc/warden/ca.pem"}}}
+	overrideFromEnv(cfg)
+	want := RemoteTLSConfig{CAFile: "/etc/warden/ca.pem", CertFile: "/etc/warden/client.crt", KeyFile: "/etc/warden/client.key"}
+	assert.Equal(t, want, cfg.Remote.TLS)
+	assert.Equal(t, want, cfg.ToCmdConfig().RemoteTLS)
+}
+
+func TestOverrideFromEnv_AdminAPIKey(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", "admin-secret")
+
+	cfg := &Config{}
+	overrideFromEnv(cfg)
+	assert.Equal(t, "admin-secret", cfg.App.AdminAPIKey)
+	assert.Equal(t, "admin-secret", cfg.ToCmdConfig().AdminAPIKey)
+}
+
+func TestOverrideFromEnv_RemoteOAuth2(t *testing.T) {
+	t.Setenv("REMOTE_OAUTH2_CLIENT_SECRET_FILE", "/run/secrets/warden-client")
+	t.Setenv("REMOTE_OAUTH2_SCOPES", "roster.read,roster.list")
+
+	cfg := &Config{Remote: RemoteConfig{OAuth2: RemoteOAuth2Config{TokenURL: "https://idp.example.com/token", ClientID: "warden"}}}
+	overrideFromEnv(cfg)
+	want := RemoteOAuth2Config{
+		TokenURL:         "https://idp.example.com/token",
+		ClientID:         "warden",
+		ClientSecretFile: "/run/secrets/warden-client",
+		Scopes:           []string{"roster.read", "roster.list"},
+	}
+	assert.Equal(t, want, cfg.Remote.OAuth2)
+	assert.Equal(t, want, cfg.ToCmdConfig().RemoteOAuth2)
+}
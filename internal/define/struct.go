@@ -2,6 +2,7 @@ package define
 
 import (
 	"strings"
+	"time"
 
 	secure "github.com/soulteary/secure-kit"
 )
@@ -78,3 +79,139 @@ func (u *AllowListUser) IsValid() bool {
 	}
 	return false
 }
+
+// Provenance records which sources contributed a user's record and when the data was loaded.
+type Provenance struct {
+	LoadedAt time.Time          `json:"loaded_at"`
+	Version  string             `json:"version,omitempty"` // dataset version of the load (Git commit), if any
+	Sources  []ProvenanceSource `json:"sources"`           // lowest precedence first; the last one wins conflicts
+}
+
+// ProvenanceSource is one contributing source: a file path, a remote or Git URL (without credentials or query)
+// or the exec source command.
+type ProvenanceSource struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // "file", "remote", "git" or "exec"
+}
+
+// Names returns the source names.
+func (p *Provenance) Names() []string {
+	names := make([]string, len(p.Sources))
+	for i := range p.Sources {
+		names[i] = p.Sources[i].Name
+	}
+	return names
+}
+
+// LoaderState is the state of the last successful load that the leader shares with the followers, which
+// do not load the sources themselves.
+type LoaderState struct {
+	LoadedAt  time.Time          `json:"loaded_at"`
+	Version   string             `json:"version,omitempty"` // dataset version of the load (Git commit), if any
+	Sources   []SourceStatus     `json:"sources"`
+	Conflicts []IdentityConflict `json:"conflicts,omitempty"`
+	Report    *LoadReport        `json:"report,omitempty"`
+	// SourceSets are the distinct source lists of the users (see Provenance.Sources); the list of each
+	// user is stored apart from the state, by index
+	SourceSets [][]ProvenanceSource `json:"source_sets,omitempty"`
+}
+
+// SourceStatus is the sync state of one configured data source.
+//
+//nolint:govet // fieldalignment: field order follows the JSON output
+type SourceStatus struct {
+	Name        string    `json:"name"`              // file path, remote / Git URL (without credentials or query) or command
+	Type        string    `json:"type"`              // "file", "remote", "git" or "exec"
+	Version     string    `json:"version,omitempty"` // version of the last successful attempt (Git commit)
+	LastAttempt time.Time `json:"last_attempt,omitzero"`
+	LastSuccess time.Time `json:"last_success,omitzero"`
+	LastError   string    `json:"last_error,omitempty"` // error of the last attempt; empty when it succeeded
+	Records     int       `json:"records"`              // records returned by the last successful attempt
+	LatencyMS   int64     `json:"latency_ms"`           // duration of the last attempt
+	Circuit     string    `json:"circuit,omitempty"`    // circuit breaker state of remote-class sources: closed, half_open or open
+	RetryAt     time.Time `json:"retry_at,omitzero"`    // when an open circuit lets the next probe through
+}
+
+// Failing reports whether the last attempt of the source failed.
+func (s *SourceStatus) Failing() bool {
+	return s.LastError != ""
+}
+
+// InstanceStatus is the dataset served by one Warden instance, as reported to the other instances
+// sharing its Redis.
+type InstanceStatus struct {
+	Instance  string    `json:"instance"`   // instance id (hostname-pid)
+	Version   int64     `json:"version"`    // Redis cache version or user store generation of the served data; 0 when only loaded from sources
+	Hash      string    `json:"hash"`       // content hash of the served user list
+	Users     int       `json:"users"`      // number of users served
+	Leader    bool      `json:"leader"`     // whether the instance holds the leadership and loads the sources
+	UpdatedAt time.Time `json:"updated_at"` // time of the report
+}
+
+// Load report reasons.
+const (
+	ReasonMissingIdentifier = "missing_identifier"  // neither phone nor mail is set (record dropped)
+	ReasonInvalidPhone      = "invalid_phone"       // record dropped
+	ReasonInvalidMail       = "invalid_mail"        // record dropped
+	ReasonDuplicateKey      = "duplicate_key"       // same phone (or mail) as an earlier record, which it replaces
+	ReasonConflictingUserID = "conflicting_user_id" // same user_id as an earlier record with another key; both are kept
+)
+
+// LoadReportMaxIssues caps LoadReport.Issues; reasons are still counted beyond it.
+const LoadReportMaxIssues = 1000
+
+// LoadReport describes the validation of the dataset applied by the latest reload.
+//
+//nolint:govet // fieldalignment: field order follows the JSON output
+type LoadReport struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Total       int            `json:"total"`     // records received
+	Accepted    int            `json:"accepted"`  // records in the cache after validation and deduplication
+	Rejected    int            `json:"rejected"`  // records dropped as invalid
+	Reasons     map[string]int `json:"reasons"`   // issue count per reason
+	Issues      []LoadIssue    `json:"issues"`    // one entry per issue, in input order (at most LoadReportMaxIssues)
+	Truncated   bool           `json:"truncated"` // more issues than LoadReportMaxIssues
+
+	Conflicts []IdentityConflict `json:"conflicts"` // identity conflicts found while merging the sources
+}
+
+// LoadIssue is one record that was dropped or needs attention.
+type LoadIssue struct {
+	Index  int    `json:"index"` // position in the loaded dataset (after merging sources)
+	Phone  string `json:"phone,omitempty"`
+	Mail   string `json:"mail,omitempty"`
+	UserID string `json:"user_id,omitempty"`
+	Reason string `json:"reason"`
+	Detail string `json:"detail,omitempty"` // validation error, or the index of the earlier record it collides with
+}
+
+// Add records an issue.
+func (r *LoadReport) Add(issue LoadIssue) {
+	if r.Reasons == nil {
+		r.Reasons = make(map[string]int)
+	}
+	r.Reasons[issue.Reason]++
+	if len(r.Issues) >= LoadReportMaxIssues {
+		r.Truncated = true
+		return
+	}
+	r.Issues = append(r.Issues, issue)
+}
+
+// IdentityConflict is a user_id or mail shared by merged records with different keys (phone, or mail
+// for records without phone).
+type IdentityConflict struct {
+	Field   string           `json:"field"`  // "user_id" or "mail"
+	Value   string           `json:"value"`  // the shared user_id or (lowercase) mail
+	Action  string           `json:"action"` // conflict policy applied: "warn", "reject" or "prefer"
+	Records []ConflictRecord `json:"records"`
+}
+
+// ConflictRecord is one record involved in an IdentityConflict.
+type ConflictRecord struct {
+	Phone   string             `json:"phone,omitempty"`
+	Mail    string             `json:"mail,omitempty"`
+	UserID  string             `json:"user_id,omitempty"`
+	Sources []ProvenanceSource `json:"sources"` // sources that listed the record, lowest precedence first
+	Dropped bool               `json:"dropped"` // removed from the dataset by the policy
+}
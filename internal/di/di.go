@@ -80,6 +80,7 @@ func (d *Dependencies) initRedis() error {
 	if d.Config.RedisPassword != "" {
 		redisCfg = redisCfg.WithPassword(d.Config.RedisPassword)
 	}
+	redisCfg.DB = d.Config.RedisDB
 
 	var err error
 	d.RedisClient, err = rediskitclient.NewClient(redisCfg)
@@ -94,7 +95,7 @@ func (d *Dependencies) initRedis() error {
 func (d *Dependencies) initCache() {
 	// Only create RedisUserCache if Redis client exists
 	if d.RedisClient != nil {
-		d.RedisUserCache = cache.NewRedisUserCache(d.RedisClient)
+		d.RedisUserCache = cache.NewRedisUserCache(d.RedisClient, cache.Namespace(d.Config.RedisKeyPrefix), d.Config.CacheTTL, d.Config.RedisEncoding)
 	} else {
 		d.RedisUserCache = nil
 	}
@@ -128,8 +129,8 @@ func (d *Dependencies) initHandlers() {
 	bodyLimitCfg.MaxSize = define.MAX_REQUEST_BODY_SIZE
 	bodyLimitMiddleware := middlewarekit.BodyLimitStd(bodyLimitCfg)
 
-	// Main data interface handler (nil responseFields = all fields)
-	d.MainHandler = compressMiddleware(
+	// Main data interface handler (nil responseFields = all fields); the full list is sent pre-compressed
+	d.MainHandler = router.CompressExceptFullList(compressMiddleware)(
 		bodyLimitMiddleware(
 			middleware.MetricsMiddleware(
 				rateLimitMiddleware(
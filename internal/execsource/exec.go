@@ -0,0 +1,223 @@
+// Package execsource runs an external command that prints user data to stdout.
+package execsource
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/soulteary/warden/internal/define"
+)
+
+const (
+	// FormatJSON is a JSON array of users.
+	FormatJSON = "json"
+	// FormatNDJSON is one JSON user object per line.
+	FormatNDJSON = "ndjson"
+	// DefaultTimeout applies when Options.Timeout is not set.
+	DefaultTimeout = 30 * time.Second
+
+	// minSecretLen is the shortest PassEnv value treated as a secret by CheckArgs (shorter values are too
+	// likely to appear in arguments by coincidence).
+	minSecretLen = 8
+	// maxStderr is the amount of stderr kept for error messages.
+	maxStderr = 512
+	// waitDelay bounds the wait for stdout/stderr after the command exits or is killed, so a child
+	// that leaves a background process holding the pipes cannot block the load.
+	waitDelay = time.Second
+)
+
+// ErrSecretInArgs is returned when an argument contains a secret; secrets must be passed through the environment.
+var ErrSecretInArgs = errors.New("argument contains a secret value, pass it through env / pass_env instead")
+
+// Options describes the command. It is run directly (no shell) with a minimal environment: PATH, the
+// PassEnv variables copied from Warden's environment and Env. Secrets belong in PassEnv, never in Args:
+// the command line is visible to every local user (ps, /proc).
+//
+//nolint:govet // fieldalignment: keep field order for readability
+type Options struct {
+	Command string            // executable path (or name looked up in PATH)
+	Args    []string          // arguments (no shell expansion)
+	Format  string            // FormatJSON, FormatNDJSON or "" (detect: '[' = JSON array, otherwise NDJSON)
+	Timeout time.Duration     // kill the command after this duration (default DefaultTimeout)
+	Env     map[string]string // extra variables (non-secret)
+	PassEnv []string          // names of Warden environment variables passed to the command (e.g. tokens)
+}
+
+// ValidFormat reports whether format is a supported output format ("" = detect).
+func ValidFormat(format string) bool {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", FormatJSON, FormatNDJSON:
+		return true
+	}
+	return false
+}
+
+// CheckArgs returns ErrSecretInArgs when an argument contains one of secrets.
+// Empty secrets and secrets shorter than 8 bytes are ignored.
+func CheckArgs(args []string, secrets ...string) error {
+	for i, a := range args {
+		for _, s := range secrets {
+			if len(s) >= minSecretLen && strings.Contains(a, s) {
+				return fmt.Errorf("args[%d]: %w", i, ErrSecretInArgs)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks the options without running the command.
+func (o *Options) Validate() error {
+	if strings.TrimSpace(o.Command) == "" {
+		return errors.New("command is empty")
+	}
+	if !ValidFormat(o.Format) {
+		return fmt.Errorf("format %q is invalid (should be %s or %s)", o.Format, FormatJSON, FormatNDJSON)
+	}
+	if o.Timeout < 0 {
+		return fmt.Errorf("timeout %s is negative", o.Timeout)
+	}
+	return CheckArgs(o.Args, o.passEnvValues()...)
+}
+
+// passEnvValues returns the current values of the PassEnv variables.
+func (o *Options) passEnvValues() []string {
+	out := make([]string, 0, len(o.PassEnv))
+	for _, name := range o.PassEnv {
+		if v, ok := os.LookupEnv(name); ok {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// environ returns the command environment: PATH, then PassEnv, then Env (sorted by name).
+func (o *Options) environ() []string {
+	out := make([]string, 0, 1+len(o.PassEnv)+len(o.Env))
+	if p, ok := os.LookupEnv("PATH"); ok {
+		out = append(out, "PATH="+p)
+	}
+	for _, name := range o.PassEnv {
+		if v, ok := os.LookupEnv(name); ok {
+			out = append(out, name+"="+v)
+		}
+	}
+	names := make([]string, 0, len(o.Env))
+	for name := range o.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out = append(out, name+"="+o.Env[name])
+	}
+	return out
+}
+
+// limitedBuffer collects up to max bytes; further writes fail, which stops the copy and closes the pipe.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	max      int
+	exceeded bool
+	truncate bool // drop the excess silently instead of failing
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); len(p) > room {
+		if !b.truncate {
+			b.exceeded = true
+			return 0, fmt.Errorf("output exceeds %d bytes", b.max)
+		}
+		b.buf.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// Run runs the command and parses its stdout. A non-zero exit status, a timeout or output larger than
+// define.MAX_JSON_SIZE is an error; stderr (truncated) is included in the error message.
+func Run(ctx context.Context, o *Options) ([]define.AllowListUser, error) {
+	if err := o.Validate(); err != nil {
+		return nil, fmt.Errorf("exec %s: %w", o.Command, err)
+	}
+	timeout := o.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stdout := &limitedBuffer{max: define.MAX_JSON_SIZE}
+	stderr := &limitedBuffer{max: maxStderr, truncate: true}
+	cmd := exec.CommandContext(ctx, o.Command, o.Args...) // #nosec G204 -- command and args from config, no shell
+	cmd.Env = o.environ()
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = waitDelay
+
+	err := cmd.Run()
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return nil, fmt.Errorf("exec %s: timed out after %s", o.Command, timeout)
+	case stdout.exceeded:
+		return nil, fmt.Errorf("exec %s: output exceeds %d bytes", o.Command, define.MAX_JSON_SIZE)
+	case err != nil:
+		if msg := strings.TrimSpace(stderr.buf.String()); msg != "" {
+			return nil, fmt.Errorf("exec %s: %w: %s", o.Command, err, msg)
+		}
+		return nil, fmt.Errorf("exec %s: %w", o.Command, err)
+	}
+	users, err := Parse(stdout.buf.Bytes(), o.Format)
+	if err != nil {
+		return nil, fmt.Errorf("exec %s: %w", o.Command, err)
+	}
+	return users, nil
+}
+
+// Parse parses command output in format (FormatJSON, FormatNDJSON or "" to detect). Empty output is an empty list.
+func Parse(data []byte, format string) ([]define.AllowListUser, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return []define.AllowListUser{}, nil
+	}
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = FormatNDJSON
+		if data[0] == '[' {
+			format = FormatJSON
+		}
+	}
+	if format == FormatJSON {
+		var users []define.AllowListUser
+		if err := json.Unmarshal(data, &users); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON output: %w", err)
+		}
+		return users, nil
+	}
+
+	users := []define.AllowListUser{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), define.MAX_JSON_SIZE)
+	for line := 1; sc.Scan(); line++ {
+		text := bytes.TrimSpace(sc.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var u define.AllowListUser
+		if err := json.Unmarshal(text, &u); err != nil {
+			return nil, fmt.Errorf("failed to parse NDJSON output at line %d: %w", line, err)
+		}
+		users = append(users, u)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NDJSON output: %w", err)
+	}
+	return users, nil
+}
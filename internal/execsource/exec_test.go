@@ -0,0 +1,116 @@
+package execsource
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeScript writes an executable shell script and returns its path.
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "roster.sh")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o700)) // #nosec G306 -- test script must be executable
+	return path
+}
+
+func TestRun_JSONAndNDJSON(t *testing.T) {
+	jsonScript := writeScript(t, `echo '[{"phone":"13800138000","mail":"a@example.com"}]'`)
+	users, err := Run(context.Background(), &Options{Command: jsonScript})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "13800138000", users[0].Phone)
+
+	ndjsonScript := writeScript(t, `printf '{"phone":"13800138000"}\n\n{"mail":"b@example.com"}\n'`)
+	users, err = Run(context.Background(), &Options{Command: ndjsonScript, Format: FormatNDJSON})
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "b@example.com", users[1].Mail)
+
+	users, err = Run(context.Background(), &Options{Command: writeScript(t, "true")})
+	require.NoError(t, err)
+	assert.Empty(t, users, "empty output is an empty list")
+}
+
+func TestRun_Failures(t *testing.T) {
+	t.Run("non-zero exit", func(t *testing.T) {
+		script := writeScript(t, `echo '[]'; echo "upstream unavailable" >&2; exit 3`)
+		_, err := Run(context.Background(), &Options{Command: script})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "exit status 3")
+		assert.Contains(t, err.Error(), "upstream unavailable")
+	})
+	t.Run("timeout", func(t *testing.T) {
+		script := writeScript(t, `sleep 5`)
+		start := time.Now()
+		_, err := Run(context.Background(), &Options{Command: script, Timeout: 100 * time.Millisecond})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "timed out")
+		assert.Less(t, time.Since(start), 3*time.Second)
+	})
+	t.Run("invalid output", func(t *testing.T) {
+		script := writeScript(t, `echo '{"phone":"1"}'; echo 'not json'`)
+		_, err := Run(context.Background(), &Options{Command: script})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "line 2")
+	})
+	t.Run("missing command", func(t *testing.T) {
+		_, err := Run(context.Background(), &Options{Command: filepath.Join(t.TempDir(), "missing")})
+		require.Error(t, err)
+	})
+}
+
+func TestRun_Environment(t *testing.T) {
+	t.Setenv("ROSTER_TOKEN", "s3cr3t-token-value")
+	t.Setenv("API_KEY", "warden-api-key")
+	script := writeScript(t, `printf '[{"phone":"%s","mail":"%s","name":"%s"}]' "$ROSTER_TOKEN" "$API_KEY" "$REGION"`)
+	users, err := Run(context.Background(), &Options{
+		Command: script,
+		PassEnv: []string{"ROSTER_TOKEN"},
+		Env:     map[string]string{"REGION": "eu"},
+	})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "s3cr3t-token-value", users[0].Phone, "pass_env variables are passed")
+	assert.Empty(t, users[0].Mail, "other Warden variables are not")
+	assert.Equal(t, "eu", users[0].Name)
+}
+
+func TestCheckArgs(t *testing.T) {
+	t.Setenv("ROSTER_TOKEN", "s3cr3t-token-value")
+	o := &Options{Command: "/bin/true", Args: []string{"--token=s3cr3t-token-value"}, PassEnv: []string{"ROSTER_TOKEN"}}
+	err := o.Validate()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrSecretInArgs))
+	_, err = Run(context.Background(), o)
+	assert.True(t, errors.Is(err, ErrSecretInArgs), "the command is not started")
+
+	require.NoError(t, CheckArgs([]string{"export", "--format", "json"}, "", "short", "long-secret-value"))
+	assert.Error(t, CheckArgs([]string{"x", "long-secret-value"}, "long-secret-value"))
+}
+
+func TestValidate(t *testing.T) {
+	assert.Error(t, (&Options{}).Validate())
+	assert.Error(t, (&Options{Command: "/bin/true", Format: "csv"}).Validate())
+	assert.Error(t, (&Options{Command: "/bin/true", Timeout: -time.Second}).Validate())
+	assert.NoError(t, (&Options{Command: "/bin/true", Format: "NDJSON"}).Validate())
+}
+
+func TestParse(t *testing.T) {
+	users, err := Parse([]byte("  [{\"phone\":\"1\"}]\n"), "")
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	users, err = Parse([]byte(`{"phone":"1"}`), "")
+	require.NoError(t, err, "a single object is NDJSON")
+	assert.Len(t, users, 1)
+
+	_, err = Parse([]byte(`{"phone":"1"}`), FormatJSON)
+	assert.Error(t, err)
+}
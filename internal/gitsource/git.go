@@ -0,0 +1,312 @@
+// Package gitsource reads user data files from a Git repository at a branch, tag or commit.
+//
+// The repository is fetched into a local bare cache with the git command line. Nothing is checked out:
+// data files are read from the commit tree, so the cache never contains uncommitted changes.
+package gitsource
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/internal/signature"
+)
+
+const (
+	// DefaultTimeout applies to every git command when Options.Timeout is not set.
+	DefaultTimeout = 60 * time.Second
+	// DefaultRef is used when Options.Ref is empty (the remote's default branch).
+	DefaultRef = "HEAD"
+	// SignatureSuffix is the suffix of a detached signature committed next to a data file.
+	SignatureSuffix = signature.FileSuffix
+
+	// pinnedRef keeps the fetched commit reachable so automatic gc does not prune it.
+	pinnedRef = "refs/warden/current"
+)
+
+// DefaultPaths are the data file patterns used when Options.Paths is empty.
+var DefaultPaths = []string{"*.json"}
+
+var (
+	commitRe = regexp.MustCompile(`^[0-9a-fA-F]{40}([0-9a-fA-F]{24})?$`)
+	refRe    = regexp.MustCompile(`^[A-Za-z0-9._/-]+$`)
+)
+
+// Options describes the repository.
+//
+//nolint:govet // fieldalignment: keep field order for readability
+type Options struct {
+	URL     string        // repository URL or local path (bare or not)
+	Ref     string        // branch, tag or full commit SHA (default DefaultRef)
+	Paths   []string      // path.Match patterns of data files relative to the repository root (default DefaultPaths)
+	Dir     string        // local bare cache (default: a directory per URL under os.TempDir)
+	Timeout time.Duration // per git command (default DefaultTimeout)
+}
+
+// File is a data file read from a commit. Signature is the content of the committed "<path>.sig", if any.
+type File struct {
+	Path      string
+	Data      []byte
+	Signature []byte
+}
+
+// IsCommit reports whether ref is a full commit SHA (SHA-1 or SHA-256), i.e. a pinned version.
+func IsCommit(ref string) bool {
+	return commitRe.MatchString(ref)
+}
+
+// Validate checks the options without running git.
+func (o *Options) Validate() error {
+	if strings.TrimSpace(o.URL) == "" {
+		return errors.New("url is empty")
+	}
+	if strings.HasPrefix(o.URL, "-") {
+		return fmt.Errorf("url %q must not start with '-'", o.URL)
+	}
+	if ref := o.Ref; ref != "" && (!refRe.MatchString(ref) || strings.HasPrefix(ref, "-") || strings.Contains(ref, "..")) {
+		return fmt.Errorf("ref %q is not a valid branch, tag or commit", ref)
+	}
+	for _, p := range o.Paths {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("path pattern %q: %w", p, err)
+		}
+	}
+	if o.Timeout < 0 {
+		return fmt.Errorf("timeout %s is negative", o.Timeout)
+	}
+	return nil
+}
+
+// Name returns the repository URL without credentials or query, so it can be shown and logged.
+func Name(repoURL string) string {
+	u, err := url.Parse(repoURL)
+	if err != nil || u.Scheme == "" {
+		return repoURL // local path or scp-like "git@host:org/repo.git"
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// Repo is a Git repository source. Methods are safe for concurrent use; git commands run one at a time.
+type Repo struct {
+	mu      sync.Mutex
+	url     string
+	ref     string
+	paths   []string
+	dir     string
+	timeout time.Duration
+	env     []string // GIT_CONFIG_* variables of the origin remote (see remoteEnv)
+}
+
+// remoteEnv returns the GIT_CONFIG_* variables that set repoURL as the "origin" remote. git passes the
+// remote URL to its remote helper (git-remote-https) as an argument, which every local user can read in
+// the process list, so credentials of an http(s) URL are removed from it and sent as an Authorization
+// header (http.<scheme://host>.extraHeader) instead; environment variables are only readable by the
+// same user. Other URLs are used as they are.
+func remoteEnv(repoURL string) []string {
+	config := [][2]string{{"remote.origin.url", repoURL}}
+	if u, err := url.Parse(repoURL); err == nil && u.User != nil && (u.Scheme == "http" || u.Scheme == "https") {
+		password, _ := u.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(u.User.Username() + ":" + password))
+		u.User = nil
+		config = [][2]string{
+			{"remote.origin.url", u.String()},
+			{"http." + u.Scheme + "://" + u.Host + ".extraHeader", "Authorization: Basic " + credentials},
+		}
+	}
+	env := []string{"GIT_CONFIG_COUNT=" + strconv.Itoa(len(config))}
+	for i, kv := range config {
+		env = append(env, "GIT_CONFIG_KEY_"+strconv.Itoa(i)+"="+kv[0], "GIT_CONFIG_VALUE_"+strconv.Itoa(i)+"="+kv[1])
+	}
+	return env
+}
+
+// New creates a Repo for o. The local cache is created on the first Sync.
+func New(o *Options) (*Repo, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	r := &Repo{url: o.URL, ref: o.Ref, paths: o.Paths, dir: o.Dir, timeout: o.Timeout, env: remoteEnv(o.URL)}
+	if r.ref == "" {
+		r.ref = DefaultRef
+	}
+	if len(r.paths) == 0 {
+		r.paths = DefaultPaths
+	}
+	if r.dir == "" {
+		sum := sha256.Sum256([]byte(o.URL))
+		r.dir = filepath.Join(os.TempDir(), "warden-git-"+hex.EncodeToString(sum[:8]))
+	}
+	if r.timeout == 0 {
+		r.timeout = DefaultTimeout
+	}
+	return r, nil
+}
+
+// Name returns the sanitized repository URL (see Name).
+func (r *Repo) Name() string {
+	return Name(r.url)
+}
+
+// Ref returns the configured branch, tag or commit.
+func (r *Repo) Ref() string {
+	return r.ref
+}
+
+// git runs git against the local cache. The repository is the "origin" remote, configured through
+// GIT_CONFIG_* variables (see remoteEnv), so it is never passed on the git command line.
+func (r *Repo) git(ctx context.Context, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", append([]string{"--git-dir", r.dir}, args...)...) // #nosec G204 -- fixed binary, validated arguments
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0"), r.env...)
+	out, err := cmd.Output()
+	if err == nil {
+		return out, nil
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("git %s: timed out after %s", args[0], r.timeout)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if msg := strings.TrimSpace(string(exitErr.Stderr)); msg != "" {
+			// Do not leak credentials from the configured URL
+			return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.ReplaceAll(msg, r.url, r.Name()))
+		}
+	}
+	return nil, fmt.Errorf("git %s: %w", args[0], err)
+}
+
+// init creates the local bare cache when it does not exist yet.
+func (r *Repo) init(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(r.dir, "HEAD")); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(r.dir, 0o700); err != nil {
+		return err
+	}
+	_, err := r.git(ctx, "init", "--bare", "--quiet")
+	return err
+}
+
+// hasCommit reports whether commit is in the local cache.
+func (r *Repo) hasCommit(ctx context.Context, commit string) bool {
+	_, err := r.git(ctx, "cat-file", "-e", commit+"^{commit}")
+	return err == nil
+}
+
+// Sync fetches the configured ref and returns its commit SHA. A pinned commit is fetched only once;
+// branches and tags are fetched (shallow) on every call.
+func (r *Repo) Sync(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.init(ctx); err != nil {
+		return "", err
+	}
+	if IsCommit(r.ref) {
+		commit := strings.ToLower(r.ref)
+		if !r.hasCommit(ctx, commit) {
+			if _, err := r.git(ctx, "fetch", "--no-tags", "--quiet", "--depth=1", "origin", "+"+commit+":"+pinnedRef); err != nil {
+				// Servers that refuse fetching by SHA: fetch all branches and tags instead
+				if _, err := r.git(ctx, "fetch", "--no-tags", "--quiet", "origin", "+refs/heads/*:refs/warden/heads/*", "+refs/tags/*:refs/warden/tags/*"); err != nil {
+					return "", err
+				}
+			}
+			if !r.hasCommit(ctx, commit) {
+				return "", fmt.Errorf("commit %s not found in %s", commit, r.Name())
+			}
+		}
+		return commit, nil
+	}
+	if _, err := r.git(ctx, "fetch", "--no-tags", "--quiet", "--depth=1", "origin", "+"+r.ref+":"+pinnedRef); err != nil {
+		return "", err
+	}
+	out, err := r.git(ctx, "rev-parse", "--verify", pinnedRef+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Files returns the data files of commit matching the configured patterns, sorted by path.
+// Files larger than define.MAX_JSON_SIZE are rejected.
+func (r *Repo) Files(ctx context.Context, commit string) ([]File, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out, err := r.git(ctx, "ls-tree", "-r", "-z", "--name-only", commit)
+	if err != nil {
+		return nil, err
+	}
+	inTree := make(map[string]bool)
+	var matched []string
+	for _, p := range strings.Split(string(out), "\x00") {
+		if p == "" {
+			continue
+		}
+		inTree[p] = true
+		if r.match(p) {
+			matched = append(matched, p)
+		}
+	}
+	sort.Strings(matched)
+
+	files := make([]File, 0, len(matched))
+	for _, p := range matched {
+		data, err := r.blob(ctx, commit, p)
+		if err != nil {
+			return nil, err
+		}
+		f := File{Path: p, Data: data}
+		if inTree[p+SignatureSuffix] {
+			if f.Signature, err = r.blob(ctx, commit, p+SignatureSuffix); err != nil {
+				return nil, err
+			}
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// match reports whether p matches one of the configured patterns.
+func (r *Repo) match(p string) bool {
+	for _, pattern := range r.paths {
+		if ok, _ := path.Match(pattern, p); ok { //nolint:errcheck // patterns are checked by Validate
+			return true
+		}
+	}
+	return false
+}
+
+// blob reads the file p of commit, rejecting files larger than define.MAX_JSON_SIZE.
+func (r *Repo) blob(ctx context.Context, commit, p string) ([]byte, error) {
+	obj := commit + ":" + p
+	out, err := r.git(ctx, "cat-file", "-s", obj)
+	if err != nil {
+		return nil, err
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	if size > define.MAX_JSON_SIZE {
+		return nil, fmt.Errorf("%s exceeds %d bytes", p, define.MAX_JSON_SIZE)
+	}
+	return r.git(ctx, "cat-file", "blob", obj)
+}
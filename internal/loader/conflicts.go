@@ -0,0 +1,60 @@
+package loader
+
+import (
+	"context"
+
+	"github.com/soulteary/warden/internal/auditlog"
+	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/internal/merge"
+	"github.com/soulteary/warden/internal/prommetrics"
+)
+
+// IdentityConflicts returns the identity conflicts found by the last successful Load (nil when none).
+func (r *RulesLoader) IdentityConflicts() []define.IdentityConflict {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.followed != nil {
+		return r.followed.state.Conflicts
+	}
+	return r.conflicts
+}
+
+// conflictID identifies a conflict across loads.
+func conflictID(c *define.IdentityConflict) string {
+	return c.Field + ":" + c.Value
+}
+
+// recordConflicts updates the conflict metrics and writes an audit record for every
+// conflict that was not present in the previous load, so an unchanged dataset is not reported again.
+func recordConflicts(ctx context.Context, previous, current []define.IdentityConflict) {
+	seen := make(map[string]bool, len(previous))
+	for i := range previous {
+		seen[conflictID(&previous[i])] = true
+	}
+	counts := map[string]int{merge.ConflictFieldUserID: 0, merge.ConflictFieldMail: 0}
+	detected := make(map[string]map[string]int)
+	for i := range current {
+		c := &current[i]
+		counts[c.Field]++
+		if seen[conflictID(c)] {
+			continue
+		}
+		if detected[c.Field] == nil {
+			detected[c.Field] = make(map[string]int)
+		}
+		detected[c.Field][c.Action]++
+		var keys, dropped []string
+		for _, rec := range c.Records {
+			k, _ := allowListUserKey(define.AllowListUser{Phone: rec.Phone, Mail: rec.Mail})
+			keys = append(keys, k)
+			if rec.Dropped {
+				dropped = append(dropped, k)
+			}
+		}
+		auditlog.LogIdentityConflict(ctx, c.Field, c.Value, c.Action, keys, dropped)
+	}
+	prommetrics.RecordIdentityConflicts(counts, detected)
+}
@@ -0,0 +1,33 @@
+package loader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/internal/prommetrics"
+)
+
+func TestRecordConflicts_OnlyNewConflictsCounted(t *testing.T) {
+	prommetrics.Init()
+	ctx := context.Background()
+	first := []define.IdentityConflict{{Field: "user_id", Value: "u1", Action: "warn", Records: []define.ConflictRecord{{Phone: "1"}, {Phone: "2"}}}}
+	recordConflicts(ctx, nil, first)
+	recordConflicts(ctx, first, first)
+	second := append(first, define.IdentityConflict{Field: "mail", Value: "a@example.com", Action: "warn"})
+	recordConflicts(ctx, first, second)
+
+	rr := httptest.NewRecorder()
+	prommetrics.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+	body := rr.Body.String()
+	assert.Contains(t, body, `warden_identity_conflicts_detected_total{action="warn",field="user_id"} 1`)
+	assert.Contains(t, body, `warden_identity_conflicts_detected_total{action="warn",field="mail"} 1`)
+	assert.Contains(t, body, `warden_identity_conflicts{field="mail"} 1`)
+
+	var r *RulesLoader
+	assert.Nil(t, r.IdentityConflicts())
+}
@@ -3,16 +3,29 @@ package loader
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
+	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	parserkit "github.com/soulteary/parser-kit"
+	"github.com/soulteary/warden/internal/breaker"
 	"github.com/soulteary/warden/internal/cmd"
+	"github.com/soulteary/warden/internal/config"
 	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/internal/execsource"
+	"github.com/soulteary/warden/internal/gitsource"
+	"github.com/soulteary/warden/internal/merge"
+	"github.com/soulteary/warden/internal/prommetrics"
 	"github.com/soulteary/warden/internal/remote"
+	"github.com/soulteary/warden/internal/signature"
 )
 
 // normalizeAllowListUser normalizes each user in place (defaults, user_id) and returns the slice.
@@ -36,6 +49,7 @@ func allowListUserKey(u define.AllowListUser) (string, bool) {
 }
 
 // BuildLoadOptions builds parser-kit LoadOptions from warden config and app mode.
+// The remote URL is not fetched through parser-kit (see fetchRemote), so remote TLS settings are not set here.
 func BuildLoadOptions(cfg *cmd.Config, appMode string) *parserkit.LoadOptions {
 	mode := strings.ToUpper(strings.TrimSpace(appMode))
 	opts := parserkit.DefaultLoadOptions()
@@ -188,6 +202,201 @@ type RulesLoader struct {
 	appMode                string
 	remoteRSAPrivateKey    string // file path (preferred)
 	remoteRSAPrivateKeyPEM string // inline PEM when file not set
+	pagination             remote.Pagination
+	verifier               *signature.Verifier // nil = signature verification disabled
+	remoteKeys             []remote.KeySpec    // envelope decryption keys (with key ids)
+	mergePolicy            *merge.Policy       // nil = later sources replace whole records
+	conflictPolicy         string              // identity conflict policy (merge.ConflictWarn when empty)
+	decryptLegacy          bool                // accept the legacy unauthenticated encrypted format
+	exec                   *execsource.Options // nil = no exec source
+	git                    *gitsource.Repo     // nil = no Git source
+	breakerOpts            *breaker.Options    // nil = circuit breaker disabled
+	remoteTLS              *remote.TLSOptions  // CA bundle and client certificate for the remote URL (nil = defaults)
+	remoteToken            *remote.TokenSource // OAuth2 bearer token for the remote URL (nil = static auth header)
+
+	mu         sync.Mutex
+	snapshots  map[string]*remoteSnapshot // last successful remote response per URL (conditional fetch)
+	files      *fileSnapshot              // last successful file load (skips re-parsing unchanged files)
+	provenance map[string][]merge.Source  // sources per user key of the last successful Load
+	loadedAt   time.Time                  // time of the last successful Load
+	version    string                     // dataset version of the last successful Load (Git commit)
+	gitSnap    *gitSnapshot               // users of the last Git commit read (skips re-reading an unchanged commit)
+	conflicts  []define.IdentityConflict  // identity conflicts of the last successful Load
+	followed   *followed                  // load of the leader reported until the next successful Load (see Follow)
+
+	status      map[merge.Source]*define.SourceStatus // sync state per tracked source
+	sourceOrder []merge.Source                        // tracked sources in configuration order
+	breakers    map[merge.Source]*breaker.Breaker     // circuit breaker per tracked remote-class source
+}
+
+// loaded is the result of a Load before it is recorded.
+type loaded struct {
+	users     []define.AllowListUser
+	sources   map[string][]merge.Source // provenance by key
+	version   string                    // dataset version (Git commit), if any
+	conflicts []define.IdentityConflict // identity conflicts found while merging
+}
+
+// batch is the records loaded from one source.
+type batch struct {
+	src     merge.Source
+	users   []define.AllowListUser
+	version string // source version (Git commit), if any
+}
+
+// gitSnapshot is the users read from one Git commit.
+type gitSnapshot struct {
+	commit string
+	users  []define.AllowListUser
+}
+
+// fileSnapshot is the result of the last file load together with the stat fingerprint of its sources.
+type fileSnapshot struct {
+	fingerprint string
+	batches     []batch
+}
+
+// racyWindow: files modified this close to a load are always re-read, because a second write
+// within the filesystem's mtime granularity would otherwise leave the fingerprint unchanged.
+const racyWindow = 2 * time.Second
+
+// filesFingerprint returns a cheap change fingerprint (path, size, mtime) of the file sources
+// and the newest modification time among them.
+// os.Stat follows symlinks, so a ConfigMap ..data swap changes the fingerprint as well.
+// Detached signature files are included, so a re-signed file is verified again.
+func filesFingerprint(sources []parserkit.Source) (string, time.Time) {
+	var (
+		sb     strings.Builder
+		newest time.Time
+	)
+	for _, s := range sources {
+		for _, path := range []string{s.Config.FilePath, s.Config.FilePath + signature.FileSuffix} {
+			sb.WriteString(path)
+			if fi, err := os.Stat(path); err == nil {
+				fmt.Fprintf(&sb, ":%d:%d", fi.Size(), fi.ModTime().UnixNano())
+				if fi.ModTime().After(newest) {
+					newest = fi.ModTime()
+				}
+			} else {
+				sb.WriteString(":missing")
+			}
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String(), newest
+}
+
+// loadFiles loads file sources, one batch per contributing file. When no file changed since the last
+// successful load, the previous batches are returned without reading or parsing the files again.
+func (r *RulesLoader) loadFiles(sources []parserkit.Source) ([]batch, error) {
+	fp, newest := filesFingerprint(sources)
+	r.mu.Lock()
+	snap := r.files
+	r.mu.Unlock()
+	if snap != nil && snap.fingerprint == fp && time.Since(newest) > racyWindow {
+		r.touchSources(fileSourceNames(sources))
+		return snap.batches, nil
+	}
+	batches, err := r.loadFilesDirect(sources)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.files = &fileSnapshot{fingerprint: fp, batches: batches}
+	r.mu.Unlock()
+	return batches, nil
+}
+
+// remoteSnapshot is the last successful response of a remote source, reused when the remote answers 304.
+type remoteSnapshot struct {
+	validators remote.Validators
+	users      []define.AllowListUser
+}
+
+// paginationFromConfig converts the YAML/env pagination settings to remote.Pagination.
+func paginationFromConfig(c *config.RemotePaginationConfig) remote.Pagination {
+	return remote.Pagination{
+		Strategy:    c.Strategy,
+		PageParam:   c.PageParam,
+		SizeParam:   c.SizeParam,
+		OffsetParam: c.OffsetParam,
+		LimitParam:  c.LimitParam,
+		CursorParam: c.CursorParam,
+		CursorField: c.CursorField,
+		ItemsField:  c.ItemsField,
+		TotalField:  c.TotalField,
+		PageSize:    c.PageSize,
+		StartPage:   c.StartPage,
+		MaxPages:    c.MaxPages,
+	}
+}
+
+// execOptionsFromConfig converts the YAML/env exec source settings to execsource.Options (nil when disabled).
+func execOptionsFromConfig(c *config.ExecSourceConfig) *execsource.Options {
+	if strings.TrimSpace(c.Command) == "" {
+		return nil
+	}
+	return &execsource.Options{
+		Command: c.Command,
+		Args:    c.Args,
+		Format:  c.Format,
+		Timeout: c.Timeout,
+		Env:     c.Env,
+		PassEnv: c.PassEnv,
+	}
+}
+
+// gitRepoFromConfig creates the Git source from the YAML/env settings (nil when disabled).
+func gitRepoFromConfig(c *config.GitSourceConfig) (*gitsource.Repo, error) {
+	if strings.TrimSpace(c.URL) == "" {
+		return nil, nil
+	}
+	return gitsource.New(&gitsource.Options{
+		URL:     c.URL,
+		Ref:     c.Ref,
+		Paths:   c.Paths,
+		Dir:     c.Dir,
+		Timeout: c.Timeout,
+	})
+}
+
+// breakerOptionsFromConfig converts the YAML/env circuit breaker settings to breaker.Options (nil when disabled).
+func breakerOptionsFromConfig(c *config.CircuitBreakerConfig) *breaker.Options {
+	if c.Disabled {
+		return nil
+	}
+	return &breaker.Options{Threshold: c.Threshold, BaseDelay: c.BaseDelay, MaxDelay: c.MaxDelay}
+}
+
+// remoteTLSFromConfig converts the YAML/env remote TLS settings to remote.TLSOptions (nil when unset).
+func remoteTLSFromConfig(c *config.RemoteTLSConfig) *remote.TLSOptions {
+	t := &remote.TLSOptions{CAFile: c.CAFile, CertFile: c.CertFile, KeyFile: c.KeyFile, ServerName: c.ServerName}
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// remoteTokenFromConfig creates the OAuth2 token source of the remote URL (nil when unset).
+// Token requests use the HTTP client of the remote fetch (timeout, insecure_tls, CA bundle and client
+// certificate); the token is shared by all loads, so it is cached across them.
+func remoteTokenFromConfig(c *config.RemoteOAuth2Config, fetch *remote.FetchOptions) (*remote.TokenSource, error) {
+	o := remote.OAuth2Options{
+		TokenURL:         c.TokenURL,
+		ClientID:         c.ClientID,
+		ClientSecretFile: c.ClientSecretFile,
+		Scopes:           c.Scopes,
+		Audience:         c.Audience,
+		AuthStyle:        c.AuthStyle,
+	}
+	if o.IsZero() {
+		return nil, nil
+	}
+	client, err := fetch.HTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	return remote.NewTokenSource(o, client), nil
 }
 
 // NewRulesLoader creates a RulesLoader using cfg and appMode.
@@ -201,13 +410,58 @@ func NewRulesLoader(cfg *cmd.Config, appMode string) (*RulesLoader, error) {
 	decrypt := false
 	keyPath := ""
 	keyPEM := ""
+	var (
+		pagination     remote.Pagination
+		verifier       *signature.Verifier
+		keys           []remote.KeySpec
+		legacy         bool
+		policy         *merge.Policy
+		execOpts       *execsource.Options
+		gitRepo        *gitsource.Repo
+		breakerOpt     = &breaker.Options{}
+		remoteTLS      *remote.TLSOptions
+		token          *remote.TokenSource
+		conflictPolicy string
+	)
 	if cfg != nil {
 		if cfg.HTTPTimeout > 0 {
 			timeout = time.Duration(cfg.HTTPTimeout) * time.Second
 		}
-		decrypt = cfg.RemoteDecryptEnabled && (cfg.RemoteRSAPrivateKeyFile != "" || cfg.RemoteRSAPrivateKey != "")
+		decrypt = cfg.RemoteDecryptEnabled && (cfg.RemoteRSAPrivateKeyFile != "" || cfg.RemoteRSAPrivateKey != "" || len(cfg.RemoteKeys) > 0)
 		keyPath = cfg.RemoteRSAPrivateKeyFile
 		keyPEM = cfg.RemoteRSAPrivateKey
+		for _, k := range cfg.RemoteKeys {
+			keys = append(keys, remote.KeySpec{ID: k.ID, File: k.File})
+		}
+		legacy = cfg.DecryptLegacy
+		if policy, err = merge.NewPolicy(cfg.Merge.Fields); err != nil {
+			return nil, err
+		}
+		if err = merge.ValidateConflictPolicy(cfg.Merge.Conflicts); err != nil {
+			return nil, err
+		}
+		conflictPolicy = strings.ToLower(strings.TrimSpace(cfg.Merge.Conflicts))
+		pagination = paginationFromConfig(&cfg.RemotePagination)
+		execOpts = execOptionsFromConfig(&cfg.Exec)
+		if gitRepo, err = gitRepoFromConfig(&cfg.Git); err != nil {
+			return nil, err
+		}
+		breakerOpt = breakerOptionsFromConfig(&cfg.CircuitBreaker)
+		remoteTLS = remoteTLSFromConfig(&cfg.RemoteTLS)
+		fetch := &remote.FetchOptions{Timeout: timeout, InsecureTLS: cfg.HTTPInsecureTLS, TLS: remoteTLS}
+		if token, err = remoteTokenFromConfig(&cfg.RemoteOAuth2, fetch); err != nil {
+			return nil, err
+		}
+		if len(cfg.Signature.PublicKeys) > 0 {
+			// Command output carries no signature, so it would bypass verification
+			if execOpts != nil {
+				return nil, fmt.Errorf("exec source cannot be used with signature verification: %w", signature.ErrUnsigned)
+			}
+			verifier, err = signature.LoadVerifier(cfg.Signature.PublicKeys)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 	return &RulesLoader{
 		dl:                     dl,
@@ -217,74 +471,520 @@ func NewRulesLoader(cfg *cmd.Config, appMode string) (*RulesLoader, error) {
 		remoteRSAPrivateKeyPEM: keyPEM,
 		httpTimeout:            timeout,
 		httpInsecureTLS:        cfg != nil && cfg.HTTPInsecureTLS,
+		pagination:             pagination,
+		verifier:               verifier,
+		remoteKeys:             keys,
+		decryptLegacy:          legacy,
+		mergePolicy:            policy,
+		exec:                   execOpts,
+		git:                    gitRepo,
+		breakerOpts:            breakerOpt,
+		conflictPolicy:         conflictPolicy,
+		remoteTLS:              remoteTLS,
+		remoteToken:            token,
+		snapshots:              make(map[string]*remoteSnapshot),
+		status:                 make(map[merge.Source]*define.SourceStatus),
+		breakers:               make(map[merge.Source]*breaker.Breaker),
 	}, nil
 }
 
-// FromFile loads rules from a local file.
+// mergeStrategy reports whether r.appMode combines all sources (false: first successful source only).
+func (r *RulesLoader) mergeStrategy() bool {
+	return BuildLoadOptions(nil, r.appMode).LoadStrategy == parserkit.LoadStrategyMerge
+}
+
+// loadFilesDirect loads file sources in priority order and returns one batch per successfully read file.
+// Each file is read once, its detached signature verified (when configured) and the verified bytes parsed,
+// so a file swapped between verification and parsing cannot slip through.
+// Sources are selected like the parser-kit strategy for r.appMode: fallback returns the first source that
+// loads (a missing file counts as empty in ONLY_LOCAL); merge returns all sources that load and fails only
+// when none of them has data. An unsigned or tampered file fails the whole load so the previous data is kept.
+func (r *RulesLoader) loadFilesDirect(sources []parserkit.Source) ([]batch, error) {
+	sorted := make([]parserkit.Source, len(sources))
+	copy(sorted, sources)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Priority < sorted[j].Priority })
+	mergeAll := r.mergeStrategy()
+	allowMissing := BuildLoadOptions(nil, r.appMode).AllowEmptyFile
+
+	var (
+		lastErr error
+		hasData bool
+		batches []batch
+	)
+	for _, src := range sorted {
+		path := src.Config.FilePath
+		source := merge.Source{Name: path, Origin: merge.Local}
+		start := time.Now()
+		users, unverified, err := r.readUserFile(path)
+		r.recordSource(source, start, len(users), err)
+		switch {
+		case unverified:
+			return nil, err
+		case errors.Is(err, os.ErrNotExist) && allowMissing:
+			// Missing files count as empty in ONLY_LOCAL (still reported as failing in the source status)
+			users = []define.AllowListUser{}
+		case err != nil:
+			lastErr = err
+			continue
+		}
+		b := batch{src: source, users: users}
+		if !mergeAll {
+			return []batch{b}, nil
+		}
+		batches = append(batches, b)
+		for i := range users {
+			if _, ok := allowListUserKey(users[i]); ok {
+				hasData = true
+				break
+			}
+		}
+	}
+	if !hasData && lastErr != nil {
+		return nil, fmt.Errorf("all sources failed, last error: %w", lastErr)
+	}
+	return batches, nil
+}
+
+// readUserFile reads, verifies (when configured) and parses one user data file.
+// A missing file yields an error wrapping os.ErrNotExist; unverified reports a failed signature check.
+func (r *RulesLoader) readUserFile(path string) (users []define.AllowListUser, unverified bool, err error) {
+	data, err := readLimited(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, fmt.Errorf("file not found: %s: %w", path, os.ErrNotExist)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if r.verifier != nil {
+		if err := r.verifier.VerifyFile(path, data); err != nil {
+			return nil, true, err
+		}
+	}
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, false, fmt.Errorf("%s: failed to parse JSON: %w", path, err)
+	}
+	return users, false, nil
+}
+
+// readLimited reads path, rejecting files larger than define.MAX_JSON_SIZE.
+func readLimited(path string) ([]byte, error) {
+	f, err := os.Open(filepath.Clean(path)) // #nosec G304 -- path from config
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }() //nolint:errcheck // #nosec G104 -- read-only file
+	data, err := io.ReadAll(io.LimitReader(f, define.MAX_JSON_SIZE+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > define.MAX_JSON_SIZE {
+		return nil, fmt.Errorf("%s exceeds %d bytes", path, define.MAX_JSON_SIZE)
+	}
+	return data, nil
+}
+
+// FromFile loads rules from a local file (verifying its signature when configured).
 func (r *RulesLoader) FromFile(ctx context.Context, path string) ([]define.AllowListUser, error) {
-	return r.dl.FromFile(ctx, path)
+	if r.verifier == nil {
+		return r.dl.FromFile(ctx, path)
+	}
+	batches, err := r.loadFilesDirect([]parserkit.Source{{Type: parserkit.SourceTypeFile, Config: parserkit.SourceConfig{FilePath: path}}})
+	if err != nil || len(batches) == 0 {
+		return []define.AllowListUser{}, err
+	}
+	return normalizeAllowListUser(append([]define.AllowListUser(nil), batches[0].users...)), nil
+}
+
+// remoteFetchOptions returns the remote.FetchOptions for the configured remote source.
+// A circuit breaker probe is a single request: a source that is still down fails fast.
+func (r *RulesLoader) remoteFetchOptions(auth string, probe bool) *remote.FetchOptions {
+	retries := define.HTTP_RETRY_MAX_RETRIES
+	if probe {
+		retries = 0
+	}
+	return &remote.FetchOptions{
+		AuthHeader:     auth,
+		RSAKeyPath:     r.remoteRSAPrivateKey,
+		RSAKeyPEM:      r.remoteRSAPrivateKeyPEM,
+		Timeout:        r.httpTimeout,
+		DecryptEnabled: r.remoteDecrypt,
+		InsecureTLS:    r.httpInsecureTLS,
+		Retries:        retries,
+		RetryDelay:     define.HTTP_RETRY_DELAY,
+		Verifier:       r.verifier,
+		Keys:           r.remoteKeys,
+		LegacyDecrypt:  r.decryptLegacy,
+		TLS:            r.remoteTLS,
+		Token:          r.remoteToken,
+	}
+}
+
+// fetchRemote fetches the remote source with If-None-Match / If-Modified-Since from the previous response.
+// On 304 the users of the previous response are returned without downloading or parsing the payload again.
+// probe disables retries (circuit breaker probe).
+func (r *RulesLoader) fetchRemote(ctx context.Context, configURL, auth string, probe bool) ([]define.AllowListUser, error) {
+	r.mu.Lock()
+	snap := r.snapshots[configURL]
+	r.mu.Unlock()
+	var prev remote.Validators
+	if snap != nil {
+		prev = snap.validators
+	}
+
+	source := merge.Source{Name: remoteSourceName(configURL), Origin: merge.Remote}
+	start := time.Now()
+	users, validators, err := remote.FetchUsersConditional(ctx, configURL, r.pagination, r.remoteFetchOptions(auth, probe), prev)
+	if errors.Is(err, remote.ErrNotModified) && snap != nil {
+		prommetrics.RecordRemoteFetch(true)
+		r.recordSource(source, start, len(snap.users), nil)
+		return append([]define.AllowListUser(nil), snap.users...), nil
+	}
+	prommetrics.RecordRemoteFetch(false)
+	if err != nil {
+		// The status is served to admins: do not leak credentials from the configured URL
+		r.recordSource(source, start, 0, errors.New(strings.ReplaceAll(err.Error(), configURL, source.Name)))
+		return nil, err
+	}
+	r.recordSource(source, start, len(users), nil)
+
+	r.mu.Lock()
+	if validators.IsZero() {
+		delete(r.snapshots, configURL)
+	} else {
+		r.snapshots[configURL] = &remoteSnapshot{
+			validators: validators,
+			users:      append([]define.AllowListUser(nil), users...),
+		}
+	}
+	r.mu.Unlock()
+	return users, nil
 }
 
 // Load loads rules from sources built from (rulesFile, dataDir, configURL, auth) and r.appMode.
-// When remote decrypt is enabled, fetches remote with RSA decryption then merges with file sources.
+// The remote source is fetched via the remote package (conditional requests, decryption, pagination),
+// the Git and exec sources (when configured) are read, and all are merged with the file sources by mode.
+// As with the parser-kit merge strategy, a failed remote, Git or exec source is skipped when other sources
+// are available, except in ONLY_REMOTE mode.
+// On success the contributing sources of every user and the dataset version are recorded
+// (see Provenance and DatasetVersion).
 func (r *RulesLoader) Load(ctx context.Context, rulesFile, dataDir, configURL, auth string) ([]define.AllowListUser, error) {
 	mode := strings.ToUpper(strings.TrimSpace(r.appMode))
-	if r.remoteDecrypt && configURL != "" && (r.remoteRSAPrivateKey != "" || r.remoteRSAPrivateKeyPEM != "") {
-		remoteUsers, err := remote.FetchDecryptedUsers(ctx, configURL, auth, true, r.remoteRSAPrivateKey, r.remoteRSAPrivateKeyPEM, r.httpTimeout, r.httpInsecureTLS)
-		if err != nil {
-			return nil, fmt.Errorf("remote decrypt fetch: %w", err)
-		}
-		remoteUsers = normalizeAllowListUser(remoteUsers)
-		fileSources := BuildSources(rulesFile, dataDir, "", "", r.appMode)
-		if len(fileSources) == 0 {
-			return remoteUsers, nil
+	fileSources := BuildSources(rulesFile, dataDir, "", "", r.appMode)
+	r.trackSources(r.configuredSources(fileSources, configURL))
+	var (
+		data loaded
+		err  error
+	)
+	switch {
+	case (configURL != "" || r.git != nil || r.exec != nil) && mode != "ONLY_LOCAL":
+		data, err = r.loadWithRemote(ctx, fileSources, configURL, auth, mode)
+	case len(fileSources) == 0:
+		err = fmt.Errorf("no sources for mode %s", r.appMode)
+	default:
+		data, err = r.loadFileUsers(fileSources)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Sources are merged as read; defaults (status, generated user_id) are only filled in afterwards, so a
+	// source that lacks a field cannot override the value another source has.
+	data.users = normalizeAllowListUser(data.users)
+	r.mu.Lock()
+	r.provenance = data.sources
+	r.loadedAt = time.Now()
+	r.version = data.version
+	previous := r.conflicts
+	r.conflicts = data.conflicts
+	r.followed = nil
+	r.mu.Unlock()
+	recordConflicts(ctx, previous, data.conflicts)
+	return data.users, nil
+}
+
+// execSource identifies the exec source in provenance and status.
+func (r *RulesLoader) execSource() merge.Source {
+	return merge.Source{Name: r.exec.Command, Origin: merge.Exec}
+}
+
+// runExec runs the exec source command and records the attempt.
+func (r *RulesLoader) runExec(ctx context.Context) ([]define.AllowListUser, error) {
+	start := time.Now()
+	users, err := execsource.Run(ctx, r.exec)
+	r.recordSource(r.execSource(), start, len(users), err)
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// gitSource identifies the Git source in provenance and status.
+func (r *RulesLoader) gitSource() merge.Source {
+	return merge.Source{Name: r.git.Name(), Origin: merge.Git}
+}
+
+// fetchGit fetches the Git source and returns the users of its commit together with the commit SHA.
+// The files of an unchanged commit are not read again. With signature verification enabled every data file
+// needs a committed "<path>.sig". Files are combined in path order (later paths take precedence).
+func (r *RulesLoader) fetchGit(ctx context.Context) ([]define.AllowListUser, string, error) {
+	start := time.Now()
+	users, commit, err := r.readGit(ctx)
+	r.recordSource(r.gitSource(), start, len(users), err)
+	if err != nil {
+		return nil, "", fmt.Errorf("git source: %w", err)
+	}
+	r.recordVersion(r.gitSource(), commit)
+	return users, commit, nil
+}
+
+func (r *RulesLoader) readGit(ctx context.Context) ([]define.AllowListUser, string, error) {
+	commit, err := r.git.Sync(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	r.mu.Lock()
+	snap := r.gitSnap
+	r.mu.Unlock()
+	if snap != nil && snap.commit == commit {
+		return snap.users, commit, nil
+	}
+	files, err := r.git.Files(ctx, commit)
+	if err != nil {
+		return nil, "", err
+	}
+	batches := make([]batch, 0, len(files))
+	for _, f := range files {
+		if r.verifier != nil {
+			if err := r.verifier.Verify(f.Data, string(f.Signature)); err != nil {
+				return nil, "", fmt.Errorf("%s: %w", f.Path, err)
+			}
 		}
-		fileUsers, err := r.dl.Load(ctx, fileSources...)
+		users, err := execsource.Parse(f.Data, "")
 		if err != nil {
-			return remoteUsers, nil
+			return nil, "", fmt.Errorf("%s: %w", f.Path, err)
 		}
-		return mergeByMode(remoteUsers, fileUsers, mode), nil
+		batches = append(batches, batch{src: r.gitSource(), users: users})
 	}
-	sources := BuildSources(rulesFile, dataDir, configURL, auth, r.appMode)
-	if len(sources) == 0 {
-		return nil, fmt.Errorf("no sources for mode %s", r.appMode)
+	users := []define.AllowListUser{}
+	if len(batches) > 0 {
+		users, _ = combine(batches, r.mergePolicy)
 	}
-	return r.dl.Load(ctx, sources...)
+	r.mu.Lock()
+	r.gitSnap = &gitSnapshot{commit: commit, users: users}
+	r.mu.Unlock()
+	return users, commit, nil
 }
 
-// mergeByMode merges remoteUsers and fileUsers by mode (REMOTE_FIRST = remote wins, LOCAL_FIRST = file wins).
-func mergeByMode(remoteUsers, fileUsers []define.AllowListUser, mode string) []define.AllowListUser {
-	keyToUser := make(map[string]define.AllowListUser)
-	if mode == "LOCAL_FIRST" || mode == "LOCAL_FIRST_ALLOW_REMOTE_FAILED" {
-		for i := range remoteUsers {
-			k, ok := allowListUserKey(remoteUsers[i])
-			if ok {
-				keyToUser[k] = remoteUsers[i]
-			}
+// fetchRemotes loads the remote URL, the Git source and then the exec source (lowest precedence first),
+// each through its circuit breaker. In ONLY_REMOTE mode the first one that succeeds is used (fallback);
+// otherwise every successful one is returned. An error is returned only when none succeeds.
+func (r *RulesLoader) fetchRemotes(ctx context.Context, configURL, auth, mode string) ([]batch, error) {
+	var (
+		batches []batch
+		errs    []error
+	)
+	if configURL != "" {
+		src := merge.Source{Name: remoteSourceName(configURL), Origin: merge.Remote}
+		var users []define.AllowListUser
+		err := r.guard(src, func(probe bool) (err error) {
+			users, err = r.fetchRemote(ctx, configURL, auth, probe)
+			return err
+		})
+		if err == nil {
+			batches = append(batches, batch{src: src, users: users})
+		} else {
+			errs = append(errs, fmt.Errorf("remote fetch: %w", err))
 		}
-		for i := range fileUsers {
-			k, ok := allowListUserKey(fileUsers[i])
-			if ok {
-				keyToUser[k] = fileUsers[i]
-			}
+	}
+	if r.git != nil && (mode != "ONLY_REMOTE" || len(batches) == 0) {
+		var (
+			users  []define.AllowListUser
+			commit string
+		)
+		err := r.guard(r.gitSource(), func(bool) (err error) {
+			users, commit, err = r.fetchGit(ctx)
+			return err
+		})
+		if err == nil {
+			batches = append(batches, batch{src: r.gitSource(), users: users, version: commit})
+		} else {
+			errs = append(errs, err)
 		}
-	} else {
-		for i := range fileUsers {
-			k, ok := allowListUserKey(fileUsers[i])
-			if ok {
-				keyToUser[k] = fileUsers[i]
-			}
+	}
+	if r.exec != nil && (mode != "ONLY_REMOTE" || len(batches) == 0) {
+		var users []define.AllowListUser
+		err := r.guard(r.execSource(), func(bool) (err error) {
+			users, err = r.runExec(ctx)
+			return err
+		})
+		if err == nil {
+			batches = append(batches, batch{src: r.execSource(), users: users})
+		} else {
+			errs = append(errs, err)
 		}
-		for i := range remoteUsers {
-			k, ok := allowListUserKey(remoteUsers[i])
-			if ok {
-				keyToUser[k] = remoteUsers[i]
-			}
+	}
+	if len(batches) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	return batches, nil
+}
+
+// loadWithRemote loads the remote, Git and exec sources and merges them with fileSources by mode.
+// The returned version is the one of the first remote-class source that has one (the Git commit).
+func (r *RulesLoader) loadWithRemote(ctx context.Context, fileSources []parserkit.Source, configURL, auth, mode string) (loaded, error) {
+	remoteBatches, err := r.fetchRemotes(ctx, configURL, auth, mode)
+	if err != nil {
+		if mode == "ONLY_REMOTE" || len(fileSources) == 0 {
+			return loaded{}, err
+		}
+		return r.loadFileUsers(fileSources)
+	}
+	version := ""
+	for _, b := range remoteBatches {
+		if b.version != "" {
+			version = b.version
+			break
+		}
+	}
+	var fileBatches []batch
+	if len(fileSources) > 0 {
+		// Files that fail to load are skipped: the remote data alone is still served
+		if fileBatches, err = r.loadFiles(fileSources); err != nil {
+			fileBatches = nil
 		}
 	}
-	out := make([]define.AllowListUser, 0, len(keyToUser))
-	for k := range keyToUser {
-		out = append(out, keyToUser[k])
+	if len(remoteBatches) == 1 && len(fileBatches) == 0 {
+		users := append([]define.AllowListUser(nil), remoteBatches[0].users...)
+		return loaded{users: users, sources: attribute(remoteBatches[0]), version: version}, nil
+	}
+	data := r.combineResolved(orderByMode(remoteBatches, fileBatches, mode))
+	data.version = version
+	return data, nil
+}
+
+// loadFileUsers loads file sources only. With the fallback strategy the records of the selected file are
+// returned as is; with the merge strategy all files are combined by key.
+func (r *RulesLoader) loadFileUsers(sources []parserkit.Source) (loaded, error) {
+	batches, err := r.loadFiles(sources)
+	if err != nil {
+		return loaded{}, err
+	}
+	if r.mergeStrategy() {
+		return r.combineResolved(batches), nil
+	}
+	if len(batches) == 0 {
+		return loaded{users: []define.AllowListUser{}, sources: map[string][]merge.Source{}}, nil
+	}
+	return loaded{users: append([]define.AllowListUser(nil), batches[0].users...), sources: attribute(batches[0])}, nil
+}
+
+// orderByMode returns the batches lowest precedence first, in the order BuildSources gives parser-kit: the
+// remote-class sources are read first in DEFAULT / REMOTE_FIRST and the files first in LOCAL_FIRST, and a
+// source read later overrides an earlier one. So files win over remote in REMOTE_FIRST and remote wins over
+// files in LOCAL_FIRST. Among files, later (higher-priority number) files take precedence; among
+// remoteBatches (remote URL, Git source, exec source) the later one takes precedence.
+func orderByMode(remoteBatches, fileBatches []batch, mode string) []batch {
+	out := make([]batch, 0, len(fileBatches)+len(remoteBatches))
+	if mode == "LOCAL_FIRST" || mode == "LOCAL_FIRST_ALLOW_REMOTE_FAILED" {
+		out = append(out, fileBatches...)
+		return append(out, remoteBatches...)
+	}
+	out = append(out, remoteBatches...)
+	return append(out, fileBatches...)
+}
+
+// combine merges batches (lowest precedence first) by key. With a field policy, records of the same user
+// are merged field by field instead of replaced as a whole. The result keeps first-seen order so list
+// responses are stable across reloads.
+func combine(batches []batch, policy *merge.Policy) ([]define.AllowListUser, map[string][]merge.Source) {
+	merger := merge.NewMerger(policy, allowListUserKey)
+	for _, b := range batches {
+		merger.Add(b.users, b.src)
+	}
+	return merger.Users(), merger.Sources()
+}
+
+// combineResolved merges batches like combine and applies the identity conflict policy.
+// The conflicts are only checked when several sources are merged; a single source is served as is.
+func (r *RulesLoader) combineResolved(batches []batch) loaded {
+	merger := merge.NewMerger(r.mergePolicy, allowListUserKey)
+	for _, b := range batches {
+		merger.Add(b.users, b.src)
+	}
+	users, conflicts := merger.Resolve(r.conflictPolicy)
+	return loaded{users: users, sources: merger.Sources(), conflicts: conflicts}
+}
+
+// attribute returns the sources by key for records that are used without merging.
+func attribute(b batch) map[string][]merge.Source {
+	out := make(map[string][]merge.Source, len(b.users))
+	for i := range b.users {
+		if k, ok := provenanceKey(&b.users[i]); ok {
+			out[k] = []merge.Source{b.src}
+		}
 	}
 	return out
 }
+
+// provenanceKey is the merge key of u, or its user_id for records without phone and mail.
+func provenanceKey(u *define.AllowListUser) (string, bool) {
+	if k, ok := allowListUserKey(*u); ok {
+		return k, true
+	}
+	if u.UserID == "" {
+		return "", false
+	}
+	return "user_id:" + u.UserID, true
+}
+
+// remoteSourceName returns configURL without credentials, query or fragment, so it can be shown and logged.
+func remoteSourceName(configURL string) string {
+	u, err := url.Parse(configURL)
+	if err != nil {
+		return "remote"
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// Provenance returns the sources that contributed u in the last successful Load and the load time.
+// ok is false when u was not part of that load.
+func (r *RulesLoader) Provenance(u *define.AllowListUser) (define.Provenance, bool) {
+	if r == nil || u == nil {
+		return define.Provenance{}, false
+	}
+	k, ok := provenanceKey(u)
+	if !ok {
+		return define.Provenance{}, false
+	}
+	r.mu.Lock()
+	if f := r.followed; f != nil {
+		r.mu.Unlock()
+		return f.provenance(u)
+	}
+	defer r.mu.Unlock()
+	srcs, ok := r.provenance[k]
+	if !ok {
+		return define.Provenance{}, false
+	}
+	p := define.Provenance{LoadedAt: r.loadedAt, Version: r.version, Sources: make([]define.ProvenanceSource, len(srcs))}
+	for i, s := range srcs {
+		p.Sources[i] = define.ProvenanceSource{Name: s.Name, Type: s.Origin.String()}
+	}
+	return p, true
+}
+
+// DatasetVersion returns the version of the dataset served by the last successful Load: the commit SHA
+// of the Git source, or "" when no source is versioned.
+func (r *RulesLoader) DatasetVersion() string {
+	if r == nil {
+		return ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.followed != nil {
+		return r.followed.state.Version
+	}
+	return r.version
+}
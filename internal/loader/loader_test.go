@@ -2,8 +2,18 @@ package loader
 
 import (
 	"context"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -12,7 +22,12 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/soulteary/warden/internal/cmd"
+	"github.com/soulteary/warden/internal/config"
 	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/internal/merge"
+	"github.com/soulteary/warden/internal/remote"
+	"github.com/soulteary/warden/internal/signature"
+	"github.com/soulteary/warden/internal/testutil"
 )
 
 func TestBuildLoadOptions(t *testing.T) {
@@ -134,6 +149,17 @@ func TestNewRulesLoader(t *testing.T) {
 	})
 }
 
+func TestNewRulesLoader_RemoteTLS(t *testing.T) {
+	r, err := NewRulesLoader(&cmd.Config{HTTPTimeout: 5}, "DEFAULT")
+	require.NoError(t, err)
+	assert.Nil(t, r.remoteFetchOptions("", false).TLS)
+
+	cfg := &cmd.Config{HTTPTimeout: 5, RemoteTLS: config.RemoteTLSConfig{CAFile: "/etc/warden/ca.pem", CertFile: "/etc/warden/client.crt", KeyFile: "/etc/warden/client.key"}}
+	r, err = NewRulesLoader(cfg, "DEFAULT")
+	require.NoError(t, err)
+	assert.Equal(t, &remote.TLSOptions{CAFile: "/etc/warden/ca.pem", CertFile: "/etc/warden/client.crt", KeyFile: "/etc/warden/client.key"}, r.remoteFetchOptions("", false).TLS)
+}
+
 func TestRulesLoader_FromFile(t *testing.T) {
 	tmpDir := t.TempDir()
 	path := filepath.Join(tmpDir, "users.json")
@@ -211,50 +237,716 @@ func TestAllowListUserKey(t *testing.T) {
 	})
 }
 
-func TestMergeByMode(t *testing.T) {
-	remote := []define.AllowListUser{
+func TestCombine_OrderByMode(t *testing.T) {
+	remoteUsers := []define.AllowListUser{
 		{Phone: "13800138000", Mail: "r@example.com", UserID: "remote1"},
 		{Phone: "13900139000", Mail: "r2@example.com", UserID: "remote2"},
 	}
-	local := []define.AllowListUser{
+	localUsers := []define.AllowListUser{
 		{Phone: "13800138000", Mail: "l@example.com", UserID: "local1"},
 		{Phone: "13700137000", Mail: "l2@example.com", UserID: "local2"},
 	}
+	remoteBatch := batch{src: merge.Source{Name: "https://example.com/users", Origin: merge.Remote}, users: remoteUsers}
+	localBatch := batch{src: merge.Source{Name: "/data/users.json"}, users: localUsers}
+	mergeByMode := func(mode string) []define.AllowListUser {
+		out, _ := combine(orderByMode([]batch{remoteBatch}, []batch{localBatch}, mode), nil)
+		return out
+	}
 
 	t.Run("REMOTE_FIRST", func(t *testing.T) {
-		out := mergeByMode(remote, local, "REMOTE_FIRST")
+		out := mergeByMode("REMOTE_FIRST")
 		require.Len(t, out, 3)
 		byPhone := make(map[string]define.AllowListUser)
 		for _, u := range out {
 			k, _ := allowListUserKey(u)
 			byPhone[k] = u
 		}
-		assert.Equal(t, "r@example.com", byPhone["13800138000"].Mail, "remote wins on same key")
+		assert.Equal(t, "l@example.com", byPhone["13800138000"].Mail, "files are read after remote and win on same key")
 		assert.Equal(t, "r2@example.com", byPhone["13900139000"].Mail)
 		assert.Equal(t, "l2@example.com", byPhone["13700137000"].Mail)
 	})
 
 	t.Run("LOCAL_FIRST", func(t *testing.T) {
-		out := mergeByMode(remote, local, "LOCAL_FIRST")
+		out := mergeByMode("LOCAL_FIRST")
 		require.Len(t, out, 3)
 		byPhone := make(map[string]define.AllowListUser)
 		for _, u := range out {
 			k, _ := allowListUserKey(u)
 			byPhone[k] = u
 		}
-		assert.Equal(t, "l@example.com", byPhone["13800138000"].Mail, "local wins on same key")
+		assert.Equal(t, "r@example.com", byPhone["13800138000"].Mail, "remote is read after files and wins on same key")
 		assert.Equal(t, "r2@example.com", byPhone["13900139000"].Mail)
 		assert.Equal(t, "l2@example.com", byPhone["13700137000"].Mail)
 	})
 
 	t.Run("LOCAL_FIRST_ALLOW_REMOTE_FAILED", func(t *testing.T) {
-		out := mergeByMode(remote, local, "LOCAL_FIRST_ALLOW_REMOTE_FAILED")
+		out := mergeByMode("LOCAL_FIRST_ALLOW_REMOTE_FAILED")
 		require.Len(t, out, 3)
 		byPhone := make(map[string]define.AllowListUser)
 		for _, u := range out {
 			k, _ := allowListUserKey(u)
 			byPhone[k] = u
 		}
-		assert.Equal(t, "l@example.com", byPhone["13800138000"].Mail)
+		assert.Equal(t, "r@example.com", byPhone["13800138000"].Mail)
 	})
 }
+
+// TestRulesLoader_Load_ModePrecedence pins which record wins when a file and the remote source hold the same
+// user: the source read later, as with the parser-kit merge strategy (files in DEFAULT, remote in LOCAL_FIRST).
+func TestRulesLoader_Load_ModePrecedence(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`[{"phone":"13800138000","name":"Remote"}]`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "users.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"phone":"13800138000","name":"Local"}]`), 0o600))
+
+	for mode, want := range map[string]string{
+		"DEFAULT":                          "Local",
+		"REMOTE_FIRST":                     "Local",
+		"REMOTE_FIRST_ALLOW_REMOTE_FAILED": "Local",
+		"LOCAL_FIRST":                      "Remote",
+		"LOCAL_FIRST_ALLOW_REMOTE_FAILED":  "Remote",
+	} {
+		t.Run(mode, func(t *testing.T) {
+			r, err := NewRulesLoader(&cmd.Config{HTTPTimeout: 5}, mode)
+			require.NoError(t, err)
+			users, err := r.Load(context.Background(), path, "", srv.URL, "")
+			require.NoError(t, err)
+			require.Len(t, users, 1)
+			assert.Equal(t, want, users[0].Name)
+
+			// parser-kit, which loaded remote and file sources before, resolves the same way
+			legacy, err := r.dl.Load(context.Background(), BuildSources(path, "", srv.URL, "", mode)...)
+			require.NoError(t, err)
+			require.Len(t, legacy, 1)
+			assert.Equal(t, want, legacy[0].Name)
+		})
+	}
+}
+
+func TestRulesLoader_Load_PaginatedRemote(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		var body string
+		switch r.URL.Query().Get("page") {
+		case "1":
+			body = `{"data":[{"phone":"13800138000"},{"phone":"13800138001"}],"total":3}`
+		case "2":
+			body = `{"data":[{"phone":"13800138002"}],"total":3}`
+		default:
+			t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+		}
+		_, err := w.Write([]byte(body))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "users.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"phone":"13900139000"}]`), 0o600))
+
+	cfg := &cmd.Config{
+		HTTPTimeout:      5,
+		RemotePagination: config.RemotePaginationConfig{Strategy: "page", PageSize: 2},
+	}
+	r, err := NewRulesLoader(cfg, "REMOTE_FIRST")
+	require.NoError(t, err)
+
+	users, err := r.Load(context.Background(), path, "", srv.URL, "")
+	require.NoError(t, err)
+	assert.Len(t, users, 4, "three paginated remote users merged with one local user")
+}
+
+func TestRulesLoader_Load_ConditionalRemote(t *testing.T) {
+	var calls, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`[{"phone":"13800138000"},{"phone":"13800138001"}]`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+
+	r, err := NewRulesLoader(&cmd.Config{HTTPTimeout: 5}, "ONLY_REMOTE")
+	require.NoError(t, err)
+
+	first, err := r.Load(context.Background(), "", "", srv.URL, "")
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+
+	second, err := r.Load(context.Background(), "", "", srv.URL, "")
+	require.NoError(t, err)
+	assert.Equal(t, first, second, "304 reuses the previous response")
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 1, notModified)
+
+	// Mutating the returned slice must not leak into the cached snapshot.
+	second[0].Phone = "changed"
+	third, err := r.Load(context.Background(), "", "", srv.URL, "")
+	require.NoError(t, err)
+	assert.Equal(t, "13800138000", third[0].Phone)
+}
+
+func TestRulesLoader_Load_RemoteFailureFallsBackToFiles(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "users.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"phone":"13900139000"}]`), 0o600))
+
+	r, err := NewRulesLoader(&cmd.Config{HTTPTimeout: 5}, "REMOTE_FIRST")
+	require.NoError(t, err)
+	users, err := r.Load(context.Background(), path, "", srv.URL, "")
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+
+	r, err = NewRulesLoader(&cmd.Config{HTTPTimeout: 5}, "ONLY_REMOTE")
+	require.NoError(t, err)
+	_, err = r.Load(context.Background(), path, "", srv.URL, "")
+	require.Error(t, err)
+}
+
+// execConfig returns a config whose exec source runs a shell script printing out and exiting with code.
+func execConfig(t *testing.T, out string, code int) *cmd.Config {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "roster.sh")
+	body := fmt.Sprintf("#!/bin/sh\nprintf '%%s' '%s'\nexit %d\n", out, code)
+	require.NoError(t, os.WriteFile(script, []byte(body), 0o700)) // #nosec G306 -- test script must be executable
+	c := &cmd.Config{HTTPTimeout: 5}
+	c.Exec.Command = script
+	return c
+}
+
+func TestRulesLoader_Load_ExecSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"phone":"13800138000","name":"File"},{"phone":"13700137000"}]`), 0o600))
+	ndjson := `{"phone":"13800138000","name":"Exec"}` + "\n" + `{"phone":"13900139000"}`
+
+	t.Run("merged with files by mode", func(t *testing.T) {
+		cfg := execConfig(t, ndjson, 0)
+		r, err := NewRulesLoader(cfg, "REMOTE_FIRST")
+		require.NoError(t, err)
+		users, err := r.Load(context.Background(), path, "", "", "")
+		require.NoError(t, err)
+		require.Len(t, users, 3)
+		assert.Equal(t, "File", users[0].Name, "files win in REMOTE_FIRST")
+		p, ok := r.Provenance(&users[0])
+		require.True(t, ok)
+		assert.Equal(t, []define.ProvenanceSource{{Name: cfg.Exec.Command, Type: "exec"}, {Name: path, Type: "file"}}, p.Sources)
+
+		r, err = NewRulesLoader(cfg, "LOCAL_FIRST")
+		require.NoError(t, err)
+		users, err = r.Load(context.Background(), path, "", "", "")
+		require.NoError(t, err)
+		assert.Equal(t, "Exec", users[0].Name, "exec wins in LOCAL_FIRST")
+	})
+
+	t.Run("non-zero exit is a source failure", func(t *testing.T) {
+		cfg := execConfig(t, ndjson, 2)
+		r, err := NewRulesLoader(cfg, "REMOTE_FIRST_ALLOW_REMOTE_FAILED")
+		require.NoError(t, err)
+		users, err := r.Load(context.Background(), path, "", "", "")
+		require.NoError(t, err, "files are used when the command fails")
+		assert.Len(t, users, 2)
+		st := statusByName(r.SourceStatuses())[cfg.Exec.Command]
+		assert.Equal(t, "exec", st.Type)
+		assert.Contains(t, st.LastError, "exit status 2")
+
+		r, err = NewRulesLoader(cfg, "ONLY_REMOTE")
+		require.NoError(t, err)
+		_, err = r.Load(context.Background(), path, "", "", "")
+		require.Error(t, err)
+	})
+
+	t.Run("ONLY_REMOTE falls back from the remote URL", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer srv.Close()
+		r, err := NewRulesLoader(execConfig(t, ndjson, 0), "ONLY_REMOTE")
+		require.NoError(t, err)
+		users, err := r.Load(context.Background(), "", "", srv.URL, "")
+		require.NoError(t, err)
+		assert.Len(t, users, 2)
+	})
+
+	t.Run("ignored in ONLY_LOCAL", func(t *testing.T) {
+		r, err := NewRulesLoader(execConfig(t, ndjson, 0), "ONLY_LOCAL")
+		require.NoError(t, err)
+		users, err := r.Load(context.Background(), path, "", "", "")
+		require.NoError(t, err)
+		assert.Len(t, users, 2)
+		assert.Len(t, r.SourceStatuses(), 1)
+	})
+}
+
+func TestRulesLoader_Load_GitSource(t *testing.T) {
+	g := testutil.NewGitRepo(t)
+	first := g.Commit(map[string]string{
+		"a.json": `[{"phone":"13800138000","name":"Git"}]`,
+		"b.json": `[{"phone":"13900139000"}]`,
+	})
+
+	path := filepath.Join(t.TempDir(), "users.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"phone":"13800138000","name":"File"},{"phone":"13700137000"}]`), 0o600))
+
+	cfg := &cmd.Config{HTTPTimeout: 5}
+	cfg.Git = config.GitSourceConfig{URL: g.Bare, Ref: "main", Dir: filepath.Join(t.TempDir(), "cache")}
+	r, err := NewRulesLoader(cfg, "LOCAL_FIRST")
+	require.NoError(t, err)
+	users, err := r.Load(context.Background(), path, "", "", "")
+	require.NoError(t, err)
+	require.Len(t, users, 3)
+	assert.Equal(t, "Git", users[0].Name, "Git wins in LOCAL_FIRST")
+	assert.Equal(t, first, r.DatasetVersion())
+	p, ok := r.Provenance(&users[0])
+	require.True(t, ok)
+	assert.Equal(t, first, p.Version)
+	assert.Equal(t, []define.ProvenanceSource{{Name: path, Type: "file"}, {Name: g.Bare, Type: "git"}}, p.Sources)
+	st := statusByName(r.SourceStatuses())[g.Bare]
+	assert.Equal(t, "git", st.Type)
+	assert.Equal(t, first, st.Version)
+	assert.Equal(t, 2, st.Records)
+
+	// A new commit on the branch is picked up on the next load.
+	second := g.Commit(map[string]string{"b.json": `[{"phone":"13900139000"},{"phone":"13600136000"}]`})
+	users, err = r.Load(context.Background(), path, "", "", "")
+	require.NoError(t, err)
+	assert.Len(t, users, 4)
+	assert.Equal(t, second, r.DatasetVersion())
+
+	t.Run("pinned commit", func(t *testing.T) {
+		pinned := *cfg
+		pinned.Git.Ref = first
+		pinned.Git.Dir = filepath.Join(t.TempDir(), "cache")
+		r, err := NewRulesLoader(&pinned, "ONLY_REMOTE")
+		require.NoError(t, err)
+		users, err := r.Load(context.Background(), "", "", "", "")
+		require.NoError(t, err)
+		assert.Len(t, users, 2)
+		assert.Equal(t, first, r.DatasetVersion())
+	})
+
+	t.Run("unknown ref is a source failure", func(t *testing.T) {
+		broken := *cfg
+		broken.Git.Ref = "missing"
+		broken.Git.Dir = filepath.Join(t.TempDir(), "cache")
+		r, err := NewRulesLoader(&broken, "REMOTE_FIRST_ALLOW_REMOTE_FAILED")
+		require.NoError(t, err)
+		users, err := r.Load(context.Background(), path, "", "", "")
+		require.NoError(t, err, "files are used when the Git source fails")
+		assert.Len(t, users, 2)
+		assert.Empty(t, r.DatasetVersion())
+		assert.NotEmpty(t, statusByName(r.SourceStatuses())[g.Bare].LastError)
+
+		r, err = NewRulesLoader(&broken, "ONLY_REMOTE")
+		require.NoError(t, err)
+		_, err = r.Load(context.Background(), path, "", "", "")
+		require.Error(t, err)
+	})
+}
+
+func TestRulesLoader_Load_SignedGitSource(t *testing.T) {
+	g := testutil.NewGitRepo(t)
+	priv, cfg := newSigner(t)
+	writeSigned(t, priv, filepath.Join(g.Work, "a.json"), `[{"phone":"13800138000"}]`)
+	g.Commit(nil)
+	cfg.Git = config.GitSourceConfig{URL: g.Bare, Dir: filepath.Join(t.TempDir(), "cache")}
+
+	r, err := NewRulesLoader(cfg, "ONLY_REMOTE")
+	require.NoError(t, err)
+	users, err := r.Load(context.Background(), "", "", "", "")
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	// An unsigned data file rejects the commit.
+	g.Commit(map[string]string{"b.json": `[{"phone":"13900139000"}]`})
+	_, err = r.Load(context.Background(), "", "", "", "")
+	assert.ErrorIs(t, err, signature.ErrUnsigned)
+}
+
+func TestRulesLoader_Load_SkipsUnchangedFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"phone":"13800138000"}]`), 0o600))
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(path, old, old))
+
+	r, err := NewRulesLoader(nil, "ONLY_LOCAL")
+	require.NoError(t, err)
+	users, err := r.Load(context.Background(), path, "", "", "")
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+
+	// Same size and mtime: the file is not read again.
+	require.NoError(t, os.WriteFile(path, []byte(`[{"phone":"13900139000"}]`), 0o600))
+	require.NoError(t, os.Chtimes(path, old, old))
+	users, err = r.Load(context.Background(), path, "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "13800138000", users[0].Phone)
+
+	// A new mtime invalidates the snapshot.
+	require.NoError(t, os.Chtimes(path, old.Add(time.Minute), old.Add(time.Minute)))
+	users, err = r.Load(context.Background(), path, "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "13900139000", users[0].Phone)
+}
+
+func TestRulesLoader_Load_RecentFilesAlwaysReread(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"phone":"13800138000"}]`), 0o600))
+	now := time.Now()
+	require.NoError(t, os.Chtimes(path, now, now))
+
+	r, err := NewRulesLoader(nil, "ONLY_LOCAL")
+	require.NoError(t, err)
+	_, err = r.Load(context.Background(), path, "", "", "")
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`[{"phone":"13900139000"}]`), 0o600))
+	require.NoError(t, os.Chtimes(path, now, now))
+	users, err := r.Load(context.Background(), path, "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "13900139000", users[0].Phone, "files modified within racyWindow are re-read")
+}
+
+// newSigner returns a signing key and a cmd.Config trusting its public key.
+func newSigner(t *testing.T) (ed25519.PrivateKey, *cmd.Config) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	keyPath := filepath.Join(t.TempDir(), "trusted.pem")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+	return priv, &cmd.Config{HTTPTimeout: 5, Signature: config.SignatureConfig{PublicKeys: []string{keyPath}}}
+}
+
+// writeSigned writes data to path together with its detached signature.
+func writeSigned(t *testing.T, priv ed25519.PrivateKey, path, data string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	sig, err := signature.Sign(priv, []byte(data))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path+signature.FileSuffix, []byte(sig), 0o600))
+}
+
+func TestRulesLoader_Load_SignedFiles(t *testing.T) {
+	priv, cfg := newSigner(t)
+	dir := t.TempDir()
+	writeSigned(t, priv, filepath.Join(dir, "a.json"), `[{"phone":"13800138000","name":"a"}]`)
+	writeSigned(t, priv, filepath.Join(dir, "b.json"), `[{"phone":"13800138000","name":"b"},{"phone":"13800138001"}]`)
+
+	r, err := NewRulesLoader(cfg, "DEFAULT")
+	require.NoError(t, err)
+	users, err := r.Load(context.Background(), filepath.Join(dir, "missing.json"), dir, "", "")
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "b", users[0].Name, "later file overrides by key, as with parser-kit merge")
+
+	// Tampering with one file rejects the whole load.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.json"), []byte(`[{"phone":"13999999999"}]`), 0o600))
+	_, err = r.Load(context.Background(), "", dir, "", "")
+	assert.ErrorIs(t, err, signature.ErrInvalid)
+}
+
+func TestRulesLoader_Load_UnsignedFileRejected(t *testing.T) {
+	_, cfg := newSigner(t)
+	path := filepath.Join(t.TempDir(), "data.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"phone":"13800138000"}]`), 0o600))
+
+	r, err := NewRulesLoader(cfg, "ONLY_LOCAL")
+	require.NoError(t, err)
+	_, err = r.Load(context.Background(), path, "", "", "")
+	assert.ErrorIs(t, err, signature.ErrUnsigned)
+	_, err = r.FromFile(context.Background(), path)
+	assert.ErrorIs(t, err, signature.ErrUnsigned)
+}
+
+func TestRulesLoader_Load_SignedRemote(t *testing.T) {
+	priv, cfg := newSigner(t)
+	body := `[{"phone":"13800138000"}]`
+	sig, err := signature.Sign(priv, []byte(body))
+	require.NoError(t, err)
+	var sendSig bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sendSig {
+			w.Header().Set(signature.SignatureHeader, sig)
+		}
+		_, err := w.Write([]byte(body))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+
+	r, err := NewRulesLoader(cfg, "ONLY_REMOTE")
+	require.NoError(t, err)
+	_, err = r.Load(context.Background(), "", "", srv.URL, "")
+	assert.ErrorIs(t, err, signature.ErrUnsigned)
+
+	sendSig = true
+	users, err := r.Load(context.Background(), "", "", srv.URL, "")
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+}
+
+func TestNewRulesLoader_InvalidPublicKeys(t *testing.T) {
+	_, err := NewRulesLoader(&cmd.Config{Signature: config.SignatureConfig{PublicKeys: []string{"/nonexistent.pem"}}}, "DEFAULT")
+	assert.Error(t, err)
+}
+
+func TestNewRulesLoader_ExecWithSignatures(t *testing.T) {
+	_, cfg := newSigner(t)
+	cfg.Exec.Command = "/usr/bin/roster"
+	_, err := NewRulesLoader(cfg, "DEFAULT")
+	require.ErrorIs(t, err, signature.ErrUnsigned, "unsigned command output must not bypass verification")
+}
+
+func TestRulesLoader_Load_EnvelopeRemote(t *testing.T) {
+	dir := t.TempDir()
+	xKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(xKey)
+	require.NoError(t, err)
+	keyPath := filepath.Join(dir, "x25519.pem")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	sealed, err := remote.SealEnvelope("2026-10", xKey.PublicKey(), []byte(`[{"phone":"13800138000"}]`))
+	require.NoError(t, err)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", remote.EnvelopeContentType)
+		_, err := w.Write(sealed)
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+
+	cfg := &cmd.Config{
+		HTTPTimeout:          5,
+		RemoteDecryptEnabled: true,
+		RemoteKeys:           []config.RemoteKeyConfig{{ID: "2026-10", File: keyPath}},
+	}
+	r, err := NewRulesLoader(cfg, "ONLY_REMOTE")
+	require.NoError(t, err)
+	users, err := r.Load(context.Background(), "", "", srv.URL, "")
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "13800138000", users[0].Phone)
+}
+
+func TestRulesLoader_Load_FieldMerge(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.json"),
+		[]byte(`[{"phone":"13800138000","name":"Local Name","dingtalk_userid":"dt1","scope":["write"]}]`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.json"),
+		[]byte(`[{"phone":"13800138000","role":"ops"}]`), 0o600))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`[{"phone":"13800138000","status":"suspended","name":"Remote Name","scope":["read"]}]`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+
+	cfg := &cmd.Config{
+		HTTPTimeout: 5,
+		Merge:       config.MergeConfig{Fields: map[string]string{"status": "remote", "scope": "union", "name": "local"}},
+	}
+	r, err := NewRulesLoader(cfg, "REMOTE_FIRST")
+	require.NoError(t, err)
+	users, err := r.Load(context.Background(), "", dir, srv.URL, "")
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	u := users[0]
+	assert.Equal(t, "suspended", u.Status)
+	assert.Equal(t, "Local Name", u.Name)
+	assert.Equal(t, "dt1", u.DingtalkUserID, "b.json lacks dingtalk_userid but does not clear it")
+	assert.Equal(t, "ops", u.Role)
+	assert.ElementsMatch(t, []string{"read", "write"}, u.Scope)
+
+	_, err = NewRulesLoader(&cmd.Config{Merge: config.MergeConfig{Fields: map[string]string{"name": "union"}}}, "DEFAULT")
+	assert.Error(t, err)
+}
+
+func TestRulesLoader_Load_FieldMergeBeforeDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	require.NoError(t, os.WriteFile(path,
+		[]byte(`[{"phone":"13800138000","status":"suspended","user_id":"u123","name":"Local"}]`), 0o600))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`[{"phone":"13800138000","name":"Remote"},{"phone":"13900139000"}]`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+
+	cfg := &cmd.Config{HTTPTimeout: 5, Merge: config.MergeConfig{Fields: map[string]string{"name": "remote"}}}
+	r, err := NewRulesLoader(cfg, "LOCAL_FIRST")
+	require.NoError(t, err)
+	users, err := r.Load(context.Background(), path, "", srv.URL, "")
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+
+	u := users[0]
+	assert.Equal(t, "Remote", u.Name)
+	assert.Equal(t, "suspended", u.Status, "the remote record has no status; the default must not override the local one")
+	assert.Equal(t, "u123", u.UserID, "the remote record has no user_id; a generated one must not override the local one")
+
+	// Defaults are still applied to the merged result
+	assert.Equal(t, "active", users[1].Status)
+	assert.Len(t, users[1].UserID, 16)
+}
+
+func TestRulesLoader_Load_IdentityConflicts(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.json"),
+		[]byte(`[{"phone":"13800138000","user_id":"u1"},{"phone":"13700137000","mail":"ops@example.com"}]`), 0o600))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`[{"phone":"13900139000","user_id":"u1"},{"phone":"13600136000","mail":"OPS@example.com"}]`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+
+	r, err := NewRulesLoader(&cmd.Config{HTTPTimeout: 5}, "REMOTE_FIRST")
+	require.NoError(t, err)
+	users, err := r.Load(context.Background(), "", dir, srv.URL, "")
+	require.NoError(t, err)
+	assert.Len(t, users, 4, "warn keeps every record")
+	conflicts := r.IdentityConflicts()
+	require.Len(t, conflicts, 2)
+	assert.Equal(t, "user_id", conflicts[0].Field)
+	assert.Equal(t, "mail", conflicts[1].Field)
+	assert.Equal(t, "ops@example.com", conflicts[1].Value)
+
+	cfg := &cmd.Config{HTTPTimeout: 5, Merge: config.MergeConfig{Conflicts: "prefer"}}
+	r, err = NewRulesLoader(cfg, "REMOTE_FIRST")
+	require.NoError(t, err)
+	users, err = r.Load(context.Background(), "", dir, srv.URL, "")
+	require.NoError(t, err)
+	phones := []string{users[0].Phone, users[1].Phone}
+	assert.ElementsMatch(t, []string{"13800138000", "13700137000"}, phones, "the file records win in REMOTE_FIRST")
+
+	r, err = NewRulesLoader(cfg, "LOCAL_FIRST")
+	require.NoError(t, err)
+	users, err = r.Load(context.Background(), "", dir, srv.URL, "")
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.ElementsMatch(t, []string{"13900139000", "13600136000"}, []string{users[0].Phone, users[1].Phone}, "the remote records win in LOCAL_FIRST")
+
+	_, err = NewRulesLoader(&cmd.Config{Merge: config.MergeConfig{Conflicts: "drop"}}, "DEFAULT")
+	assert.Error(t, err)
+}
+
+func TestRulesLoader_Provenance(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")
+	require.NoError(t, os.WriteFile(a, []byte(`[{"phone":"13800138000"}]`), 0o600))
+	require.NoError(t, os.WriteFile(b, []byte(`[{"phone":"13900139000"}]`), 0o600))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`[{"phone":"13800138000","status":"inactive"}]`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+
+	r, err := NewRulesLoader(&cmd.Config{HTTPTimeout: 5}, "REMOTE_FIRST")
+	require.NoError(t, err)
+	_, ok := r.Provenance(&define.AllowListUser{Phone: "13800138000"})
+	assert.False(t, ok, "nothing loaded yet")
+
+	configURL := strings.Replace(srv.URL, "http://", "http://user:secret@", 1) + "/users?token=secret"
+	before := time.Now()
+	users, err := r.Load(context.Background(), "", dir, configURL, "")
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+
+	p, ok := r.Provenance(&users[0])
+	require.True(t, ok)
+	assert.Equal(t, []define.ProvenanceSource{
+		{Name: srv.URL + "/users", Type: "remote"},
+		{Name: a, Type: "file"},
+	}, p.Sources, "lowest precedence first; credentials and query are stripped")
+	assert.False(t, p.LoadedAt.Before(before))
+
+	p, ok = r.Provenance(&define.AllowListUser{Phone: "13900139000"})
+	require.True(t, ok)
+	assert.Equal(t, []string{b}, p.Names())
+
+	_, ok = r.Provenance(&define.AllowListUser{Phone: "13700137000"})
+	assert.False(t, ok)
+	var nilLoader *RulesLoader
+	_, ok = nilLoader.Provenance(&users[0])
+	assert.False(t, ok)
+}
+
+func TestNewRulesLoader_RemoteOAuth2(t *testing.T) {
+	r, err := NewRulesLoader(&cmd.Config{HTTPTimeout: 5}, "DEFAULT")
+	require.NoError(t, err)
+	assert.Nil(t, r.remoteFetchOptions("", false).Token)
+
+	var issued atomic.Int32
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != "warden" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		issued.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"abc","token_type":"bearer","expires_in":3600}`)) //nolint:errcheck // test server
+	}))
+	defer idp.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"phone":"13800138000","mail":"a@example.com"}]`)) //nolint:errcheck // test server
+	}))
+	defer api.Close()
+
+	secret := filepath.Join(t.TempDir(), "client_secret")
+	require.NoError(t, os.WriteFile(secret, []byte("s3cret\n"), 0o600))
+	cfg := &cmd.Config{HTTPTimeout: 5, RemoteOAuth2: config.RemoteOAuth2Config{TokenURL: idp.URL, ClientID: "warden", ClientSecretFile: secret}}
+	r, err = NewRulesLoader(cfg, "ONLY_REMOTE")
+	require.NoError(t, err)
+	for range 2 {
+		users, err := r.Load(context.Background(), "", "", api.URL, "")
+		require.NoError(t, err)
+		assert.Len(t, users, 1)
+	}
+	assert.Equal(t, int32(1), issued.Load(), "the token is reused across loads")
+}
+
+func TestNewRulesLoader_RemoteOAuth2PrivateCA(t *testing.T) {
+	idp := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"abc","token_type":"bearer","expires_in":3600}`)) //nolint:errcheck // test server
+	}))
+	defer idp.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"phone":"13800138000"}]`)) //nolint:errcheck // test server
+	}))
+	defer api.Close()
+
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "client_secret")
+	require.NoError(t, os.WriteFile(secret, []byte("s3cret"), 0o600))
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: idp.Certificate().Raw}), 0o600))
+
+	cfg := &cmd.Config{HTTPTimeout: 5, RemoteOAuth2: config.RemoteOAuth2Config{TokenURL: idp.URL, ClientID: "warden", ClientSecretFile: secret}}
+	r, err := NewRulesLoader(cfg, "ONLY_REMOTE")
+	require.NoError(t, err)
+	_, err = r.Load(context.Background(), "", "", api.URL, "")
+	require.Error(t, err, "the token endpoint certificate is not trusted by the system roots")
+
+	cfg.RemoteTLS = config.RemoteTLSConfig{CAFile: caFile}
+	r, err = NewRulesLoader(cfg, "ONLY_REMOTE")
+	require.NoError(t, err)
+	users, err := r.Load(context.Background(), "", "", api.URL, "")
+	require.NoError(t, err, "the token client uses the remote CA bundle")
+	assert.Len(t, users, 1)
+}
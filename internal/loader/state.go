@@ -0,0 +1,85 @@
+package loader
+
+import (
+	"strings"
+
+	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/internal/merge"
+)
+
+// ProvenanceIndex returns the index in define.LoaderState.SourceSets of the sources of the user with the
+// given key (see State); false when the user is unknown.
+type ProvenanceIndex func(key string) (int, bool)
+
+// followed is the load of the leader a follower reports (see Follow).
+type followed struct {
+	state define.LoaderState
+	index ProvenanceIndex
+}
+
+// State returns the state of the last successful Load for the followers (see Follow), with the index of
+// the source list of every user key in State.SourceSets.
+func (r *RulesLoader) State() (define.LoaderState, map[string]int) {
+	if r == nil {
+		return define.LoaderState{}, nil
+	}
+	statuses := r.SourceStatuses()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state := define.LoaderState{LoadedAt: r.loadedAt, Version: r.version, Sources: statuses, Conflicts: r.conflicts}
+	sets := make(map[string]int)
+	index := make(map[string]int, len(r.provenance))
+	for key, srcs := range r.provenance {
+		id := sourceSetID(srcs)
+		i, ok := sets[id]
+		if !ok {
+			i = len(state.SourceSets)
+			sets[id] = i
+			set := make([]define.ProvenanceSource, len(srcs))
+			for j, s := range srcs {
+				set[j] = define.ProvenanceSource{Name: s.Name, Type: s.Origin.String()}
+			}
+			state.SourceSets = append(state.SourceSets, set)
+		}
+		index[key] = i
+	}
+	return state, index
+}
+
+// Follow makes a loader that does not load the sources itself report the last load of the leader:
+// Provenance, SourceStatuses, IdentityConflicts and DatasetVersion answer from state, and index resolves
+// the source list of a user. It lasts until the next successful Load.
+func (r *RulesLoader) Follow(state define.LoaderState, index ProvenanceIndex) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.followed = &followed{state: state, index: index}
+	r.mu.Unlock()
+}
+
+// provenance returns the provenance of u in the load of the leader. The caller must not hold r.mu: index
+// may read Redis.
+func (f *followed) provenance(u *define.AllowListUser) (define.Provenance, bool) {
+	k, ok := provenanceKey(u)
+	if !ok || f.index == nil {
+		return define.Provenance{}, false
+	}
+	i, ok := f.index(k)
+	if !ok || i < 0 || i >= len(f.state.SourceSets) {
+		return define.Provenance{}, false
+	}
+	return define.Provenance{LoadedAt: f.state.LoadedAt, Version: f.state.Version, Sources: f.state.SourceSets[i]}, true
+}
+
+// sourceSetID identifies a source list.
+func sourceSetID(srcs []merge.Source) string {
+	var b strings.Builder
+	for _, s := range srcs {
+		b.WriteString(s.Origin.String())
+		b.WriteByte(0)
+		b.WriteString(s.Name)
+		b.WriteByte(0)
+	}
+	return b.String()
+}
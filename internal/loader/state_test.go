@@ -0,0 +1,64 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/cmd"
+	"github.com/soulteary/warden/internal/define"
+)
+
+func TestRulesLoader_StateAndFollow(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")
+	require.NoError(t, os.WriteFile(a, []byte(`[{"phone":"13800138000"},{"phone":"13700137000"}]`), 0o600))
+	require.NoError(t, os.WriteFile(b, []byte(`[{"phone":"13900139000"},{"phone":"13700137000","status":"inactive"}]`), 0o600))
+
+	leader, err := NewRulesLoader(&cmd.Config{HTTPTimeout: 5}, "DEFAULT")
+	require.NoError(t, err)
+	users, err := leader.Load(context.Background(), "", dir, "", "")
+	require.NoError(t, err)
+	require.Len(t, users, 3)
+
+	state, index := leader.State()
+	assert.Len(t, state.Sources, 2)
+	assert.Len(t, state.SourceSets, 3, "a, b, and a then b")
+	require.Len(t, index, 3)
+
+	follower, err := NewRulesLoader(&cmd.Config{HTTPTimeout: 5}, "DEFAULT")
+	require.NoError(t, err)
+	follower.Follow(state, func(key string) (int, bool) {
+		i, ok := index[key]
+		return i, ok
+	})
+	for i := range users {
+		want, ok := leader.Provenance(&users[i])
+		require.True(t, ok)
+		got, ok := follower.Provenance(&users[i])
+		require.True(t, ok)
+		assert.Equal(t, want.Sources, got.Sources)
+		assert.True(t, want.LoadedAt.Equal(got.LoadedAt))
+	}
+	_, ok := follower.Provenance(&define.AllowListUser{Phone: "13600136000"})
+	assert.False(t, ok)
+	assert.Equal(t, leader.SourceStatuses(), follower.SourceStatuses())
+	assert.Equal(t, leader.DatasetVersion(), follower.DatasetVersion())
+
+	// Its own load ends following
+	_, err = follower.Load(context.Background(), a, "", "", "")
+	require.NoError(t, err)
+	p, ok := follower.Provenance(&define.AllowListUser{Phone: "13700137000"})
+	require.True(t, ok)
+	assert.Equal(t, []string{a}, p.Names())
+	assert.Len(t, follower.SourceStatuses(), 1)
+
+	var r *RulesLoader
+	state, index = r.State()
+	assert.Empty(t, state.Sources)
+	assert.Nil(t, index)
+}
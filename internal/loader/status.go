@@ -0,0 +1,178 @@
+package loader
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	parserkit "github.com/soulteary/parser-kit"
+	"github.com/soulteary/warden/internal/breaker"
+	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/internal/merge"
+	"github.com/soulteary/warden/internal/prommetrics"
+)
+
+// configuredSources returns the sources Load uses for fileSources and configURL in r.appMode,
+// remote first, then the Git and exec sources, then the files in priority order.
+func (r *RulesLoader) configuredSources(fileSources []parserkit.Source, configURL string) []merge.Source {
+	var out []merge.Source
+	if strings.ToUpper(strings.TrimSpace(r.appMode)) != "ONLY_LOCAL" {
+		if configURL != "" {
+			out = append(out, merge.Source{Name: remoteSourceName(configURL), Origin: merge.Remote})
+		}
+		if r.git != nil {
+			out = append(out, r.gitSource())
+		}
+		if r.exec != nil {
+			out = append(out, r.execSource())
+		}
+	}
+	return append(out, fileSourceNames(fileSources)...)
+}
+
+// fileSourceNames returns the file sources as merge sources.
+func fileSourceNames(sources []parserkit.Source) []merge.Source {
+	out := make([]merge.Source, len(sources))
+	for i, s := range sources {
+		out[i] = merge.Source{Name: s.Config.FilePath, Origin: merge.Local}
+	}
+	return out
+}
+
+// TrackSources sets the tracked sources to the ones Load uses for (rulesFile, dataDir, configURL), so they
+// are reported before the first load. Load does the same on every call; sources that are no longer
+// configured (e.g. a file removed from data_dir) are dropped together with their metrics.
+func (r *RulesLoader) TrackSources(rulesFile, dataDir, configURL string) {
+	if r == nil {
+		return
+	}
+	r.trackSources(r.configuredSources(BuildSources(rulesFile, dataDir, "", "", r.appMode), configURL))
+}
+
+func (r *RulesLoader) trackSources(sources []merge.Source) {
+	keep := make(map[merge.Source]bool, len(sources))
+	var removed []merge.Source
+	r.mu.Lock()
+	for _, s := range sources {
+		keep[s] = true
+		if _, ok := r.status[s]; !ok {
+			r.status[s] = &define.SourceStatus{Name: s.Name, Type: s.Origin.String()}
+		}
+		if _, ok := r.breakers[s]; !ok && s.Origin != merge.Local && r.breakerOpts != nil {
+			r.breakers[s] = breaker.New(*r.breakerOpts)
+		}
+	}
+	for s := range r.status {
+		if !keep[s] {
+			delete(r.status, s)
+			delete(r.breakers, s)
+			removed = append(removed, s)
+		}
+	}
+	r.sourceOrder = sources
+	r.mu.Unlock()
+	for _, s := range removed {
+		prommetrics.DeleteSource(s.Name, s.Origin.String())
+	}
+}
+
+// recordSource records an attempt of src started at start. Untracked sources (e.g. FromFile) are ignored.
+func (r *RulesLoader) recordSource(src merge.Source, start time.Time, records int, err error) {
+	latency := time.Since(start)
+	r.mu.Lock()
+	st, ok := r.status[src]
+	if ok {
+		st.LastAttempt = start
+		st.LatencyMS = latency.Milliseconds()
+		if err != nil {
+			st.LastError = err.Error()
+		} else {
+			st.LastError = ""
+			st.LastSuccess = start.Add(latency)
+			st.Records = records
+		}
+	}
+	r.mu.Unlock()
+	if ok {
+		prommetrics.RecordSourceSync(src.Name, src.Origin.String(), start, latency, records, err)
+	}
+}
+
+// guard runs fetch for src through the circuit breaker of src, if any. While the breaker is open fetch
+// is not called and an error wrapping breaker.ErrOpen is returned; the source status keeps the last real
+// attempt. fetch gets probe = true for the single half-open attempt.
+func (r *RulesLoader) guard(src merge.Source, fetch func(probe bool) error) error {
+	r.mu.Lock()
+	b := r.breakers[src]
+	r.mu.Unlock()
+	if b == nil {
+		return fetch(false)
+	}
+	probe, err := b.Allow()
+	if err != nil {
+		return fmt.Errorf("%s: %w", src.Name, err)
+	}
+	opened := false
+	if err = fetch(probe); err != nil {
+		opened = b.Failure()
+	} else {
+		b.Success()
+	}
+	prommetrics.RecordCircuitState(src.Name, src.Origin.String(), int(b.Status().State), opened)
+	return err
+}
+
+// recordVersion records the version of the last successful attempt of src.
+func (r *RulesLoader) recordVersion(src merge.Source, version string) {
+	r.mu.Lock()
+	if st, ok := r.status[src]; ok {
+		st.Version = version
+	}
+	r.mu.Unlock()
+}
+
+// touchSources records a successful attempt for the healthy sources in srcs without reading them again
+// (file snapshot reuse); sources whose last attempt failed keep their error.
+func (r *RulesLoader) touchSources(srcs []merge.Source) {
+	now := time.Now()
+	for _, src := range srcs {
+		r.mu.Lock()
+		st, ok := r.status[src]
+		healthy := ok && !st.Failing()
+		records := 0
+		if ok {
+			records = st.Records
+		}
+		r.mu.Unlock()
+		if healthy {
+			r.recordSource(src, now, records, nil)
+		}
+	}
+}
+
+// SourceStatuses returns the sync state of the tracked sources in configuration order.
+func (r *RulesLoader) SourceStatuses() []define.SourceStatus {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.followed != nil {
+		return r.followed.state.Sources
+	}
+	out := make([]define.SourceStatus, 0, len(r.sourceOrder))
+	for _, s := range r.sourceOrder {
+		st, ok := r.status[s]
+		if !ok {
+			continue
+		}
+		cp := *st
+		if b := r.breakers[s]; b != nil {
+			bs := b.Status()
+			cp.Circuit = bs.State.String()
+			cp.RetryAt = bs.RetryAt
+		}
+		out = append(out, cp)
+	}
+	return out
+}
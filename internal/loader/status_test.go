@@ -0,0 +1,176 @@
+package loader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/breaker"
+	"github.com/soulteary/warden/internal/cmd"
+	"github.com/soulteary/warden/internal/config"
+	"github.com/soulteary/warden/internal/define"
+)
+
+// statusByName indexes statuses by source name.
+func statusByName(statuses []define.SourceStatus) map[string]define.SourceStatus {
+	out := make(map[string]define.SourceStatus, len(statuses))
+	for _, s := range statuses {
+		out[s.Name] = s
+	}
+	return out
+}
+
+func TestRulesLoader_SourceStatuses(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")
+	require.NoError(t, os.WriteFile(a, []byte(`[{"phone":"13800138000"},{"phone":"13900139000"}]`), 0o600))
+	require.NoError(t, os.WriteFile(b, []byte(`not json`), 0o600))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	configURL := srv.URL + "/users?token=secret"
+
+	r, err := NewRulesLoader(&cmd.Config{HTTPTimeout: 5}, "REMOTE_FIRST")
+	require.NoError(t, err)
+	r.TrackSources("", dir, configURL)
+	statuses := r.SourceStatuses()
+	require.Len(t, statuses, 3)
+	assert.Equal(t, define.SourceStatus{Name: srv.URL + "/users", Type: "remote", Circuit: "closed"}, statuses[0], "remote first, not attempted yet")
+
+	_, err = r.Load(context.Background(), "", dir, configURL, "")
+	require.NoError(t, err, "the remote and b.json fail, a.json still loads")
+	byName := statusByName(r.SourceStatuses())
+
+	ok := byName[a]
+	assert.Equal(t, "file", ok.Type)
+	assert.False(t, ok.Failing())
+	assert.Equal(t, 2, ok.Records)
+	assert.False(t, ok.LastSuccess.IsZero())
+
+	bad := byName[b]
+	assert.True(t, bad.Failing())
+	assert.NotEmpty(t, bad.LastError)
+
+	rem := byName[srv.URL+"/users"]
+	assert.True(t, rem.Failing())
+	assert.True(t, rem.LastSuccess.IsZero())
+	assert.NotContains(t, rem.LastError, "secret", "query is stripped from errors")
+
+	// Removing a file drops its status.
+	require.NoError(t, os.Remove(b))
+	_, err = r.Load(context.Background(), "", dir, configURL, "")
+	require.NoError(t, err)
+	byName = statusByName(r.SourceStatuses())
+	assert.Len(t, byName, 2)
+	assert.NotContains(t, byName, b)
+
+	var nilLoader *RulesLoader
+	assert.Nil(t, nilLoader.SourceStatuses())
+}
+
+func TestRulesLoader_SourceStatuses_MissingFileOnlyLocal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.json")
+	r, err := NewRulesLoader(nil, "ONLY_LOCAL")
+	require.NoError(t, err)
+	users, err := r.Load(context.Background(), path, "", "", "")
+	require.NoError(t, err, "ONLY_LOCAL allows a missing file")
+	assert.Empty(t, users)
+
+	statuses := r.SourceStatuses()
+	require.Len(t, statuses, 1)
+	assert.True(t, statuses[0].Failing(), "but the source is reported")
+	assert.Contains(t, statuses[0].LastError, "file not found")
+}
+
+func TestRulesLoader_Load_CircuitBreaker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"phone":"13800138000"}]`), 0o600))
+	var (
+		hits   atomic.Int32
+		status atomic.Int32
+	)
+	status.Store(http.StatusNotFound)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		if code := int(status.Load()); code != http.StatusOK {
+			w.WriteHeader(code)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"phone":"13900139000"}]`)) //nolint:errcheck // test server
+	}))
+	defer srv.Close()
+
+	cfg := &cmd.Config{HTTPTimeout: 5, CircuitBreaker: config.CircuitBreakerConfig{Threshold: 2, BaseDelay: 50 * time.Millisecond, MaxDelay: 100 * time.Millisecond}}
+	r, err := NewRulesLoader(cfg, "REMOTE_FIRST")
+	require.NoError(t, err)
+	load := func() []define.AllowListUser {
+		t.Helper()
+		users, err := r.Load(context.Background(), path, "", srv.URL, "")
+		require.NoError(t, err, "files load while the remote is down")
+		return users
+	}
+	remoteStatus := func() define.SourceStatus {
+		return statusByName(r.SourceStatuses())[srv.URL]
+	}
+
+	load()
+	load()
+	assert.Equal(t, int32(2), hits.Load())
+	st := remoteStatus()
+	assert.Equal(t, "open", st.Circuit)
+	assert.False(t, st.RetryAt.IsZero())
+	lastAttempt := st.LastAttempt
+
+	// While open the remote is skipped without a request.
+	assert.Len(t, load(), 1)
+	assert.Equal(t, int32(2), hits.Load())
+	assert.Equal(t, lastAttempt, remoteStatus().LastAttempt, "a skipped source keeps its last attempt")
+
+	// The half-open probe is a single request, without retries.
+	time.Sleep(60 * time.Millisecond)
+	status.Store(http.StatusServiceUnavailable)
+	load()
+	assert.Equal(t, int32(3), hits.Load())
+	assert.Equal(t, "open", remoteStatus().Circuit, "a failed probe re-opens")
+
+	// A successful probe closes the breaker.
+	time.Sleep(110 * time.Millisecond)
+	status.Store(http.StatusOK)
+	assert.Len(t, load(), 2)
+	st = remoteStatus()
+	assert.Equal(t, "closed", st.Circuit)
+	assert.True(t, st.RetryAt.IsZero())
+}
+
+func TestRulesLoader_Load_CircuitBreakerOnlyRemote(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	r, err := NewRulesLoader(&cmd.Config{HTTPTimeout: 5, CircuitBreaker: config.CircuitBreakerConfig{Threshold: 1, BaseDelay: time.Hour}}, "ONLY_REMOTE")
+	require.NoError(t, err)
+	_, err = r.Load(context.Background(), "", "", srv.URL, "")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, breaker.ErrOpen)
+	_, err = r.Load(context.Background(), "", "", srv.URL, "")
+	assert.ErrorIs(t, err, breaker.ErrOpen)
+
+	// Disabled: every load reaches the source.
+	r, err = NewRulesLoader(&cmd.Config{HTTPTimeout: 5, CircuitBreaker: config.CircuitBreakerConfig{Disabled: true}}, "ONLY_REMOTE")
+	require.NoError(t, err)
+	for range 5 {
+		_, err = r.Load(context.Background(), "", "", srv.URL, "")
+		assert.NotErrorIs(t, err, breaker.ErrOpen)
+	}
+	assert.Empty(t, statusByName(r.SourceStatuses())[srv.URL].Circuit)
+}
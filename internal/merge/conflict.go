@@ -0,0 +1,138 @@
+package merge
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/soulteary/warden/internal/define"
+)
+
+// Identity conflict policies.
+const (
+	// ConflictWarn keeps every conflicting record and only reports the conflict (default).
+	ConflictWarn = "warn"
+	// ConflictReject drops every record involved in a conflict.
+	ConflictReject = "reject"
+	// ConflictPrefer keeps the record listed by the highest-precedence source and drops the others.
+	ConflictPrefer = "prefer"
+)
+
+// Identity fields checked for conflicts.
+const (
+	ConflictFieldUserID = "user_id"
+	ConflictFieldMail   = "mail"
+)
+
+// ValidateConflictPolicy checks an identity conflict policy; empty means ConflictWarn.
+func ValidateConflictPolicy(policy string) error {
+	switch strings.ToLower(strings.TrimSpace(policy)) {
+	case "", ConflictWarn, ConflictReject, ConflictPrefer:
+		return nil
+	}
+	return fmt.Errorf("merge: unknown conflict policy %q (want %s, %s or %s)", policy, ConflictWarn, ConflictReject, ConflictPrefer)
+}
+
+// member is a merged record taking part in a conflict.
+type member struct {
+	key  string
+	user *define.AllowListUser
+	e    *entry
+}
+
+// Resolve returns the merged records like Users after applying the identity conflict policy, together with
+// the conflicts found: a user_id, or a mail, shared by records with different keys. Records without an
+// explicit user_id are not checked for user_id conflicts.
+// With ConflictPrefer the record with the highest precedence (the one added last) is kept; a record dropped
+// in one conflict does not take part in the others.
+func (m *Merger) Resolve(policy string) ([]define.AllowListUser, []define.IdentityConflict) {
+	policy = strings.ToLower(strings.TrimSpace(policy))
+	if policy == "" {
+		policy = ConflictWarn
+	}
+	users := m.Users()
+	groups := map[string]map[string][]member{ConflictFieldUserID: {}, ConflictFieldMail: {}}
+	for i, k := range m.order {
+		mb := member{key: k, user: &users[i], e: m.byKey[k]}
+		if id := users[i].UserID; id != "" {
+			groups[ConflictFieldUserID][id] = append(groups[ConflictFieldUserID][id], mb)
+		}
+		if mail := strings.ToLower(strings.TrimSpace(users[i].Mail)); mail != "" {
+			groups[ConflictFieldMail][mail] = append(groups[ConflictFieldMail][mail], mb)
+		}
+	}
+
+	dropped := make(map[string]bool)
+	var (
+		conflicts []define.IdentityConflict
+		keys      [][]string // record keys per conflict
+	)
+	for _, field := range []string{ConflictFieldUserID, ConflictFieldMail} {
+		values := make([]string, 0, len(groups[field]))
+		for v, ms := range groups[field] {
+			if len(ms) > 1 {
+				values = append(values, v)
+			}
+		}
+		sort.Strings(values)
+		for _, v := range values {
+			ms := groups[field][v]
+			switch policy {
+			case ConflictReject:
+				for _, mb := range ms {
+					dropped[mb.key] = true
+				}
+			case ConflictPrefer:
+				var winner *member
+				for i := range ms {
+					if !dropped[ms[i].key] && (winner == nil || ms[i].e.seq > winner.e.seq) {
+						winner = &ms[i]
+					}
+				}
+				for _, mb := range ms {
+					if winner != nil && mb.key != winner.key {
+						dropped[mb.key] = true
+					}
+				}
+			}
+			c := define.IdentityConflict{Field: field, Value: v, Action: policy, Records: make([]define.ConflictRecord, 0, len(ms))}
+			ks := make([]string, 0, len(ms))
+			for _, mb := range ms {
+				ks = append(ks, mb.key)
+				c.Records = append(c.Records, define.ConflictRecord{
+					Phone:   mb.user.Phone,
+					Mail:    mb.user.Mail,
+					UserID:  mb.user.UserID,
+					Sources: provenanceSources(mb.e.sources),
+				})
+			}
+			conflicts = append(conflicts, c)
+			keys = append(keys, ks)
+		}
+	}
+	// Dropped flags are final only once every conflict has been processed
+	for i := range conflicts {
+		for j := range conflicts[i].Records {
+			conflicts[i].Records[j].Dropped = dropped[keys[i][j]]
+		}
+	}
+	if len(dropped) == 0 {
+		return users, conflicts
+	}
+	kept := users[:0]
+	for i, k := range m.order {
+		if !dropped[k] {
+			kept = append(kept, users[i])
+		}
+	}
+	return kept, conflicts
+}
+
+// provenanceSources converts sources for reporting.
+func provenanceSources(sources []Source) []define.ProvenanceSource {
+	out := make([]define.ProvenanceSource, len(sources))
+	for i, s := range sources {
+		out[i] = define.ProvenanceSource{Name: s.Name, Type: s.Origin.String()}
+	}
+	return out
+}
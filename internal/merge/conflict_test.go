@@ -0,0 +1,83 @@
+package merge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/define"
+)
+
+// conflictMerger returns a Merger with a user_id conflict (u1 on phones 1 and 2) and a mail conflict
+// (shared@example.com on phones 2 and 3); remote takes precedence over the file.
+func conflictMerger() *Merger {
+	m := NewMerger(nil, phoneKey)
+	m.Add([]define.AllowListUser{
+		{Phone: "1", UserID: "u1"},
+		{Phone: "3", Mail: "Shared@example.com", UserID: "u3"},
+		{Phone: "4", Mail: "d@example.com", UserID: "u4"},
+	}, Source{Name: "/data/a.json"})
+	m.Add([]define.AllowListUser{
+		{Phone: "2", Mail: "shared@example.com", UserID: "u1"},
+		{Phone: "4", Mail: "d@example.com", UserID: "u4"},
+	}, Source{Name: "https://example.com/users", Origin: Remote})
+	return m
+}
+
+func phones(users []define.AllowListUser) []string {
+	out := make([]string, len(users))
+	for i := range users {
+		out[i] = users[i].Phone
+	}
+	return out
+}
+
+func TestMerger_ResolveWarn(t *testing.T) {
+	users, conflicts := conflictMerger().Resolve("")
+	assert.Equal(t, []string{"1", "3", "4", "2"}, phones(users), "warn keeps every record")
+	require.Len(t, conflicts, 2)
+
+	assert.Equal(t, ConflictFieldUserID, conflicts[0].Field)
+	assert.Equal(t, "u1", conflicts[0].Value)
+	assert.Equal(t, ConflictWarn, conflicts[0].Action)
+	require.Len(t, conflicts[0].Records, 2)
+	assert.Equal(t, []define.ProvenanceSource{{Name: "https://example.com/users", Type: "remote"}}, conflicts[0].Records[1].Sources)
+	assert.False(t, conflicts[0].Records[0].Dropped)
+
+	assert.Equal(t, ConflictFieldMail, conflicts[1].Field)
+	assert.Equal(t, "shared@example.com", conflicts[1].Value, "mail is compared case-insensitively")
+}
+
+func TestMerger_ResolveReject(t *testing.T) {
+	users, conflicts := conflictMerger().Resolve(ConflictReject)
+	assert.Equal(t, []string{"4"}, phones(users))
+	require.Len(t, conflicts, 2)
+	for _, c := range conflicts {
+		for _, r := range c.Records {
+			assert.True(t, r.Dropped)
+		}
+	}
+}
+
+func TestMerger_ResolvePrefer(t *testing.T) {
+	users, conflicts := conflictMerger().Resolve(ConflictPrefer)
+	// Phone 2 comes from the remote (higher precedence) and wins both conflicts
+	assert.Equal(t, []string{"4", "2"}, phones(users))
+	require.Len(t, conflicts, 2)
+	assert.True(t, conflicts[0].Records[0].Dropped)
+	assert.False(t, conflicts[0].Records[1].Dropped)
+
+	// Within one source the later record wins
+	m := NewMerger(nil, phoneKey)
+	m.Add([]define.AllowListUser{{Phone: "1", UserID: "u"}, {Phone: "2", UserID: "u"}}, Source{Name: "/data/a.json"})
+	users, _ = m.Resolve(ConflictPrefer)
+	assert.Equal(t, []string{"2"}, phones(users))
+}
+
+func TestValidateConflictPolicy(t *testing.T) {
+	for _, p := range []string{"", "warn", "Reject", " prefer "} {
+		assert.NoError(t, ValidateConflictPolicy(p))
+	}
+	assert.Error(t, ValidateConflictPolicy("drop"))
+}
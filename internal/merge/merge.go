@@ -0,0 +1,255 @@
+// Package merge combines user records from several sources into one record per user.
+//
+// Without a field policy a later (higher-precedence) record replaces the earlier one as a whole,
+// which is the historical behaviour. With a policy, records are merged field by field:
+//   - PreferRemote / PreferLocal: the highest-precedence non-empty value from that kind of source,
+//     falling back to any source when it has none (the exec and Git sources count as remote)
+//   - Union (scope only): the union of all sources, in first-seen order
+//   - fields without a rule: the highest-precedence non-empty value, so a source that lacks a field
+//     no longer clears it
+package merge
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/soulteary/warden/internal/define"
+)
+
+// Field strategies.
+const (
+	PreferRemote = "remote"
+	PreferLocal  = "local"
+	Union        = "union"
+)
+
+// Origin is the kind of source a record comes from.
+type Origin int
+
+const (
+	// Local is a data_file / data_dir file.
+	Local Origin = iota
+	// Remote is the remote config URL.
+	Remote
+	// Exec is the output of the configured exec source command.
+	Exec
+	// Git is the configured Git repository source.
+	Git
+)
+
+// String returns "file", "remote", "exec" or "git".
+func (o Origin) String() string {
+	switch o {
+	case Remote:
+		return "remote"
+	case Exec:
+		return "exec"
+	case Git:
+		return "git"
+	}
+	return "file"
+}
+
+// Source identifies one source of records (file path or sanitized URL).
+type Source struct {
+	Name   string
+	Origin Origin
+}
+
+// field reads and writes one scalar field of a user record.
+type field struct {
+	get func(*define.AllowListUser) string
+	set func(*define.AllowListUser, string)
+}
+
+// scalarFields are the mergeable string fields by JSON name. phone is the merge key and is not listed.
+var scalarFields = map[string]field{
+	"mail":            {func(u *define.AllowListUser) string { return u.Mail }, func(u *define.AllowListUser, v string) { u.Mail = v }},
+	"user_id":         {func(u *define.AllowListUser) string { return u.UserID }, func(u *define.AllowListUser, v string) { u.UserID = v }},
+	"status":          {func(u *define.AllowListUser) string { return u.Status }, func(u *define.AllowListUser, v string) { u.Status = v }},
+	"role":            {func(u *define.AllowListUser) string { return u.Role }, func(u *define.AllowListUser, v string) { u.Role = v }},
+	"name":            {func(u *define.AllowListUser) string { return u.Name }, func(u *define.AllowListUser, v string) { u.Name = v }},
+	"dingtalk_userid": {func(u *define.AllowListUser) string { return u.DingtalkUserID }, func(u *define.AllowListUser, v string) { u.DingtalkUserID = v }},
+}
+
+// scopeField is the only list field; it additionally supports Union.
+const scopeField = "scope"
+
+// Policy is a validated per-field merge configuration. A nil Policy replaces whole records.
+type Policy struct {
+	fields map[string]string
+}
+
+// NewPolicy validates fields (JSON field name -> strategy). It returns nil for an empty map.
+func NewPolicy(fields map[string]string) (*Policy, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	p := &Policy{fields: make(map[string]string, len(fields))}
+	for name, strategy := range fields {
+		name = strings.ToLower(strings.TrimSpace(name))
+		strategy = strings.ToLower(strings.TrimSpace(strategy))
+		_, scalar := scalarFields[name]
+		if !scalar && name != scopeField {
+			return nil, fmt.Errorf("merge: unknown field %q (want one of %s)", name, strings.Join(FieldNames(), ", "))
+		}
+		switch strategy {
+		case PreferRemote, PreferLocal:
+		case Union:
+			if scalar {
+				return nil, fmt.Errorf("merge: strategy %q is only supported for %s", Union, scopeField)
+			}
+		default:
+			return nil, fmt.Errorf("merge: unknown strategy %q for %s (want %s, %s or %s)", strategy, name, PreferRemote, PreferLocal, Union)
+		}
+		p.fields[name] = strategy
+	}
+	return p, nil
+}
+
+// FieldNames returns the mergeable field names, sorted.
+func FieldNames() []string {
+	names := []string{scopeField}
+	for n := range scalarFields {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// contribution is one source's record for a user.
+type contribution struct {
+	user   define.AllowListUser
+	origin Origin
+}
+
+// entry is the accumulated state of one user.
+type entry struct {
+	contribs []contribution // only the last one without a policy
+	sources  []Source       // every source that listed the user, lowest precedence first
+	seq      int            // position of the latest record added for the user (higher = higher precedence)
+}
+
+// Merger accumulates records by key; later Add calls take precedence over earlier ones.
+type Merger struct {
+	policy *Policy
+	key    func(define.AllowListUser) (string, bool)
+	byKey  map[string]*entry
+	order  []string
+	seq    int
+}
+
+// NewMerger creates a Merger using key to identify users. policy may be nil.
+func NewMerger(policy *Policy, key func(define.AllowListUser) (string, bool)) *Merger {
+	return &Merger{policy: policy, key: key, byKey: make(map[string]*entry)}
+}
+
+// Add adds the records of one source. Records without a key are dropped.
+func (m *Merger) Add(users []define.AllowListUser, src Source) {
+	for i := range users {
+		k, ok := m.key(users[i])
+		if !ok {
+			continue
+		}
+		e, exists := m.byKey[k]
+		if !exists {
+			e = &entry{}
+			m.byKey[k] = e
+			m.order = append(m.order, k)
+		}
+		m.seq++
+		e.seq = m.seq
+		if n := len(e.sources); n == 0 || e.sources[n-1] != src {
+			e.sources = append(e.sources, src)
+		}
+		c := contribution{user: users[i], origin: src.Origin}
+		if m.policy == nil {
+			e.contribs = []contribution{c}
+			continue
+		}
+		e.contribs = append(e.contribs, c)
+	}
+}
+
+// Users returns the merged records in first-seen order.
+func (m *Merger) Users() []define.AllowListUser {
+	out := make([]define.AllowListUser, 0, len(m.order))
+	for _, k := range m.order {
+		out = append(out, m.policy.resolve(m.byKey[k].contribs))
+	}
+	return out
+}
+
+// Sources returns, by key, the sources that listed each user (lowest precedence first).
+// Without a policy the record of the last source is the one returned by Users.
+func (m *Merger) Sources() map[string][]Source {
+	out := make(map[string][]Source, len(m.byKey))
+	for k, e := range m.byKey {
+		out[k] = e.sources
+	}
+	return out
+}
+
+// resolve merges the contributions of one user (lowest precedence first).
+func (p *Policy) resolve(cs []contribution) define.AllowListUser {
+	u := cs[len(cs)-1].user
+	if p == nil || len(cs) == 1 {
+		return u
+	}
+	for name, f := range scalarFields {
+		f.set(&u, pick(cs, p.fields[name], func(c *contribution) (string, bool) {
+			v := f.get(&c.user)
+			return v, v != ""
+		}))
+	}
+	if p.fields[scopeField] == Union {
+		u.Scope = unionScope(cs)
+	} else {
+		u.Scope = pick(cs, p.fields[scopeField], func(c *contribution) ([]string, bool) {
+			return c.user.Scope, len(c.user.Scope) > 0
+		})
+		if u.Scope == nil {
+			u.Scope = []string{}
+		}
+	}
+	return u
+}
+
+// pick returns the highest-precedence set value, preferring the origin named by strategy.
+func pick[T any](cs []contribution, strategy string, get func(*contribution) (T, bool)) T {
+	if strategy == PreferRemote || strategy == PreferLocal {
+		wantLocal := strategy == PreferLocal
+		for i := len(cs) - 1; i >= 0; i-- {
+			if (cs[i].origin == Local) != wantLocal {
+				continue
+			}
+			if v, ok := get(&cs[i]); ok {
+				return v
+			}
+		}
+	}
+	for i := len(cs) - 1; i >= 0; i-- {
+		if v, ok := get(&cs[i]); ok {
+			return v
+		}
+	}
+	var zero T
+	return zero
+}
+
+// unionScope returns the scopes of all contributions without duplicates, in first-seen order.
+func unionScope(cs []contribution) []string {
+	seen := make(map[string]struct{})
+	out := []string{}
+	for i := range cs {
+		for _, s := range cs[i].user.Scope {
+			if _, ok := seen[s]; ok {
+				continue
+			}
+			seen[s] = struct{}{}
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+package merge
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/define"
+)
+
+// phoneKey keys users by phone (like the loader does).
+func phoneKey(u define.AllowListUser) (string, bool) {
+	return u.Phone, u.Phone != ""
+}
+
+func TestNewPolicy(t *testing.T) {
+	p, err := NewPolicy(nil)
+	require.NoError(t, err)
+	assert.Nil(t, p)
+
+	_, err = NewPolicy(map[string]string{"Status": "Remote", "scope": "union"})
+	assert.NoError(t, err, "names and strategies are case-insensitive")
+
+	_, err = NewPolicy(map[string]string{"phone": "remote"})
+	assert.Error(t, err, "phone is the merge key")
+	_, err = NewPolicy(map[string]string{"name": "union"})
+	assert.Error(t, err, "union is scope only")
+	_, err = NewPolicy(map[string]string{"status": "newest"})
+	assert.Error(t, err)
+}
+
+func TestMerger_NoPolicyReplacesRecord(t *testing.T) {
+	m := NewMerger(nil, phoneKey)
+	local, remote := Source{Name: "/data/data.json"}, Source{Name: "https://example.com/users", Origin: Remote}
+	m.Add([]define.AllowListUser{{Phone: "1", Name: "Local", DingtalkUserID: "dt1"}}, local)
+	m.Add([]define.AllowListUser{{Phone: "1", Status: "active"}, {Mail: "nokey@example.com"}}, remote)
+
+	out := m.Users()
+	require.Len(t, out, 1)
+	assert.Empty(t, out[0].DingtalkUserID, "historical behaviour: the later record wins as a whole")
+	assert.Equal(t, map[string][]Source{"1": {local, remote}}, m.Sources(), "both sources are recorded")
+}
+
+func TestMerger_FieldPolicy(t *testing.T) {
+	p, err := NewPolicy(map[string]string{"status": PreferRemote, "scope": Union, "name": PreferLocal})
+	require.NoError(t, err)
+	m := NewMerger(p, phoneKey)
+	// LOCAL_FIRST order: remote added first, local takes precedence.
+	m.Add([]define.AllowListUser{
+		{Phone: "1", Status: "suspended", Scope: []string{"read", "admin"}, Name: "Remote Name", Role: "remote-role"},
+		{Phone: "2", Status: "active", Scope: []string{"read"}},
+	}, Source{Name: "remote", Origin: Remote})
+	m.Add([]define.AllowListUser{
+		{Phone: "1", Status: "active", Scope: []string{"write", "read"}, Name: "Local Name", DingtalkUserID: "dt1"},
+		{Phone: "3", Status: "active"},
+	}, Source{Name: "local"})
+
+	out := m.Users()
+	require.Len(t, out, 3)
+	assert.Equal(t, []string{"1", "2", "3"}, []string{out[0].Phone, out[1].Phone, out[2].Phone}, "first-seen order")
+
+	u := out[0]
+	assert.Equal(t, "suspended", u.Status, "status from remote even though local has precedence")
+	assert.Equal(t, []string{"read", "admin", "write"}, u.Scope)
+	assert.Equal(t, "Local Name", u.Name)
+	assert.Equal(t, "dt1", u.DingtalkUserID, "field missing in one source is kept from the other")
+	assert.Equal(t, "remote-role", u.Role, "unset locally, so the remote value fills in")
+}
+
+func TestMerger_PreferredOriginFallsBack(t *testing.T) {
+	p, err := NewPolicy(map[string]string{"name": PreferRemote, "scope": PreferRemote})
+	require.NoError(t, err)
+	m := NewMerger(p, phoneKey)
+	m.Add([]define.AllowListUser{{Phone: "1", Scope: []string{}}}, Source{Name: "remote", Origin: Remote})
+	m.Add([]define.AllowListUser{{Phone: "1", Name: "Local", Scope: []string{"read"}}}, Source{Name: "local"})
+
+	u := m.Users()[0]
+	assert.Equal(t, "Local", u.Name, "remote has no name")
+	assert.Equal(t, []string{"read"}, u.Scope)
+}
+
+func TestMerger_ExecCountsAsRemote(t *testing.T) {
+	p, err := NewPolicy(map[string]string{"status": PreferRemote, "name": PreferLocal})
+	require.NoError(t, err)
+	m := NewMerger(p, phoneKey)
+	m.Add([]define.AllowListUser{{Phone: "1", Status: "suspended", Name: "Exec"}}, Source{Name: "/usr/local/bin/roster", Origin: Exec})
+	m.Add([]define.AllowListUser{{Phone: "1", Status: "active", Name: "Local"}}, Source{Name: "local"})
+
+	u := m.Users()[0]
+	assert.Equal(t, "suspended", u.Status)
+	assert.Equal(t, "Local", u.Name)
+	assert.Equal(t, "exec", Exec.String())
+}
+
+func TestFieldNames(t *testing.T) {
+	names := FieldNames()
+	assert.Contains(t, names, "scope")
+	assert.Contains(t, names, "dingtalk_userid")
+	assert.NotContains(t, names, "phone")
+	assert.True(t, strings.Compare(names[0], names[len(names)-1]) < 0, "sorted")
+}
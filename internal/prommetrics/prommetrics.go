@@ -46,6 +46,65 @@ var (
 
 	// RateLimitHits records number of rate limit hits (legacy, uses ip label)
 	RateLimitHits *prometheus.CounterVec
+
+	// RemoteFetchTotal records number of remote source fetches
+	RemoteFetchTotal prometheus.Counter
+
+	// RemoteFetchSkipped records number of remote fetches skipped because the source was unchanged (HTTP 304)
+	RemoteFetchSkipped prometheus.Counter
+
+	// SourceUp records whether the last sync attempt of each data source succeeded (1) or failed (0)
+	SourceUp *prometheus.GaugeVec
+
+	// SourceRecords records number of records returned by the last successful sync of each data source
+	SourceRecords *prometheus.GaugeVec
+
+	// SourceLatency records duration of the last sync attempt of each data source
+	SourceLatency *prometheus.GaugeVec
+
+	// SourceLastAttempt records time of the last sync attempt of each data source
+	SourceLastAttempt *prometheus.GaugeVec
+
+	// SourceLastSuccess records time of the last successful sync of each data source
+	SourceLastSuccess *prometheus.GaugeVec
+
+	// SourceCircuitState records the circuit breaker state of each remote-class source (0 closed, 1 half-open, 2 open)
+	SourceCircuitState *prometheus.GaugeVec
+
+	// SourceCircuitOpens records number of times the circuit breaker of each remote-class source opened
+	SourceCircuitOpens *prometheus.CounterVec
+
+	// LoadIssues records number of dropped or conflicting records found when applying a dataset, by reason
+	LoadIssues *prometheus.CounterVec
+
+	// IdentityConflicts records number of identity conflicts in the dataset of the last load, by field
+	IdentityConflicts *prometheus.GaugeVec
+
+	// IdentityConflictsDetected records number of new identity conflicts detected while merging sources, by field and action
+	IdentityConflictsDetected *prometheus.CounterVec
+
+	// UserStoreLookups records number of lookups served by the per-user Redis store, by result
+	UserStoreLookups *prometheus.CounterVec
+
+	// DataVersion records the Redis cache version or user store generation of the data served by this instance
+	DataVersion prometheus.Gauge
+
+	// CacheNotifications records number of cache update notifications published and received, by direction
+	CacheNotifications *prometheus.CounterVec
+
+	// Leader records whether this instance is the leader that loads the sources (1) or a follower (0)
+	Leader prometheus.Gauge
+
+	// LeaderTransitions records number of times this instance acquired or lost the leadership, by event
+	LeaderTransitions *prometheus.CounterVec
+
+	// RedisPayloadBytes records the size of the last user list payload written to or read from Redis, by op and encoding
+	RedisPayloadBytes *prometheus.GaugeVec
+	// RedisPayloadDecodeDuration records time spent decoding user list payloads read from Redis, by encoding
+	RedisPayloadDecodeDuration *prometheus.HistogramVec
+
+	// MembershipFilterRejects records lookups answered as definite misses by the membership filter, by index
+	MembershipFilterRejects *prometheus.CounterVec
 )
 
 func init() {
@@ -99,6 +158,105 @@ func Init() {
 		Help("Total number of rate limit hits (legacy, by IP)").
 		Labels("ip").
 		BuildVec()
+
+	// Remote source metrics
+	RemoteFetchTotal = Registry.Counter("remote_fetch_total").
+		Help("Total number of remote source fetches").
+		Build()
+
+	RemoteFetchSkipped = Registry.Counter("remote_fetch_skipped_total").
+		Help("Total number of remote fetches skipped because the source was not modified").
+		Build()
+
+	// Per-source sync metrics (source = file path, URL or command, type = file/remote/git/exec)
+	SourceUp = Registry.Gauge("source_up").
+		Help("Whether the last sync attempt of the data source succeeded (1) or failed (0)").
+		Labels("source", "type").
+		BuildVec()
+
+	SourceRecords = Registry.Gauge("source_records").
+		Help("Number of records returned by the last successful sync of the data source").
+		Labels("source", "type").
+		BuildVec()
+
+	SourceLatency = Registry.Gauge("source_sync_latency_seconds").
+		Help("Duration of the last sync attempt of the data source in seconds").
+		Labels("source", "type").
+		BuildVec()
+
+	SourceLastAttempt = Registry.Gauge("source_last_attempt_timestamp_seconds").
+		Help("Unix time of the last sync attempt of the data source").
+		Labels("source", "type").
+		BuildVec()
+
+	SourceLastSuccess = Registry.Gauge("source_last_success_timestamp_seconds").
+		Help("Unix time of the last successful sync of the data source").
+		Labels("source", "type").
+		BuildVec()
+
+	SourceCircuitState = Registry.Gauge("source_circuit_state").
+		Help("Circuit breaker state of the data source (0 closed, 1 half-open, 2 open)").
+		Labels("source", "type").
+		BuildVec()
+
+	SourceCircuitOpens = Registry.Counter("source_circuit_opens_total").
+		Help("Total number of times the circuit breaker of the data source opened").
+		Labels("source", "type").
+		BuildVec()
+
+	LoadIssues = Registry.Counter("load_issues_total").
+		Help("Total number of dropped or conflicting records found when applying a dataset, by reason").
+		Labels("reason").
+		BuildVec()
+
+	IdentityConflicts = Registry.Gauge("identity_conflicts").
+		Help("Number of identity conflicts (user_id or mail shared by different users) in the dataset of the last load").
+		Labels("field").
+		BuildVec()
+
+	IdentityConflictsDetected = Registry.Counter("identity_conflicts_detected_total").
+		Help("Total number of new identity conflicts detected while merging sources").
+		Labels("field", "action").
+		BuildVec()
+
+	UserStoreLookups = Registry.Counter("user_store_lookups_total").
+		Help("Total number of lookups served by the per-user Redis store (result: local, redis, error)").
+		Labels("result").
+		BuildVec()
+
+	DataVersion = Registry.Gauge("data_version").
+		Help("Redis cache version or user store generation of the data served by this instance (0 = loaded from sources only)").
+		Build()
+
+	CacheNotifications = Registry.Counter("cache_notifications_total").
+		Help("Total number of cache update notifications (direction: published, received)").
+		Labels("direction").
+		BuildVec()
+
+	Leader = Registry.Gauge("leader").
+		Help("Whether this instance is the leader that loads the sources and writes Redis (1 = leader, 0 = follower)").
+		Build()
+
+	LeaderTransitions = Registry.Counter("leader_transitions_total").
+		Help("Total number of leadership changes of this instance (event: acquired, lost)").
+		Labels("event").
+		BuildVec()
+
+	RedisPayloadBytes = Registry.Gauge("redis_payload_bytes").
+		Help("Size in bytes of the last user list payload written to or read from Redis (op: write, read; encoding: json, zstd)").
+		Labels("op", "encoding").
+		BuildVec()
+
+	RedisPayloadDecodeDuration = Registry.Histogram("redis_payload_decode_duration_seconds").
+		Help("Time spent decoding user list payloads read from Redis in seconds").
+		Labels("encoding").
+		Buckets(metricskit.DefaultBuckets()).
+		BuildVec()
+
+	MembershipFilterRejects = Registry.Counter("membership_filter_rejects_total").
+		Help("Total number of lookups answered as definite misses by the membership filter (index: phone, mail, user_id)").
+		Labels("index").
+		BuildVec()
 }
 
 // Handler returns Prometheus metrics endpoint handler
@@ -142,3 +300,92 @@ func RecordRateLimitHit(ip string) {
 	// Also record in the new metrics with "ip" scope
 	RateLimit.RecordHit("ip")
 }
+
+// RecordRemoteFetch records a remote source fetch; notModified marks a fetch answered with 304
+func RecordRemoteFetch(notModified bool) {
+	RemoteFetchTotal.Inc()
+	if notModified {
+		RemoteFetchSkipped.Inc()
+	}
+}
+
+// RecordSourceSync records a sync attempt of a data source started at attempt.
+// On failure records and the last success time keep their previous values.
+func RecordSourceSync(source, sourceType string, attempt time.Time, latency time.Duration, records int, err error) {
+	SourceLastAttempt.WithLabelValues(source, sourceType).Set(float64(attempt.Unix()))
+	SourceLatency.WithLabelValues(source, sourceType).Set(latency.Seconds())
+	if err != nil {
+		SourceUp.WithLabelValues(source, sourceType).Set(0)
+		return
+	}
+	SourceUp.WithLabelValues(source, sourceType).Set(1)
+	SourceRecords.WithLabelValues(source, sourceType).Set(float64(records))
+	SourceLastSuccess.WithLabelValues(source, sourceType).Set(float64(attempt.Add(latency).Unix()))
+}
+
+// RecordCircuitState records the circuit breaker state of a data source; opened counts a transition to open.
+func RecordCircuitState(source, sourceType string, state int, opened bool) {
+	SourceCircuitState.WithLabelValues(source, sourceType).Set(float64(state))
+	if opened {
+		SourceCircuitOpens.WithLabelValues(source, sourceType).Inc()
+	}
+}
+
+// RecordLoadIssues adds the issue counts of a load report (reason -> count)
+func RecordLoadIssues(reasons map[string]int) {
+	for reason, n := range reasons {
+		LoadIssues.WithLabelValues(reason).Add(float64(n))
+	}
+}
+
+// RecordIdentityConflicts sets the current conflict count per field and counts newly detected conflicts
+// (field -> action -> count)
+func RecordIdentityConflicts(current map[string]int, detected map[string]map[string]int) {
+	for field, n := range current {
+		IdentityConflicts.WithLabelValues(field).Set(float64(n))
+	}
+	for field, byAction := range detected {
+		for action, n := range byAction {
+			IdentityConflictsDetected.WithLabelValues(field, action).Add(float64(n))
+		}
+	}
+}
+
+// RecordUserStoreLookup records a per-user store lookup answered from the local LRU ("local"), from Redis
+// ("redis") or by the memory cache after a Redis error ("error")
+func RecordUserStoreLookup(result string) {
+	UserStoreLookups.WithLabelValues(result).Inc()
+}
+
+// RecordLeadership records that this instance acquired (true) or lost (false) the leadership
+func RecordLeadership(leader bool) {
+	if leader {
+		Leader.Set(1)
+		LeaderTransitions.WithLabelValues("acquired").Inc()
+		return
+	}
+	Leader.Set(0)
+	LeaderTransitions.WithLabelValues("lost").Inc()
+}
+
+// RecordRedisPayload records the size of a user list payload written to ("write") or read from ("read")
+// Redis; decode is the decode time of a read
+func RecordRedisPayload(op, encoding string, size int, decode time.Duration) {
+	RedisPayloadBytes.WithLabelValues(op, encoding).Set(float64(size))
+	if op == "read" {
+		RedisPayloadDecodeDuration.WithLabelValues(encoding).Observe(decode.Seconds())
+	}
+}
+
+// RecordMembershipFilterReject records a lookup by index that the membership filter ruled out
+func RecordMembershipFilterReject(index string) {
+	MembershipFilterRejects.WithLabelValues(index).Inc()
+}
+
+// DeleteSource removes the metrics of a data source that is no longer configured
+func DeleteSource(source, sourceType string) {
+	for _, g := range []*prometheus.GaugeVec{SourceUp, SourceRecords, SourceLatency, SourceLastAttempt, SourceLastSuccess, SourceCircuitState} {
+		g.DeleteLabelValues(source, sourceType)
+	}
+	SourceCircuitOpens.DeleteLabelValues(source, sourceType)
+}
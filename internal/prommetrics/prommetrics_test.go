@@ -1,9 +1,11 @@
 package prommetrics
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -129,6 +131,8 @@ func TestMetrics_VariablesInitialized(t *testing.T) {
 	assert.NotNil(t, CacheHits, "CacheHits应该已初始化")
 	assert.NotNil(t, CacheMisses, "CacheMisses应该已初始化")
 	assert.NotNil(t, RateLimitHits, "RateLimitHits应该已初始化")
+	assert.NotNil(t, RemoteFetchTotal, "RemoteFetchTotal应该已初始化")
+	assert.NotNil(t, RemoteFetchSkipped, "RemoteFetchSkipped应该已初始化")
 }
 
 // TestRecordFunctions covers RecordHTTPRequest, RecordCacheHit, RecordCacheMiss,
@@ -159,3 +163,132 @@ func TestRecordBackgroundTask(t *testing.T) {
 func TestRecordRateLimitHit(t *testing.T) {
 	RecordRateLimitHit("127.0.0.1")
 }
+
+func TestRecordRemoteFetch(t *testing.T) {
+	RecordRemoteFetch(false)
+	RecordRemoteFetch(true)
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+	assert.Contains(t, rr.Body.String(), "warden_remote_fetch_skipped_total")
+}
+
+func TestRecordSourceSync(t *testing.T) {
+	RecordSourceSync("/data/a.json", "file", time.Now(), 0, 3, nil)
+	RecordSourceSync("https://example.com/users", "remote", time.Now(), 0, 0, errors.New("timeout"))
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+	body := rr.Body.String()
+	assert.Contains(t, body, `warden_source_records{source="/data/a.json",type="file"} 3`)
+	assert.Contains(t, body, `warden_source_up{source="https://example.com/users",type="remote"} 0`)
+
+	DeleteSource("/data/a.json", "file")
+	rr = httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+	assert.NotContains(t, rr.Body.String(), `source="/data/a.json"`)
+}
+
+func TestRecordCircuitState(t *testing.T) {
+	RecordCircuitState("https://example.com/roster", "remote", 2, true)
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+	body := rr.Body.String()
+	assert.Contains(t, body, `warden_source_circuit_state{source="https://example.com/roster",type="remote"} 2`)
+	assert.Contains(t, body, `warden_source_circuit_opens_total{source="https://example.com/roster",type="remote"} 1`)
+
+	DeleteSource("https://example.com/roster", "remote")
+	rr = httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+	assert.NotContains(t, rr.Body.String(), `source="https://example.com/roster"`)
+}
+
+func TestRecordLoadIssues(t *testing.T) {
+	Init()
+	RecordLoadIssues(map[string]int{"invalid_phone": 2, "duplicate_key": 1})
+	RecordLoadIssues(map[string]int{"invalid_phone": 1})
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+	body := rr.Body.String()
+	assert.Contains(t, body, `warden_load_issues_total{reason="invalid_phone"} 3`)
+	assert.Contains(t, body, `warden_load_issues_total{reason="duplicate_key"} 1`)
+}
+
+func TestRecordIdentityConflicts(t *testing.T) {
+	Init()
+	RecordIdentityConflicts(map[string]int{"user_id": 2, "mail": 0}, map[string]map[string]int{"user_id": {"reject": 2}})
+	RecordIdentityConflicts(map[string]int{"user_id": 1, "mail": 0}, nil)
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+	body := rr.Body.String()
+	assert.Contains(t, body, `warden_identity_conflicts{field="user_id"} 1`)
+	assert.Contains(t, body, `warden_identity_conflicts{field="mail"} 0`)
+	assert.Contains(t, body, `warden_identity_conflicts_detected_total{action="reject",field="user_id"} 2`)
+}
+
+func TestRecordUserStoreLookup(t *testing.T) {
+	Init()
+	RecordUserStoreLookup("local")
+	RecordUserStoreLookup("local")
+	RecordUserStoreLookup("redis")
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+	body := rr.Body.String()
+	assert.Contains(t, body, `warden_user_store_lookups_total{result="local"} 2`)
+	assert.Contains(t, body, `warden_user_store_lookups_total{result="redis"} 1`)
+}
+
+func TestCacheSyncMetrics(t *testing.T) {
+	Init()
+	DataVersion.Set(7)
+	CacheNotifications.WithLabelValues("published").Inc()
+	CacheNotifications.WithLabelValues("received").Add(2)
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+	body := rr.Body.String()
+	assert.Contains(t, body, `warden_data_version 7`)
+	assert.Contains(t, body, `warden_cache_notifications_total{direction="published"} 1`)
+	assert.Contains(t, body, `warden_cache_notifications_total{direction="received"} 2`)
+}
+
+func TestRecordRedisPayload(t *testing.T) {
+	Init()
+	RecordRedisPayload("write", "zstd", 2048, 0)
+	RecordRedisPayload("read", "zstd", 1024, 5*time.Millisecond)
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+	body := rr.Body.String()
+	assert.Contains(t, body, `warden_redis_payload_bytes{encoding="zstd",op="write"} 2048`)
+	assert.Contains(t, body, `warden_redis_payload_bytes{encoding="zstd",op="read"} 1024`)
+	assert.Contains(t, body, `warden_redis_payload_decode_duration_seconds_count{encoding="zstd"} 1`)
+}
+
+func TestRecordMembershipFilterReject(t *testing.T) {
+	Init()
+	RecordMembershipFilterReject("mail")
+	RecordMembershipFilterReject("mail")
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+	assert.Contains(t, rr.Body.String(), `warden_membership_filter_rejects_total{index="mail"} 2`)
+}
+
+func TestRecordLeadership(t *testing.T) {
+	Init()
+	RecordLeadership(true)
+	RecordLeadership(false)
+	RecordLeadership(true)
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+	body := rr.Body.String()
+	assert.Contains(t, body, `warden_leader 1`)
+	assert.Contains(t, body, `warden_leader_transitions_total{event="acquired"} 2`)
+	assert.Contains(t, body, `warden_leader_transitions_total{event="lost"} 1`)
+}
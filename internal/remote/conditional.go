@@ -0,0 +1,69 @@
+// Package remote provides remote config fetch with optional RSA decryption.
+// conditional.go: conditional requests (ETag / Last-Modified) so unchanged payloads are not re-downloaded.
+package remote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/soulteary/warden/internal/define"
+)
+
+// ErrNotModified is returned when the remote answered 304 Not Modified to a conditional request.
+var ErrNotModified = errors.New("remote fetch: not modified")
+
+// Validators are the cache validators of a previous response.
+type Validators struct {
+	ETag         string // sent back as If-None-Match
+	LastModified string // sent back as If-Modified-Since
+}
+
+// IsZero reports whether no validator is set (the request is unconditional).
+func (v Validators) IsZero() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+// apply sets the conditional request headers on req.
+func (v Validators) apply(req *http.Request) {
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// validatorsFromHeader extracts ETag / Last-Modified from response headers.
+func validatorsFromHeader(h http.Header) Validators {
+	return Validators{
+		ETag:         strings.TrimSpace(h.Get("ETag")),
+		LastModified: strings.TrimSpace(h.Get("Last-Modified")),
+	}
+}
+
+// FetchUsersConditional fetches rawURL like FetchPaginatedUsers, sending prev as If-None-Match / If-Modified-Since.
+// It returns the validators of the new response, or ErrNotModified when the remote answered 304.
+// Paginated sources are always fetched in full (an unchanged first page says nothing about the others),
+// so they return zero Validators.
+func FetchUsersConditional(ctx context.Context, rawURL string, p Pagination, opts *FetchOptions, prev Validators) ([]define.AllowListUser, Validators, error) {
+	if opts == nil {
+		opts = &FetchOptions{}
+	}
+	if p.Enabled() {
+		users, err := FetchPaginatedUsers(ctx, rawURL, p, opts)
+		return users, Validators{}, err
+	}
+	o := *opts
+	o.Conditional = prev
+	body, header, err := fetch(ctx, rawURL, &o, define.MAX_JSON_SIZE)
+	if err != nil {
+		return nil, Validators{}, err
+	}
+	users, _, err := decodePage(body, p.withDefaults().ItemsField)
+	if err != nil {
+		return nil, Validators{}, err
+	}
+	return users, validatorsFromHeader(header), nil
+}
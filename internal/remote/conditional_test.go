@@ -0,0 +1,76 @@
+package remote
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchUsersConditional(t *testing.T) {
+	const lastModified = "Wed, 21 Oct 2026 07:28:00 GMT"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"abc"` && r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"abc"`)
+		w.Header().Set("Last-Modified", lastModified)
+		writeJSON(t, w, makeUsers(0, 2))
+	}))
+	defer srv.Close()
+
+	users, v, err := FetchUsersConditional(context.Background(), srv.URL, Pagination{}, &FetchOptions{Timeout: testTimeout}, Validators{})
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, Validators{ETag: `"abc"`, LastModified: lastModified}, v)
+
+	_, _, err = FetchUsersConditional(context.Background(), srv.URL, Pagination{}, &FetchOptions{Timeout: testTimeout}, v)
+	assert.ErrorIs(t, err, ErrNotModified)
+}
+
+func TestFetchUsersConditional_PaginatedIgnoresValidators(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("If-None-Match"))
+		w.Header().Set("ETag", `"abc"`)
+		if r.URL.Query().Get("page") != "1" {
+			writeJSON(t, w, makeUsers(0, 0))
+			return
+		}
+		writeJSON(t, w, makeUsers(0, 1))
+	}))
+	defer srv.Close()
+
+	p := Pagination{Strategy: PaginationPage, PageSize: 2}
+	users, v, err := FetchUsersConditional(context.Background(), srv.URL, p, &FetchOptions{Timeout: testTimeout}, Validators{ETag: `"abc"`})
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.True(t, v.IsZero())
+}
+
+func TestFetch_RetriesTransientStatus(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(t, w, makeUsers(0, 1))
+	}))
+	defer srv.Close()
+
+	opts := &FetchOptions{Timeout: testTimeout, Retries: 2, RetryDelay: time.Millisecond}
+	users, _, err := FetchUsersConditional(context.Background(), srv.URL, Pagination{}, opts, Validators{})
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.Equal(t, 3, calls)
+
+	calls = -10
+	_, _, err = FetchUsersConditional(context.Background(), srv.URL, Pagination{}, opts, Validators{})
+	require.Error(t, err, "gives up after Retries extra attempts")
+}
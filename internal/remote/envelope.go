@@ -0,0 +1,337 @@
+// Package remote provides remote config fetch with optional RSA decryption.
+// envelope.go: versioned AES-GCM envelope with RSA-OAEP or X25519 key wrapping and key IDs for rotation.
+package remote
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// EnvelopeContentType is the Content-Type value when the response body is a versioned envelope.
+	EnvelopeContentType = "application/x-warden-envelope+json"
+	// EnvelopeVersion is the current envelope format version.
+	EnvelopeVersion = 1
+	// AlgRSAOAEP wraps the content key with RSA-OAEP (SHA-256); any RSA key of 2048 bits or more.
+	AlgRSAOAEP = "RSA-OAEP-256+A256GCM"
+	// AlgX25519 derives the content key from an X25519 exchange with an ephemeral key via HKDF-SHA256.
+	AlgX25519 = "X25519-HKDF-SHA256+A256GCM"
+
+	// minRSABits is the smallest accepted RSA key size.
+	minRSABits = 2048
+	// gcmNonceSize is the AES-GCM nonce size in bytes.
+	gcmNonceSize = 12
+	// x25519Info is the HKDF info string binding derived keys to this format.
+	x25519Info = "warden-envelope-v1"
+)
+
+// ErrUnknownKeyID is returned when an envelope names a key ID that is not configured.
+var ErrUnknownKeyID = errors.New("remote decrypt: unknown key id")
+
+// KeySpec is a private key file used to open envelopes. ID must match the envelope "kid";
+// a key with an empty ID is tried for any envelope.
+type KeySpec struct {
+	ID   string
+	File string
+}
+
+// envelope is the JSON wire format. Binary fields are standard base64.
+//
+//nolint:govet // fieldalignment: keep field order matching the wire format
+type envelope struct {
+	Version int    `json:"v"`
+	Alg     string `json:"alg"`
+	KeyID   string `json:"kid,omitempty"`
+	EncKey  string `json:"ek,omitempty"`  // RSA-OAEP wrapped content key
+	EphPub  string `json:"epk,omitempty"` // X25519 ephemeral public key
+	Nonce   string `json:"iv"`
+	Data    string `json:"ct"` // AES-256-GCM ciphertext || tag
+}
+
+// aad binds the header fields to the ciphertext so they cannot be swapped.
+func (e *envelope) aad() []byte {
+	return fmt.Appendf(nil, "%d|%s|%s", e.Version, e.Alg, e.KeyID)
+}
+
+// privateKey is one decryption key of a keyring.
+type privateKey struct {
+	id  string
+	key any // *rsa.PrivateKey or *ecdh.PrivateKey (X25519)
+}
+
+// keyring holds the decryption keys of a fetch.
+type keyring []privateKey
+
+// loadKeyring loads the legacy single RSA key (path or PEM, unnamed) and opts.Keys.
+// Keys are read on every fetch so rotated key files take effect without a restart.
+func loadKeyring(opts *FetchOptions) (keyring, error) {
+	var ring keyring
+	if opts.RSAKeyPath != "" || opts.RSAKeyPEM != "" {
+		k, err := loadRSAPrivateKey(opts.RSAKeyPath, opts.RSAKeyPEM)
+		if err != nil {
+			return nil, err
+		}
+		ring = append(ring, privateKey{key: k})
+	}
+	for _, spec := range opts.Keys {
+		data, err := os.ReadFile(filepath.Clean(spec.File)) // #nosec G304 -- path from config
+		if err != nil {
+			return nil, err
+		}
+		k, err := ParsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", spec.File, err)
+		}
+		ring = append(ring, privateKey{id: spec.ID, key: k})
+	}
+	if len(ring) == 0 {
+		return nil, fmt.Errorf("no private key configured")
+	}
+	return ring, nil
+}
+
+// rsaKeys returns the RSA keys of the ring (the only kind usable for the legacy format).
+func (r keyring) rsaKeys() []*rsa.PrivateKey {
+	var out []*rsa.PrivateKey
+	for _, k := range r {
+		if rk, ok := k.key.(*rsa.PrivateKey); ok {
+			out = append(out, rk)
+		}
+	}
+	return out
+}
+
+// candidates returns the keys to try for kid: keys with that ID, else unnamed keys.
+// An empty kid tries every key.
+func (r keyring) candidates(kid string) ([]privateKey, error) {
+	if kid == "" {
+		return r, nil
+	}
+	var named, unnamed []privateKey
+	for _, k := range r {
+		switch k.id {
+		case kid:
+			named = append(named, k)
+		case "":
+			unnamed = append(unnamed, k)
+		}
+	}
+	if len(named) > 0 {
+		return named, nil
+	}
+	if len(unnamed) > 0 {
+		return unnamed, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownKeyID, kid)
+}
+
+// ParsePrivateKey parses an RSA (PKCS#1 or PKCS#8, at least 2048 bits) or X25519 (PKCS#8) private key PEM.
+func ParsePrivateKey(data []byte) (any, error) {
+	block, _ := pem.Decode(bytes.TrimSpace(data))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block in key source")
+	}
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return checkRSASize(k)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return checkRSASize(k)
+	case *ecdh.PrivateKey:
+		if k.Curve() != ecdh.X25519() {
+			return nil, fmt.Errorf("unsupported ECDH curve (want X25519)")
+		}
+		return k, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T (want RSA or X25519)", key)
+	}
+}
+
+// checkRSASize rejects RSA keys below minRSABits.
+func checkRSASize(k *rsa.PrivateKey) (*rsa.PrivateKey, error) {
+	if k.N.BitLen() < minRSABits {
+		return nil, fmt.Errorf("RSA key is %d bits, need at least %d", k.N.BitLen(), minRSABits)
+	}
+	return k, nil
+}
+
+// openEnvelope decrypts an EnvelopeContentType body with the first matching key of ring.
+func openEnvelope(body []byte, ring keyring) ([]byte, error) {
+	var env envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return nil, fmt.Errorf("envelope: %w", err)
+	}
+	if env.Version != EnvelopeVersion {
+		return nil, fmt.Errorf("envelope: unsupported version %d", env.Version)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(env.Nonce)
+	if err != nil || len(nonce) != gcmNonceSize {
+		return nil, fmt.Errorf("envelope: invalid iv")
+	}
+	ct, err := base64.StdEncoding.DecodeString(env.Data)
+	if err != nil {
+		return nil, fmt.Errorf("envelope: ct: %w", err)
+	}
+	keys, err := ring.candidates(env.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	tried := false
+	for _, k := range keys {
+		var cek []byte
+		switch env.Alg {
+		case AlgRSAOAEP:
+			rk, ok := k.key.(*rsa.PrivateKey)
+			if !ok {
+				continue
+			}
+			cek, err = unwrapRSA(&env, rk)
+		case AlgX25519:
+			xk, ok := k.key.(*ecdh.PrivateKey)
+			if !ok {
+				continue
+			}
+			cek, err = deriveX25519(&env, xk)
+		default:
+			return nil, fmt.Errorf("envelope: unsupported alg %q", env.Alg)
+		}
+		tried = true
+		if err == nil {
+			var plain []byte
+			if plain, err = gcmOpen(cek, nonce, ct, env.aad()); err == nil {
+				return plain, nil
+			}
+		}
+		lastErr = err
+	}
+	if !tried {
+		return nil, fmt.Errorf("envelope: no %s key configured for kid %q", env.Alg, env.KeyID)
+	}
+	return nil, fmt.Errorf("envelope: %w", lastErr)
+}
+
+// unwrapRSA decrypts the RSA-OAEP wrapped content key.
+func unwrapRSA(env *envelope, priv *rsa.PrivateKey) ([]byte, error) {
+	ek, err := base64.StdEncoding.DecodeString(env.EncKey)
+	if err != nil {
+		return nil, fmt.Errorf("ek: %w", err)
+	}
+	cek, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, priv, ek, nil)
+	if err != nil {
+		return nil, fmt.Errorf("rsa decrypt: %w", err)
+	}
+	if len(cek) != AESKeySize {
+		return nil, fmt.Errorf("content key has %d bytes, want %d", len(cek), AESKeySize)
+	}
+	return cek, nil
+}
+
+// deriveX25519 derives the content key from the envelope's ephemeral public key and priv.
+func deriveX25519(env *envelope, priv *ecdh.PrivateKey) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(env.EphPub)
+	if err != nil {
+		return nil, fmt.Errorf("epk: %w", err)
+	}
+	eph, err := ecdh.X25519().NewPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("epk: %w", err)
+	}
+	return x25519KDF(priv, eph, eph, priv.PublicKey())
+}
+
+// x25519KDF runs the exchange and HKDF; the salt binds both public keys (ephemeral, recipient).
+func x25519KDF(priv *ecdh.PrivateKey, peer, ephPub, recipient *ecdh.PublicKey) ([]byte, error) {
+	shared, err := priv.ECDH(peer)
+	if err != nil {
+		return nil, fmt.Errorf("x25519: %w", err)
+	}
+	salt := append(append([]byte{}, ephPub.Bytes()...), recipient.Bytes()...)
+	return hkdf.Key(sha256.New, shared, salt, x25519Info, AESKeySize)
+}
+
+// gcmOpen decrypts and authenticates ct with AES-256-GCM.
+func gcmOpen(key, nonce, ct, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, nonce, ct, aad)
+	if err != nil {
+		return nil, fmt.Errorf("aes-gcm: %w", err)
+	}
+	return plain, nil
+}
+
+// SealEnvelope encrypts plaintext for pub (*rsa.PublicKey or X25519 *ecdh.PublicKey) and returns the
+// JSON envelope to be served with EnvelopeContentType. kid names the recipient key (may be empty).
+func SealEnvelope(kid string, pub any, plaintext []byte) ([]byte, error) {
+	env := envelope{Version: EnvelopeVersion, KeyID: strings.TrimSpace(kid)}
+	var cek []byte
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		env.Alg = AlgRSAOAEP
+		cek = make([]byte, AESKeySize)
+		if _, err := rand.Read(cek); err != nil {
+			return nil, err
+		}
+		ek, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, k, cek, nil)
+		if err != nil {
+			return nil, err
+		}
+		env.EncKey = base64.StdEncoding.EncodeToString(ek)
+	case *ecdh.PublicKey:
+		if k.Curve() != ecdh.X25519() {
+			return nil, fmt.Errorf("unsupported ECDH curve (want X25519)")
+		}
+		eph, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		if cek, err = x25519KDF(eph, k, eph.PublicKey(), k); err != nil {
+			return nil, err
+		}
+		env.Alg = AlgX25519
+		env.EphPub = base64.StdEncoding.EncodeToString(eph.PublicKey().Bytes())
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T (want RSA or X25519)", pub)
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcmNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	env.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	env.Data = base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, env.aad()))
+	return json.Marshal(&env)
+}
@@ -0,0 +1,162 @@
+package remote
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePKCS8 writes key as a PKCS#8 PEM file and returns its path.
+func writePKCS8(t *testing.T, dir, name string, key any) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return path
+}
+
+// serveEnvelope starts a server answering every request with body and contentType.
+func serveEnvelope(t *testing.T, contentType string, body []byte) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		_, _ = w.Write(body) //nolint:errcheck // test server
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestEnvelope_RoundTripRSA3072AndX25519(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 3072)
+	require.NoError(t, err)
+	xKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	opts := &FetchOptions{
+		DecryptEnabled: true,
+		Timeout:        testTimeout,
+		Keys: []KeySpec{
+			{ID: "rsa-2026", File: writePKCS8(t, dir, "rsa.pem", rsaKey)},
+			{ID: "x-2026", File: writePKCS8(t, dir, "x.pem", xKey)},
+		},
+	}
+
+	plaintext := []byte(`[{"phone":"13800138000"}]`)
+	for kid, pub := range map[string]any{"rsa-2026": &rsaKey.PublicKey, "x-2026": xKey.PublicKey()} {
+		sealed, err := SealEnvelope(kid, pub, plaintext)
+		require.NoError(t, err)
+		url := serveEnvelope(t, EnvelopeContentType+"; charset=utf-8", sealed)
+		body, _, err := fetch(context.Background(), url, opts, 1<<20)
+		require.NoError(t, err, kid)
+		assert.Equal(t, plaintext, body, kid)
+	}
+}
+
+func TestEnvelope_TamperedRejected(t *testing.T) {
+	xKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ring := keyring{{id: "k1", key: xKey}}
+	sealed, err := SealEnvelope("k1", xKey.PublicKey(), []byte(`[]`))
+	require.NoError(t, err)
+
+	var env envelope
+	require.NoError(t, json.Unmarshal(sealed, &env))
+	ct, err := base64.StdEncoding.DecodeString(env.Data)
+	require.NoError(t, err)
+	ct[0] ^= 1
+	env.Data = base64.StdEncoding.EncodeToString(ct)
+	tampered, err := json.Marshal(&env)
+	require.NoError(t, err)
+	_, err = openEnvelope(tampered, ring)
+	assert.Error(t, err, "modified ciphertext")
+
+	// The header is authenticated too: relabelling the key id breaks the tag.
+	require.NoError(t, json.Unmarshal(sealed, &env))
+	env.KeyID = "k2"
+	relabelled, err := json.Marshal(&env)
+	require.NoError(t, err)
+	_, err = openEnvelope(relabelled, append(ring, privateKey{id: "k2", key: xKey}))
+	assert.Error(t, err)
+}
+
+func TestEnvelope_KeySelection(t *testing.T) {
+	oldKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	newKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ring := keyring{{id: "old", key: oldKey}, {id: "new", key: newKey}}
+
+	for _, kid := range []string{"old", "new"} {
+		pub := oldKey.PublicKey()
+		if kid == "new" {
+			pub = newKey.PublicKey()
+		}
+		sealed, err := SealEnvelope(kid, pub, []byte(kid))
+		require.NoError(t, err)
+		plain, err := openEnvelope(sealed, ring)
+		require.NoError(t, err)
+		assert.Equal(t, kid, string(plain))
+	}
+
+	// Without a kid every key is tried.
+	sealed, err := SealEnvelope("", newKey.PublicKey(), []byte("x"))
+	require.NoError(t, err)
+	_, err = openEnvelope(sealed, ring)
+	assert.NoError(t, err)
+
+	sealed, err = SealEnvelope("retired", newKey.PublicKey(), []byte("x"))
+	require.NoError(t, err)
+	_, err = openEnvelope(sealed, ring)
+	assert.ErrorIs(t, err, ErrUnknownKeyID)
+}
+
+func TestReadBody_LegacyRequiresFlag(t *testing.T) {
+	dir := t.TempDir()
+	priv, err := rsa.GenerateKey(rand.Reader, 4096)
+	require.NoError(t, err)
+	keyPath := writePKCS8(t, dir, "rsa.pem", priv)
+	plaintext := []byte(`[{"phone":"13800138000","mail":"a@example.com"}]`)
+	body := sealLegacy(t, &priv.PublicKey, plaintext)
+	url := serveEnvelope(t, EncryptedContentType, body)
+
+	opts := &FetchOptions{DecryptEnabled: true, RSAKeyPath: keyPath, Timeout: testTimeout}
+	_, _, err = fetch(context.Background(), url, opts, 1<<20)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "REMOTE_DECRYPT_LEGACY")
+
+	opts.LegacyDecrypt = true
+	dec, _, err := fetch(context.Background(), url, opts, 1<<20)
+	require.NoError(t, err, "legacy format works with RSA-4096 keys")
+	assert.Equal(t, plaintext, dec)
+}
+
+func TestParsePrivateKey_Errors(t *testing.T) {
+	_, err := ParsePrivateKey([]byte("nope"))
+	assert.Error(t, err)
+
+	small, err := rsa.GenerateKey(rand.Reader, 1024) //nolint:gosec // testing the size check
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(small)
+	require.NoError(t, err)
+	_, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	assert.Error(t, err)
+
+	p256, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, err = SealEnvelope("", p256.PublicKey(), nil)
+	assert.Error(t, err)
+}
@@ -9,10 +9,8 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
-	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
-	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"io"
@@ -22,13 +20,14 @@ import (
 	"strings"
 	"time"
 
-	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/internal/signature"
 )
 
 const (
 	// EncryptedContentType is the Content-Type value when response body is RSA+AES encrypted.
 	EncryptedContentType = "application/x-warden-encrypted"
 	// RSAKeySize2048 ciphertext block size in bytes.
+	// Deprecated: the legacy format now uses the size of the configured key; kept for compatibility.
 	RSAKeySize2048 = 256
 	// AESKeySize + IVSize = 32 + 16 = 48 bytes encrypted by RSA.
 	AESKeySize = 32
@@ -36,54 +35,179 @@ const (
 	IVSize = 16
 )
 
-// FetchDecrypted fetches url with optional auth header. If decryptEnabled and rsaKey (file path or PEM) are set,
-// and response Content-Type is EncryptedContentType (or body looks like base64), decrypts with RSA private key.
-// Expected encrypted format: base64( RSA-OAEP_SHA256(aes_key_32 + iv_16) || aes_ctr_ciphertext ).
-// Returns decrypted or raw body and error.
-// rsaKeyPath and rsaKeyPEM: use file when rsaKeyPath is non-empty, else use rsaKeyPEM (inline PEM).
-func FetchDecrypted(ctx context.Context, url, authHeader string, decryptEnabled bool, rsaKeyPath, rsaKeyPEM string, timeout time.Duration, insecureTLS bool) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+// FetchOptions holds the request options shared by all remote fetch helpers.
+//
+//nolint:govet // fieldalignment: keep field order for readability
+type FetchOptions struct {
+	AuthHeader     string              // Authorization header value (optional)
+	RSAKeyPath     string              // RSA private key PEM file (preferred over RSAKeyPEM)
+	RSAKeyPEM      string              // inline RSA private key PEM
+	Timeout        time.Duration       // per-request timeout
+	DecryptEnabled bool                // decrypt EncryptedContentType responses
+	InsecureTLS    bool                // skip TLS verification (development only)
+	Retries        int                 // extra attempts on network errors and retryable status codes
+	RetryDelay     time.Duration       // delay before the first retry, doubled on each further retry
+	Conditional    Validators          // validators of the previous response (If-None-Match / If-Modified-Since)
+	Verifier       *signature.Verifier // when set, every response must carry a valid signature.SignatureHeader
+	Keys           []KeySpec           // additional private keys (with key IDs) for EnvelopeContentType responses
+	LegacyDecrypt  bool                // also accept the unauthenticated EncryptedContentType format (AES-CTR)
+	TLS            *TLSOptions         // CA bundle and client certificate (mTLS); nil = system roots, no client certificate
+	Token          *TokenSource        // OAuth2 bearer token, used instead of AuthHeader when set
+}
+
+// retryableStatus reports whether a response status is worth retrying (same set as parser-kit).
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// decryptConfigured reports whether decryption is enabled and a key source is set.
+func (o *FetchOptions) decryptConfigured() bool {
+	return o.DecryptEnabled && (o.RSAKeyPath != "" || o.RSAKeyPEM != "" || len(o.Keys) > 0)
+}
+
+// hasContentType reports whether the Content-Type header ct is want (parameters ignored).
+func hasContentType(ct, want string) bool {
+	ct = strings.TrimSpace(strings.ToLower(ct))
+	return ct == want || strings.HasPrefix(ct, want+";")
+}
+
+// HTTPClient builds the HTTP client for opts: Timeout, InsecureTLS and TLS (CA bundle, client certificate).
+// Other clients talking to the remote's infrastructure, such as the OAuth2 token client, use it too.
+func (o *FetchOptions) HTTPClient() (*http.Client, error) {
+	client := &http.Client{Timeout: o.Timeout}
+	if o.InsecureTLS || !o.TLS.IsZero() {
+		tlsCfg, err := o.TLS.Config(o.InsecureTLS)
+		if err != nil {
+			return nil, fmt.Errorf("remote fetch: tls: %w", err)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:errcheck,forcetypeassert // DefaultTransport is always *http.Transport
+		transport.TLSClientConfig = tlsCfg
+		client.Transport = transport
+	}
+	return client, nil
+}
+
+// fetch performs a GET against url and returns the (optionally decrypted) body and response headers.
+// Network errors and retryable status codes are retried opts.Retries times with exponential backoff.
+// A 304 response to a conditional request returns ErrNotModified.
+// Bodies larger than maxBytes are rejected instead of being silently truncated.
+func fetch(ctx context.Context, url string, opts *FetchOptions, maxBytes int64) ([]byte, http.Header, error) {
+	client, err := opts.HTTPClient()
 	if err != nil {
-		return nil, fmt.Errorf("remote fetch: %w", err)
+		return nil, nil, err
+	}
+	delay := opts.RetryDelay
+	for attempt := 0; ; attempt++ {
+		body, header, retry, err := fetchOnce(ctx, client, url, opts, maxBytes)
+		if err == nil || !retry || attempt >= opts.Retries {
+			return body, header, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, fmt.Errorf("remote fetch: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+		delay *= 2
 	}
-	if authHeader != "" {
-		req.Header.Set("Authorization", authHeader)
+}
+
+// fetchOnce performs a single request; retry reports whether the failure is transient.
+func fetchOnce(ctx context.Context, client *http.Client, url string, opts *FetchOptions, maxBytes int64) (body []byte, header http.Header, retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("remote fetch: %w", err)
 	}
-	client := &http.Client{Timeout: timeout}
-	if insecureTLS {
-		client.Transport = &http.Transport{
-			// InsecureSkipVerify is intentional when HTTP_INSECURE_TLS is set (e.g. dev/self-signed).
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // #nosec G402
+	if opts.Token != nil {
+		token, terr := opts.Token.Token(ctx)
+		if terr != nil {
+			return nil, nil, false, fmt.Errorf("remote fetch: %w", terr)
 		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if opts.AuthHeader != "" {
+		req.Header.Set("Authorization", opts.AuthHeader)
 	}
+	opts.Conditional.apply(req)
 	resp, err := client.Do(req) // #nosec G704 -- URL from config, caller is responsible for allowlist
 	if err != nil {
-		return nil, fmt.Errorf("remote fetch: %w", err)
+		return nil, nil, ctx.Err() == nil, fmt.Errorf("remote fetch: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }() //nolint:errcheck // #nosec G104 -- ignore close in defer to avoid masking main error
+	if resp.StatusCode == http.StatusNotModified && !opts.Conditional.IsZero() {
+		return nil, resp.Header, false, ErrNotModified
+	}
+	if resp.StatusCode == http.StatusUnauthorized && opts.Token != nil {
+		// The token may have been revoked before it expired: retry with a new one
+		opts.Token.Invalidate()
+		return nil, nil, true, fmt.Errorf("remote fetch: status %d", resp.StatusCode)
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("remote fetch: status %d", resp.StatusCode)
+		return nil, nil, retryableStatus(resp.StatusCode), fmt.Errorf("remote fetch: status %d", resp.StatusCode)
 	}
-	body, err := io.ReadAll(io.LimitReader(resp.Body, define.MAX_JSON_SIZE))
+	body, header, err = readBody(resp, opts, maxBytes)
+	if err == nil && opts.Verifier != nil {
+		// The signature covers the payload as parsed, i.e. after decryption.
+		if verr := opts.Verifier.Verify(body, resp.Header.Get(signature.SignatureHeader)); verr != nil {
+			return nil, nil, false, fmt.Errorf("remote fetch: %w", verr)
+		}
+	}
+	return body, header, false, err
+}
+
+// readBody reads (and when configured decrypts) a 200 response body.
+func readBody(resp *http.Response, opts *FetchOptions, maxBytes int64) ([]byte, http.Header, error) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
 	if err != nil {
-		return nil, fmt.Errorf("remote fetch: read %w", err)
+		return nil, nil, fmt.Errorf("remote fetch: read %w", err)
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, nil, fmt.Errorf("remote fetch: response exceeds %d bytes", maxBytes)
+	}
+	if !opts.decryptConfigured() {
+		return body, resp.Header, nil
 	}
-	if !decryptEnabled || (rsaKeyPath == "" && rsaKeyPEM == "") {
-		return body, nil
+	ct := resp.Header.Get("Content-Type")
+	isEnvelope, isLegacy := hasContentType(ct, EnvelopeContentType), hasContentType(ct, EncryptedContentType)
+	if !isEnvelope && !isLegacy {
+		return body, resp.Header, nil
 	}
-	ct := strings.TrimSpace(strings.ToLower(resp.Header.Get("Content-Type")))
-	if ct != EncryptedContentType && !strings.HasPrefix(ct, EncryptedContentType+";") {
-		return body, nil
+	if isLegacy && !opts.LegacyDecrypt {
+		return nil, nil, fmt.Errorf("remote decrypt: legacy %s payload rejected (unauthenticated AES-CTR); set REMOTE_DECRYPT_LEGACY=true to accept it", EncryptedContentType)
 	}
-	privKey, err := loadRSAPrivateKey(rsaKeyPath, rsaKeyPEM)
+	ring, err := loadKeyring(opts)
 	if err != nil {
-		return nil, fmt.Errorf("remote decrypt: load key %w", err)
+		return nil, nil, fmt.Errorf("remote decrypt: load key %w", err)
+	}
+	var dec []byte
+	if isEnvelope {
+		dec, err = openEnvelope(body, ring)
+	} else {
+		dec, err = decryptLegacy(body, ring.rsaKeys())
 	}
-	dec, err := decryptHybrid(body, privKey)
 	if err != nil {
-		return nil, fmt.Errorf("remote decrypt: %w", err)
+		return nil, nil, fmt.Errorf("remote decrypt: %w", err)
 	}
-	return dec, nil
+	return dec, resp.Header, nil
+}
+
+// decryptLegacy tries each RSA key on a legacy EncryptedContentType body.
+func decryptLegacy(body []byte, keys []*rsa.PrivateKey) ([]byte, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("legacy format needs an RSA private key")
+	}
+	var lastErr error
+	for _, k := range keys {
+		dec, err := decryptHybrid(body, k)
+		if err == nil {
+			return dec, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
 }
 
 // loadRSAPrivateKey loads RSA private key from file path (if keyPath != "") or from inline PEM (keyPEM).
@@ -124,15 +248,17 @@ func loadRSAPrivateKey(keyPath, keyPEM string) (*rsa.PrivateKey, error) {
 }
 
 // decryptHybrid expects body = base64( RSA-OAEP_SHA256(aes_key_32 + iv_16) || aes_ctr_ciphertext ).
+// The RSA block is priv.Size() bytes long (256 for RSA-2048). The format has no integrity check.
 func decryptHybrid(body []byte, priv *rsa.PrivateKey) ([]byte, error) {
 	raw, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(body)))
 	if err != nil {
 		return nil, fmt.Errorf("base64 decode: %w", err)
 	}
-	if len(raw) < RSAKeySize2048+AESKeySize+IVSize {
+	keySize := priv.Size()
+	if len(raw) < keySize+AESKeySize+IVSize {
 		return nil, fmt.Errorf("body too short for hybrid cipher")
 	}
-	encKeyBlock := raw[:RSAKeySize2048]
+	encKeyBlock := raw[:keySize]
 	plainKeyIV, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, priv, encKeyBlock, nil)
 	if err != nil {
 		return nil, fmt.Errorf("rsa decrypt: %w", err)
@@ -142,7 +268,7 @@ func decryptHybrid(body []byte, priv *rsa.PrivateKey) ([]byte, error) {
 	}
 	aesKey := plainKeyIV[:AESKeySize]
 	iv := plainKeyIV[AESKeySize : AESKeySize+IVSize]
-	ciphertext := raw[RSAKeySize2048:]
+	ciphertext := raw[keySize:]
 	block, err := aes.NewCipher(aesKey)
 	if err != nil {
 		return nil, err
@@ -152,18 +278,3 @@ func decryptHybrid(body []byte, priv *rsa.PrivateKey) ([]byte, error) {
 	stream.XORKeyStream(plain, ciphertext)
 	return plain, nil
 }
-
-// FetchDecryptedUsers fetches remote URL and returns parsed []AllowListUser.
-// If decrypt is enabled and response is encrypted, decrypts then parses JSON.
-// rsaKeyPath and rsaKeyPEM: use file when rsaKeyPath is non-empty, else use rsaKeyPEM.
-func FetchDecryptedUsers(ctx context.Context, url, authHeader string, decryptEnabled bool, rsaKeyPath, rsaKeyPEM string, timeout time.Duration, insecureTLS bool) ([]define.AllowListUser, error) {
-	body, err := FetchDecrypted(ctx, url, authHeader, decryptEnabled, rsaKeyPath, rsaKeyPEM, timeout, insecureTLS)
-	if err != nil {
-		return nil, err
-	}
-	var users []define.AllowListUser
-	if err := json.Unmarshal(body, &users); err != nil {
-		return nil, fmt.Errorf("remote json parse: %w", err)
-	}
-	return users, nil
-}
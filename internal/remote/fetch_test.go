@@ -19,6 +19,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/define"
 )
 
 const testTimeout = 5 * time.Second
@@ -82,20 +84,18 @@ func TestLoadRSAPrivateKey_FileNotFound(t *testing.T) {
 	assert.True(t, os.IsNotExist(err))
 }
 
-func TestDecryptHybrid_RoundTrip(t *testing.T) {
-	priv, err := rsa.GenerateKey(rand.Reader, 2048)
-	require.NoError(t, err)
-
-	plaintext := []byte(`[{"phone":"13800138000","mail":"a@example.com"}]`)
+// sealLegacy encrypts plaintext in the legacy EncryptedContentType format for pub.
+func sealLegacy(t *testing.T, pub *rsa.PublicKey, plaintext []byte) []byte {
+	t.Helper()
 	aesKey := make([]byte, AESKeySize)
 	iv := make([]byte, IVSize)
-	_, err = rand.Read(aesKey)
+	_, err := rand.Read(aesKey)
 	require.NoError(t, err)
 	_, err = rand.Read(iv)
 	require.NoError(t, err)
 
 	keyIV := append(append([]byte{}, aesKey...), iv...)
-	encKeyBlock, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &priv.PublicKey, keyIV, nil)
+	encKeyBlock, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, keyIV, nil)
 	require.NoError(t, err)
 
 	block, err := aes.NewCipher(aesKey)
@@ -105,9 +105,15 @@ func TestDecryptHybrid_RoundTrip(t *testing.T) {
 	stream.XORKeyStream(ciphertext, plaintext)
 
 	raw := append(append([]byte{}, encKeyBlock...), ciphertext...)
-	body := []byte(base64.StdEncoding.EncodeToString(raw))
+	return []byte(base64.StdEncoding.EncodeToString(raw))
+}
+
+func TestDecryptHybrid_RoundTrip(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
 
-	dec, err := decryptHybrid(body, priv)
+	plaintext := []byte(`[{"phone":"13800138000","mail":"a@example.com"}]`)
+	dec, err := decryptHybrid(sealLegacy(t, &priv.PublicKey, plaintext), priv)
 	require.NoError(t, err)
 	assert.Equal(t, plaintext, dec)
 }
@@ -129,7 +135,7 @@ func TestDecryptHybrid_BodyTooShort(t *testing.T) {
 	assert.Contains(t, err.Error(), "too short")
 }
 
-func TestFetchDecrypted_PlainResponse(t *testing.T) {
+func TestFetch_PlainResponse(t *testing.T) {
 	payload := []byte(`[{"phone":"138","mail":"x@y.com"}]`)
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -139,24 +145,24 @@ func TestFetchDecrypted_PlainResponse(t *testing.T) {
 	defer srv.Close()
 
 	ctx := context.Background()
-	body, err := FetchDecrypted(ctx, srv.URL, "", false, "", "", testTimeout, false)
+	body, _, err := fetch(ctx, srv.URL, &FetchOptions{Timeout: testTimeout}, define.MAX_JSON_SIZE)
 	require.NoError(t, err)
 	assert.Equal(t, payload, body)
 }
 
-func TestFetchDecrypted_StatusCodeNotOK(t *testing.T) {
+func TestFetch_StatusCodeNotOK(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 	}))
 	defer srv.Close()
 
 	ctx := context.Background()
-	_, err := FetchDecrypted(ctx, srv.URL, "", false, "", "", testTimeout, false)
+	_, _, err := fetch(ctx, srv.URL, &FetchOptions{Timeout: testTimeout}, define.MAX_JSON_SIZE)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "status 404")
 }
 
-func TestFetchDecryptedUsers_PlainJSON(t *testing.T) {
+func TestFetchUsersConditional_PlainJSON(t *testing.T) {
 	payload := []byte(`[{"phone":"13800138000","mail":"a@example.com"}]`)
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -166,7 +172,7 @@ func TestFetchDecryptedUsers_PlainJSON(t *testing.T) {
 	defer srv.Close()
 
 	ctx := context.Background()
-	users, err := FetchDecryptedUsers(ctx, srv.URL, "", false, "", "", testTimeout, false)
+	users, _, err := FetchUsersConditional(ctx, srv.URL, Pagination{}, &FetchOptions{Timeout: testTimeout}, Validators{})
 	require.NoError(t, err)
 	require.Len(t, users, 1)
 	assert.Equal(t, "13800138000", users[0].Phone)
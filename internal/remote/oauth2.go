@@ -0,0 +1,195 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// OAuth2AuthBasic sends the client credentials as HTTP Basic auth (client_secret_basic, default).
+	OAuth2AuthBasic = "basic"
+	// OAuth2AuthBody sends the client credentials as form parameters (client_secret_post).
+	OAuth2AuthBody = "body"
+
+	// DefaultTokenLifetime is assumed when the token response has no expires_in.
+	DefaultTokenLifetime = 5 * time.Minute
+	// tokenRefreshMargin is how long before expiry a cached token is replaced (at most a quarter of its lifetime).
+	tokenRefreshMargin = time.Minute
+	// maxTokenResponseSize limits the token endpoint response.
+	maxTokenResponseSize = 1 << 20
+)
+
+// OAuth2Options configures the OAuth2 client credentials grant (RFC 6749 section 4.4) used to obtain
+// a bearer token for the remote source. The client secret is read from ClientSecretFile on every token
+// request, so a rotated secret is used without a restart.
+type OAuth2Options struct {
+	TokenURL         string   // token endpoint
+	ClientID         string   // client id
+	ClientSecretFile string   // file containing the client secret (surrounding whitespace is trimmed)
+	Scopes           []string // requested scopes (optional)
+	Audience         string   // audience parameter required by some IdPs (optional)
+	AuthStyle        string   // OAuth2AuthBasic (default) or OAuth2AuthBody
+}
+
+// IsZero reports whether no OAuth2 option is set.
+func (o *OAuth2Options) IsZero() bool {
+	return o == nil || (o.TokenURL == "" && o.ClientID == "" && o.ClientSecretFile == "" && len(o.Scopes) == 0 && o.Audience == "" && o.AuthStyle == "")
+}
+
+// Validate checks that the token URL, client id and secret file are set and that the secret can be read.
+func (o *OAuth2Options) Validate() error {
+	if o.IsZero() {
+		return nil
+	}
+	u, err := url.Parse(o.TokenURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("token URL %q must be an http(s) URL", o.TokenURL)
+	}
+	if o.ClientID == "" {
+		return errors.New("client id is required")
+	}
+	if o.AuthStyle != "" && o.AuthStyle != OAuth2AuthBasic && o.AuthStyle != OAuth2AuthBody {
+		return fmt.Errorf("auth style %q must be %s or %s", o.AuthStyle, OAuth2AuthBasic, OAuth2AuthBody)
+	}
+	_, err = o.secret()
+	return err
+}
+
+// secret reads the client secret from ClientSecretFile.
+func (o *OAuth2Options) secret() (string, error) {
+	if o.ClientSecretFile == "" {
+		return "", errors.New("client secret file is required")
+	}
+	data, err := os.ReadFile(o.ClientSecretFile) // #nosec G304 -- path from config
+	if err != nil {
+		return "", fmt.Errorf("client secret: %w", err)
+	}
+	s := strings.TrimSpace(string(data))
+	if s == "" {
+		return "", fmt.Errorf("client secret file %s is empty", o.ClientSecretFile)
+	}
+	return s, nil
+}
+
+// TokenSource obtains and caches access tokens with the client credentials grant. A cached token is
+// replaced shortly before it expires. Methods are safe for concurrent use; concurrent callers share
+// one token request.
+type TokenSource struct {
+	opts   OAuth2Options
+	client *http.Client
+
+	mu        sync.Mutex
+	token     string
+	refreshAt time.Time
+
+	now func() time.Time
+}
+
+// NewTokenSource creates a TokenSource. client is used for token requests (nil = http.DefaultClient).
+func NewTokenSource(o OAuth2Options, client *http.Client) *TokenSource {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &TokenSource{opts: o, client: client, now: time.Now}
+}
+
+// Token returns a valid access token, requesting a new one when none is cached or the cached one is about to expire.
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && s.now().Before(s.refreshAt) {
+		return s.token, nil
+	}
+	token, lifetime, err := s.request(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.token = token
+	s.refreshAt = s.now().Add(lifetime - min(tokenRefreshMargin, lifetime/4))
+	return token, nil
+}
+
+// Invalidate drops the cached token, e.g. after the remote rejected it with 401.
+func (s *TokenSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = ""
+}
+
+// tokenResponse is the token endpoint response (RFC 6749 sections 5.1 and 5.2).
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// request performs the client credentials grant and returns the token and its lifetime.
+func (s *TokenSource) request(ctx context.Context) (string, time.Duration, error) {
+	secret, err := s.opts.secret()
+	if err != nil {
+		return "", 0, fmt.Errorf("oauth2 token: %w", err)
+	}
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.opts.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.opts.Scopes, " "))
+	}
+	if s.opts.Audience != "" {
+		form.Set("audience", s.opts.Audience)
+	}
+	if s.opts.AuthStyle == OAuth2AuthBody {
+		form.Set("client_id", s.opts.ClientID)
+		form.Set("client_secret", secret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.opts.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("oauth2 token: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if s.opts.AuthStyle != OAuth2AuthBody {
+		// RFC 6749 section 2.3.1: credentials are form-encoded before Basic encoding
+		req.SetBasicAuth(url.QueryEscape(s.opts.ClientID), url.QueryEscape(secret))
+	}
+	resp, err := s.client.Do(req) // #nosec G704 -- token URL from config
+	if err != nil {
+		return "", 0, fmt.Errorf("oauth2 token: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }() //nolint:errcheck // #nosec G104 -- ignore close in defer to avoid masking main error
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTokenResponseSize))
+	if err != nil {
+		return "", 0, fmt.Errorf("oauth2 token: read %w", err)
+	}
+	var tr tokenResponse
+	jsonErr := json.Unmarshal(body, &tr)
+	if resp.StatusCode != http.StatusOK {
+		if jsonErr == nil && tr.Error != "" {
+			return "", 0, fmt.Errorf("oauth2 token: status %d: %s %s", resp.StatusCode, tr.Error, tr.ErrorDescription)
+		}
+		return "", 0, fmt.Errorf("oauth2 token: status %d", resp.StatusCode)
+	}
+	if jsonErr != nil {
+		return "", 0, fmt.Errorf("oauth2 token: decode response: %w", jsonErr)
+	}
+	if tr.AccessToken == "" {
+		return "", 0, errors.New("oauth2 token: response has no access_token")
+	}
+	if tr.TokenType != "" && !strings.EqualFold(tr.TokenType, "bearer") {
+		return "", 0, fmt.Errorf("oauth2 token: unsupported token type %q", tr.TokenType)
+	}
+	lifetime := DefaultTokenLifetime
+	if tr.ExpiresIn > 0 {
+		lifetime = time.Duration(tr.ExpiresIn) * time.Second
+	}
+	return tr.AccessToken, lifetime, nil
+}
@@ -0,0 +1,170 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tokenServer is a stand-in for an IdP token endpoint issuing "token-1", "token-2", ...
+type tokenServer struct {
+	*httptest.Server
+	issued    atomic.Int32
+	expiresIn int64
+	lastForm  atomic.Value // url.Values of the last request
+}
+
+func newTokenServer(t *testing.T, clientID, secret string) *tokenServer {
+	t.Helper()
+	ts := &tokenServer{expiresIn: 3600}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Method != http.MethodPost || r.PostForm.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		ts.lastForm.Store(r.PostForm)
+		id, sec, ok := r.BasicAuth()
+		if !ok {
+			id, sec = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if id != clientID || sec != secret {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client","error_description":"bad credentials"}`)) //nolint:errcheck // test server
+			return
+		}
+		n := ts.issued.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{ //nolint:errcheck // test server
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   ts.expiresIn,
+		})
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func writeSecret(t *testing.T, secret string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "client_secret")
+	require.NoError(t, os.WriteFile(path, []byte(secret+"\n"), 0o600))
+	return path
+}
+
+func TestTokenSource_CachesAndRefreshes(t *testing.T) {
+	ts := newTokenServer(t, "warden", "s3cret")
+	src := NewTokenSource(OAuth2Options{
+		TokenURL:         ts.URL,
+		ClientID:         "warden",
+		ClientSecretFile: writeSecret(t, "s3cret"),
+		Scopes:           []string{"roster.read", "roster.list"},
+		Audience:         "https://roster.internal",
+	}, nil)
+	now := time.Now()
+	src.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	tok, err := src.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", tok)
+	form, ok := ts.lastForm.Load().(url.Values)
+	require.True(t, ok)
+	assert.Equal(t, []string{"roster.read roster.list"}, form["scope"])
+	assert.Equal(t, []string{"https://roster.internal"}, form["audience"])
+	assert.Empty(t, form["client_secret"], "basic auth style does not send the secret in the body")
+
+	// Cached until shortly before expiry
+	now = now.Add(58 * time.Minute)
+	tok, err = src.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", tok)
+
+	now = now.Add(90 * time.Second)
+	tok, err = src.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", tok)
+
+	src.Invalidate()
+	tok, err = src.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-3", tok)
+}
+
+func TestTokenSource_BodyAuthAndErrors(t *testing.T) {
+	ts := newTokenServer(t, "warden", "s3cret")
+	ctx := context.Background()
+
+	src := NewTokenSource(OAuth2Options{TokenURL: ts.URL, ClientID: "warden", ClientSecretFile: writeSecret(t, "s3cret"), AuthStyle: OAuth2AuthBody}, nil)
+	tok, err := src.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", tok)
+
+	src = NewTokenSource(OAuth2Options{TokenURL: ts.URL, ClientID: "warden", ClientSecretFile: writeSecret(t, "wrong")}, nil)
+	_, err = src.Token(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid_client")
+
+	src = NewTokenSource(OAuth2Options{TokenURL: ts.URL, ClientID: "warden", ClientSecretFile: filepath.Join(t.TempDir(), "missing")}, nil)
+	_, err = src.Token(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "client secret")
+}
+
+func TestFetchUsersConditional_OAuth2(t *testing.T) {
+	ts := newTokenServer(t, "warden", "s3cret")
+	var revoked atomic.Bool
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if auth == "" || (revoked.Load() && auth == "Bearer token-1") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"phone":"13800138000"}]`)) //nolint:errcheck // test server
+	}))
+	defer api.Close()
+
+	opts := &FetchOptions{
+		Timeout:    testTimeout,
+		AuthHeader: "static",
+		Retries:    1,
+		Token:      NewTokenSource(OAuth2Options{TokenURL: ts.URL, ClientID: "warden", ClientSecretFile: writeSecret(t, "s3cret")}, nil),
+	}
+	ctx := context.Background()
+	users, _, err := FetchUsersConditional(ctx, api.URL, Pagination{}, opts, Validators{})
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+	_, _, err = FetchUsersConditional(ctx, api.URL, Pagination{}, opts, Validators{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), ts.issued.Load(), "token is cached across fetches")
+
+	// A token rejected before its expiry is replaced and the request retried
+	revoked.Store(true)
+	_, _, err = FetchUsersConditional(ctx, api.URL, Pagination{}, opts, Validators{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), ts.issued.Load())
+}
+
+func TestOAuth2Options_Validate(t *testing.T) {
+	secret := writeSecret(t, "s3cret")
+	var nilOpts *OAuth2Options
+	assert.True(t, nilOpts.IsZero())
+	assert.NoError(t, nilOpts.Validate())
+	assert.NoError(t, (&OAuth2Options{TokenURL: "https://idp.example.com/token", ClientID: "warden", ClientSecretFile: secret}).Validate())
+
+	assert.ErrorContains(t, (&OAuth2Options{TokenURL: "idp/token", ClientID: "warden", ClientSecretFile: secret}).Validate(), "http(s) URL")
+	assert.ErrorContains(t, (&OAuth2Options{TokenURL: "https://idp.example.com/token", ClientSecretFile: secret}).Validate(), "client id")
+	assert.ErrorContains(t, (&OAuth2Options{TokenURL: "https://idp.example.com/token", ClientID: "warden"}).Validate(), "client secret file")
+	assert.ErrorContains(t, (&OAuth2Options{TokenURL: "https://idp.example.com/token", ClientID: "warden", ClientSecretFile: secret, AuthStyle: "jwt"}).Validate(), "auth style")
+	assert.ErrorContains(t, (&OAuth2Options{TokenURL: "https://idp.example.com/token", ClientID: "warden", ClientSecretFile: writeSecret(t, " ")}).Validate(), "empty")
+}
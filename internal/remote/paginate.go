@@ -0,0 +1,328 @@
+// Package remote provides remote config fetch with optional RSA decryption.
+// paginate.go: multi-page fetching for upstreams that cap the number of records per response.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/soulteary/warden/internal/define"
+)
+
+// Pagination strategies supported by FetchPaginatedUsers.
+const (
+	PaginationNone   = ""       // single request (no pagination)
+	PaginationPage   = "page"   // ?page=N&page_size=M
+	PaginationOffset = "offset" // ?offset=<records received so far>&limit=M
+	PaginationCursor = "cursor" // ?cursor=<value from previous response body>
+	PaginationLink   = "link"   // follow RFC 5988 Link: <...>; rel="next"
+)
+
+// Pagination defaults applied when a field is left empty.
+const (
+	DefaultPageSize     = 500
+	DefaultMaxPages     = 100
+	DefaultPageParam    = "page"
+	DefaultSizeParam    = "page_size"
+	DefaultOffsetParam  = "offset"
+	DefaultLimitParam   = "limit"
+	DefaultCursorParam  = "cursor"
+	DefaultCursorField  = "next_cursor"
+	DefaultItemsField   = "data"
+	DefaultTotalField   = "total"
+	defaultStartPageNum = 1
+)
+
+// Pagination describes how to walk a paginated remote source.
+// Zero values fall back to the Default* constants above.
+//
+//nolint:govet // fieldalignment: keep field order for readability
+type Pagination struct {
+	Strategy    string // one of the Pagination* constants
+	PageParam   string // page strategy: page number query parameter
+	SizeParam   string // page strategy: page size query parameter
+	OffsetParam string // offset strategy: offset query parameter
+	LimitParam  string // offset strategy: limit query parameter
+	CursorParam string // cursor strategy: cursor query parameter
+	CursorField string // cursor strategy: dotted path of next cursor in response body (e.g. "meta.next_cursor")
+	ItemsField  string // dotted path of the user array when the response body is an object (e.g. "data")
+	TotalField  string // page/offset strategies: dotted path of the total record count in the response body (e.g. "meta.total")
+	PageSize    int    // records requested per page
+	StartPage   int    // page strategy: first page number (default 1)
+	MaxPages    int    // hard cap on the number of requests per fetch
+}
+
+// Enabled reports whether a pagination strategy is configured.
+func (p *Pagination) Enabled() bool {
+	return p != nil && strings.TrimSpace(p.Strategy) != PaginationNone
+}
+
+// Validate checks that the strategy is known.
+func (p *Pagination) Validate() error {
+	if p == nil {
+		return nil
+	}
+	switch strings.ToLower(strings.TrimSpace(p.Strategy)) {
+	case PaginationNone, PaginationPage, PaginationOffset, PaginationCursor, PaginationLink:
+		return nil
+	default:
+		return fmt.Errorf("unknown pagination strategy %q (want page, offset, cursor or link)", p.Strategy)
+	}
+}
+
+// withDefaults returns a copy of p with empty fields filled in.
+func (p Pagination) withDefaults() Pagination {
+	p.Strategy = strings.ToLower(strings.TrimSpace(p.Strategy))
+	if p.PageParam == "" {
+		p.PageParam = DefaultPageParam
+	}
+	if p.SizeParam == "" {
+		p.SizeParam = DefaultSizeParam
+	}
+	if p.OffsetParam == "" {
+		p.OffsetParam = DefaultOffsetParam
+	}
+	if p.LimitParam == "" {
+		p.LimitParam = DefaultLimitParam
+	}
+	if p.CursorParam == "" {
+		p.CursorParam = DefaultCursorParam
+	}
+	if p.CursorField == "" {
+		p.CursorField = DefaultCursorField
+	}
+	if p.ItemsField == "" {
+		p.ItemsField = DefaultItemsField
+	}
+	if p.TotalField == "" {
+		p.TotalField = DefaultTotalField
+	}
+	if p.PageSize <= 0 {
+		p.PageSize = DefaultPageSize
+	}
+	if p.StartPage <= 0 {
+		p.StartPage = defaultStartPageNum
+	}
+	if p.MaxPages <= 0 {
+		p.MaxPages = DefaultMaxPages
+	}
+	return p
+}
+
+// FetchPaginatedUsers fetches all pages of rawURL according to p and returns the concatenated users.
+// The combined size of all page bodies is capped at define.MAX_JSON_SIZE and the number of requests at p.MaxPages;
+// exceeding either is an error so that a partially fetched list never replaces a complete one.
+// When p has no strategy, a single request is made.
+//
+// Page and offset walks end on an empty page or once the total count at p.TotalField is reached, never on a
+// short page: upstreams often cap the page size below the one requested. Offsets advance by the number of
+// records actually received. A cursor walk fails when the upstream returns a cursor it already returned.
+func FetchPaginatedUsers(ctx context.Context, rawURL string, p Pagination, opts *FetchOptions) ([]define.AllowListUser, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &FetchOptions{}
+	}
+	// Each page is fetched unconditionally: validators only describe a single response.
+	o := *opts
+	o.Conditional = Validators{}
+	opts = &o
+	p = p.withDefaults()
+	base, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("remote pagination: %w", err)
+	}
+
+	var (
+		all     []define.AllowListUser
+		budget  int64 = define.MAX_JSON_SIZE
+		nextURL       = base
+		cursor  string
+		cursors = map[string]bool{}
+	)
+	for page := 0; ; page++ {
+		if page >= p.MaxPages {
+			return nil, fmt.Errorf("remote pagination: exceeded max pages (%d)", p.MaxPages)
+		}
+		reqURL := pageURL(base, nextURL, &p, page, len(all), cursor)
+		body, header, err := fetch(ctx, reqURL.String(), opts, budget)
+		if err != nil {
+			return nil, fmt.Errorf("remote pagination: page %d: %w", page+1, err)
+		}
+		budget -= int64(len(body))
+
+		items, root, err := decodePage(body, p.ItemsField)
+		if err != nil {
+			return nil, fmt.Errorf("remote pagination: page %d: %w", page+1, err)
+		}
+		all = append(all, items...)
+
+		switch p.Strategy {
+		case PaginationNone:
+			return all, nil
+		case PaginationPage, PaginationOffset:
+			if len(items) == 0 {
+				return all, nil
+			}
+			if total, ok := lookupInt(root, p.TotalField); ok && len(all) >= total {
+				return all, nil
+			}
+		case PaginationCursor:
+			cursor = lookupString(root, p.CursorField)
+			if cursor == "" || len(items) == 0 {
+				return all, nil
+			}
+			if cursors[cursor] {
+				return nil, fmt.Errorf("remote pagination: page %d repeats cursor %q", page+1, cursor)
+			}
+			cursors[cursor] = true
+		case PaginationLink:
+			next, ok := nextLink(header, reqURL)
+			if !ok || len(items) == 0 {
+				return all, nil
+			}
+			if next.Scheme != base.Scheme || next.Host != base.Host {
+				return nil, fmt.Errorf("remote pagination: next link %q leaves origin %s://%s", next.Redacted(), base.Scheme, base.Host)
+			}
+			nextURL = next
+		}
+	}
+}
+
+// pageURL builds the request URL for the given zero-based page index; received is the number of records so far.
+func pageURL(base, linkNext *url.URL, p *Pagination, page, received int, cursor string) *url.URL {
+	if p.Strategy == PaginationLink {
+		return linkNext
+	}
+	u := *base
+	q := u.Query()
+	switch p.Strategy {
+	case PaginationPage:
+		q.Set(p.PageParam, strconv.Itoa(p.StartPage+page))
+		q.Set(p.SizeParam, strconv.Itoa(p.PageSize))
+	case PaginationOffset:
+		q.Set(p.OffsetParam, strconv.Itoa(received))
+		q.Set(p.LimitParam, strconv.Itoa(p.PageSize))
+	case PaginationCursor:
+		if cursor != "" {
+			q.Set(p.CursorParam, cursor)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return &u
+}
+
+// decodePage parses a page body. A JSON array is used as-is; a JSON object must hold the array at itemsField.
+// The decoded object (nil for array bodies) is returned so callers can read cursor fields from it.
+func decodePage(body []byte, itemsField string) ([]define.AllowListUser, map[string]json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var users []define.AllowListUser
+		if err := json.Unmarshal(trimmed, &users); err != nil {
+			return nil, nil, fmt.Errorf("json parse: %w", err)
+		}
+		return users, nil, nil
+	}
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &root); err != nil {
+		return nil, nil, fmt.Errorf("json parse: %w", err)
+	}
+	raw, ok := lookupRaw(root, itemsField)
+	if !ok {
+		return nil, nil, fmt.Errorf("items field %q not found", itemsField)
+	}
+	var users []define.AllowListUser
+	if err := json.Unmarshal(raw, &users); err != nil {
+		return nil, nil, fmt.Errorf("items field %q: %w", itemsField, err)
+	}
+	return users, root, nil
+}
+
+// lookupRaw resolves a dotted path (e.g. "meta.next") inside a decoded JSON object.
+func lookupRaw(root map[string]json.RawMessage, path string) (json.RawMessage, bool) {
+	if root == nil {
+		return nil, false
+	}
+	parts := strings.Split(path, ".")
+	cur := root
+	for i, part := range parts {
+		raw, ok := cur[part]
+		if !ok {
+			return nil, false
+		}
+		if i == len(parts)-1 {
+			return raw, true
+		}
+		cur = nil
+		if err := json.Unmarshal(raw, &cur); err != nil {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// lookupString returns the string (or number) value at path, or "" when missing or null.
+func lookupString(root map[string]json.RawMessage, path string) string {
+	raw, ok := lookupRaw(root, path)
+	if !ok {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n.String()
+	}
+	return ""
+}
+
+// lookupInt returns the integer value at path; ok is false when it is missing or not a number.
+func lookupInt(root map[string]json.RawMessage, path string) (int, bool) {
+	raw, ok := lookupRaw(root, path)
+	if !ok {
+		return 0, false
+	}
+	var n int
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// nextLink extracts the rel="next" target from RFC 5988 Link headers, resolved against current.
+func nextLink(header http.Header, current *url.URL) (*url.URL, bool) {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			segments := strings.Split(link, ";")
+			target := strings.TrimSpace(segments[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range segments[1:] {
+				key, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(key), "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(val), `"`)) {
+					if strings.EqualFold(rel, "next") {
+						ref, err := url.Parse(strings.Trim(target, "<>"))
+						if err != nil {
+							return nil, false
+						}
+						return current.ResolveReference(ref), true
+					}
+				}
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,246 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/define"
+)
+
+// makeUsers returns n users with phones starting at 13800000000+start.
+func makeUsers(start, n int) []define.AllowListUser {
+	users := make([]define.AllowListUser, n)
+	for i := range users {
+		users[i] = define.AllowListUser{Phone: strconv.Itoa(13800000000 + start + i)}
+	}
+	return users
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v interface{}) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	require.NoError(t, json.NewEncoder(w).Encode(v))
+}
+
+func TestFetchPaginatedUsers_PageStrategy(t *testing.T) {
+	const total = 7
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		require.NoError(t, err)
+		size, err := strconv.Atoi(r.URL.Query().Get("page_size"))
+		require.NoError(t, err)
+		start := (page - 1) * size
+		n := max(0, min(size, total-start))
+		writeJSON(t, w, makeUsers(start, n))
+	}))
+	defer srv.Close()
+
+	users, err := FetchPaginatedUsers(context.Background(), srv.URL, Pagination{Strategy: PaginationPage, PageSize: 3}, &FetchOptions{Timeout: testTimeout})
+	require.NoError(t, err)
+	require.Len(t, users, total)
+	assert.Equal(t, "13800000006", users[6].Phone)
+}
+
+// cappedServer serves total users with at most limit per response, whatever page size is requested.
+func cappedServer(t *testing.T, total, limit int, withTotal bool) (*httptest.Server, *int) {
+	t.Helper()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		q := r.URL.Query()
+		var start int
+		if v := q.Get("offset"); v != "" {
+			start, _ = strconv.Atoi(v)
+		} else {
+			page, _ := strconv.Atoi(q.Get("page"))
+			start = (page - 1) * limit
+		}
+		items := makeUsers(start, max(0, min(limit, total-start)))
+		if withTotal {
+			writeJSON(t, w, map[string]interface{}{"data": items, "meta": map[string]int{"total": total}})
+			return
+		}
+		writeJSON(t, w, items)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestFetchPaginatedUsers_UpstreamCapsPageSize(t *testing.T) {
+	for _, strategy := range []string{PaginationPage, PaginationOffset} {
+		t.Run(strategy, func(t *testing.T) {
+			// The upstream returns 2 records per response although 5 were requested.
+			srv, calls := cappedServer(t, 7, 2, false)
+			users, err := FetchPaginatedUsers(context.Background(), srv.URL, Pagination{Strategy: strategy, PageSize: 5}, &FetchOptions{Timeout: testTimeout})
+			require.NoError(t, err)
+			require.Len(t, users, 7)
+			assert.Equal(t, "13800000006", users[6].Phone)
+			assert.Equal(t, 5, *calls, "four non-empty pages and one empty page")
+		})
+	}
+}
+
+func TestFetchPaginatedUsers_TotalFieldEndsWalk(t *testing.T) {
+	srv, calls := cappedServer(t, 6, 2, true)
+	p := Pagination{Strategy: PaginationOffset, PageSize: 5, TotalField: "meta.total"}
+	users, err := FetchPaginatedUsers(context.Background(), srv.URL, p, &FetchOptions{Timeout: testTimeout})
+	require.NoError(t, err)
+	assert.Len(t, users, 6)
+	assert.Equal(t, 3, *calls, "no request for an empty page once the total is reached")
+}
+
+func TestFetchPaginatedUsers_OffsetStrategyWithItemsField(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+		require.NoError(t, err)
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		require.NoError(t, err)
+		n := limit
+		if offset >= 4 {
+			n = 0
+		}
+		writeJSON(t, w, map[string]interface{}{"result": map[string]interface{}{"items": makeUsers(offset, n)}})
+	}))
+	defer srv.Close()
+
+	p := Pagination{Strategy: PaginationOffset, PageSize: 2, ItemsField: "result.items"}
+	users, err := FetchPaginatedUsers(context.Background(), srv.URL, p, &FetchOptions{Timeout: testTimeout})
+	require.NoError(t, err)
+	assert.Len(t, users, 4)
+}
+
+func TestFetchPaginatedUsers_CursorStrategy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			writeJSON(t, w, map[string]interface{}{"data": makeUsers(0, 2), "meta": map[string]string{"next": "abc"}})
+		case "abc":
+			writeJSON(t, w, map[string]interface{}{"data": makeUsers(2, 1), "meta": map[string]interface{}{"next": nil}})
+		default:
+			t.Errorf("unexpected cursor %q", r.URL.Query().Get("cursor"))
+		}
+	}))
+	defer srv.Close()
+
+	p := Pagination{Strategy: PaginationCursor, CursorField: "meta.next"}
+	users, err := FetchPaginatedUsers(context.Background(), srv.URL, p, &FetchOptions{Timeout: testTimeout})
+	require.NoError(t, err)
+	assert.Len(t, users, 3)
+}
+
+func TestFetchPaginatedUsers_CursorRepeated(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]interface{}{"data": makeUsers(0, 1), "next_cursor": "same"})
+	}))
+	defer srv.Close()
+
+	_, err := FetchPaginatedUsers(context.Background(), srv.URL, Pagination{Strategy: PaginationCursor}, &FetchOptions{Timeout: testTimeout})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "repeats cursor")
+}
+
+func TestFetchPaginatedUsers_LinkStrategy(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer t", r.Header.Get("Authorization"))
+		page := r.URL.Query().Get("p")
+		if page == "" {
+			w.Header().Set("Link", `</users?p=2>; rel="next", <`+srv.URL+`/users?p=2>; rel="last"`)
+			writeJSON(t, w, makeUsers(0, 2))
+			return
+		}
+		w.Header().Set("Link", `</users?p=1>; rel="first"`)
+		writeJSON(t, w, makeUsers(2, 2))
+	}))
+	defer srv.Close()
+
+	p := Pagination{Strategy: PaginationLink}
+	users, err := FetchPaginatedUsers(context.Background(), srv.URL+"/users", p, &FetchOptions{AuthHeader: "Bearer t", Timeout: testTimeout})
+	require.NoError(t, err)
+	assert.Len(t, users, 4)
+}
+
+func TestFetchPaginatedUsers_LinkLeavingOriginRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", `<http://evil.invalid/users?p=2>; rel="next"`)
+		writeJSON(t, w, makeUsers(0, 1))
+	}))
+	defer srv.Close()
+
+	_, err := FetchPaginatedUsers(context.Background(), srv.URL, Pagination{Strategy: PaginationLink}, &FetchOptions{Timeout: testTimeout})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "leaves origin")
+}
+
+func TestFetchPaginatedUsers_MaxPagesExceeded(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, makeUsers(0, 2))
+	}))
+	defer srv.Close()
+
+	p := Pagination{Strategy: PaginationPage, PageSize: 2, MaxPages: 3}
+	_, err := FetchPaginatedUsers(context.Background(), srv.URL, p, &FetchOptions{Timeout: testTimeout})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "max pages")
+}
+
+func TestFetchPaginatedUsers_TotalSizeGuard(t *testing.T) {
+	chunk := `[{"phone":"13800000000","name":"` + strings.Repeat("x", 1<<20) + `"}]`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprint(w, chunk)
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+
+	// Every page is full (PageSize 1), so the walk only ends when the size budget is exhausted.
+	p := Pagination{Strategy: PaginationPage, PageSize: 1, MaxPages: 1000}
+	_, err := FetchPaginatedUsers(context.Background(), srv.URL, p, &FetchOptions{Timeout: testTimeout})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds")
+}
+
+func TestFetchPaginatedUsers_NoStrategySingleRequest(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Empty(t, r.URL.RawQuery)
+		writeJSON(t, w, makeUsers(0, 3))
+	}))
+	defer srv.Close()
+
+	users, err := FetchPaginatedUsers(context.Background(), srv.URL, Pagination{}, nil)
+	require.NoError(t, err)
+	assert.Len(t, users, 3)
+	assert.Equal(t, 1, calls)
+}
+
+func TestPagination_Validate(t *testing.T) {
+	assert.NoError(t, (&Pagination{}).Validate())
+	assert.NoError(t, (&Pagination{Strategy: "Cursor"}).Validate())
+	assert.Error(t, (&Pagination{Strategy: "bogus"}).Validate())
+	assert.False(t, (&Pagination{}).Enabled())
+	assert.True(t, (&Pagination{Strategy: PaginationLink}).Enabled())
+}
+
+func TestNextLink(t *testing.T) {
+	cur, err := http.NewRequest(http.MethodGet, "https://api.example.com/v1/users?page=1", http.NoBody)
+	require.NoError(t, err)
+	h := http.Header{}
+	h.Add("Link", `<https://api.example.com/v1/users?page=1>; rel="prev first"`)
+	h.Add("Link", `<?page=2>; rel="next"`)
+	next, ok := nextLink(h, cur.URL)
+	require.True(t, ok)
+	assert.Equal(t, "https://api.example.com/v1/users?page=2", next.String())
+
+	_, ok = nextLink(http.Header{}, cur.URL)
+	assert.False(t, ok)
+}
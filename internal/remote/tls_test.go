@@ -0,0 +1,151 @@
+package remote
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/define"
+)
+
+// testCA is a throwaway certificate authority.
+type testCA struct {
+	t    *testing.T
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{t: t, cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a certificate signed by the CA for the server (127.0.0.1) or a client.
+func (ca *testCA) issue(server bool) tls.Certificate {
+	ca.t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(ca.t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if server {
+		tmpl.Subject.CommonName = "upstream.test"
+		tmpl.DNSNames = []string{"upstream.test"}
+		tmpl.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1)}
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(ca.t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// writePair writes cert and its key as PEM files and returns their paths.
+func writePair(t *testing.T, cert tls.Certificate) (certFile, keyFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	require.NoError(t, err)
+	certFile, keyFile = filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestFetch_MutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`[{"phone":"13800138000"}]`)) //nolint:errcheck // test server
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{ca.issue(true)},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0o600))
+	certFile, keyFile := writePair(t, ca.issue(false))
+	ctx := context.Background()
+
+	get := func(tlsOpts *TLSOptions) error {
+		_, _, err := fetch(ctx, srv.URL, &FetchOptions{Timeout: testTimeout, TLS: tlsOpts}, define.MAX_JSON_SIZE)
+		return err
+	}
+	require.Error(t, get(nil), "server certificate is not trusted by the system roots")
+	require.Error(t, get(&TLSOptions{CAFile: caFile}), "server requires a client certificate")
+
+	opts := &TLSOptions{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}
+	require.NoError(t, get(opts))
+
+	// The server name can be overridden, e.g. when connecting by IP.
+	opts.ServerName = "upstream.test"
+	require.NoError(t, get(opts))
+	opts.ServerName = "other.test"
+	require.Error(t, get(opts))
+
+	// The client certificate is also used by the paginated / conditional fetch path.
+	users, _, err := FetchUsersConditional(ctx, srv.URL, Pagination{}, &FetchOptions{Timeout: testTimeout, TLS: &TLSOptions{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}}, Validators{})
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+}
+
+func TestTLSOptions_Validate(t *testing.T) {
+	ca := newTestCA(t)
+	certFile, keyFile := writePair(t, ca.issue(false))
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0o600))
+	notPEM := filepath.Join(t.TempDir(), "ca.txt")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o600))
+
+	var nilOpts *TLSOptions
+	assert.True(t, nilOpts.IsZero())
+	assert.NoError(t, nilOpts.Validate())
+	assert.NoError(t, (&TLSOptions{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}).Validate())
+
+	assert.ErrorContains(t, (&TLSOptions{CertFile: certFile}).Validate(), "set together")
+	assert.ErrorContains(t, (&TLSOptions{CertFile: certFile, KeyFile: caFile}).Validate(), "client certificate")
+	assert.ErrorContains(t, (&TLSOptions{CAFile: notPEM}).Validate(), "no PEM certificates")
+	assert.ErrorContains(t, (&TLSOptions{CAFile: filepath.Join(t.TempDir(), "missing.pem")}).Validate(), "CA bundle")
+}
@@ -0,0 +1,395 @@
+// Package router provides HTTP routing functionality.
+// Admin handlers: GET /v1/admin/users/{id}/provenance, GET /v1/admin/sources, GET /v1/admin/load-report,
+// GET /v1/admin/instances, GET /v1/admin/datasets, POST /v1/admin/rollback, POST /v1/admin/unpin
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/soulteary/tracing-kit"
+	"github.com/soulteary/warden/internal/cache"
+	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/internal/i18n"
+	"github.com/soulteary/warden/internal/logger"
+)
+
+// ProvenanceLookup returns the sources a user was loaded from (implemented by loader.RulesLoader).
+type ProvenanceLookup interface {
+	Provenance(u *define.AllowListUser) (define.Provenance, bool)
+}
+
+// ProvenanceResponse is the response body for GET /v1/admin/users/{id}/provenance.
+type ProvenanceResponse struct {
+	UserID string `json:"user_id"`
+	define.Provenance
+}
+
+// SourceStatusLookup returns the sync status of every configured source (implemented by loader.RulesLoader).
+type SourceStatusLookup interface {
+	SourceStatuses() []define.SourceStatus
+}
+
+// SourcesResponse is the response body for GET /v1/admin/sources.
+type SourcesResponse struct {
+	Sources []define.SourceStatus `json:"sources"`
+}
+
+// LoadReportLookup returns the validation report of the latest reload (implemented by cache.SafeUserCache
+// and the app).
+type LoadReportLookup interface {
+	Report() (define.LoadReport, bool)
+}
+
+// ConflictLookup returns the identity conflicts of the last load (implemented by loader.RulesLoader).
+type ConflictLookup interface {
+	IdentityConflicts() []define.IdentityConflict
+}
+
+// InstanceLookup returns the status of every instance sharing the Redis cache (implemented by the app).
+type InstanceLookup interface {
+	Instances(ctx context.Context) ([]define.InstanceStatus, error)
+}
+
+// InstancesResponse is the response body for GET /v1/admin/instances.
+type InstancesResponse struct {
+	Instances []define.InstanceStatus `json:"instances"`
+	// Converged is true when every instance serves the same dataset hash.
+	Converged bool `json:"converged"`
+}
+
+// DatasetManager lists the kept datasets, rolls back to one of them and removes the pin (implemented by the app).
+type DatasetManager interface {
+	ActiveVersion() int64
+	Datasets(ctx context.Context) ([]cache.Dataset, int64, error)
+	Rollback(ctx context.Context, version int64) (cache.Dataset, error)
+	Unpin(ctx context.Context) error
+}
+
+// DatasetsResponse is the response body for GET /v1/admin/datasets, POST /v1/admin/rollback and
+// POST /v1/admin/unpin.
+type DatasetsResponse struct {
+	// Active is the version this instance serves, 0 when unknown.
+	Active int64 `json:"active"`
+	// Pinned is the version pinned by a rollback, 0 when the sources are loaded.
+	Pinned   int64           `json:"pinned"`
+	Datasets []cache.Dataset `json:"datasets"`
+}
+
+// sourceNames returns the comma-separated source names of u for audit metadata, or "" when unknown.
+func sourceNames(lookup ProvenanceLookup, u *define.AllowListUser) string {
+	if lookup == nil {
+		return ""
+	}
+	p, ok := lookup.Provenance(u)
+	if !ok {
+		return ""
+	}
+	return strings.Join(p.Names(), ",")
+}
+
+// GetUserProvenance returns a handler for GET /v1/admin/users/{id}/provenance.
+// id is matched against user_id, then phone, then mail. The response lists the sources that contributed
+// the user's record in the last successful load (lowest precedence first) and the load time.
+func GetUserProvenance(userCache cache.UserLookup, lookup ProvenanceLookup) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.StartSpan(r.Context(), "warden.admin.provenance")
+		defer span.End()
+
+		if r.Method != http.MethodGet {
+			tracing.RecordError(span, errors.New("method not allowed"))
+			logger.FromRequest(r).Warn().Str("method", r.Method).Msg(i18n.T(r, "log.unsupported_method"))
+			WriteJSONError(w, http.StatusMethodNotAllowed, i18n.T(r, "http.method_not_allowed"))
+			return
+		}
+
+		id := strings.TrimSpace(r.PathValue("id"))
+		if id == "" || len(id) > define.MAX_IDENTIFIER_LENGTH {
+			tracing.RecordError(span, fmt.Errorf("invalid identifier"))
+			WriteJSONError(w, http.StatusBadRequest, i18n.T(r, "error.invalid_identifier"))
+			return
+		}
+
+		user, found := userCache.GetByUserID(id)
+		if !found {
+			user, found = userCache.GetByPhone(id)
+		}
+		if !found {
+			user, found = userCache.GetByMail(id)
+		}
+		if !found {
+			span.SetAttributes(attribute.Bool("warden.user.found", false))
+			WriteJSONError(w, http.StatusNotFound, i18n.T(r, "http.user_not_found"))
+			return
+		}
+		span.SetAttributes(attribute.Bool("warden.user.found", true), attribute.String("warden.user.id", user.UserID))
+
+		var (
+			p  define.Provenance
+			ok bool
+		)
+		if lookup != nil {
+			p, ok = lookup.Provenance(&user)
+		}
+		if !ok {
+			WriteJSONError(w, http.StatusNotFound, i18n.T(r, "http.provenance_not_found"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(ProvenanceResponse{UserID: user.UserID, Provenance: p}); err != nil {
+			tracing.RecordError(span, err)
+			logger.FromRequest(r).Error().Err(err).Msg(i18n.T(r, "error.json_encode_failed"))
+		}
+	}
+}
+
+// GetSources returns a handler for GET /v1/admin/sources.
+// It lists every configured source in configuration order with its last attempt, last success,
+// last error, record count and latency.
+func GetSources(lookup SourceStatusLookup) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.StartSpan(r.Context(), "warden.admin.sources")
+		defer span.End()
+
+		if r.Method != http.MethodGet {
+			tracing.RecordError(span, errors.New("method not allowed"))
+			logger.FromRequest(r).Warn().Str("method", r.Method).Msg(i18n.T(r, "log.unsupported_method"))
+			WriteJSONError(w, http.StatusMethodNotAllowed, i18n.T(r, "http.method_not_allowed"))
+			return
+		}
+
+		resp := SourcesResponse{Sources: []define.SourceStatus{}}
+		if lookup != nil {
+			if statuses := lookup.SourceStatuses(); statuses != nil {
+				resp.Sources = statuses
+			}
+		}
+		span.SetAttributes(attribute.Int("warden.sources.count", len(resp.Sources)))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			tracing.RecordError(span, err)
+			logger.FromRequest(r).Error().Err(err).Msg(i18n.T(r, "error.json_encode_failed"))
+		}
+	}
+}
+
+// GetLoadReport returns a handler for GET /v1/admin/load-report.
+// It returns the validation report of the dataset applied by the latest reload: record counts, issue
+// counts per reason and one entry per dropped or conflicting record, plus the identity conflicts found
+// while merging the sources (conflicts may be nil).
+func GetLoadReport(lookup LoadReportLookup, conflicts ConflictLookup) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.StartSpan(r.Context(), "warden.admin.load_report")
+		defer span.End()
+
+		if r.Method != http.MethodGet {
+			tracing.RecordError(span, errors.New("method not allowed"))
+			logger.FromRequest(r).Warn().Str("method", r.Method).Msg(i18n.T(r, "log.unsupported_method"))
+			WriteJSONError(w, http.StatusMethodNotAllowed, i18n.T(r, "http.method_not_allowed"))
+			return
+		}
+
+		var (
+			report define.LoadReport
+			ok     bool
+		)
+		if lookup != nil {
+			report, ok = lookup.Report()
+		}
+		if !ok {
+			WriteJSONError(w, http.StatusNotFound, i18n.T(r, "http.load_report_not_found"))
+			return
+		}
+		report.Conflicts = []define.IdentityConflict{}
+		if conflicts != nil {
+			if cs := conflicts.IdentityConflicts(); cs != nil {
+				report.Conflicts = cs
+			}
+		}
+		span.SetAttributes(
+			attribute.Int("warden.load_report.total", report.Total),
+			attribute.Int("warden.load_report.issues", len(report.Issues)),
+			attribute.Int("warden.load_report.conflicts", len(report.Conflicts)),
+		)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			tracing.RecordError(span, err)
+			logger.FromRequest(r).Error().Err(err).Msg(i18n.T(r, "error.json_encode_failed"))
+		}
+	}
+}
+
+// GetInstances returns a handler for GET /v1/admin/instances.
+// It lists the instances that reported within cache.InstanceExpiry with the data version and hash they
+// serve, and whether they have converged on the same dataset.
+func GetInstances(lookup InstanceLookup) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.StartSpan(r.Context(), "warden.admin.instances")
+		defer span.End()
+
+		if r.Method != http.MethodGet {
+			tracing.RecordError(span, errors.New("method not allowed"))
+			logger.FromRequest(r).Warn().Str("method", r.Method).Msg(i18n.T(r, "log.unsupported_method"))
+			WriteJSONError(w, http.StatusMethodNotAllowed, i18n.T(r, "http.method_not_allowed"))
+			return
+		}
+
+		resp := InstancesResponse{Instances: []define.InstanceStatus{}, Converged: true}
+		if lookup != nil {
+			instances, err := lookup.Instances(ctx)
+			if err != nil {
+				tracing.RecordError(span, err)
+				logger.FromRequest(r).Error().Err(err).Msg(i18n.T(r, "http.internal_server_error"))
+				WriteJSONError(w, http.StatusInternalServerError, i18n.T(r, "http.internal_server_error"))
+				return
+			}
+			if instances != nil {
+				resp.Instances = instances
+			}
+		}
+		for _, st := range resp.Instances {
+			if st.Hash != resp.Instances[0].Hash {
+				resp.Converged = false
+				break
+			}
+		}
+		span.SetAttributes(
+			attribute.Int("warden.instances.count", len(resp.Instances)),
+			attribute.Bool("warden.instances.converged", resp.Converged),
+		)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			tracing.RecordError(span, err)
+			logger.FromRequest(r).Error().Err(err).Msg(i18n.T(r, "error.json_encode_failed"))
+		}
+	}
+}
+
+// writeDatasets writes the DatasetsResponse of m.
+func writeDatasets(ctx context.Context, w http.ResponseWriter, r *http.Request, span trace.Span, m DatasetManager) {
+	resp := DatasetsResponse{Datasets: []cache.Dataset{}}
+	if m != nil {
+		datasets, pinned, err := m.Datasets(ctx)
+		if err != nil {
+			tracing.RecordError(span, err)
+			logger.FromRequest(r).Error().Err(err).Msg(i18n.T(r, "http.internal_server_error"))
+			WriteJSONError(w, http.StatusInternalServerError, i18n.T(r, "http.internal_server_error"))
+			return
+		}
+		resp.Active, resp.Pinned = m.ActiveVersion(), pinned
+		if datasets != nil {
+			resp.Datasets = datasets
+		}
+	}
+	span.SetAttributes(
+		attribute.Int64("warden.datasets.active", resp.Active),
+		attribute.Int64("warden.datasets.pinned", resp.Pinned),
+		attribute.Int("warden.datasets.count", len(resp.Datasets)),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		tracing.RecordError(span, err)
+		logger.FromRequest(r).Error().Err(err).Msg(i18n.T(r, "error.json_encode_failed"))
+	}
+}
+
+// GetDatasets returns a handler for GET /v1/admin/datasets.
+// It lists the kept datasets (newest first) with version, hash, user count and apply time, plus the
+// active and pinned versions.
+func GetDatasets(m DatasetManager) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.StartSpan(r.Context(), "warden.admin.datasets")
+		defer span.End()
+
+		if r.Method != http.MethodGet {
+			tracing.RecordError(span, errors.New("method not allowed"))
+			logger.FromRequest(r).Warn().Str("method", r.Method).Msg(i18n.T(r, "log.unsupported_method"))
+			WriteJSONError(w, http.StatusMethodNotAllowed, i18n.T(r, "http.method_not_allowed"))
+			return
+		}
+		writeDatasets(ctx, w, r, span, m)
+	}
+}
+
+// PostRollback returns a handler for POST /v1/admin/rollback?version=N.
+// It applies kept dataset N again and pins it, so the sources are not loaded until POST /v1/admin/unpin.
+// Unknown or dropped versions get 404.
+func PostRollback(m DatasetManager) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.StartSpan(r.Context(), "warden.admin.rollback")
+		defer span.End()
+
+		if r.Method != http.MethodPost {
+			tracing.RecordError(span, errors.New("method not allowed"))
+			logger.FromRequest(r).Warn().Str("method", r.Method).Msg(i18n.T(r, "log.unsupported_method"))
+			WriteJSONError(w, http.StatusMethodNotAllowed, i18n.T(r, "http.method_not_allowed"))
+			return
+		}
+
+		version, err := strconv.ParseInt(strings.TrimSpace(r.URL.Query().Get("version")), 10, 64)
+		if err != nil || version <= 0 {
+			tracing.RecordError(span, fmt.Errorf("invalid dataset version"))
+			WriteJSONError(w, http.StatusBadRequest, i18n.T(r, "http.invalid_dataset_version"))
+			return
+		}
+		span.SetAttributes(attribute.Int64("warden.rollback.version", version))
+		if m == nil {
+			WriteJSONError(w, http.StatusNotFound, i18n.T(r, "http.dataset_not_found"))
+			return
+		}
+		if _, err := m.Rollback(ctx, version); err != nil {
+			tracing.RecordError(span, err)
+			if errors.Is(err, cache.ErrDatasetNotFound) {
+				WriteJSONError(w, http.StatusNotFound, i18n.T(r, "http.dataset_not_found"))
+				return
+			}
+			logger.FromRequest(r).Error().Err(err).Msg(i18n.T(r, "http.internal_server_error"))
+			WriteJSONError(w, http.StatusInternalServerError, i18n.T(r, "http.internal_server_error"))
+			return
+		}
+		writeDatasets(ctx, w, r, span, m)
+	}
+}
+
+// PostUnpin returns a handler for POST /v1/admin/unpin.
+// It removes the pin of a rollback; the sources are loaded again.
+func PostUnpin(m DatasetManager) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.StartSpan(r.Context(), "warden.admin.unpin")
+		defer span.End()
+
+		if r.Method != http.MethodPost {
+			tracing.RecordError(span, errors.New("method not allowed"))
+			logger.FromRequest(r).Warn().Str("method", r.Method).Msg(i18n.T(r, "log.unsupported_method"))
+			WriteJSONError(w, http.StatusMethodNotAllowed, i18n.T(r, "http.method_not_allowed"))
+			return
+		}
+		if m != nil {
+			if err := m.Unpin(ctx); err != nil {
+				tracing.RecordError(span, err)
+				logger.FromRequest(r).Error().Err(err).Msg(i18n.T(r, "http.internal_server_error"))
+				WriteJSONError(w, http.StatusInternalServerError, i18n.T(r, "http.internal_server_error"))
+				return
+			}
+		}
+		writeDatasets(ctx, w, r, span, m)
+	}
+}
@@ -0,0 +1,256 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/cache"
+	"github.com/soulteary/warden/internal/define"
+)
+
+// fakeProvenance returns a fixed provenance for the users it knows by phone.
+type fakeProvenance map[string]define.Provenance
+
+func (f fakeProvenance) Provenance(u *define.AllowListUser) (define.Provenance, bool) {
+	p, ok := f[u.Phone]
+	return p, ok
+}
+
+// serveProvenance runs the provenance handler for id through a mux so the {id} path value is set.
+func serveProvenance(t *testing.T, userCache *cache.SafeUserCache, lookup ProvenanceLookup, method, id string) *httptest.ResponseRecorder {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/admin/users/{id}/provenance", GetUserProvenance(userCache, lookup))
+	req := httptest.NewRequest(method, "/v1/admin/users/"+id+"/provenance", http.NoBody)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetUserProvenance(t *testing.T) {
+	userCache := cache.NewSafeUserCache()
+	userCache.Set([]define.AllowListUser{
+		{Phone: "13800138000", Mail: "a@example.com", UserID: "uid1", Status: "active"},
+		{Phone: "13900139000", UserID: "uid2", Status: "active"},
+	})
+	loadedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	lookup := fakeProvenance{"13800138000": {
+		LoadedAt: loadedAt,
+		Sources: []define.ProvenanceSource{
+			{Name: "/data/a.json", Type: "file"},
+			{Name: "https://example.com/users", Type: "remote"},
+		},
+	}}
+
+	for _, id := range []string{"uid1", "13800138000", "a@example.com"} {
+		w := serveProvenance(t, userCache, lookup, http.MethodGet, id)
+		require.Equal(t, http.StatusOK, w.Code, id)
+		var resp ProvenanceResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, "uid1", resp.UserID)
+		assert.True(t, loadedAt.Equal(resp.LoadedAt))
+		assert.Equal(t, lookup["13800138000"].Sources, resp.Sources)
+	}
+
+	assert.Equal(t, http.StatusNotFound, serveProvenance(t, userCache, lookup, http.MethodGet, "missing").Code)
+	assert.Equal(t, http.StatusNotFound, serveProvenance(t, userCache, lookup, http.MethodGet, "uid2").Code, "no provenance recorded")
+	assert.Equal(t, http.StatusNotFound, serveProvenance(t, userCache, nil, http.MethodGet, "uid1").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serveProvenance(t, userCache, lookup, http.MethodPost, "uid1").Code)
+}
+
+func TestSourceNames(t *testing.T) {
+	lookup := fakeProvenance{"1": {Sources: []define.ProvenanceSource{{Name: "a.json"}, {Name: "b.json"}}}}
+	assert.Equal(t, "a.json,b.json", sourceNames(lookup, &define.AllowListUser{Phone: "1"}))
+	assert.Empty(t, sourceNames(lookup, &define.AllowListUser{Phone: "2"}))
+	assert.Empty(t, sourceNames(nil, &define.AllowListUser{Phone: "1"}))
+}
+
+// fakeSources returns a fixed list of source statuses.
+type fakeSources []define.SourceStatus
+
+func (f fakeSources) SourceStatuses() []define.SourceStatus { return f }
+
+func TestGetSources(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	lookup := fakeSources{
+		{Name: "https://example.com/users", Type: "remote", LastAttempt: at, LastError: "status 502", LatencyMS: 12},
+		{Name: "/data/a.json", Type: "file", LastAttempt: at, LastSuccess: at, Records: 3},
+	}
+
+	w := httptest.NewRecorder()
+	GetSources(lookup)(w, httptest.NewRequest(http.MethodGet, "/v1/admin/sources", http.NoBody))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp SourcesResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.Sources, 2)
+	assert.Equal(t, "status 502", resp.Sources[0].LastError)
+	assert.True(t, resp.Sources[0].LastSuccess.IsZero())
+	assert.Equal(t, 3, resp.Sources[1].Records)
+
+	w = httptest.NewRecorder()
+	GetSources(nil)(w, httptest.NewRequest(http.MethodGet, "/v1/admin/sources", http.NoBody))
+	assert.JSONEq(t, `{"sources":[]}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	GetSources(lookup)(w, httptest.NewRequest(http.MethodPost, "/v1/admin/sources", http.NoBody))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+// fakeReport implements LoadReportLookup.
+type fakeReport struct {
+	report *define.LoadReport
+}
+
+func (f fakeReport) Report() (define.LoadReport, bool) {
+	if f.report == nil {
+		return define.LoadReport{}, false
+	}
+	return *f.report, true
+}
+
+func TestGetLoadReport(t *testing.T) {
+	w := httptest.NewRecorder()
+	GetLoadReport(fakeReport{}, nil)(w, httptest.NewRequest(http.MethodGet, "/v1/admin/load-report", http.NoBody))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	report := &define.LoadReport{Total: 3, Accepted: 1, Rejected: 1}
+	report.Add(define.LoadIssue{Index: 1, Phone: "123", Reason: define.ReasonInvalidPhone, Detail: "invalid phone"})
+	report.Add(define.LoadIssue{Index: 2, Phone: "13800138000", Reason: define.ReasonDuplicateKey, Detail: "replaces record 0"})
+	w = httptest.NewRecorder()
+	GetLoadReport(fakeReport{report: report}, nil)(w, httptest.NewRequest(http.MethodGet, "/v1/admin/load-report", http.NoBody))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp define.LoadReport
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, 3, resp.Total)
+	assert.Equal(t, map[string]int{define.ReasonInvalidPhone: 1, define.ReasonDuplicateKey: 1}, resp.Reasons)
+	require.Len(t, resp.Issues, 2)
+	assert.Equal(t, "replaces record 0", resp.Issues[1].Detail)
+
+	w = httptest.NewRecorder()
+	GetLoadReport(nil, nil)(w, httptest.NewRequest(http.MethodPost, "/v1/admin/load-report", http.NoBody))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+// fakeInstances returns fixed instance statuses.
+type fakeInstances struct {
+	instances []define.InstanceStatus
+	err       error
+}
+
+func (f fakeInstances) Instances(context.Context) ([]define.InstanceStatus, error) {
+	return f.instances, f.err
+}
+
+func TestGetInstances(t *testing.T) {
+	serve := func(lookup InstanceLookup, method string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		GetInstances(lookup)(w, httptest.NewRequest(method, "/v1/admin/instances", http.NoBody))
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) InstancesResponse {
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp InstancesResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return resp
+	}
+
+	resp := decode(serve(nil, http.MethodGet))
+	assert.Empty(t, resp.Instances)
+	assert.True(t, resp.Converged)
+
+	a := define.InstanceStatus{Instance: "a", Version: 2, Hash: "h2", Users: 3}
+	b := define.InstanceStatus{Instance: "b", Version: 2, Hash: "h2", Users: 3}
+	resp = decode(serve(fakeInstances{instances: []define.InstanceStatus{a, b}}, http.MethodGet))
+	require.Len(t, resp.Instances, 2)
+	assert.Equal(t, "b", resp.Instances[1].Instance)
+	assert.True(t, resp.Converged)
+
+	b.Version, b.Hash = 1, "h1"
+	resp = decode(serve(fakeInstances{instances: []define.InstanceStatus{a, b}}, http.MethodGet))
+	assert.False(t, resp.Converged)
+
+	assert.Equal(t, http.StatusInternalServerError, serve(fakeInstances{err: errors.New("redis down")}, http.MethodGet).Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(nil, http.MethodPost).Code)
+}
+
+// fakeDatasets implements DatasetManager in memory.
+type fakeDatasets struct {
+	datasets []cache.Dataset
+	active   int64
+	pinned   int64
+	err      error
+}
+
+func (f *fakeDatasets) ActiveVersion() int64 { return f.active }
+
+func (f *fakeDatasets) Datasets(context.Context) ([]cache.Dataset, int64, error) {
+	return f.datasets, f.pinned, f.err
+}
+
+func (f *fakeDatasets) Rollback(_ context.Context, version int64) (cache.Dataset, error) {
+	if f.err != nil {
+		return cache.Dataset{}, f.err
+	}
+	for _, ds := range f.datasets {
+		if ds.Version == version {
+			f.active, f.pinned = version, version
+			return ds, nil
+		}
+	}
+	return cache.Dataset{}, cache.ErrDatasetNotFound
+}
+
+func (f *fakeDatasets) Unpin(context.Context) error {
+	f.pinned = 0
+	return f.err
+}
+
+func TestDatasetHandlers(t *testing.T) {
+	m := &fakeDatasets{datasets: []cache.Dataset{{Version: 3, Hash: "h3", Users: 2}, {Version: 2, Hash: "h2", Users: 1}}, active: 3}
+	serve := func(h func(http.ResponseWriter, *http.Request), method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(method, target, http.NoBody))
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) DatasetsResponse {
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp DatasetsResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return resp
+	}
+
+	resp := decode(serve(GetDatasets(m), http.MethodGet, "/v1/admin/datasets"))
+	assert.Equal(t, int64(3), resp.Active)
+	assert.Zero(t, resp.Pinned)
+	assert.Len(t, resp.Datasets, 2)
+
+	resp = decode(serve(PostRollback(m), http.MethodPost, "/v1/admin/rollback?version=2"))
+	assert.Equal(t, int64(2), resp.Active)
+	assert.Equal(t, int64(2), resp.Pinned)
+
+	resp = decode(serve(PostUnpin(m), http.MethodPost, "/v1/admin/unpin"))
+	assert.Equal(t, int64(2), resp.Active)
+	assert.Zero(t, resp.Pinned)
+
+	assert.Equal(t, http.StatusBadRequest, serve(PostRollback(m), http.MethodPost, "/v1/admin/rollback").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(PostRollback(m), http.MethodPost, "/v1/admin/rollback?version=abc").Code)
+	assert.Equal(t, http.StatusNotFound, serve(PostRollback(m), http.MethodPost, "/v1/admin/rollback?version=1").Code)
+	assert.Equal(t, http.StatusNotFound, serve(PostRollback(nil), http.MethodPost, "/v1/admin/rollback?version=1").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(PostRollback(m), http.MethodGet, "/v1/admin/rollback?version=2").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(PostUnpin(m), http.MethodGet, "/v1/admin/unpin").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(GetDatasets(m), http.MethodPost, "/v1/admin/datasets").Code)
+
+	assert.Empty(t, decode(serve(GetDatasets(nil), http.MethodGet, "/v1/admin/datasets")).Datasets)
+
+	m.err = errors.New("redis down")
+	assert.Equal(t, http.StatusInternalServerError, serve(GetDatasets(m), http.MethodGet, "/v1/admin/datasets").Code)
+	assert.Equal(t, http.StatusInternalServerError, serve(PostRollback(m), http.MethodPost, "/v1/admin/rollback?version=2").Code)
+}
@@ -167,87 +167,81 @@ func encodeJSONResponse(w http.ResponseWriter, r *http.Request, data interface{}
 
 // JSON returns a JSON response handler for user data.
 // If responseFields is non-empty, only those fields are included in the response (whitelist).
+// The full list (no pagination parameters) is encoded once per cache generation and sent pre-compressed
+// with Content-Length; wrap the handler with CompressExceptFullList rather than a plain compress middleware.
 func JSON(userCache *cache.SafeUserCache, responseFields []string) func(http.ResponseWriter, *http.Request) {
+	list := newListResponses(userCache, responseFields)
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			logger.FromRequest(r).Warn().
-				Str("method", r.Method).
-				Msg(i18n.T(r, "log.unsupported_method"))
-			http.Error(w, i18n.T(r, "http.method_not_allowed"), http.StatusMethodNotAllowed)
+		page, pageSize, hasPagination, ok := checkListRequest(w, r)
+		if !ok {
 			return
 		}
-
-		page, pageSize, hasPagination, err := parsePaginationParams(r)
-		if err != nil {
-			logger.FromRequest(r).Warn().
-				Err(err).
-				Msg(i18n.T(r, "log.pagination_validation_failed"))
-			http.Error(w, i18n.T(r, "http.invalid_pagination_parameters"), http.StatusBadRequest)
-			return
-		}
-
-		userData := userCache.Get()
 		prommetrics.CacheHits.Inc()
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-
-		useFieldFilter := len(responseFields) > 0
-
 		if !hasPagination {
-			var payload interface{} = userData
-			if useFieldFilter {
-				payload = UsersToMaps(userData, responseFields)
+			body, err := list.get()
+			if err != nil {
+				logger.FromRequest(r).Error().Err(err).Msg(i18n.T(r, "error.json_encode_failed"))
+				http.Error(w, i18n.T(r, "http.internal_server_error"), http.StatusInternalServerError)
+				return
 			}
-			switch {
-			case len(userData) < define.SMALL_DATA_THRESHOLD:
-				if err := json.NewEncoder(w).Encode(payload); err != nil {
-					logger.FromRequest(r).Error().Err(err).Msg(i18n.T(r, "error.json_encode_failed"))
-					http.Error(w, i18n.T(r, "http.internal_server_error"), http.StatusInternalServerError)
-					return
-				}
-			case len(userData) < define.LARGE_DATA_THRESHOLD:
-				buf := getBuffer()
-				defer putBuffer(buf)
-				if err := json.NewEncoder(buf).Encode(payload); err != nil {
-					logger.FromRequest(r).Error().Err(err).Msg(i18n.T(r, "error.json_encode_failed"))
-					http.Error(w, i18n.T(r, "http.internal_server_error"), http.StatusInternalServerError)
-					return
-				}
-				if _, err := w.Write(buf.Bytes()); err != nil {
-					logger.FromRequest(r).Error().Err(err).Msg(i18n.T(r, "error.write_response_failed"))
-					return
-				}
-			default:
-				if err := json.NewEncoder(w).Encode(payload); err != nil {
-					logger.FromRequest(r).Error().Err(err).Msg(i18n.T(r, "error.stream_encode_failed"))
-					http.Error(w, i18n.T(r, "http.internal_server_error"), http.StatusInternalServerError)
-					return
-				}
+			if err := body.write(w, r); err != nil {
+				logger.FromRequest(r).Error().Err(err).Msg(i18n.T(r, "error.write_response_failed"))
+				return
 			}
 			logger.FromRequest(r).Info().Msg(i18n.T(r, "log.request_data_api"))
 			return
 		}
 
-		paginatedData, total, totalPages := paginate(userData, page, pageSize)
-		var response map[string]interface{}
-		if useFieldFilter {
-			response = map[string]interface{}{
-				"data":       UsersToMaps(paginatedData, responseFields),
-				"pagination": map[string]int{"page": page, "page_size": pageSize, "total": total, "total_pages": totalPages},
-			}
-		} else {
-			response = buildPaginatedResponse(paginatedData, page, pageSize, total, totalPages)
-		}
-		if err := encodeJSONResponse(w, r, response); err != nil {
-			return
+		paginatedData, total, totalPages := paginate(userCache.Get(), page, pageSize)
+		writePage(w, r, paginatedData, page, pageSize, total, totalPages, responseFields)
+	}
+}
+
+// checkListRequest validates the method and the pagination parameters of a list request and answers
+// invalid ones; ok is false when the request has been answered.
+func checkListRequest(w http.ResponseWriter, r *http.Request) (page, pageSize int, hasPagination, ok bool) {
+	if r.Method != http.MethodGet {
+		logger.FromRequest(r).Warn().
+			Str("method", r.Method).
+			Msg(i18n.T(r, "log.unsupported_method"))
+		http.Error(w, i18n.T(r, "http.method_not_allowed"), http.StatusMethodNotAllowed)
+		return 0, 0, false, false
+	}
+
+	page, pageSize, hasPagination, err := parsePaginationParams(r)
+	if err != nil {
+		logger.FromRequest(r).Warn().
+			Err(err).
+			Msg(i18n.T(r, "log.pagination_validation_failed"))
+		http.Error(w, i18n.T(r, "http.invalid_pagination_parameters"), http.StatusBadRequest)
+		return 0, 0, false, false
+	}
+	return page, pageSize, hasPagination, true
+}
+
+// writePage writes one page of the user list with its pagination block.
+func writePage(w http.ResponseWriter, r *http.Request, data []define.AllowListUser, page, pageSize, total, totalPages int, responseFields []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	var response map[string]interface{}
+	if len(responseFields) > 0 {
+		response = map[string]interface{}{
+			"data":       UsersToMaps(data, responseFields),
+			"pagination": map[string]int{"page": page, "page_size": pageSize, "total": total, "total_pages": totalPages},
 		}
-		logger.FromRequest(r).Info().
-			Int("page", page).
-			Int("page_size", pageSize).
-			Int("total", total).
-			Msg(i18n.T(r, "log.request_data_api"))
+	} else {
+		response = buildPaginatedResponse(data, page, pageSize, total, totalPages)
+	}
+	if err := encodeJSONResponse(w, r, response); err != nil {
+		return
 	}
+	logger.FromRequest(r).Info().
+		Int("page", page).
+		Int("page_size", pageSize).
+		Int("total", total).
+		Msg(i18n.T(r, "log.request_data_api"))
 }
 
 // WriteJSONError writes a JSON error response { "error": message } with the given status code.
@@ -0,0 +1,184 @@
+// Package router provides HTTP routing functionality.
+// list_response.go: full user list responses, encoded and compressed once per cache generation.
+package router
+
+import (
+	// Standard library
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	// Third-party libraries
+	"github.com/andybalholm/brotli"
+
+	// Internal packages
+	"github.com/soulteary/warden/internal/cache"
+	"github.com/soulteary/warden/internal/i18n"
+	"github.com/soulteary/warden/internal/logger"
+)
+
+// listCompressMinSize matches the compress middleware: smaller bodies are sent as they are.
+const listCompressMinSize = 1024
+
+// listBrotliQuality trades a few percent of size for encoding speed; brotli's top qualities are an order
+// of magnitude slower, which the first request after a dataset swap would have to wait for.
+const listBrotliQuality = brotli.DefaultCompression
+
+// listBody is the full-list response body for one cache generation. The compressed variants are built
+// on first use, so an encoding no client asks for costs nothing.
+type listBody struct {
+	gen    uint64
+	plain  []byte
+	gzip   func() ([]byte, error)
+	brotli func() ([]byte, error)
+}
+
+// listResponses caches the full-list body of one JSON handler, and thus of one response field set.
+type listResponses struct {
+	userCache *cache.SafeUserCache
+	fields    []string
+	mu        sync.Mutex // serializes rebuilds
+	cur       atomic.Pointer[listBody]
+}
+
+func newListResponses(userCache *cache.SafeUserCache, fields []string) *listResponses {
+	return &listResponses{userCache: userCache, fields: fields}
+}
+
+// get returns the body for the current cache generation, encoding it when the list changed.
+func (l *listResponses) get() (*listBody, error) {
+	if b := l.cur.Load(); b != nil && b.gen == l.userCache.Generation() {
+		return b, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b := l.cur.Load(); b != nil && b.gen == l.userCache.Generation() {
+		return b, nil
+	}
+
+	users, gen := l.userCache.GetWithGeneration()
+	var payload interface{} = users
+	if len(l.fields) > 0 {
+		payload = UsersToMaps(users, l.fields)
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(payload); err != nil {
+		return nil, err
+	}
+	plain := buf.Bytes()
+
+	b := &listBody{
+		gen:   gen,
+		plain: plain,
+		gzip: sync.OnceValues(func() ([]byte, error) {
+			var out bytes.Buffer
+			zw, err := gzip.NewWriterLevel(&out, gzip.BestCompression)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := zw.Write(plain); err != nil {
+				return nil, err
+			}
+			if err := zw.Close(); err != nil {
+				return nil, err
+			}
+			return out.Bytes(), nil
+		}),
+		brotli: sync.OnceValues(func() ([]byte, error) {
+			var out bytes.Buffer
+			bw := brotli.NewWriterLevel(&out, listBrotliQuality)
+			if _, err := bw.Write(plain); err != nil {
+				return nil, err
+			}
+			if err := bw.Close(); err != nil {
+				return nil, err
+			}
+			return out.Bytes(), nil
+		}),
+	}
+	l.cur.Store(b)
+	return b, nil
+}
+
+// write sends the body in the best encoding the client accepts (br, then gzip), with Content-Length.
+// If compressing fails the plain body is sent instead.
+func (b *listBody) write(w http.ResponseWriter, r *http.Request) error {
+	h := w.Header()
+	body, encoding := b.plain, ""
+	if len(b.plain) >= listCompressMinSize {
+		br, gz := acceptedEncodings(r.Header.Get("Accept-Encoding"))
+		var compressed []byte
+		var err error
+		switch {
+		case br:
+			compressed, err = b.brotli()
+			encoding = "br"
+		case gz:
+			compressed, err = b.gzip()
+			encoding = "gzip"
+		}
+		switch {
+		case err != nil:
+			logger.FromRequest(r).Warn().Err(err).Str("encoding", encoding).Msg(i18n.T(r, "log.list_compress_failed"))
+			encoding = ""
+		case encoding != "":
+			body = compressed
+		}
+		h.Set("Vary", "Accept-Encoding")
+	}
+
+	h.Set("Content-Type", "application/json")
+	if encoding != "" {
+		h.Set("Content-Encoding", encoding)
+	}
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write(body)
+	return err
+}
+
+// acceptedEncodings reports whether an Accept-Encoding header allows br and gzip (q=0 refuses a coding).
+func acceptedEncodings(header string) (br, gz bool) {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(strings.TrimSpace(q), 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "br":
+			br = true
+		case "gzip", "x-gzip":
+			gz = true
+		}
+	}
+	return br, gz
+}
+
+// isFullListRequest reports whether JSON serves r from the precomputed list body (no pagination parameters).
+func isFullListRequest(r *http.Request) bool {
+	q := r.URL.Query()
+	return q.Get("page") == "" && q.Get("page_size") == ""
+}
+
+// CompressExceptFullList applies compress to every request except full-list requests, whose bodies JSON
+// already sends compressed; the compress middleware cannot tell and would compress them a second time.
+func CompressExceptFullList(compress func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		compressed := compress(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isFullListRequest(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			compressed.ServeHTTP(w, r)
+		})
+	}
+}
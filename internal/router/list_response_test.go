@@ -0,0 +1,164 @@
+package router
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	middlewarekit "github.com/soulteary/middleware-kit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/cache"
+	"github.com/soulteary/warden/internal/define"
+)
+
+func listTestUsers(n int) []define.AllowListUser {
+	users := make([]define.AllowListUser, n)
+	for i := range users {
+		users[i] = define.AllowListUser{Phone: fmt.Sprintf("138%08d", i), Mail: fmt.Sprintf("user%d@example.com", i), Name: "User"}
+	}
+	return users
+}
+
+func getList(t *testing.T, h http.Handler, acceptEncoding string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	return w
+}
+
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder) []byte {
+	t.Helper()
+	var r io.Reader = bytes.NewReader(w.Body.Bytes())
+	switch w.Header().Get("Content-Encoding") {
+	case "gzip":
+		zr, err := gzip.NewReader(r)
+		require.NoError(t, err)
+		r = zr
+	case "br":
+		r = brotli.NewReader(r)
+	}
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return data
+}
+
+func TestJSON_FullListEncodings(t *testing.T) {
+	userCache := cache.NewSafeUserCache()
+	userCache.Set(listTestUsers(50))
+	want, err := json.Marshal(userCache.Get())
+	require.NoError(t, err)
+	h := http.HandlerFunc(JSON(userCache, nil))
+
+	tests := []struct {
+		accept   string
+		encoding string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0, gzip", "gzip"},
+		{"identity", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			w := getList(t, h, tt.accept)
+			assert.Equal(t, tt.encoding, w.Header().Get("Content-Encoding"))
+			assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+			assert.Equal(t, strconv.Itoa(w.Body.Len()), w.Header().Get("Content-Length"))
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			assert.JSONEq(t, string(want), string(decodeBody(t, w)))
+		})
+	}
+}
+
+func TestJSON_FullListSmallBodyUncompressed(t *testing.T) {
+	userCache := cache.NewSafeUserCache()
+	userCache.Set(listTestUsers(1))
+	w := getList(t, http.HandlerFunc(JSON(userCache, nil)), "gzip, br")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, strconv.Itoa(w.Body.Len()), w.Header().Get("Content-Length"))
+}
+
+func TestJSON_FullListRebuiltPerGeneration(t *testing.T) {
+	userCache := cache.NewSafeUserCache()
+	users := listTestUsers(3)
+	userCache.Set(users)
+	list := newListResponses(userCache, []string{"phone"})
+
+	first, err := list.get()
+	require.NoError(t, err)
+	again, err := list.get()
+	require.NoError(t, err)
+	assert.Same(t, first, again, "body must be reused while the list is unchanged")
+
+	// A reorder keeps the content hash but must still produce a new body
+	userCache.Set([]define.AllowListUser{users[2], users[1], users[0]})
+	reordered, err := list.get()
+	require.NoError(t, err)
+	assert.NotSame(t, first, reordered)
+	assert.JSONEq(t, `[{"phone":"13800000002"},{"phone":"13800000001"},{"phone":"13800000000"}]`, string(reordered.plain))
+
+	require.NoError(t, userCache.Upsert(define.AllowListUser{Phone: "13900000000"}))
+	upserted, err := list.get()
+	require.NoError(t, err)
+	assert.Contains(t, string(upserted.plain), "13900000000")
+}
+
+func TestCompressExceptFullList(t *testing.T) {
+	userCache := cache.NewSafeUserCache()
+	userCache.Set(listTestUsers(200))
+	want, err := json.Marshal(userCache.Get())
+	require.NoError(t, err)
+	h := CompressExceptFullList(middlewarekit.CompressStd(middlewarekit.DefaultCompressConfig()))(http.HandlerFunc(JSON(userCache, nil)))
+
+	// Full list: compressed once, by JSON
+	w := getList(t, h, "gzip")
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.JSONEq(t, string(want), string(decodeBody(t, w)))
+
+	// Paginated: compressed by the middleware
+	req := httptest.NewRequest(http.MethodGet, "/?page=1&page_size=100", http.NoBody)
+	req.Header.Set("Accept-Encoding", "gzip")
+	pw := httptest.NewRecorder()
+	h.ServeHTTP(pw, req)
+	require.Equal(t, http.StatusOK, pw.Code)
+	assert.Equal(t, "gzip", pw.Header().Get("Content-Encoding"))
+	var page struct {
+		Data []define.AllowListUser `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(decodeBody(t, pw), &page))
+	assert.Len(t, page.Data, 100)
+}
+
+func TestAcceptedEncodings(t *testing.T) {
+	tests := []struct {
+		header string
+		br, gz bool
+	}{
+		{"", false, false},
+		{"gzip", false, true},
+		{"GZIP, BR", true, true},
+		{"br;q=0.5, x-gzip;q=1", true, true},
+		{"br;q=0, gzip;q=0.0", false, false},
+		{"deflate, identity", false, false},
+	}
+	for _, tt := range tests {
+		br, gz := acceptedEncodings(tt.header)
+		assert.Equal(t, tt.br, br, tt.header)
+		assert.Equal(t, tt.gz, gz, tt.header)
+	}
+}
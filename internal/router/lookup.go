@@ -38,7 +38,9 @@ type Destination struct {
 // GetLookup returns a handler for GET /v1/lookup?identifier=xxx.
 // identifier is auto-detected: if it contains @ then mail; else try phone then user_id.
 // Returns { user_id, destination: { email?, phone? }, status, channel_hint } for Stargate/Herald.
-func GetLookup(userCache *cache.SafeUserCache) func(http.ResponseWriter, *http.Request) {
+// userCache is the memory cache or, with REDIS_USER_STORE=hash, the per-user Redis store.
+// lookup (optional) supplies the user's data sources for the audit log.
+func GetLookup(userCache cache.UserLookup, lookup ProvenanceLookup) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		_, span := tracing.StartSpan(r.Context(), "warden.lookup")
 		defer span.End()
@@ -87,7 +89,7 @@ func GetLookup(userCache *cache.SafeUserCache) func(http.ResponseWriter, *http.R
 			} else {
 				sanitized = logger.SanitizePhone(identifier)
 			}
-			auditlog.LogUserQuery(r.Context(), "", sanitized, "identifier", r.RemoteAddr, false, "user_not_found")
+			auditlog.LogUserQuery(r.Context(), "", sanitized, "identifier", r.RemoteAddr, false, "user_not_found", "")
 			WriteJSONError(w, http.StatusNotFound, i18n.T(r, "http.user_not_found"))
 			return
 		}
@@ -130,6 +132,6 @@ func GetLookup(userCache *cache.SafeUserCache) func(http.ResponseWriter, *http.R
 		} else {
 			sanitized = logger.SanitizePhone(identifier)
 		}
-		auditlog.LogUserQuery(r.Context(), user.UserID, sanitized, "identifier", r.RemoteAddr, true, "")
+		auditlog.LogUserQuery(r.Context(), user.UserID, sanitized, "identifier", r.RemoteAddr, true, "", sourceNames(lookup, &user))
 	}
 }
@@ -22,7 +22,7 @@ func TestGetLookup_ByMail(t *testing.T) {
 	userCache := cache.NewSafeUserCache()
 	userCache.Set(testUsers)
 
-	handler := GetLookup(userCache)
+	handler := GetLookup(userCache, nil)
 
 	req := httptest.NewRequest("GET", "/v1/lookup?identifier=test1@example.com", http.NoBody)
 	w := httptest.NewRecorder()
@@ -49,7 +49,7 @@ func TestGetLookup_EmailOnlyUser(t *testing.T) {
 	userCache := cache.NewSafeUserCache()
 	userCache.Set(testUsers)
 
-	handler := GetLookup(userCache)
+	handler := GetLookup(userCache, nil)
 
 	req := httptest.NewRequest("GET", "/v1/lookup?identifier=only@example.com", http.NoBody)
 	w := httptest.NewRecorder()
@@ -71,7 +71,7 @@ func TestGetLookup_NotFound(t *testing.T) {
 	userCache := cache.NewSafeUserCache()
 	userCache.Set([]define.AllowListUser{})
 
-	handler := GetLookup(userCache)
+	handler := GetLookup(userCache, nil)
 
 	req := httptest.NewRequest("GET", "/v1/lookup?identifier=nobody@example.com", http.NoBody)
 	w := httptest.NewRecorder()
@@ -82,7 +82,7 @@ func TestGetLookup_NotFound(t *testing.T) {
 
 func TestGetLookup_MissingIdentifier(t *testing.T) {
 	userCache := cache.NewSafeUserCache()
-	handler := GetLookup(userCache)
+	handler := GetLookup(userCache, nil)
 
 	req := httptest.NewRequest("GET", "/v1/lookup", http.NoBody)
 	w := httptest.NewRecorder()
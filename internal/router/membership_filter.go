@@ -0,0 +1,60 @@
+// Package router provides HTTP routing functionality.
+// membership_filter.go: GET /v1/membership-filter, the Bloom filter of the current dataset for SDK clients.
+package router
+
+import (
+	// Standard library
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	// Internal packages
+	"github.com/soulteary/warden/internal/cache"
+	"github.com/soulteary/warden/internal/i18n"
+	"github.com/soulteary/warden/internal/logger"
+	"github.com/soulteary/warden/pkg/warden"
+)
+
+// encodedFilter is a membership filter with its binary encoding.
+type encodedFilter struct {
+	filter *warden.MembershipFilter
+	data   []byte
+}
+
+// GetMembershipFilter returns a handler for GET /v1/membership-filter. It serves the membership filter of
+// the current list in the binary format of warden.MembershipFilter, encoded once per filter, and 404 when
+// the filter is not enabled (or, with the per-user Redis store, no generation has been loaded yet).
+func GetMembershipFilter(userCache cache.MembershipFilterSource) func(http.ResponseWriter, *http.Request) {
+	var cur atomic.Pointer[encodedFilter]
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			logger.FromRequest(r).Warn().Str("method", r.Method).Msg(i18n.T(r, "log.unsupported_method"))
+			WriteJSONError(w, http.StatusMethodNotAllowed, i18n.T(r, "http.method_not_allowed"))
+			return
+		}
+
+		filter := userCache.MembershipFilter()
+		if filter == nil {
+			WriteJSONError(w, http.StatusNotFound, i18n.T(r, "http.membership_filter_disabled"))
+			return
+		}
+		enc := cur.Load()
+		if enc == nil || enc.filter != filter {
+			data, err := filter.MarshalBinary()
+			if err != nil {
+				logger.FromRequest(r).Error().Err(err).Msg(i18n.T(r, "log.membership_filter_encode_failed"))
+				WriteJSONError(w, http.StatusInternalServerError, i18n.T(r, "http.internal_server_error"))
+				return
+			}
+			enc = &encodedFilter{filter: filter, data: data}
+			cur.Store(enc)
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.Itoa(len(enc.data)))
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(enc.data); err != nil {
+			logger.FromRequest(r).Error().Err(err).Msg(i18n.T(r, "error.write_response_failed"))
+		}
+	}
+}
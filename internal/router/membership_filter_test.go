@@ -0,0 +1,52 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/cache"
+	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/pkg/warden"
+)
+
+func TestGetMembershipFilter(t *testing.T) {
+	userCache := cache.NewSafeUserCache()
+	userCache.Set([]define.AllowListUser{{Phone: "13800138000", Mail: "a@example.com"}})
+	handler := GetMembershipFilter(userCache)
+
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/v1/membership-filter", http.NoBody))
+		return w
+	}
+
+	w := get()
+	assert.Equal(t, http.StatusNotFound, w.Code, "404 while the filter is disabled")
+
+	userCache.EnableMembershipFilter(0.01)
+	w = get()
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, strconv.Itoa(w.Body.Len()), w.Header().Get("Content-Length"))
+
+	var f warden.MembershipFilter
+	require.NoError(t, f.UnmarshalBinary(w.Body.Bytes()))
+	assert.True(t, f.MayContain(warden.IdentifierPhone, "13800138000"))
+	assert.True(t, f.MayContain(warden.IdentifierMail, "a@example.com"))
+
+	// A new dataset is served on the next request
+	userCache.Set([]define.AllowListUser{{Phone: "13900139000"}})
+	w = get()
+	require.NoError(t, f.UnmarshalBinary(w.Body.Bytes()))
+	assert.True(t, f.MayContain(warden.IdentifierPhone, "13900139000"))
+	assert.False(t, f.MayContain(warden.IdentifierMail, "a@example.com"))
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/v1/membership-filter", http.NoBody))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,149 @@
+// Package router provides HTTP routing functionality.
+// store_list.go: user list responses read page by page from the per-user Redis store.
+package router
+
+import (
+	// Standard library
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	// Internal packages
+	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/internal/i18n"
+	"github.com/soulteary/warden/internal/logger"
+	"github.com/soulteary/warden/internal/prommetrics"
+)
+
+// UserPages is a user list that stays in Redis and is read page by page (cache.RedisUserStore).
+type UserPages interface {
+	// Page returns up to limit users from offset and the number of users in the list.
+	Page(offset, limit int) ([]define.AllowListUser, int, error)
+	// Each calls fn with the whole list, one page at a time.
+	Each(fn func(users []define.AllowListUser) error) error
+}
+
+// PagedJSON is JSON for a list kept in store: a page is read from the store and the full list is streamed
+// page by page, so no instance has to hold the list in memory. The full list is gzip-compressed while it
+// is written when the client accepts it (wrap the handler with CompressExceptFullList, like JSON).
+// Requests are served by fallback (JSON of the memory cache) while the store is empty or unreachable,
+// as long as nothing has been written yet.
+func PagedJSON(store UserPages, fallback http.HandlerFunc, responseFields []string) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, pageSize, hasPagination, ok := checkListRequest(w, r)
+		if !ok {
+			return
+		}
+
+		if hasPagination {
+			users, total, err := store.Page((page-1)*pageSize, pageSize)
+			if err != nil {
+				logger.FromRequest(r).Warn().Err(err).Msg(i18n.T(r, "log.user_store_page_failed"))
+				fallback(w, r)
+				return
+			}
+			prommetrics.CacheHits.Inc()
+			writePage(w, r, users, page, pageSize, total, (total+pageSize-1)/pageSize, responseFields)
+			return
+		}
+
+		stream := &listStream{w: w, r: r, fields: responseFields}
+		err := store.Each(stream.write)
+		if err == nil {
+			err = stream.close()
+		}
+		switch {
+		case err != nil && !stream.started:
+			logger.FromRequest(r).Warn().Err(err).Msg(i18n.T(r, "log.user_store_list_failed"))
+			fallback(w, r)
+		case err != nil:
+			// The status line is out; the client sees a truncated body
+			logger.FromRequest(r).Error().Err(err).Msg(i18n.T(r, "error.write_response_failed"))
+		default:
+			prommetrics.CacheHits.Inc()
+			logger.FromRequest(r).Info().Msg(i18n.T(r, "log.request_data_api"))
+		}
+	}
+}
+
+// listStream writes a JSON array of users page by page, in the format of the full-list body of JSON.
+type listStream struct {
+	w       http.ResponseWriter
+	r       *http.Request
+	fields  []string
+	out     *bufio.Writer
+	gz      *gzip.Writer
+	started bool // the response header has been written
+	n       int  // users written
+}
+
+// start writes the response header, choosing gzip when the client accepts it.
+func (s *listStream) start() {
+	h := s.w.Header()
+	h.Set("Content-Type", "application/json")
+	h.Set("Vary", "Accept-Encoding")
+	var dst io.Writer = s.w
+	if _, gz := acceptedEncodings(s.r.Header.Get("Accept-Encoding")); gz {
+		h.Set("Content-Encoding", "gzip")
+		s.gz = gzip.NewWriter(s.w)
+		dst = s.gz
+	}
+	s.w.WriteHeader(http.StatusOK)
+	s.started = true
+	s.out = bufio.NewWriter(dst)
+}
+
+// write appends a page of users.
+func (s *listStream) write(users []define.AllowListUser) error {
+	if !s.started {
+		s.start()
+	}
+	var page interface{} = users
+	if len(s.fields) > 0 {
+		page = UsersToMaps(users, s.fields)
+	}
+	data, err := json.Marshal(page)
+	if err != nil {
+		return err
+	}
+	// data is "[...]": drop the brackets and join it to the previous page
+	sep := byte(',')
+	if s.n == 0 {
+		sep = '['
+	}
+	if err := s.out.WriteByte(sep); err != nil {
+		return err
+	}
+	if _, err := s.out.Write(data[1 : len(data)-1]); err != nil {
+		return err
+	}
+	s.n += len(users)
+	return nil
+}
+
+// close ends the array and flushes the response. An empty list is written as JSON writes it: [], or
+// null with response fields.
+func (s *listStream) close() error {
+	if !s.started {
+		s.start()
+	}
+	end := "]\n"
+	switch {
+	case s.n == 0 && len(s.fields) > 0:
+		end = "null\n"
+	case s.n == 0:
+		end = "[]\n"
+	}
+	if _, err := s.out.WriteString(end); err != nil {
+		return err
+	}
+	if err := s.out.Flush(); err != nil {
+		return err
+	}
+	if s.gz != nil {
+		return s.gz.Close()
+	}
+	return nil
+}
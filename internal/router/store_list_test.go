@@ -0,0 +1,106 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/soulteary/warden/internal/cache"
+	"github.com/soulteary/warden/internal/define"
+)
+
+// fakePages serves users in pages of pageSize; err fails reads from page failAt on (-1 = never).
+type fakePages struct {
+	users    []define.AllowListUser
+	pageSize int
+	failAt   int
+	err      error
+}
+
+func (f *fakePages) Page(offset, limit int) ([]define.AllowListUser, int, error) {
+	if f.err != nil && f.failAt == 0 {
+		return nil, 0, f.err
+	}
+	if offset >= len(f.users) {
+		return []define.AllowListUser{}, len(f.users), nil
+	}
+	return f.users[offset:min(offset+limit, len(f.users))], len(f.users), nil
+}
+
+func (f *fakePages) Each(fn func([]define.AllowListUser) error) error {
+	for i, start := 0, 0; start < len(f.users) || (i == 0 && f.err != nil); i, start = i+1, start+f.pageSize {
+		if f.err != nil && i == f.failAt {
+			return f.err
+		}
+		if err := fn(f.users[start:min(start+f.pageSize, len(f.users))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestPagedJSON_SameBodyAsJSON(t *testing.T) {
+	for _, fields := range [][]string{nil, {"phone", "name"}} {
+		for _, n := range []int{0, 1, 7, 2500} {
+			users := listTestUsers(n)
+			userCache := cache.NewSafeUserCache()
+			userCache.Set(users)
+			memory := http.HandlerFunc(JSON(userCache, fields))
+			store := &fakePages{users: userCache.Get(), pageSize: 3, failAt: -1}
+			paged := http.HandlerFunc(PagedJSON(store, func(http.ResponseWriter, *http.Request) {
+				t.Fatal("fallback used")
+			}, fields))
+
+			want := decodeBody(t, getList(t, memory, ""))
+			got := getList(t, paged, "")
+			assert.Equal(t, "application/json", got.Header().Get("Content-Type"))
+			assert.Equal(t, string(want), got.Body.String(), "n=%d fields=%v", n, fields)
+
+			gz := getList(t, paged, "br, gzip")
+			assert.Equal(t, "gzip", gz.Header().Get("Content-Encoding"))
+			assert.Equal(t, string(want), string(decodeBody(t, gz)))
+
+			for _, query := range []string{"?page=1&page_size=5", "?page=2&page_size=5", "?page=1000&page_size=5", "?page_size=1000"} {
+				want := httptest.NewRecorder()
+				memory.ServeHTTP(want, httptest.NewRequest(http.MethodGet, "/"+query, http.NoBody))
+				got := httptest.NewRecorder()
+				paged.ServeHTTP(got, httptest.NewRequest(http.MethodGet, "/"+query, http.NoBody))
+				require.Equal(t, http.StatusOK, got.Code)
+				assert.JSONEq(t, want.Body.String(), got.Body.String(), "n=%d %s", n, query)
+			}
+		}
+	}
+}
+
+func TestPagedJSON_Fallback(t *testing.T) {
+	userCache := cache.NewSafeUserCache()
+	userCache.Set(listTestUsers(3))
+	memory := JSON(userCache, nil)
+	broken := errors.New("redis down")
+
+	// The store fails before anything was written: the memory cache answers
+	paged := http.HandlerFunc(PagedJSON(&fakePages{pageSize: 2, failAt: 0, err: broken}, memory, nil))
+	for _, target := range []string{"/", "/?page=1&page_size=2"} {
+		want := httptest.NewRecorder()
+		memory(want, httptest.NewRequest(http.MethodGet, target, http.NoBody))
+		got := httptest.NewRecorder()
+		paged.ServeHTTP(got, httptest.NewRequest(http.MethodGet, target, http.NoBody))
+		assert.Equal(t, http.StatusOK, got.Code)
+		assert.Equal(t, want.Body.String(), got.Body.String(), target)
+	}
+
+	// The store fails after the first page: the body is cut off, the memory cache is not mixed in
+	paged = http.HandlerFunc(PagedJSON(&fakePages{users: listTestUsers(5), pageSize: 2, failAt: 1, err: broken}, memory, nil))
+	got := httptest.NewRecorder()
+	paged.ServeHTTP(got, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	assert.Equal(t, http.StatusOK, got.Code)
+	assert.NotContains(t, got.Body.String(), "]", "never a complete list")
+
+	got = httptest.NewRecorder()
+	paged.ServeHTTP(got, httptest.NewRequest(http.MethodPost, "/", http.NoBody))
+	assert.Equal(t, http.StatusMethodNotAllowed, got.Code)
+}
@@ -23,8 +23,10 @@ import (
 )
 
 // GetUserByIdentifier queries a single user by identifier.
+// userCache is the memory cache or, with REDIS_USER_STORE=hash, the per-user Redis store.
 // If responseFields is non-empty, only those fields are included in the JSON response.
-func GetUserByIdentifier(userCache *cache.SafeUserCache, responseFields []string) func(http.ResponseWriter, *http.Request) {
+// lookup (optional) supplies the user's data sources for the audit log.
+func GetUserByIdentifier(userCache cache.UserLookup, responseFields []string, lookup ProvenanceLookup) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Start span for user query
 		_, span := tracing.StartSpan(r.Context(), "warden.get_user")
@@ -122,7 +124,7 @@ func GetUserByIdentifier(userCache *cache.SafeUserCache, responseFields []string
 				identifier = userID
 				identifierType = "user_id"
 			}
-			auditlog.LogUserQuery(r.Context(), "", sanitizeIdentifierForAudit(identifier, identifierType), identifierType, r.RemoteAddr, false, "user_not_found")
+			auditlog.LogUserQuery(r.Context(), "", sanitizeIdentifierForAudit(identifier, identifierType), identifierType, r.RemoteAddr, false, "user_not_found", "")
 
 			WriteJSONError(w, http.StatusNotFound, i18n.T(r, "http.user_not_found"))
 			return
@@ -166,7 +168,7 @@ func GetUserByIdentifier(userCache *cache.SafeUserCache, responseFields []string
 			identifier = userID
 			identifierType = "user_id"
 		}
-		auditlog.LogUserQuery(r.Context(), user.UserID, sanitizeIdentifierForAudit(identifier, identifierType), identifierType, r.RemoteAddr, true, "")
+		auditlog.LogUserQuery(r.Context(), user.UserID, sanitizeIdentifierForAudit(identifier, identifierType), identifierType, r.RemoteAddr, true, "", sourceNames(lookup, &user))
 	}
 }
 
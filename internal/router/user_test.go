@@ -30,7 +30,7 @@ func TestGetUserByIdentifier_ByPhone(t *testing.T) {
 	userCache := cache.NewSafeUserCache()
 	userCache.Set(testUsers)
 
-	handler := GetUserByIdentifier(userCache, nil)
+	handler := GetUserByIdentifier(userCache, nil, nil)
 
 	req := httptest.NewRequest("GET", "/user?phone=13800138000", http.NoBody)
 	w := httptest.NewRecorder()
@@ -57,7 +57,7 @@ func TestGetUserByIdentifier_ByMail(t *testing.T) {
 	userCache := cache.NewSafeUserCache()
 	userCache.Set(testUsers)
 
-	handler := GetUserByIdentifier(userCache, nil)
+	handler := GetUserByIdentifier(userCache, nil, nil)
 
 	req := httptest.NewRequest("GET", "/user?mail=test2@example.com", http.NoBody)
 	w := httptest.NewRecorder()
@@ -84,7 +84,7 @@ func TestGetUserByIdentifier_ByUserID(t *testing.T) {
 	userCache := cache.NewSafeUserCache()
 	userCache.Set(testUsers)
 
-	handler := GetUserByIdentifier(userCache, nil)
+	handler := GetUserByIdentifier(userCache, nil, nil)
 
 	req := httptest.NewRequest("GET", "/user?user_id=user1", http.NoBody)
 	w := httptest.NewRecorder()
@@ -109,7 +109,7 @@ func TestGetUserByIdentifier_WithResponseFields(t *testing.T) {
 	userCache := cache.NewSafeUserCache()
 	userCache.Set(testUsers)
 	responseFields := []string{"phone", "user_id"}
-	handler := GetUserByIdentifier(userCache, responseFields)
+	handler := GetUserByIdentifier(userCache, responseFields, nil)
 
 	req := httptest.NewRequest("GET", "/user?phone=13800138000", http.NoBody)
 	w := httptest.NewRecorder()
@@ -127,7 +127,7 @@ func TestGetUserByIdentifier_WithResponseFields(t *testing.T) {
 // TestGetUserByIdentifier_MissingIdentifier tests missing identifier
 func TestGetUserByIdentifier_MissingIdentifier(t *testing.T) {
 	userCache := cache.NewSafeUserCache()
-	handler := GetUserByIdentifier(userCache, nil)
+	handler := GetUserByIdentifier(userCache, nil, nil)
 
 	req := httptest.NewRequest("GET", "/user", http.NoBody)
 	w := httptest.NewRecorder()
@@ -140,7 +140,7 @@ func TestGetUserByIdentifier_MissingIdentifier(t *testing.T) {
 // TestGetUserByIdentifier_MultipleIdentifiers tests providing multiple identifiers
 func TestGetUserByIdentifier_MultipleIdentifiers(t *testing.T) {
 	userCache := cache.NewSafeUserCache()
-	handler := GetUserByIdentifier(userCache, nil)
+	handler := GetUserByIdentifier(userCache, nil, nil)
 
 	req := httptest.NewRequest("GET", "/user?phone=13800138000&mail=test@example.com", http.NoBody)
 	w := httptest.NewRecorder()
@@ -153,7 +153,7 @@ func TestGetUserByIdentifier_MultipleIdentifiers(t *testing.T) {
 // TestGetUserByIdentifier_IdentifierTooLong tests that identifier over MAX_IDENTIFIER_LENGTH returns 400
 func TestGetUserByIdentifier_IdentifierTooLong(t *testing.T) {
 	userCache := cache.NewSafeUserCache()
-	handler := GetUserByIdentifier(userCache, nil)
+	handler := GetUserByIdentifier(userCache, nil, nil)
 
 	longPhone := strings.Repeat("1", define.MAX_IDENTIFIER_LENGTH+1)
 
@@ -174,7 +174,7 @@ func TestGetUserByIdentifier_UserNotFound(t *testing.T) {
 	userCache := cache.NewSafeUserCache()
 	userCache.Set(testUsers)
 
-	handler := GetUserByIdentifier(userCache, nil)
+	handler := GetUserByIdentifier(userCache, nil, nil)
 
 	req := httptest.NewRequest("GET", "/user?phone=99999999999", http.NoBody)
 	w := httptest.NewRecorder()
@@ -187,7 +187,7 @@ func TestGetUserByIdentifier_UserNotFound(t *testing.T) {
 // TestGetUserByIdentifier_InvalidMethod tests invalid HTTP method
 func TestGetUserByIdentifier_InvalidMethod(t *testing.T) {
 	userCache := cache.NewSafeUserCache()
-	handler := GetUserByIdentifier(userCache, nil)
+	handler := GetUserByIdentifier(userCache, nil, nil)
 
 	methods := []string{"POST", "PUT", "DELETE", "PATCH"}
 
@@ -212,7 +212,7 @@ func TestGetUserByIdentifier_WithSpaces(t *testing.T) {
 	userCache := cache.NewSafeUserCache()
 	userCache.Set(testUsers)
 
-	handler := GetUserByIdentifier(userCache, nil)
+	handler := GetUserByIdentifier(userCache, nil, nil)
 
 	// Test phone number with spaces (URL encoded space is %20)
 	req := httptest.NewRequest("GET", "/user?phone=%2013800138000%20", http.NoBody)
@@ -240,7 +240,7 @@ func TestGetUserByIdentifier_Contract_StargateFields(t *testing.T) {
 	userCache := cache.NewSafeUserCache()
 	userCache.Set(testUsers)
 
-	handler := GetUserByIdentifier(userCache, nil)
+	handler := GetUserByIdentifier(userCache, nil, nil)
 	req := httptest.NewRequest("GET", "/user?phone=13800138000", http.NoBody)
 	w := httptest.NewRecorder()
 
@@ -273,7 +273,7 @@ func TestGetUserByIdentifier_JSONEncodingError(t *testing.T) {
 	userCache := cache.NewSafeUserCache()
 	userCache.Set(testUsers)
 
-	handler := GetUserByIdentifier(userCache, nil)
+	handler := GetUserByIdentifier(userCache, nil, nil)
 
 	req := httptest.NewRequest("GET", "/user?phone=13800138000", http.NoBody)
 	w := httptest.NewRecorder()
@@ -0,0 +1,50 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// DatasetVersionHeader carries the version of the served dataset (the Git source commit) on data responses.
+const DatasetVersionHeader = "X-Warden-Dataset-Version"
+
+// DatasetVersioner returns the version of the served dataset, "" when unversioned (implemented by loader.RulesLoader).
+type DatasetVersioner interface {
+	DatasetVersion() string
+}
+
+// DatasetVersionMiddleware sets DatasetVersionHeader when the dataset has a version.
+func DatasetVersionMiddleware(v DatasetVersioner) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if v != nil {
+				if version := v.DatasetVersion(); version != "" {
+					w.Header().Set(DatasetVersionHeader, version)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ActiveVersionHeader carries the history version of the served dataset (see POST /v1/admin/rollback) on every response.
+const ActiveVersionHeader = "X-Warden-Active-Version"
+
+// ActiveVersioner returns the history version of the served dataset, 0 when unknown (implemented by the app).
+type ActiveVersioner interface {
+	ActiveVersion() int64
+}
+
+// ActiveVersionMiddleware sets ActiveVersionHeader when the active version is known.
+func ActiveVersionMiddleware(v ActiveVersioner) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if v != nil {
+				if version := v.ActiveVersion(); version > 0 {
+					w.Header().Set(ActiveVersionHeader, strconv.FormatInt(version, 10))
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
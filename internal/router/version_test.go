@@ -0,0 +1,62 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeVersion string
+
+func (f fakeVersion) DatasetVersion() string { return string(f) }
+
+func TestDatasetVersionMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name    string
+		v       DatasetVersioner
+		want    string
+		present bool
+	}{
+		{name: "versioned", v: fakeVersion("0123456789abcdef0123456789abcdef01234567"), want: "0123456789abcdef0123456789abcdef01234567", present: true},
+		{name: "unversioned", v: fakeVersion("")},
+		{name: "nil versioner", v: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			DatasetVersionMiddleware(tt.v)(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			_, ok := rec.Header()[DatasetVersionHeader]
+			assert.Equal(t, tt.present, ok)
+			assert.Equal(t, tt.want, rec.Header().Get(DatasetVersionHeader))
+		})
+	}
+}
+
+type fakeActiveVersion int64
+
+func (f fakeActiveVersion) ActiveVersion() int64 { return int64(f) }
+
+func TestActiveVersionMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	rec := httptest.NewRecorder()
+	ActiveVersionMiddleware(fakeActiveVersion(7))(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", http.NoBody))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "7", rec.Header().Get(ActiveVersionHeader))
+
+	for _, v := range []ActiveVersioner{fakeActiveVersion(0), nil} {
+		rec = httptest.NewRecorder()
+		ActiveVersionMiddleware(v)(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+		_, ok := rec.Header()[ActiveVersionHeader]
+		assert.False(t, ok)
+	}
+}
@@ -0,0 +1,204 @@
+// Package signature provides detached Ed25519 / ECDSA signatures for user data.
+//
+// A signature is the base64 (standard encoding) of:
+//   - Ed25519: the 64-byte signature over the raw payload
+//   - ECDSA: the ASN.1 DER signature over the SHA-256 digest of the payload
+//
+// Local files carry it in a detached "<file>.sig" file, remote responses in the SignatureHeader header.
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// SignatureHeader is the response header carrying the signature of a remote payload.
+	SignatureHeader = "X-Warden-Signature"
+	// FileSuffix is appended to a data file path to locate its detached signature.
+	FileSuffix = ".sig"
+)
+
+var (
+	// ErrUnsigned is returned when verification is required but no signature is present.
+	ErrUnsigned = errors.New("signature: data is not signed")
+	// ErrInvalid is returned when no trusted key verifies the signature.
+	ErrInvalid = errors.New("signature: invalid signature")
+)
+
+// Verifier verifies payloads against a set of trusted public keys.
+type Verifier struct {
+	keys []crypto.PublicKey
+}
+
+// NewVerifier creates a Verifier trusting keys. Only Ed25519 and ECDSA keys are accepted.
+func NewVerifier(keys ...crypto.PublicKey) (*Verifier, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("signature: no trusted public keys")
+	}
+	for _, k := range keys {
+		switch k.(type) {
+		case ed25519.PublicKey, *ecdsa.PublicKey:
+		default:
+			return nil, fmt.Errorf("signature: unsupported public key type %T (want Ed25519 or ECDSA)", k)
+		}
+	}
+	return &Verifier{keys: keys}, nil
+}
+
+// LoadVerifier reads PEM public keys from paths. A path may be a file holding one or more
+// "PUBLIC KEY" blocks, or a directory whose *.pem files are all loaded.
+func LoadVerifier(paths []string) (*Verifier, error) {
+	var keys []crypto.PublicKey
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		files := []string{p}
+		if fi, err := os.Stat(p); err == nil && fi.IsDir() {
+			matches, err := filepath.Glob(filepath.Join(p, "*.pem"))
+			if err != nil {
+				return nil, fmt.Errorf("signature: %w", err)
+			}
+			files = matches
+		}
+		for _, f := range files {
+			data, err := os.ReadFile(filepath.Clean(f)) // #nosec G304 -- path from config
+			if err != nil {
+				return nil, fmt.Errorf("signature: read public key: %w", err)
+			}
+			parsed, err := ParsePublicKeys(data)
+			if err != nil {
+				return nil, fmt.Errorf("signature: %s: %w", f, err)
+			}
+			keys = append(keys, parsed...)
+		}
+	}
+	return NewVerifier(keys...)
+}
+
+// ParsePublicKeys parses all PKIX "PUBLIC KEY" PEM blocks in data.
+func ParsePublicKeys(data []byte) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		k, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no PUBLIC KEY PEM block found")
+	}
+	return keys, nil
+}
+
+// Verify checks the base64 signature sig over payload against the trusted keys.
+func (v *Verifier) Verify(payload []byte, sig string) error {
+	sig = strings.TrimSpace(sig)
+	if sig == "" {
+		return ErrUnsigned
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("%w: base64: %v", ErrInvalid, err)
+	}
+	digest := sha256.Sum256(payload)
+	for _, k := range v.keys {
+		switch key := k.(type) {
+		case ed25519.PublicKey:
+			if ed25519.Verify(key, payload, raw) {
+				return nil
+			}
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(key, digest[:], raw) {
+				return nil
+			}
+		}
+	}
+	return ErrInvalid
+}
+
+// VerifyFile verifies the file content data against the detached signature at path+FileSuffix.
+func (v *Verifier) VerifyFile(path string, data []byte) error {
+	sig, err := os.ReadFile(filepath.Clean(path + FileSuffix)) // #nosec G304 -- path from config
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s has no %s file", ErrUnsigned, path, FileSuffix)
+		}
+		return fmt.Errorf("signature: %w", err)
+	}
+	if err := v.Verify(data, string(sig)); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// ParsePrivateKey parses an Ed25519 or ECDSA private key from PEM ("PRIVATE KEY" PKCS#8 or "EC PRIVATE KEY").
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signature: no PEM block in private key")
+	}
+	var (
+		key any
+		err error
+	)
+	if block.Type == "EC PRIVATE KEY" {
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	} else {
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("signature: parse private key: %w", err)
+	}
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		return k, nil
+	default:
+		return nil, fmt.Errorf("signature: unsupported private key type %T (want Ed25519 or ECDSA)", key)
+	}
+}
+
+// Sign returns the base64 signature of payload in the format accepted by Verify.
+func Sign(key crypto.Signer, payload []byte) (string, error) {
+	var (
+		raw []byte
+		err error
+	)
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		raw = ed25519.Sign(k, payload)
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(payload)
+		raw, err = ecdsa.SignASN1(rand.Reader, k, digest[:])
+	default:
+		err = fmt.Errorf("signature: unsupported private key type %T", key)
+	}
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
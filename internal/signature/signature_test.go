@@ -0,0 +1,134 @@
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePublicKey writes pub as a PKIX PEM file and returns its path.
+func writePublicKey(t *testing.T, dir, name string, pub crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+	return path
+}
+
+func TestSignVerify_Ed25519AndECDSA(t *testing.T) {
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ecPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	v, err := NewVerifier(edPub, &ecPriv.PublicKey)
+	require.NoError(t, err)
+
+	payload := []byte(`[{"phone":"13800138000"}]`)
+	for _, key := range []crypto.Signer{edPriv, ecPriv} {
+		sig, err := Sign(key, payload)
+		require.NoError(t, err)
+		assert.NoError(t, v.Verify(payload, sig))
+		assert.ErrorIs(t, v.Verify([]byte(`[{"phone":"13900139000"}]`), sig), ErrInvalid, "tampered payload")
+	}
+	assert.ErrorIs(t, v.Verify(payload, ""), ErrUnsigned)
+	assert.ErrorIs(t, v.Verify(payload, "not base64!"), ErrInvalid)
+}
+
+func TestVerify_UntrustedKey(t *testing.T) {
+	trusted, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	v, err := NewVerifier(trusted)
+	require.NoError(t, err)
+	sig, err := Sign(other, []byte("x"))
+	require.NoError(t, err)
+	assert.ErrorIs(t, v.Verify([]byte("x"), sig), ErrInvalid)
+}
+
+func TestNewVerifier_Errors(t *testing.T) {
+	_, err := NewVerifier()
+	assert.Error(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, err = NewVerifier(&rsaKey.PublicKey)
+	assert.Error(t, err, "RSA keys are not accepted")
+}
+
+func TestLoadVerifier_FileAndDir(t *testing.T) {
+	dir := t.TempDir()
+	pub1, priv1, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	pub2, priv2, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keyDir := filepath.Join(dir, "keys")
+	require.NoError(t, os.Mkdir(keyDir, 0o750))
+	file := writePublicKey(t, dir, "one.pem", pub1)
+	writePublicKey(t, keyDir, "two.pem", pub2)
+
+	v, err := LoadVerifier([]string{file, keyDir})
+	require.NoError(t, err)
+	for _, priv := range []ed25519.PrivateKey{priv1, priv2} {
+		sig, err := Sign(priv, []byte("x"))
+		require.NoError(t, err)
+		assert.NoError(t, v.Verify([]byte("x"), sig))
+	}
+
+	_, err = LoadVerifier([]string{filepath.Join(dir, "missing.pem")})
+	assert.Error(t, err)
+	_, err = LoadVerifier(nil)
+	assert.Error(t, err)
+}
+
+func TestVerifyFile(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	v, err := NewVerifier(pub)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "data.json")
+	data := []byte(`[]`)
+	assert.ErrorIs(t, v.VerifyFile(path, data), ErrUnsigned)
+
+	sig, err := Sign(priv, data)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path+FileSuffix, []byte(sig+"\n"), 0o600))
+	assert.NoError(t, v.VerifyFile(path, data))
+	assert.ErrorIs(t, v.VerifyFile(path, []byte(`[{}]`)), ErrInvalid)
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	_, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(edPriv)
+	require.NoError(t, err)
+	k, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+	assert.IsType(t, ed25519.PrivateKey{}, k)
+
+	ecPriv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	der, err = x509.MarshalECPrivateKey(ecPriv)
+	require.NoError(t, err)
+	k, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+	assert.IsType(t, &ecdsa.PrivateKey{}, k)
+
+	_, err = ParsePrivateKey([]byte("nope"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,61 @@
+// Package testutil provides helpers shared by the tests of several packages.
+package testutil
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// GitRepo is a local bare repository (Bare) with a working clone (Work) used to commit to it.
+type GitRepo struct {
+	t    testing.TB
+	Bare string
+	Work string
+}
+
+// NewGitRepo creates a bare repository (default branch main) and a clone of it. The test is skipped when
+// git is not installed; the user's and the system git configuration are ignored.
+func NewGitRepo(t testing.TB) *GitRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	dir := t.TempDir()
+	g := &GitRepo{t: t, Bare: filepath.Join(dir, "users.git"), Work: filepath.Join(dir, "work")}
+	g.Run(dir, "init", "--quiet", "--bare", "--initial-branch=main", g.Bare)
+	g.Run(dir, "clone", "--quiet", g.Bare, g.Work)
+	g.Run(g.Work, "checkout", "--quiet", "-b", "main")
+	return g
+}
+
+// Run runs git with args in dir and returns its trimmed output; a failure fails the test.
+func (g *GitRepo) Run(dir string, args ...string) string {
+	g.t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(g.t, err, string(out))
+	return strings.TrimSpace(string(out))
+}
+
+// Commit writes files (path relative to Work -> content), commits and pushes everything in Work to main,
+// and returns the commit SHA. Files written to Work directly are committed as well.
+func (g *GitRepo) Commit(files map[string]string) string {
+	g.t.Helper()
+	for p, content := range files {
+		full := filepath.Join(g.Work, p)
+		require.NoError(g.t, os.MkdirAll(filepath.Dir(full), 0o750))
+		require.NoError(g.t, os.WriteFile(full, []byte(content), 0o600))
+	}
+	g.Run(g.Work, "add", "-A")
+	g.Run(g.Work, "commit", "--quiet", "-m", "update")
+	g.Run(g.Work, "push", "--quiet", "origin", "main")
+	return g.Run(g.Work, "rev-parse", "HEAD")
+}
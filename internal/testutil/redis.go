@@ -0,0 +1,599 @@
+package testutil
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// FakeRedis is an in-memory Redis server speaking RESP over net.Pipe. It implements the commands the
+// caches use (strings, hashes, lists, SCAN with prefix patterns, pub/sub) but no Lua scripts; tests may
+// inspect and change its state while holding Mu.
+type FakeRedis struct {
+	Data   map[string]string
+	Hashes map[string]map[string]string
+	Lists  map[string][]string
+	TTLs   map[string]int64 // expiry in seconds set by SET EX and EXPIRE, 0 after PERSIST (recorded, never applied)
+	Role   string           // replication role reported by INFO ("master" when empty)
+	Mu     sync.Mutex
+
+	subs map[string][]*fakeConn
+}
+
+// fakeConn serializes writes to a connection, which PUBLISH on another connection also writes to.
+type fakeConn struct {
+	w  *bufio.Writer
+	mu sync.Mutex
+}
+
+// NewFakeRedis creates an empty server. Connect with Dialer, or use NewFakeRedisClient.
+func NewFakeRedis() *FakeRedis {
+	return &FakeRedis{Data: make(map[string]string), TTLs: make(map[string]int64), Hashes: make(map[string]map[string]string), Lists: make(map[string][]string), subs: make(map[string][]*fakeConn)}
+}
+
+// Subscribers returns the number of connections subscribed to channel.
+func (f *FakeRedis) Subscribers(channel string) int {
+	f.Mu.Lock()
+	defer f.Mu.Unlock()
+	return len(f.subs[channel])
+}
+
+// exists reports whether key holds a value of any type; the caller holds Mu.
+func (f *FakeRedis) exists(key string) bool {
+	_, str := f.Data[key]
+	_, hash := f.Hashes[key]
+	_, list := f.Lists[key]
+	return str || hash || list
+}
+
+// Dialer connects to the server; use it as redis.Options.Dialer.
+func (f *FakeRedis) Dialer(_ context.Context, _, _ string) (net.Conn, error) {
+	clientConn, serverConn := net.Pipe()
+	go f.serveConn(serverConn)
+	return clientConn, nil
+}
+
+func (f *FakeRedis) serveConn(conn net.Conn) {
+	defer func() {
+		if err := conn.Close(); err != nil {
+			return
+		}
+	}()
+
+	reader := bufio.NewReader(conn)
+	out := newQueueWriter(conn)
+	defer out.close()
+	c := &fakeConn{w: bufio.NewWriter(out)}
+	defer f.unsubscribe(c)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		c.mu.Lock()
+		if handled, err := f.handlePubSub(c, args); handled {
+			if err == nil {
+				err = c.w.Flush()
+			}
+			c.mu.Unlock()
+			if err != nil {
+				return
+			}
+			continue
+		}
+		err = f.handleCommand(args, c.w)
+		if err == nil {
+			err = c.w.Flush()
+		}
+		c.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// queueWriter queues writes and copies them to w in the background, so a reply never blocks the server
+// while a client is still sending a large pipeline (net.Pipe has no buffer).
+type queueWriter struct {
+	w      io.Writer
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  [][]byte
+	closed bool
+}
+
+func newQueueWriter(w io.Writer) *queueWriter {
+	q := &queueWriter{w: w}
+	q.cond = sync.NewCond(&q.mu)
+	go q.run()
+	return q
+}
+
+func (q *queueWriter) Write(p []byte) (int, error) {
+	q.mu.Lock()
+	q.queue = append(q.queue, append([]byte(nil), p...))
+	q.mu.Unlock()
+	q.cond.Signal()
+	return len(p), nil
+}
+
+func (q *queueWriter) run() {
+	for {
+		q.mu.Lock()
+		for len(q.queue) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.queue) == 0 {
+			q.mu.Unlock()
+			return
+		}
+		p := q.queue[0]
+		q.queue = q.queue[1:]
+		q.mu.Unlock()
+		if _, err := q.w.Write(p); err != nil {
+			return
+		}
+	}
+}
+
+func (q *queueWriter) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+// handlePubSub handles SUBSCRIBE and PUBLISH; handled is false for other commands.
+func (f *FakeRedis) handlePubSub(c *fakeConn, args []string) (handled bool, err error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+	switch strings.ToUpper(args[0]) {
+	case "SUBSCRIBE":
+		for i, channel := range args[1:] {
+			f.Mu.Lock()
+			f.subs[channel] = append(f.subs[channel], c)
+			f.Mu.Unlock()
+			if _, err := c.w.WriteString("*3\r\n"); err != nil {
+				return true, err
+			}
+			if err := writeBulkString(c.w, "subscribe"); err != nil {
+				return true, err
+			}
+			if err := writeBulkString(c.w, channel); err != nil {
+				return true, err
+			}
+			if err := writeInt(c.w, int64(i+1)); err != nil {
+				return true, err
+			}
+		}
+		return true, nil
+	case "PUBLISH":
+		if len(args) < 3 {
+			return true, writeError(c.w, "invalid args")
+		}
+		f.Mu.Lock()
+		subs := append([]*fakeConn(nil), f.subs[args[1]]...)
+		f.Mu.Unlock()
+		for _, sub := range subs {
+			if sub == c {
+				continue
+			}
+			sub.mu.Lock()
+			if writeArray(sub.w, []string{"message", args[1], args[2]}) == nil {
+				_ = sub.w.Flush() //nolint:errcheck // the subscriber may have gone away
+			}
+			sub.mu.Unlock()
+		}
+		return true, writeInt(c.w, int64(len(subs)))
+	default:
+		return false, nil
+	}
+}
+
+// unsubscribe removes c from every channel.
+func (f *FakeRedis) unsubscribe(c *fakeConn) {
+	f.Mu.Lock()
+	defer f.Mu.Unlock()
+	for channel, subs := range f.subs {
+		kept := subs[:0]
+		for _, sub := range subs {
+			if sub != c {
+				kept = append(kept, sub)
+			}
+		}
+		f.subs[channel] = kept
+	}
+}
+
+func (f *FakeRedis) handleCommand(args []string, w *bufio.Writer) error {
+	if len(args) == 0 {
+		return writeError(w, "empty command")
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return writeSimpleString(w, "PONG")
+	case "CLIENT":
+		return writeSimpleString(w, "OK")
+	case "QUIT":
+		return writeSimpleString(w, "OK")
+	case "INFO":
+		f.Mu.Lock()
+		role := f.Role
+		f.Mu.Unlock()
+		if role == "" {
+			role = "master"
+		}
+		return writeBulkString(w, "# Replication\r\nrole:"+role+"\r\nconnected_slaves:1\r\n")
+	case "SET":
+		// Expiry options are recorded but not applied; only NX changes the behavior
+		if len(args) < 3 {
+			return writeError(w, "invalid args")
+		}
+		nx := false
+		var ttl int64
+		for i, opt := range args[3:] {
+			nx = nx || strings.EqualFold(opt, "NX")
+			if strings.EqualFold(opt, "EX") && 4+i < len(args) {
+				ttl, _ = strconv.ParseInt(args[4+i], 10, 64) //nolint:errcheck // 0 when malformed
+			}
+		}
+		f.Mu.Lock()
+		if _, exists := f.Data[args[1]]; nx && exists {
+			f.Mu.Unlock()
+			return writeNil(w)
+		}
+		f.Data[args[1]] = args[2]
+		f.TTLs[args[1]] = ttl
+		f.Mu.Unlock()
+		return writeSimpleString(w, "OK")
+	case "EVALSHA", "EVAL":
+		// Lua scripts are not emulated: tests of scripts run them on miniredis (see NewMiniRedisClient)
+		return writeError(w, "scripts are not supported")
+	case "INCR":
+		if len(args) < 2 {
+			return writeError(w, "invalid args")
+		}
+		f.Mu.Lock()
+		valStr := f.Data[args[1]]
+		val, err := strconv.ParseInt(valStr, 10, 64)
+		if err != nil {
+			val = 0
+		}
+		val++
+		f.Data[args[1]] = strconv.FormatInt(val, 10)
+		f.Mu.Unlock()
+		return writeInt(w, val)
+	case "EXPIRE":
+		if len(args) < 3 {
+			return writeError(w, "invalid args")
+		}
+		seconds, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return writeError(w, "invalid expire time")
+		}
+		f.Mu.Lock()
+		defer f.Mu.Unlock()
+		if !f.exists(args[1]) {
+			return writeInt(w, 0)
+		}
+		f.TTLs[args[1]] = seconds
+		return writeInt(w, 1)
+	case "PERSIST":
+		if len(args) < 2 {
+			return writeError(w, "invalid args")
+		}
+		f.Mu.Lock()
+		defer f.Mu.Unlock()
+		if !f.exists(args[1]) || f.TTLs[args[1]] == 0 {
+			return writeInt(w, 0)
+		}
+		f.TTLs[args[1]] = 0
+		return writeInt(w, 1)
+	case "GET":
+		if len(args) < 2 {
+			return writeError(w, "invalid args")
+		}
+		f.Mu.Lock()
+		val, ok := f.Data[args[1]]
+		f.Mu.Unlock()
+		if !ok {
+			return writeNil(w)
+		}
+		return writeBulkString(w, val)
+	case "EXISTS":
+		if len(args) < 2 {
+			return writeError(w, "invalid args")
+		}
+		f.Mu.Lock()
+		_, ok := f.Data[args[1]]
+		f.Mu.Unlock()
+		if ok {
+			return writeInt(w, 1)
+		}
+		return writeInt(w, 0)
+	case "DEL":
+		if len(args) < 2 {
+			return writeError(w, "invalid args")
+		}
+		var n int64
+		f.Mu.Lock()
+		for _, key := range args[1:] {
+			if _, ok := f.Data[key]; ok {
+				delete(f.Data, key)
+				n++
+			}
+			if _, ok := f.Hashes[key]; ok {
+				delete(f.Hashes, key)
+				n++
+			}
+			if _, ok := f.Lists[key]; ok {
+				delete(f.Lists, key)
+				n++
+			}
+		}
+		f.Mu.Unlock()
+		return writeInt(w, n)
+	case "GETSET":
+		if len(args) < 3 {
+			return writeError(w, "invalid args")
+		}
+		f.Mu.Lock()
+		old, ok := f.Data[args[1]]
+		f.Data[args[1]] = args[2]
+		f.Mu.Unlock()
+		if !ok {
+			return writeNil(w)
+		}
+		return writeBulkString(w, old)
+	case "HSET":
+		if len(args) < 4 || len(args)%2 != 0 {
+			return writeError(w, "invalid args")
+		}
+		f.Mu.Lock()
+		h := f.Hashes[args[1]]
+		if h == nil {
+			h = make(map[string]string)
+			f.Hashes[args[1]] = h
+		}
+		for i := 2; i+1 < len(args); i += 2 {
+			h[args[i]] = args[i+1]
+		}
+		f.Mu.Unlock()
+		return writeInt(w, int64((len(args)-2)/2))
+	case "HDEL":
+		if len(args) < 3 {
+			return writeError(w, "invalid args")
+		}
+		f.Mu.Lock()
+		var deleted int64
+		for _, field := range args[2:] {
+			if _, ok := f.Hashes[args[1]][field]; ok {
+				delete(f.Hashes[args[1]], field)
+				deleted++
+			}
+		}
+		f.Mu.Unlock()
+		return writeInt(w, deleted)
+	case "HGET":
+		if len(args) < 3 {
+			return writeError(w, "invalid args")
+		}
+		f.Mu.Lock()
+		v, ok := f.Hashes[args[1]][args[2]]
+		f.Mu.Unlock()
+		if !ok {
+			return writeNil(w)
+		}
+		return writeBulkString(w, v)
+	case "HGETALL":
+		if len(args) < 2 {
+			return writeError(w, "invalid args")
+		}
+		f.Mu.Lock()
+		var items []string
+		for k, v := range f.Hashes[args[1]] {
+			items = append(items, k, v)
+		}
+		f.Mu.Unlock()
+		return writeArray(w, items)
+	case "RPUSH":
+		if len(args) < 3 {
+			return writeError(w, "invalid args")
+		}
+		f.Mu.Lock()
+		f.Lists[args[1]] = append(f.Lists[args[1]], args[2:]...)
+		n := len(f.Lists[args[1]])
+		f.Mu.Unlock()
+		return writeInt(w, int64(n))
+	case "LRANGE":
+		// Only non-negative start and stop (or -1) are supported
+		if len(args) < 4 {
+			return writeError(w, "invalid args")
+		}
+		start, err1 := strconv.Atoi(args[2])
+		stop, err2 := strconv.Atoi(args[3])
+		if err1 != nil || err2 != nil {
+			return writeError(w, "invalid range")
+		}
+		f.Mu.Lock()
+		list := f.Lists[args[1]]
+		if stop < 0 || stop >= len(list) {
+			stop = len(list) - 1
+		}
+		var items []string
+		if start <= stop {
+			items = append(items, list[start:stop+1]...)
+		}
+		f.Mu.Unlock()
+		return writeArray(w, items)
+	case "SCAN":
+		// Returns all matching keys at once; only "prefix*" patterns are supported
+		prefix := ""
+		for i := 2; i+1 < len(args); i++ {
+			if strings.EqualFold(args[i], "MATCH") {
+				prefix = strings.TrimSuffix(args[i+1], "*")
+			}
+		}
+		f.Mu.Lock()
+		var keys []string
+		for k := range f.Data {
+			if strings.HasPrefix(k, prefix) {
+				keys = append(keys, k)
+			}
+		}
+		for k := range f.Hashes {
+			if strings.HasPrefix(k, prefix) {
+				keys = append(keys, k)
+			}
+		}
+		for k := range f.Lists {
+			if strings.HasPrefix(k, prefix) {
+				keys = append(keys, k)
+			}
+		}
+		f.Mu.Unlock()
+		if _, err := w.WriteString("*2\r\n"); err != nil {
+			return err
+		}
+		if err := writeBulkString(w, "0"); err != nil {
+			return err
+		}
+		return writeArray(w, keys)
+	default:
+		return writeError(w, "unknown command")
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	prefix, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if prefix != '*' {
+		return nil, errors.New("unexpected RESP prefix")
+	}
+
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(line)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		bulkPrefix, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if bulkPrefix != '$' {
+			return nil, errors.New("unexpected bulk prefix")
+		}
+		lenLine, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(lenLine)
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	return line, nil
+}
+
+func writeSimpleString(w *bufio.Writer, msg string) error {
+	_, err := w.WriteString("+" + msg + "\r\n")
+	return err
+}
+
+func writeError(w *bufio.Writer, msg string) error {
+	_, err := w.WriteString("-ERR " + msg + "\r\n")
+	return err
+}
+
+func writeInt(w *bufio.Writer, value int64) error {
+	_, err := w.WriteString(":" + strconv.FormatInt(value, 10) + "\r\n")
+	return err
+}
+
+func writeBulkString(w *bufio.Writer, value string) error {
+	_, err := w.WriteString("$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n")
+	return err
+}
+
+func writeArray(w *bufio.Writer, items []string) error {
+	if _, err := w.WriteString("*" + strconv.Itoa(len(items)) + "\r\n"); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := writeBulkString(w, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeNil(w *bufio.Writer) error {
+	_, err := w.WriteString("$-1\r\n")
+	return err
+}
+
+// NewFakeRedisClient starts a FakeRedis and returns a client connected to it, closed when the test ends.
+func NewFakeRedisClient(t testing.TB) (*redis.Client, *FakeRedis) {
+	t.Helper()
+	server := NewFakeRedis()
+	client := redis.NewClient(&redis.Options{
+		Addr:   "fake",
+		Dialer: server.Dialer,
+	})
+	t.Cleanup(func() {
+		if err := client.Close(); err != nil {
+			t.Errorf("close redis client: %v", err)
+		}
+	})
+	return client, server
+}
+
+// NewMiniRedisClient returns a client connected to a new miniredis server, which runs Lua scripts like
+// Redis does; use it to test the scripts, and FakeRedis elsewhere. Keys do not expire until the test
+// calls FastForward on the server.
+func NewMiniRedisClient(t testing.TB) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		if err := client.Close(); err != nil {
+			t.Errorf("close redis client: %v", err)
+		}
+	})
+	return client, server
+}
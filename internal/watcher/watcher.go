@@ -0,0 +1,189 @@
+// Package watcher watches local data files and directories and triggers a debounced reload on change.
+//
+// The parent directory of each file is watched rather than the file itself, so that atomic
+// rename-and-replace writes (editors, `mv`, Kubernetes ConfigMap `..data` symlink swaps) are seen.
+package watcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/soulteary/warden/internal/signature"
+)
+
+// DefaultDebounce is the quiet period after the last event before OnChange is called.
+const DefaultDebounce = 500 * time.Millisecond
+
+// Options configures a Watcher.
+//
+//nolint:govet // fieldalignment: keep field order for readability
+type Options struct {
+	Files    []string      // data files to watch, with their "<file>.sig" (e.g. data_file)
+	Dirs     []string      // directories whose *.json (and *.json.sig) files are watched (e.g. data_dir)
+	Debounce time.Duration // quiet period before OnChange (default DefaultDebounce)
+	OnChange func()        // called once per burst of relevant events
+	OnError  func(error)   // optional: called on watcher errors
+}
+
+// dirMatcher selects the relevant events inside one watched directory.
+type dirMatcher struct {
+	names   map[string]struct{} // watched file base names
+	anyJSON bool                // every *.json file in the directory is relevant
+}
+
+// Watcher triggers Options.OnChange after data files change.
+type Watcher struct {
+	fs       *fsnotify.Watcher
+	dirs     map[string]*dirMatcher
+	opts     Options
+	done     chan struct{}
+	closeOne sync.Once
+	wg       sync.WaitGroup
+}
+
+// New starts watching opts.Files and opts.Dirs. It returns an error when a path cannot be watched,
+// in which case the caller should rely on periodic polling alone.
+func New(opts Options) (*Watcher, error) {
+	if opts.OnChange == nil {
+		return nil, fmt.Errorf("watcher: OnChange is required")
+	}
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultDebounce
+	}
+	w := &Watcher{
+		dirs: make(map[string]*dirMatcher),
+		opts: opts,
+		done: make(chan struct{}),
+	}
+	for _, f := range opts.Files {
+		if f == "" {
+			continue
+		}
+		w.addFile(f)
+		// Also watch the target of a symlinked file that lives in another directory.
+		if resolved, err := filepath.EvalSymlinks(f); err == nil {
+			w.addFile(resolved)
+		}
+	}
+	for _, d := range opts.Dirs {
+		if d == "" {
+			continue
+		}
+		w.matcher(filepath.Clean(d)).anyJSON = true
+	}
+	if len(w.dirs) == 0 {
+		return nil, fmt.Errorf("watcher: nothing to watch")
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("watcher: %w", err)
+	}
+	for dir := range w.dirs {
+		if err := fsw.Add(dir); err != nil {
+			_ = fsw.Close() //nolint:errcheck // #nosec G104 -- already returning the Add error
+			return nil, fmt.Errorf("watcher: watch %s: %w", dir, err)
+		}
+	}
+	w.fs = fsw
+	w.wg.Add(1)
+	go w.run()
+	return w, nil
+}
+
+// addFile registers path's base name under its parent directory.
+func (w *Watcher) addFile(path string) {
+	path = filepath.Clean(path)
+	w.matcher(filepath.Dir(path)).names[filepath.Base(path)] = struct{}{}
+}
+
+// matcher returns the matcher for dir, creating it if needed.
+func (w *Watcher) matcher(dir string) *dirMatcher {
+	m, ok := w.dirs[dir]
+	if !ok {
+		m = &dirMatcher{names: make(map[string]struct{})}
+		w.dirs[dir] = m
+	}
+	return m
+}
+
+// relevant reports whether ev may change the watched data. A detached signature ("<file>.sig") of a
+// watched file is relevant too: re-signing data that was rejected must reload it.
+func (w *Watcher) relevant(ev fsnotify.Event) bool {
+	if ev.Op == fsnotify.Chmod {
+		return false
+	}
+	m, ok := w.dirs[filepath.Dir(ev.Name)]
+	if !ok {
+		return false
+	}
+	base := filepath.Base(ev.Name)
+	// Kubernetes atomic writer: files are symlinks into ..data, which is swapped via rename of ..data_tmp.
+	if strings.HasPrefix(base, "..") {
+		return true
+	}
+	if data, ok := strings.CutSuffix(base, signature.FileSuffix); ok && data != "" {
+		base = data
+	}
+	if _, ok := m.names[base]; ok {
+		return true
+	}
+	return m.anyJSON && strings.EqualFold(filepath.Ext(base), ".json")
+}
+
+// run debounces relevant events and calls OnChange once the directory has been quiet for Debounce.
+func (w *Watcher) run() {
+	defer w.wg.Done()
+	var (
+		timer *time.Timer
+		fire  <-chan time.Time
+	)
+	for {
+		select {
+		case <-w.done:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case ev, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			if !w.relevant(ev) {
+				continue
+			}
+			if timer == nil {
+				timer = time.NewTimer(w.opts.Debounce)
+			} else {
+				timer.Reset(w.opts.Debounce)
+			}
+			fire = timer.C
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			if w.opts.OnError != nil {
+				w.opts.OnError(err)
+			}
+		case <-fire:
+			fire = nil
+			w.opts.OnChange()
+		}
+	}
+}
+
+// Close stops watching and waits for a running OnChange to return.
+func (w *Watcher) Close() error {
+	var err error
+	w.closeOne.Do(func() {
+		close(w.done)
+		err = w.fs.Close()
+		w.wg.Wait()
+	})
+	return err
+}
@@ -0,0 +1,146 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDebounce = 50 * time.Millisecond
+
+// newCounting starts a watcher that counts OnChange calls.
+func newCounting(t *testing.T, opts Options) (*Watcher, *atomic.Int32) {
+	t.Helper()
+	var n atomic.Int32
+	opts.Debounce = testDebounce
+	opts.OnChange = func() { n.Add(1) }
+	w, err := New(opts)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	return w, &n
+}
+
+func TestWatcher_FileWriteDebounced(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[]`), 0o600))
+	_, n := newCounting(t, Options{Files: []string{path}})
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, os.WriteFile(path, []byte(`[{"phone":"1"}]`), 0o600))
+	}
+	assert.Eventually(t, func() bool { return n.Load() == 1 }, 2*time.Second, 10*time.Millisecond)
+	time.Sleep(3 * testDebounce)
+	assert.Equal(t, int32(1), n.Load(), "a burst of writes triggers a single reload")
+}
+
+func TestWatcher_AtomicRename(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[]`), 0o600))
+	_, n := newCounting(t, Options{Files: []string{path}})
+
+	tmp := filepath.Join(dir, ".data.json.tmp")
+	require.NoError(t, os.WriteFile(tmp, []byte(`[{"phone":"1"}]`), 0o600))
+	require.NoError(t, os.Rename(tmp, path))
+	assert.Eventually(t, func() bool { return n.Load() >= 1 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestWatcher_ConfigMapSymlinkSwap(t *testing.T) {
+	dir := t.TempDir()
+	// Layout created by the kubelet atomic writer: data.json -> ..data/data.json, ..data -> ..v1
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "..v1"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "..v1", "data.json"), []byte(`[]`), 0o600))
+	require.NoError(t, os.Symlink("..v1", filepath.Join(dir, "..data")))
+	require.NoError(t, os.Symlink(filepath.Join("..data", "data.json"), filepath.Join(dir, "data.json")))
+	_, n := newCounting(t, Options{Files: []string{filepath.Join(dir, "data.json")}})
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "..v2"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "..v2", "data.json"), []byte(`[{"phone":"1"}]`), 0o600))
+	require.NoError(t, os.Symlink("..v2", filepath.Join(dir, "..data_tmp")))
+	require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+	assert.Eventually(t, func() bool { return n.Load() >= 1 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestWatcher_DirJSONOnly(t *testing.T) {
+	dir := t.TempDir()
+	_, n := newCounting(t, Options{Dirs: []string{dir}})
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0o600))
+	time.Sleep(4 * testDebounce)
+	assert.Equal(t, int32(0), n.Load(), "non-JSON files are ignored")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.json"), []byte(`[]`), 0o600))
+	assert.Eventually(t, func() bool { return n.Load() == 1 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestWatcher_IgnoresUnrelatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+	_, n := newCounting(t, Options{Files: []string{path}})
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.json"), []byte(`[]`), 0o600))
+	time.Sleep(4 * testDebounce)
+	assert.Equal(t, int32(0), n.Load())
+
+	// data.json did not exist when the watcher started; creating it is still seen.
+	require.NoError(t, os.WriteFile(path, []byte(`[]`), 0o600))
+	assert.Eventually(t, func() bool { return n.Load() == 1 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestNew_Errors(t *testing.T) {
+	_, err := New(Options{Files: []string{"x.json"}})
+	assert.Error(t, err, "OnChange is required")
+
+	_, err = New(Options{OnChange: func() {}})
+	assert.Error(t, err, "nothing to watch")
+
+	_, err = New(Options{Dirs: []string{filepath.Join(t.TempDir(), "missing")}, OnChange: func() {}})
+	assert.Error(t, err)
+}
+
+func TestWatcher_Relevant(t *testing.T) {
+	w := &Watcher{dirs: map[string]*dirMatcher{}}
+	w.addFile("/etc/warden/data.json")
+	assert.True(t, w.relevant(fsnotify.Event{Name: "/etc/warden/data.json", Op: fsnotify.Write}))
+	assert.True(t, w.relevant(fsnotify.Event{Name: "/etc/warden/..data", Op: fsnotify.Create}))
+	assert.False(t, w.relevant(fsnotify.Event{Name: "/etc/warden/data.json", Op: fsnotify.Chmod}))
+	assert.False(t, w.relevant(fsnotify.Event{Name: "/etc/warden/other.json", Op: fsnotify.Write}))
+	assert.False(t, w.relevant(fsnotify.Event{Name: "/tmp/data.json", Op: fsnotify.Write}))
+
+	// Detached signatures of watched files
+	w.matcher("/etc/warden/data.d").anyJSON = true
+	assert.True(t, w.relevant(fsnotify.Event{Name: "/etc/warden/data.json.sig", Op: fsnotify.Write}))
+	assert.True(t, w.relevant(fsnotify.Event{Name: "/etc/warden/data.d/b.json.sig", Op: fsnotify.Create}))
+	assert.False(t, w.relevant(fsnotify.Event{Name: "/etc/warden/other.json.sig", Op: fsnotify.Write}))
+	assert.False(t, w.relevant(fsnotify.Event{Name: "/etc/warden/data.d/notes.txt.sig", Op: fsnotify.Write}))
+	assert.False(t, w.relevant(fsnotify.Event{Name: "/etc/warden/data.d/.sig", Op: fsnotify.Write}))
+}
+
+func TestWatcher_SignatureRewrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[]`), 0o600))
+	sub := filepath.Join(dir, "data.d")
+	require.NoError(t, os.Mkdir(sub, 0o750))
+	_, n := newCounting(t, Options{Files: []string{path}, Dirs: []string{sub}})
+
+	// Re-signing the data file (e.g. after a key rotation) reloads it
+	require.NoError(t, os.WriteFile(path+".sig", []byte("sig"), 0o600))
+	assert.Eventually(t, func() bool { return n.Load() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(filepath.Join(sub, "b.json.sig"), []byte("sig"), 0o600))
+	assert.Eventually(t, func() bool { return n.Load() == 2 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestWatcher_CloseIdempotent(t *testing.T) {
+	w, _ := newCounting(t, Options{Dirs: []string{t.TempDir()}})
+	require.NoError(t, w.Close())
+	assert.NoError(t, w.Close())
+}
@@ -14,6 +14,8 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -22,7 +24,6 @@ import (
 	"github.com/pterm/pterm/putils"
 	"github.com/redis/go-redis/v9"
 	loggerkit "github.com/soulteary/logger-kit"
-	rediskitclient "github.com/soulteary/redis-kit/client"
 
 	// Middleware kit
 	middlewarekit "github.com/soulteary/middleware-kit"
@@ -38,6 +39,8 @@ import (
 	"github.com/soulteary/warden/internal/loader"
 	"github.com/soulteary/warden/internal/logger"
 	"github.com/soulteary/warden/internal/prommetrics"
+	"github.com/soulteary/warden/internal/router"
+	"github.com/soulteary/warden/internal/watcher"
 	"github.com/soulteary/warden/pkg/gocron"
 )
 
@@ -47,15 +50,26 @@ import (
 type App struct {
 	userCache            *cache.SafeUserCache
 	redisUserCache       *cache.RedisUserCache
-	redisClient          *redis.Client
+	userStore            *cache.RedisUserStore // per-user Redis layout (REDIS_USER_STORE=hash), used instead of redisUserCache
+	notifier             *cache.Notifier       // cache update notifications and instance status (nil without Redis)
+	elector              *cache.LeaderElector  // elects the instance that loads the sources (nil without Redis)
+	history              *cache.DatasetHistory // applied datasets kept for rollback (nil when DATASET_HISTORY=-1)
+	dataVersion          atomic.Int64          // Redis version of the served data (see setDataVersion)
+	activeVersion        atomic.Int64          // history version of the served data (see ActiveVersion)
+	redisClient          redis.UniversalClient // client of redisTopology (nil without Redis)
+	redisTopology        *cache.RedisTopology  // standalone, Sentinel or Cluster connection (nil without Redis)
+	namespace            cache.Namespace       // prefix of all Redis keys, channels and locks (REDIS_KEY_PREFIX)
 	rateLimiter          *middlewarekit.RateLimiter
 	rulesLoader          *loader.RulesLoader
+	loaderState          *cache.LoaderStateStore           // state of the leader's last load, for the followers (nil without Redis)
+	leaderReport         atomic.Pointer[define.LoadReport] // load report of the leader's dataset (followers)
 	log                  *loggerkit.Logger
 	port                 string
 	configURL            string
 	authorizationHeader  string
 	appMode              string
 	apiKey               string
+	adminAPIKey          string // enables /v1/admin endpoints when set
 	dataFile             string
 	dataDir              string
 	responseFields       []string
@@ -67,6 +81,9 @@ type App struct {
 	tlsKeyFile           string
 	tlsCAFile            string
 	tlsRequireClientCert bool
+	dataWatch            config.FileWatchConfig
+	membershipFilter     bool       // lookups check the membership filter first (BLOOM_FILTER_FP_RATE, see membershipFilters)
+	reloadMu             sync.Mutex // serializes backgroundTask between the scheduler and the file watcher
 }
 
 // taskIntervalU64 converts task interval to uint64, clamping negative values to 0 to avoid overflow.
@@ -89,6 +106,7 @@ func NewApp(cfg *cmd.Config) *App {
 		responseFields:       cfg.ResponseFields,
 		taskInterval:         taskIntervalU64(cfg.TaskInterval),
 		apiKey:               cfg.APIKey,
+		adminAPIKey:          cfg.AdminAPIKey,
 		redisEnabled:         cfg.RedisEnabled,
 		log:                  logger.GetLoggerKit(),
 		hmacToleranceSec:     cfg.HMACToleranceSec,
@@ -96,6 +114,7 @@ func NewApp(cfg *cmd.Config) *App {
 		tlsKeyFile:           cfg.TLSKeyFile,
 		tlsCAFile:            cfg.TLSCAFile,
 		tlsRequireClientCert: cfg.TLSRequireClientCert,
+		dataWatch:            cfg.DataWatch,
 	}
 	if cfg.HMACKeys != "" {
 		var keys map[string]string
@@ -116,13 +135,12 @@ func NewApp(cfg *cmd.Config) *App {
 
 	// Initialize cache (create memory cache first)
 	app.userCache = cache.NewSafeUserCache()
+	app.namespace = cache.Namespace(strings.TrimSpace(cfg.RedisKeyPrefix))
 
 	// Handle Redis initialization (optional)
 	if cfg.RedisEnabled {
-		// Initialize Redis client using redis-kit
-		redisCfg := rediskitclient.DefaultConfig().WithAddr(cfg.Redis)
+		// Initialize the Redis client for the configured topology (standalone, sentinel or cluster)
 		if cfg.RedisPassword != "" {
-			redisCfg = redisCfg.WithPassword(cfg.RedisPassword)
 			// Security check: if password is passed via command line argument, log warning
 			// Note: cannot directly determine password source here, but can infer from environment variable check
 			if os.Getenv("REDIS_PASSWORD") == "" && os.Getenv("REDIS_PASSWORD_FILE") == "" {
@@ -130,20 +148,38 @@ func NewApp(cfg *cmd.Config) *App {
 			}
 		}
 
-		var err error
-		app.redisClient, err = rediskitclient.NewClient(redisCfg)
+		topology, err := cache.NewRedisTopology(cache.RedisOptions{
+			Mode:             cfg.RedisTopology.Mode,
+			Addr:             cfg.Redis,
+			Addrs:            cfg.RedisTopology.Addrs,
+			MasterName:       cfg.RedisTopology.MasterName,
+			Password:         cfg.RedisPassword,
+			DB:               cfg.RedisDB,
+			SentinelPassword: cfg.RedisTopology.SentinelPassword,
+		})
 		if err != nil {
 			// Redis connection failed, log warning and fallback to memory mode
 			app.log.Warn().
 				Err(err).
 				Str("redis", cfg.Redis).
+				Str("mode", cfg.RedisTopology.Mode).
 				Msg(i18n.TWithLang(i18n.LangZH, "log.redis_connection_failed_fallback"))
 			app.redisClient = nil
 			app.redisUserCache = nil
 		} else {
-			app.log.Info().Str("redis", cfg.Redis).Msg(i18n.TWithLang(i18n.LangZH, "log.redis_connected"))
-			// Initialize Redis cache
-			app.redisUserCache = cache.NewRedisUserCache(app.redisClient)
+			app.redisTopology = topology
+			app.redisClient = topology.Client
+			// Initialize Redis cache: one list value, or one hash per user serving lookups directly
+			if strings.EqualFold(strings.TrimSpace(cfg.UserStore.Mode), cache.UserStoreHash) {
+				app.userStore = cache.NewRedisUserStore(app.redisClient, app.namespace, cfg.UserStore.LRUSize, cfg.UserStore.LRUTTL, app.userCache)
+				app.log.Info().Str("redis", cfg.Redis).Str("mode", topology.Mode()).Str("user_store", cache.UserStoreHash).Msg(i18n.TWithLang(i18n.LangZH, "log.redis_connected"))
+			} else {
+				app.redisUserCache = cache.NewRedisUserCache(app.redisClient, app.namespace, cfg.CacheTTL, cfg.RedisEncoding)
+				app.log.Info().Str("redis", cfg.Redis).Str("mode", topology.Mode()).Msg(i18n.TWithLang(i18n.LangZH, "log.redis_connected"))
+			}
+			app.notifier = cache.NewNotifier(app.redisClient, app.namespace, cache.DefaultInstanceID())
+			app.elector = cache.NewLeaderElector(app.redisClient, app.namespace, app.notifier.Instance(), cfg.LeaderLease)
+			app.loaderState = cache.NewLoaderStateStore(app.redisClient, app.namespace)
 		}
 	} else {
 		// Redis is explicitly disabled
@@ -151,6 +187,17 @@ func NewApp(cfg *cmd.Config) *App {
 		app.redisClient = nil
 		app.redisUserCache = nil
 	}
+	// The membership filter belongs to the layout lookups are served from: the per-user store writes it
+	// to Redis with every generation, so followers get it without loading the list
+	if cfg.BloomFPRate > 0 {
+		if app.userStore != nil {
+			app.userStore.EnableMembershipFilter(cfg.BloomFPRate)
+		} else {
+			app.userCache.EnableMembershipFilter(cfg.BloomFPRate)
+		}
+		app.membershipFilter = true
+	}
+	app.history = cache.NewDatasetHistory(app.redisClient, app.namespace, cfg.DatasetHistory, cfg.RedisEncoding)
 
 	// Rules loader (parser-kit, replaces internal parser)
 	rulesLoader, err := loader.NewRulesLoader(cfg, app.appMode)
@@ -162,6 +209,17 @@ func NewApp(cfg *cmd.Config) *App {
 
 	app.log.Debug().Str("mode", app.appMode).Msg(i18n.TWithLang(i18n.LangZH, "log.current_mode"))
 
+	// Settle the role before the first load: followers read what the leader wrote to Redis
+	if app.elector != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), cache.REDIS_OPERATION_TIMEOUT)
+		app.elector.Campaign(ctx)
+		cancel()
+		app.log.Info().
+			Str("instance", app.elector.Instance()).
+			Bool("leader", app.elector.IsLeader()).
+			Msg(i18n.TWithLang(i18n.LangZH, "log.leader_role"))
+	}
+
 	// Load initial data (multi-level fallback)
 	if app.rulesLoader != nil {
 		if err := app.loadInitialData(cfg.DataFile, cfg.DataDir); err != nil {
@@ -195,6 +253,13 @@ func (app *App) loadInitialData(rulesFile, dataDir string) error {
 	defer cancel()
 
 	app.log.Debug().Str("appMode", app.appMode).Msg(i18n.TWithLang(i18n.LangZH, "log.check_mode"))
+	if !app.leading() {
+		return app.loadFromLeader()
+	}
+	// A pinned rollback is served instead of the sources until it is unpinned
+	if app.applyPinned() {
+		return nil
+	}
 	if strings.ToUpper(strings.TrimSpace(app.appMode)) == "ONLY_LOCAL" {
 		app.log.Debug().Msg(i18n.TWithLang(i18n.LangZH, "log.only_local_detected"))
 		localUsers, err := app.rulesLoader.Load(ctx, rulesFile, dataDir, "", "")
@@ -203,10 +268,11 @@ func (app *App) loadInitialData(rulesFile, dataDir string) error {
 				Int("count", len(localUsers)).
 				Msg(i18n.TWithLang(i18n.LangZH, "log.loaded_from_local_file"))
 			app.userCache.Set(localUsers)
-			if app.redisUserCache != nil {
-				if err := app.redisUserCache.Set(localUsers); err != nil {
-					app.log.Warn().Err(err).Msg(i18n.TWithLang(i18n.LangZH, "log.redis_cache_update_failed"))
-				}
+			app.recordDataset(localUsers)
+			if err := app.writeRedis(localUsers); err != nil {
+				app.log.Warn().Err(err).Msg(i18n.TWithLang(i18n.LangZH, "log.redis_cache_update_failed"))
+			} else {
+				app.announceWrite()
 			}
 			return nil
 		}
@@ -233,6 +299,12 @@ func (app *App) loadInitialData(rulesFile, dataDir string) error {
 				Int("count", len(cachedUsers)).
 				Msg(i18n.TWithLang(i18n.LangZH, "log.loaded_from_redis"))
 			app.userCache.Set(cachedUsers)
+			if version, err := app.redisUserCache.GetVersion(); err == nil {
+				app.setDataVersion(version)
+			}
+			// Restored, not loaded: report the load that wrote it until the sources are loaded
+			app.followLoaderState()
+			app.syncActiveVersion()
 			return nil
 		}
 		prommetrics.CacheMisses.Inc()
@@ -245,10 +317,11 @@ func (app *App) loadInitialData(rulesFile, dataDir string) error {
 			Int("count", len(users)).
 			Msg(i18n.TWithLang(i18n.LangZH, "log.loaded_from_remote_api"))
 		app.userCache.Set(users)
-		if app.redisUserCache != nil {
-			if err := app.redisUserCache.Set(users); err != nil {
-				app.log.Warn().Err(err).Msg(i18n.TWithLang(i18n.LangZH, "log.redis_cache_update_failed"))
-			}
+		app.recordDataset(users)
+		if err := app.writeRedis(users); err != nil {
+			app.log.Warn().Err(err).Msg(i18n.TWithLang(i18n.LangZH, "log.redis_cache_update_failed"))
+		} else {
+			app.announceWrite()
 		}
 		return nil
 	}
@@ -277,6 +350,77 @@ func (app *App) loadInitialData(rulesFile, dataDir string) error {
 	return nil
 }
 
+// writeRedis stores users in the configured Redis layout, followed by the loader state (see
+// publishLoaderState); a no-op without Redis. With leader election the write is fenced: Redis checks the
+// fencing token and writes in one script, so a leader that was superseded (see cache.LeaderElector.Fence)
+// cannot overwrite the data of its successor.
+func (app *App) writeRedis(users []define.AllowListUser) error {
+	var write func(token int64) error
+	switch {
+	case app.userStore != nil:
+		write = func(token int64) error { return app.userStore.SetFenced(users, token) }
+	case app.redisUserCache != nil:
+		write = func(token int64) error { return app.redisUserCache.SetFenced(users, token) }
+	default:
+		return nil
+	}
+	var err error
+	if app.elector == nil {
+		err = write(0)
+	} else {
+		err = app.elector.Fence(write)
+	}
+	if err != nil {
+		return err
+	}
+	app.publishLoaderState()
+	return nil
+}
+
+// touchRedis is run by the leader when its data did not change: it restarts the expiry (cache.ttl) of the
+// list in the blob layout and writes users again when the list already expired, e.g. after every instance
+// was down for longer. Followers only read Redis, so a list left to expire would start new followers empty.
+func (app *App) touchRedis(users []define.AllowListUser) {
+	if app.redisUserCache == nil || app.userStore != nil {
+		return
+	}
+	stored, err := app.redisUserCache.Touch()
+	if err != nil {
+		app.log.Warn().Err(err).Msg(i18n.TWithLang(i18n.LangZH, "log.redis_cache_failed_continue"))
+		return
+	}
+	if stored {
+		return
+	}
+	if err := app.updateRedisCacheWithRetry(users); err != nil {
+		app.log.Warn().Err(err).Msg(i18n.TWithLang(i18n.LangZH, "log.redis_cache_failed_continue"))
+		return
+	}
+	app.announceWrite()
+}
+
+// userLookup returns what /user and /v1/lookup read from: the per-user Redis store when configured,
+// otherwise the memory cache; behind the membership filter when it is enabled.
+func (app *App) userLookup() cache.UserLookup {
+	var lookup cache.UserLookup = app.userCache
+	if app.userStore != nil {
+		lookup = app.userStore
+	}
+	if app.membershipFilter {
+		return cache.NewFilteredLookup(lookup, app.membershipFilters())
+	}
+	return lookup
+}
+
+// membershipFilters returns what holds the membership filter of the served list: the per-user Redis
+// store when configured, otherwise the memory cache.
+func (app *App) membershipFilters() cache.MembershipFilterSource {
+	if app.userStore != nil {
+		return app.userStore
+	}
+	return app.userCache
+}
+
 // hasChanged compares if data has changed (optimized using cached hash value)
 //
 // This function determines if data has changed by comparing cached hash values, used to optimize cache update strategy.
@@ -334,7 +478,7 @@ func (app *App) checkDataChanged(newUsers []define.AllowListUser) bool {
 //   - error: returns error on update failure, nil on success
 func (app *App) updateRedisCacheWithRetry(users []define.AllowListUser) error {
 	// If Redis cache is unavailable, return error directly
-	if app.redisUserCache == nil {
+	if app.redisUserCache == nil && app.userStore == nil {
 		return fmt.Errorf("redis cache unavailable")
 	}
 
@@ -347,12 +491,19 @@ func (app *App) updateRedisCacheWithRetry(users []define.AllowListUser) error {
 				Msg(i18n.TWithLang(i18n.LangZH, "log.retry_redis_cache"))
 		}
 
-		if err := app.redisUserCache.Set(users); err != nil {
+		if err := app.writeRedis(users); err != nil {
+			if errors.Is(err, cache.ErrNotLeader) || errors.Is(err, cache.ErrFenced) {
+				return err // another instance leads now; retrying cannot succeed
+			}
 			lastErr = err
 			if attempt < define.REDIS_RETRY_MAX_RETRIES-1 {
 				continue
 			}
 		} else {
+			if app.redisUserCache == nil {
+				app.log.Debug().Msg(i18n.TWithLang(i18n.LangZH, "log.redis_cache_updated"))
+				return nil
+			}
 			if cacheVersion, err := app.redisUserCache.GetVersion(); err == nil {
 				app.log.Debug().
 					Int64("version", cacheVersion).
@@ -373,6 +524,7 @@ func (app *App) updateRedisCacheWithRetry(users []define.AllowListUser) error {
 // - Error recovery: includes panic recovery mechanism to prevent task crashes from affecting main program
 // - Retry mechanism: automatically retries on Redis update failure
 // - Metrics collection: records task execution time, error count and other metrics
+// - Leader election: followers only pick up what the leader wrote to Redis (see followLeader)
 //
 // Parameters:
 //   - rulesFile: local rules file path, as one of the data sources
@@ -392,6 +544,8 @@ func (app *App) updateRedisCacheWithRetry(users []define.AllowListUser) error {
 //   - Uses hash values to quickly detect data changes
 //   - Returns directly when data unchanged, skipping update operations
 func (app *App) backgroundTask(rulesFile, dataDir string) {
+	app.reloadMu.Lock()
+	defer app.reloadMu.Unlock()
 	defer func() {
 		if r := recover(); r != nil {
 			prommetrics.BackgroundTaskErrors.Inc()
@@ -401,6 +555,15 @@ func (app *App) backgroundTask(rulesFile, dataDir string) {
 		}
 	}()
 
+	if !app.leading() {
+		app.followLeader()
+		return
+	}
+	if app.applyPinned() {
+		app.log.Debug().Msg(i18n.TWithLang(i18n.LangZH, "log.dataset_pinned_skip_sources"))
+		return
+	}
+
 	start := time.Now()
 	var newUsers []define.AllowListUser
 
@@ -423,6 +586,7 @@ func (app *App) backgroundTask(rulesFile, dataDir string) {
 	// Check if data has changed
 	if !app.checkDataChanged(newUsers) {
 		app.log.Debug().Msg(i18n.TWithLang(i18n.LangZH, "log.data_unchanged"))
+		app.touchRedis(newUsers)
 		return
 	}
 
@@ -433,13 +597,16 @@ func (app *App) backgroundTask(rulesFile, dataDir string) {
 	currentHash := app.userCache.GetHash()
 	newHash := cache.HashUserList(newUsers)
 	if currentHash != "" && currentHash == newHash {
+		app.recordDataset(newUsers)
 		// Data consistent, update Redis cache (if Redis is available)
-		if app.redisUserCache != nil {
+		if app.redisUserCache != nil || app.userStore != nil {
 			if err := app.updateRedisCacheWithRetry(newUsers); err != nil {
 				app.log.Warn().
 					Err(err).
 					Msg(i18n.TWithLang(i18n.LangZH, "log.redis_cache_failed_continue"))
 				prommetrics.BackgroundTaskErrors.Inc()
+			} else {
+				app.announceWrite()
 			}
 		}
 	} else {
@@ -462,6 +629,42 @@ func (app *App) backgroundTask(rulesFile, dataDir string) {
 		Msg(i18n.TWithLang(i18n.LangZH, "log.background_update"))
 }
 
+// startWatcher watches the local data file and directory and runs backgroundTask shortly after they change,
+// so edits propagate without waiting for the next task interval. Returns nil when watching is disabled,
+// not applicable (ONLY_REMOTE) or unavailable; the scheduled task keeps polling in every case.
+func (app *App) startWatcher() *watcher.Watcher {
+	if app.dataWatch.Disabled || app.rulesLoader == nil ||
+		strings.ToUpper(strings.TrimSpace(app.appMode)) == "ONLY_REMOTE" {
+		return nil
+	}
+	opts := watcher.Options{
+		Debounce: app.dataWatch.Debounce,
+		OnChange: func() {
+			app.log.Debug().Msg(i18n.TWithLang(i18n.LangZH, "log.file_changed_reload"))
+			app.backgroundTask(app.dataFile, app.dataDir)
+		},
+		OnError: func(err error) {
+			app.log.Warn().Err(err).Msg(i18n.TWithLang(i18n.LangZH, "log.file_watch_error"))
+		},
+	}
+	if app.dataFile != "" {
+		opts.Files = []string{app.dataFile}
+	}
+	if app.dataDir != "" {
+		opts.Dirs = []string{app.dataDir}
+	}
+	w, err := watcher.New(opts)
+	if err != nil {
+		app.log.Warn().Err(err).Msg(i18n.TWithLang(i18n.LangZH, "log.file_watch_failed"))
+		return nil
+	}
+	app.log.Info().
+		Str("data_file", app.dataFile).
+		Str("data_dir", app.dataDir).
+		Msg(i18n.TWithLang(i18n.LangZH, "log.file_watch_started"))
+	return w
+}
+
 // startServer starts HTTP server. When tlsCertFile and tlsKeyFile are set, TLS (and optional mTLS) is enabled;
 // the caller must use ListenAndServeTLS(certFile, keyFile) instead of ListenAndServe().
 func startServer(port, tlsCertFile, tlsKeyFile, tlsCAFile string, tlsRequireClientCert bool) *http.Server {
@@ -541,6 +744,11 @@ func showBanner() {
 }
 
 func main() {
+	// Subcommands (run without starting the service)
+	if len(os.Args) > 1 && os.Args[1] == cmd.SignCommand {
+		os.Exit(cmd.RunSign(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Display startup banner
 	showBanner()
 
@@ -618,8 +826,7 @@ func main() {
 	app.log.Info().Msgf(i18n.TWithLang(i18n.LangZH, "log.app_version"), version.Version, version.BuildDate, version.Commit)
 
 	// Start scheduled task scheduler
-	// Select lock implementation based on Redis availability
-	gocron.SetLocker(&cache.Locker{Cache: app.redisClient})
+	// With leader election only the leader loads on each tick; otherwise every tick takes a lock
 	scheduler := gocron.NewScheduler()
 	schedulerStopped := scheduler.Start()
 	defer func() {
@@ -627,7 +834,13 @@ func main() {
 		scheduler.Clear()
 		app.log.Info().Msg(i18n.TWithLang(i18n.LangZH, "log.scheduler_closed"))
 	}()
-	if err := scheduler.Every(app.taskInterval).Seconds().Lock().Do(app.backgroundTask, app.dataFile, app.dataDir); err != nil {
+	job := scheduler.Every(app.taskInterval).Seconds()
+	if app.elector == nil {
+		// Only reached without Redis (the elector exists whenever Redis is connected): a local lock
+		gocron.SetLocker(&cache.Locker{Namespace: app.namespace})
+		job = job.Lock()
+	}
+	if err := job.Do(app.backgroundTask, app.dataFile, app.dataDir); err != nil {
 		// Clean up resources before exiting (defer executes on function return, but log.Fatal exits immediately)
 		// So need to manually clean up
 		close(schedulerStopped)
@@ -639,8 +852,21 @@ func main() {
 			Msg(i18n.TWithLang(i18n.LangZH, "log.scheduler_init_failed"))
 	}
 
+	// Pick up data written to Redis by other instances without waiting for the next task run
+	app.startCacheSync(ctx)
+
+	// Keep (or take over) the leadership; resigned on shutdown so another instance takes over at once
+	electionDone := app.startLeaderElection(ctx)
+
+	// Reload immediately on local data changes (polling above stays as fallback)
+	if w := app.startWatcher(); w != nil {
+		defer func() { _ = w.Close() }() //nolint:errcheck // #nosec G104 -- best effort on shutdown
+	}
+
 	// Start server (TLS/mTLS when cert and key are set)
 	srv := startServer(app.port, app.tlsCertFile, app.tlsKeyFile, app.tlsCAFile, app.tlsRequireClientCert)
+	// Every response carries the history version of the served dataset
+	srv.Handler = router.ActiveVersionMiddleware(app)(http.DefaultServeMux)
 	app.log.Info().Msgf(i18n.TWithLang(i18n.LangZH, "log.service_listening"), app.port)
 	go func() {
 		var err error
@@ -664,6 +890,7 @@ func main() {
 
 	// Graceful shutdown
 	shutdownServer(srv, app.rateLimiter, app.log)
+	<-electionDone
 
 	app.log.Info().Msg(i18n.TWithLang(i18n.LangZH, "log.goodbye"))
 }
@@ -0,0 +1,182 @@
+// Package main - dataset history, rollback and pinning.
+package main
+
+import (
+	"context"
+
+	"github.com/soulteary/warden/internal/cache"
+	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/internal/i18n"
+	"github.com/soulteary/warden/internal/prommetrics"
+)
+
+// datasetTimeout bounds history operations, which may move a whole user list to or from Redis.
+const datasetTimeout = 2 * cache.REDIS_OPERATION_TIMEOUT
+
+// ActiveVersion returns the history version of the dataset this instance serves, 0 when unknown
+// (no history, or data that was never recorded).
+func (app *App) ActiveVersion() int64 {
+	return app.activeVersion.Load()
+}
+
+// recordDataset adds the users just applied from the sources to the history and makes them the active
+// version. With leader election the history is written fenced, like the list (see writeRedis).
+func (app *App) recordDataset(users []define.AllowListUser) {
+	if app.history == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), datasetTimeout)
+	defer cancel()
+	var ds cache.Dataset
+	record := func(token int64) (err error) {
+		ds, err = app.history.RecordFenced(ctx, users, app.userCache.GetHash(), token)
+		return err
+	}
+	var err error
+	if app.elector == nil {
+		err = record(0)
+	} else {
+		err = app.elector.Fence(record)
+	}
+	if err != nil {
+		app.activeVersion.Store(0)
+		app.log.Warn().Err(err).Msg(i18n.TWithLang(i18n.LangZH, "log.dataset_record_failed"))
+		return
+	}
+	app.activeVersion.Store(ds.Version)
+	app.log.Debug().Int64("version", ds.Version).Int("count", ds.Users).Msg(i18n.TWithLang(i18n.LangZH, "log.dataset_recorded"))
+}
+
+// syncActiveVersion looks up the active version of data read from Redis by its hash, preferring the
+// pinned version when several kept datasets have the same content.
+func (app *App) syncActiveVersion() {
+	if app.history == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cache.REDIS_OPERATION_TIMEOUT)
+	defer cancel()
+	hash, _ := app.served()
+	if pinned := app.history.Pinned(ctx); pinned != 0 {
+		if datasets, err := app.history.List(ctx); err == nil {
+			for _, ds := range datasets {
+				if ds.Version == pinned && ds.Hash == hash {
+					app.activeVersion.Store(pinned)
+					return
+				}
+			}
+		}
+	}
+	app.activeVersion.Store(app.history.VersionOf(ctx, hash))
+}
+
+// applyPinned is run by the leader instead of loading the sources while a version is pinned: it applies
+// the pinned dataset (and writes it to Redis) unless it is already served. Returns false when nothing is
+// pinned. The caller holds reloadMu.
+func (app *App) applyPinned() bool {
+	if app.history == nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), datasetTimeout)
+	defer cancel()
+	pinned := app.history.Pinned(ctx)
+	if pinned == 0 {
+		return false
+	}
+	ds, users, err := app.history.Get(ctx, pinned)
+	if err != nil {
+		app.log.Warn().Err(err).Int64("version", pinned).Msg(i18n.TWithLang(i18n.LangZH, "log.dataset_pinned_missing"))
+		return false
+	}
+	if hash, _ := app.served(); app.activeVersion.Load() == ds.Version && hash == ds.Hash {
+		app.touchRedis(users)
+		return true
+	}
+	app.applyDataset(ds, users)
+	app.log.Info().Int64("version", ds.Version).Int("count", ds.Users).Msg(i18n.TWithLang(i18n.LangZH, "log.dataset_pinned_applied"))
+	return true
+}
+
+// applyDataset serves users of a kept dataset and, when this instance leads, writes them to Redis for the
+// other instances. A follower of the hash layout serves the store only the leader writes, so it cannot
+// apply the dataset itself: it serves it once the leader applied the pin (see applyPinned) and announced
+// the write. The active version is only set once the served data has the content of the dataset. The
+// caller holds reloadMu.
+func (app *App) applyDataset(ds cache.Dataset, users []define.AllowListUser) {
+	leading := app.leading()
+	if leading || app.userStore == nil {
+		app.userCache.Set(users)
+	}
+	switch {
+	case leading && (app.redisUserCache != nil || app.userStore != nil):
+		if err := app.updateRedisCacheWithRetry(users); err != nil {
+			app.log.Warn().Err(err).Msg(i18n.TWithLang(i18n.LangZH, "log.redis_cache_failed_continue"))
+		} else {
+			app.announceWrite()
+		}
+	case !leading && app.userStore != nil:
+		// The leader may have applied the pin already
+		if err := app.userStore.Refresh(); err != nil {
+			app.log.Warn().Err(err).Msg(i18n.TWithLang(i18n.LangZH, "log.cache_notify_reload_failed"))
+		}
+	}
+	hash, count := app.served()
+	if hash == ds.Hash {
+		app.activeVersion.Store(ds.Version)
+	}
+	prommetrics.CacheSize.Set(float64(count))
+}
+
+// Rollback applies a kept dataset again and pins it, so the sources are not loaded until Unpin. A
+// follower of the blob layout applies the dataset locally at once, one of the hash layout once the leader
+// applied it; the leader applies and distributes it on its next run.
+func (app *App) Rollback(ctx context.Context, version int64) (cache.Dataset, error) {
+	if app.history == nil {
+		return cache.Dataset{}, cache.ErrDatasetNotFound
+	}
+	app.reloadMu.Lock()
+	defer app.reloadMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, datasetTimeout)
+	defer cancel()
+	ds, users, err := app.history.Get(ctx, version)
+	if err != nil {
+		return cache.Dataset{}, err
+	}
+	if err := app.history.Pin(ctx, version); err != nil {
+		return cache.Dataset{}, err
+	}
+	app.applyDataset(ds, users)
+	app.log.Warn().Int64("version", ds.Version).Str("hash", ds.Hash).Int("count", ds.Users).Msg(i18n.TWithLang(i18n.LangZH, "log.dataset_rolled_back"))
+	return ds, nil
+}
+
+// Unpin removes the pin; the leader loads the sources again right away.
+func (app *App) Unpin(ctx context.Context) error {
+	if app.history == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, cache.REDIS_OPERATION_TIMEOUT)
+	defer cancel()
+	if err := app.history.Unpin(ctx); err != nil {
+		return err
+	}
+	app.log.Info().Msg(i18n.TWithLang(i18n.LangZH, "log.dataset_unpinned"))
+	if app.leading() {
+		go app.backgroundTask(app.dataFile, app.dataDir)
+	}
+	return nil
+}
+
+// Datasets returns the kept datasets (newest first) and the pinned version (0 when none).
+func (app *App) Datasets(ctx context.Context) ([]cache.Dataset, int64, error) {
+	if app.history == nil {
+		return []cache.Dataset{}, 0, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, cache.REDIS_OPERATION_TIMEOUT)
+	defer cancel()
+	datasets, err := app.history.List(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return datasets, app.history.Pinned(ctx), nil
+}
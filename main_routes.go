@@ -9,7 +9,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/redis/go-redis/v9"
 	health "github.com/soulteary/health-kit"
 	loggerkit "github.com/soulteary/logger-kit"
 	middlewarekit "github.com/soulteary/middleware-kit"
@@ -59,6 +58,13 @@ func registerRoutes(app *App) {
 	optionalAuthMiddleware := middlewarekit.APIKeyAuthStd(optionalAuthCfg)
 
 	compressMiddleware := middlewarekit.CompressStd(middlewarekit.DefaultCompressConfig())
+	// The full user list is encoded once per dataset and sent pre-compressed by router.JSON, or compressed
+	// while it is streamed from the per-user Redis store by router.PagedJSON
+	listCompressMiddleware := router.CompressExceptFullList(compressMiddleware)
+	listHandler := router.JSON(app.userCache, app.responseFields)
+	if app.userStore != nil {
+		listHandler = router.PagedJSON(app.userStore, listHandler, app.responseFields)
+	}
 	bodyLimitCfg := middlewarekit.DefaultBodyLimitConfig()
 	bodyLimitCfg.MaxSize = define.MAX_REQUEST_BODY_SIZE
 	bodyLimitCfg.TrustedProxyConfig = trustedProxyConfig
@@ -72,6 +78,9 @@ func registerRoutes(app *App) {
 
 	healthWhitelist := os.Getenv("HEALTH_CHECK_IP_WHITELIST")
 
+	// Data responses carry the dataset version (Git source commit) when one is configured
+	datasetVersion := router.DatasetVersionMiddleware(app.rulesLoader)
+
 	metricsHandler := i18nMiddleware(
 		router.AccessLogMiddleware()(
 			securityHeadersMiddleware(
@@ -92,12 +101,12 @@ func registerRoutes(app *App) {
 			securityHeadersMiddleware(
 				errorHandlerMiddleware(
 					wrapWithTracingIfEnabled(tracingMiddleware,
-						compressMiddleware(
+						listCompressMiddleware(
 							bodyLimitMiddleware(
 								middleware.MetricsMiddleware(
 									rateLimitMiddleware(
 										authMiddleware(
-											router.ProcessWithLogger(router.JSON(app.userCache, app.responseFields)),
+											datasetVersion(router.ProcessWithLogger(listHandler)),
 										),
 									),
 								),
@@ -121,7 +130,7 @@ func registerRoutes(app *App) {
 								middleware.MetricsMiddleware(
 									rateLimitMiddleware(
 										authMiddleware(
-											router.ProcessWithLogger(router.GetUserByIdentifier(app.userCache, app.responseFields)),
+											datasetVersion(router.ProcessWithLogger(router.GetUserByIdentifier(app.userLookup(), app.responseFields, app.rulesLoader))),
 										),
 									),
 								),
@@ -144,7 +153,7 @@ func registerRoutes(app *App) {
 								middleware.MetricsMiddleware(
 									rateLimitMiddleware(
 										authMiddleware(
-											router.ProcessWithLogger(router.GetLookup(app.userCache)),
+											datasetVersion(router.ProcessWithLogger(router.GetLookup(app.userLookup(), app.rulesLoader))),
 										),
 									),
 								),
@@ -157,7 +166,36 @@ func registerRoutes(app *App) {
 	)
 	http.Handle("/v1/lookup", lookupHandler)
 
-	healthAggregator := setupHealthChecker(app.redisClient, app.userCache, app.appMode, app.redisEnabled, healthWhitelist)
+	membershipFilterHandler := i18nMiddleware(
+		router.AccessLogMiddleware()(
+			securityHeadersMiddleware(
+				errorHandlerMiddleware(
+					wrapWithTracingIfEnabled(tracingMiddleware,
+						bodyLimitMiddleware(
+							middleware.MetricsMiddleware(
+								rateLimitMiddleware(
+									authMiddleware(
+										datasetVersion(router.ProcessWithLogger(router.GetMembershipFilter(app.membershipFilters()))),
+									),
+								),
+							),
+						),
+					),
+				),
+			),
+		),
+	)
+	http.Handle("/v1/membership-filter", membershipFilterHandler)
+
+	app.rulesLoader.TrackSources(app.dataFile, app.dataDir, app.configURL)
+	servedUsers := func() int {
+		_, users := app.served()
+		return users
+	}
+	healthAggregator := setupHealthChecker(app.redisTopology, servedUsers, app.appMode, app.redisEnabled, healthWhitelist, app.rulesLoader.SourceStatuses)
+	if app.elector != nil {
+		healthAggregator.AddChecker(leaderChecker(app.elector))
+	}
 	healthHandler := i18nMiddleware(
 		router.AccessLogMiddleware()(
 			securityHeadersMiddleware(
@@ -198,10 +236,153 @@ func registerRoutes(app *App) {
 		),
 	)
 	http.Handle("/log/level", logLevelHandler)
+
+	// Admin endpoints use their own key (no HMAC / mTLS / API_KEY fallback) and are disabled without it
+	if app.adminAPIKey == "" {
+		return
+	}
+	adminAuthCfg := authBaseCfg
+	adminAuthCfg.APIKey = app.adminAPIKey
+	adminAuthMiddleware := middlewarekit.APIKeyAuthStd(adminAuthCfg)
+	provenanceHandler := i18nMiddleware(
+		router.AccessLogMiddleware()(
+			securityHeadersMiddleware(
+				errorHandlerMiddleware(
+					wrapWithTracingIfEnabled(tracingMiddleware,
+						middleware.MetricsMiddleware(
+							rateLimitMiddleware(
+								adminAuthMiddleware(
+									router.ProcessWithLogger(router.GetUserProvenance(app.userLookup(), app.rulesLoader)),
+								),
+							),
+						),
+					),
+				),
+			),
+		),
+	)
+	http.Handle("/v1/admin/users/{id}/provenance", provenanceHandler)
+
+	sourcesHandler := i18nMiddleware(
+		router.AccessLogMiddleware()(
+			securityHeadersMiddleware(
+				errorHandlerMiddleware(
+					wrapWithTracingIfEnabled(tracingMiddleware,
+						middleware.MetricsMiddleware(
+							rateLimitMiddleware(
+								adminAuthMiddleware(
+									router.ProcessWithLogger(router.GetSources(app.rulesLoader)),
+								),
+							),
+						),
+					),
+				),
+			),
+		),
+	)
+	http.Handle("/v1/admin/sources", sourcesHandler)
+
+	loadReportHandler := i18nMiddleware(
+		router.AccessLogMiddleware()(
+			securityHeadersMiddleware(
+				errorHandlerMiddleware(
+					wrapWithTracingIfEnabled(tracingMiddleware,
+						middleware.MetricsMiddleware(
+							rateLimitMiddleware(
+								adminAuthMiddleware(
+									router.ProcessWithLogger(router.GetLoadReport(app, app.rulesLoader)),
+								),
+							),
+						),
+					),
+				),
+			),
+		),
+	)
+	http.Handle("/v1/admin/load-report", loadReportHandler)
+
+	instancesHandler := i18nMiddleware(
+		router.AccessLogMiddleware()(
+			securityHeadersMiddleware(
+				errorHandlerMiddleware(
+					wrapWithTracingIfEnabled(tracingMiddleware,
+						middleware.MetricsMiddleware(
+							rateLimitMiddleware(
+								adminAuthMiddleware(
+									router.ProcessWithLogger(router.GetInstances(app)),
+								),
+							),
+						),
+					),
+				),
+			),
+		),
+	)
+	http.Handle("/v1/admin/instances", instancesHandler)
+
+	datasetsHandler := i18nMiddleware(
+		router.AccessLogMiddleware()(
+			securityHeadersMiddleware(
+				errorHandlerMiddleware(
+					wrapWithTracingIfEnabled(tracingMiddleware,
+						middleware.MetricsMiddleware(
+							rateLimitMiddleware(
+								adminAuthMiddleware(
+									router.ProcessWithLogger(router.GetDatasets(app)),
+								),
+							),
+						),
+					),
+				),
+			),
+		),
+	)
+	http.Handle("/v1/admin/datasets", datasetsHandler)
+
+	rollbackHandler := i18nMiddleware(
+		router.AccessLogMiddleware()(
+			securityHeadersMiddleware(
+				errorHandlerMiddleware(
+					wrapWithTracingIfEnabled(tracingMiddleware,
+						middleware.MetricsMiddleware(
+							rateLimitMiddleware(
+								adminAuthMiddleware(
+									router.ProcessWithLogger(router.PostRollback(app)),
+								),
+							),
+						),
+					),
+				),
+			),
+		),
+	)
+	http.Handle("/v1/admin/rollback", rollbackHandler)
+
+	unpinHandler := i18nMiddleware(
+		router.AccessLogMiddleware()(
+			securityHeadersMiddleware(
+				errorHandlerMiddleware(
+					wrapWithTracingIfEnabled(tracingMiddleware,
+						middleware.MetricsMiddleware(
+							rateLimitMiddleware(
+								adminAuthMiddleware(
+									router.ProcessWithLogger(router.PostUnpin(app)),
+								),
+							),
+						),
+					),
+				),
+			),
+		),
+	)
+	http.Handle("/v1/admin/unpin", unpinHandler)
 }
 
-// setupHealthChecker creates a health check aggregator with all dependencies
-func setupHealthChecker(redisClient *redis.Client, userCache *cache.SafeUserCache, appMode string, redisEnabled bool, ipWhitelist string) *health.Aggregator {
+// setupHealthChecker creates a health check aggregator with all dependencies.
+// redis and data are critical (data counts the users served, see App.served); each source tracked in
+// sourceStatuses gets a non-critical "source:<name>" check, so a failing source degrades the status
+// without failing the health endpoint.
+func setupHealthChecker(redisTopology *cache.RedisTopology, servedUsers func() int, appMode string, redisEnabled bool, ipWhitelist string, sourceStatuses func() []define.SourceStatus) *health.Aggregator {
 	isProduction := appMode == "production" || appMode == "prod"
 	isOnlyLocalMode := strings.ToUpper(strings.TrimSpace(appMode)) == "ONLY_LOCAL"
 
@@ -220,7 +401,8 @@ func setupHealthChecker(redisClient *redis.Client, userCache *cache.SafeUserCach
 		WithTimeout(5 * time.Second).
 		WithIPWhitelist(ipList).
 		WithDetails(!isProduction).
-		WithChecks(!isProduction)
+		WithChecks(!isProduction).
+		WithCriticalChecks([]string{"redis", "data"})
 
 	aggregator := health.NewAggregator(healthConfig)
 
@@ -228,8 +410,8 @@ func setupHealthChecker(redisClient *redis.Client, userCache *cache.SafeUserCach
 	case !redisEnabled:
 		aggregator.AddChecker(health.NewDisabledChecker("redis").
 			WithMessage("Redis is disabled"))
-	case redisClient != nil:
-		aggregator.AddChecker(health.NewRedisChecker(redisClient))
+	case redisTopology != nil:
+		aggregator.AddChecker(redisChecker(redisTopology))
 	default:
 		aggregator.AddChecker(health.NewCustomChecker("redis", func(_ context.Context) error {
 			return errors.New("client not initialized")
@@ -237,10 +419,10 @@ func setupHealthChecker(redisClient *redis.Client, userCache *cache.SafeUserCach
 	}
 
 	aggregator.AddChecker(health.NewCustomChecker("data", func(_ context.Context) error {
-		if userCache == nil {
+		if servedUsers == nil {
 			return errors.New("cache not initialized")
 		}
-		if userCache.Len() == 0 {
+		if servedUsers() == 0 {
 			if isOnlyLocalMode {
 				return nil
 			}
@@ -249,9 +431,119 @@ func setupHealthChecker(redisClient *redis.Client, userCache *cache.SafeUserCach
 		return nil
 	}))
 
+	if sourceStatuses != nil {
+		for _, st := range sourceStatuses() {
+			aggregator.AddChecker(sourceChecker(st.Name, sourceStatuses))
+		}
+	}
+
 	return aggregator
 }
 
+// redisChecker pings Redis and reports the state of the deployment (mode, role and replicas; the master
+// seen by the Sentinels; the cluster state). It fails when the deployment cannot serve writes.
+func redisChecker(topology *cache.RedisTopology) health.Checker {
+	return health.NewCheckerFunc("redis", func(ctx context.Context) health.CheckResult {
+		result := health.CheckResult{Name: "redis", Status: health.StatusHealthy, Timestamp: time.Now()}
+		ctx, cancel := context.WithTimeout(ctx, cache.REDIS_OPERATION_TIMEOUT)
+		defer cancel()
+		start := time.Now()
+		st, err := topology.Status(ctx)
+		result.Latency = time.Since(start)
+		result.Metadata = st.Metadata()
+		if err != nil {
+			result.Status = health.StatusUnhealthy
+			result.Error = err.Error()
+		}
+		return result
+	})
+}
+
+// sourceChecker reports the last sync of the named source. Sources that are no longer configured report
+// disabled; sources that have not been attempted yet report healthy.
+func sourceChecker(name string, sourceStatuses func() []define.SourceStatus) health.Checker {
+	return health.NewCheckerFunc("source:"+name, func(_ context.Context) health.CheckResult {
+		result := health.CheckResult{Name: "source:" + name, Status: health.StatusDisabled, Timestamp: time.Now()}
+		for _, st := range sourceStatuses() {
+			if st.Name != name {
+				continue
+			}
+			result.Metadata = map[string]any{
+				"type":       st.Type,
+				"records":    st.Records,
+				"latency_ms": st.LatencyMS,
+			}
+			if !st.LastAttempt.IsZero() {
+				result.Metadata["last_attempt"] = st.LastAttempt
+			}
+			if !st.LastSuccess.IsZero() {
+				result.Metadata["last_success"] = st.LastSuccess
+			}
+			if st.Version != "" {
+				result.Metadata["version"] = st.Version
+			}
+			if st.Circuit != "" {
+				result.Metadata["circuit"] = st.Circuit
+			}
+			if !st.RetryAt.IsZero() {
+				result.Metadata["retry_at"] = st.RetryAt
+			}
+			switch {
+			case st.Failing():
+				result.Status = health.StatusUnhealthy
+				result.Error = st.LastError
+				if !st.RetryAt.IsZero() {
+					result.Message = "circuit open, next attempt at " + st.RetryAt.Format(time.RFC3339)
+				}
+			case st.LastAttempt.IsZero():
+				result.Status = health.StatusHealthy
+				result.Message = "not loaded yet"
+			default:
+				result.Status = health.StatusHealthy
+			}
+			return result
+		}
+		result.Message = "source no longer configured"
+		return result
+	})
+}
+
+// leaderChecker reports the role of this instance in the leader election and the current leader. It is
+// not critical: an instance that cannot reach Redis keeps serving its own sources, which only degrades.
+func leaderChecker(elector *cache.LeaderElector) health.Checker {
+	return health.NewCheckerFunc("leader", func(ctx context.Context) health.CheckResult {
+		result := health.CheckResult{Name: "leader", Status: health.StatusHealthy, Timestamp: time.Now()}
+		role := "follower"
+		if elector.IsLeader() {
+			role = "leader"
+		}
+		result.Metadata = map[string]any{
+			"instance": elector.Instance(),
+			"role":     role,
+		}
+		if token := elector.Token(); token > 0 {
+			result.Metadata["token"] = token
+		}
+		if !elector.Reachable() {
+			result.Status = health.StatusUnhealthy
+			result.Error = "election unavailable, serving own sources"
+			return result
+		}
+		holder, err := elector.Leader(ctx)
+		if err != nil {
+			result.Status = health.StatusUnhealthy
+			result.Error = err.Error()
+			return result
+		}
+		if holder == "" {
+			result.Message = "no leader elected yet"
+		} else {
+			result.Metadata["leader"] = holder
+		}
+		return result
+	})
+}
+
 // wrapWithTracingIfEnabled wraps handler with tracing middleware if enabled
 func wrapWithTracingIfEnabled(tracingMiddleware func(http.Handler) http.Handler, handler http.Handler) http.Handler {
 	if tracingMiddleware != nil {
@@ -0,0 +1,307 @@
+// Package main - cross-instance cache synchronization over Redis pub/sub and leader election.
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/soulteary/warden/internal/cache"
+	"github.com/soulteary/warden/internal/define"
+	"github.com/soulteary/warden/internal/i18n"
+	"github.com/soulteary/warden/internal/prommetrics"
+)
+
+// leading reports whether this instance loads the sources and writes Redis: always without Redis,
+// otherwise while it holds the leadership, or while it cannot reach Redis to know who does (every instance
+// then serves its own sources, as without Redis).
+func (app *App) leading() bool {
+	return app.elector == nil || app.elector.IsLeader() || !app.elector.Reachable()
+}
+
+// readRedis reads the user list of the blob layout (cache.ErrUserCacheMissing when none was written; an
+// empty list is a valid dataset); nil without it. The hash layout is never read as a whole: lookups and
+// the list are served from the store (see served).
+func (app *App) readRedis() ([]define.AllowListUser, error) {
+	if app.redisUserCache == nil {
+		return nil, nil
+	}
+	return app.redisUserCache.Get()
+}
+
+// served returns the content hash and the number of users of the data this instance serves: the
+// generation of the per-user Redis store once one is known, otherwise the memory cache.
+func (app *App) served() (hash string, users int) {
+	if app.userStore != nil {
+		if info, ok := app.userStore.Info(); ok {
+			return info.Hash, info.Users
+		}
+	}
+	return app.userCache.GetHash(), app.userCache.Len()
+}
+
+// redisVersion returns the version of the data in Redis: the cache version (blob layout) or the store
+// generation (hash layout); 0 without Redis.
+func (app *App) redisVersion() (int64, error) {
+	switch {
+	case app.userStore != nil:
+		return app.userStore.GetVersion()
+	case app.redisUserCache != nil:
+		return app.redisUserCache.GetVersion()
+	default:
+		return 0, nil
+	}
+}
+
+// setDataVersion records the Redis version of the data this instance serves.
+func (app *App) setDataVersion(version int64) {
+	app.dataVersion.Store(version)
+	prommetrics.DataVersion.Set(float64(version))
+}
+
+// announceWrite is called after this instance wrote users to Redis: it records the new version and
+// tells the other instances to pick it up.
+func (app *App) announceWrite() {
+	version, err := app.redisVersion()
+	if err != nil {
+		app.log.Warn().Err(err).Msg(i18n.TWithLang(i18n.LangZH, "log.cache_notify_failed"))
+		return
+	}
+	app.setDataVersion(version)
+	if app.notifier == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cache.REDIS_OPERATION_TIMEOUT)
+	defer cancel()
+	hash, _ := app.served()
+	if err := app.notifier.Publish(ctx, version, hash); err != nil {
+		app.log.Warn().Err(err).Msg(i18n.TWithLang(i18n.LangZH, "log.cache_notify_failed"))
+		return
+	}
+	prommetrics.CacheNotifications.WithLabelValues("published").Inc()
+	app.reportStatus(ctx)
+}
+
+// applyCacheUpdate reloads the data another instance wrote to Redis (see reloadFromRedis).
+func (app *App) applyCacheUpdate(u cache.CacheUpdate) {
+	prommetrics.CacheNotifications.WithLabelValues("received").Inc()
+	app.reloadMu.Lock()
+	defer app.reloadMu.Unlock()
+	app.reloadFromRedis(u)
+}
+
+// reloadFromRedis picks up the data announced by u: the blob layout is read into the memory cache unless
+// it already has the announced content; the hash layout only drops the local LRU and loads the meta data
+// of the new generation, the users stay in Redis. The caller holds reloadMu.
+func (app *App) reloadFromRedis(u cache.CacheUpdate) {
+	switch {
+	case app.userStore != nil:
+		if err := app.userStore.Refresh(); err != nil {
+			app.log.Warn().Err(err).Str("instance", u.Instance).Msg(i18n.TWithLang(i18n.LangZH, "log.cache_notify_reload_failed"))
+			return
+		}
+	case app.redisUserCache == nil:
+		return
+	case u.Hash == "" || u.Hash != app.userCache.GetHash():
+		users, err := app.readRedis()
+		if err != nil {
+			app.log.Warn().Err(err).Str("instance", u.Instance).Msg(i18n.TWithLang(i18n.LangZH, "log.cache_notify_reload_failed"))
+			return
+		}
+		app.userCache.Set(users)
+	}
+	app.followLoaderState()
+	_, count := app.served()
+	prommetrics.CacheSize.Set(float64(count))
+	app.setDataVersion(u.Version)
+	app.syncActiveVersion()
+	app.log.Info().
+		Str("instance", u.Instance).
+		Int64("version", u.Version).
+		Int("count", count).
+		Msg(i18n.TWithLang(i18n.LangZH, "log.cache_notify_applied"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), cache.REDIS_OPERATION_TIMEOUT)
+	defer cancel()
+	app.reportStatus(ctx)
+}
+
+// loadFromLeader is the initial load of a follower: it serves what the leader wrote to Redis and leaves
+// the sources to the leader. With nothing in Redis yet it starts empty and picks up the first write of
+// the leader through the cache update notification.
+func (app *App) loadFromLeader() error {
+	if app.userStore != nil {
+		if err := app.userStore.Refresh(); err != nil {
+			prommetrics.CacheMisses.Inc()
+			app.log.Info().Err(err).Msg(i18n.TWithLang(i18n.LangZH, "log.leader_follower_waiting"))
+			return nil
+		}
+		info, _ := app.userStore.Info()
+		prommetrics.CacheHits.Inc()
+		prommetrics.CacheSize.Set(float64(info.Users))
+		app.log.Info().
+			Int("count", info.Users).
+			Msg(i18n.TWithLang(i18n.LangZH, "log.loaded_from_redis"))
+		app.setDataVersion(info.Generation)
+		app.followLoaderState()
+		app.syncActiveVersion()
+		return nil
+	}
+	users, err := app.readRedis()
+	if err != nil {
+		prommetrics.CacheMisses.Inc()
+		app.log.Info().Err(err).Msg(i18n.TWithLang(i18n.LangZH, "log.leader_follower_waiting"))
+		return nil
+	}
+	prommetrics.CacheHits.Inc()
+	app.log.Info().
+		Int("count", len(users)).
+		Msg(i18n.TWithLang(i18n.LangZH, "log.loaded_from_redis"))
+	app.userCache.Set(users)
+	if version, err := app.redisVersion(); err == nil {
+		app.setDataVersion(version)
+	}
+	app.followLoaderState()
+	app.syncActiveVersion()
+	return nil
+}
+
+// publishLoaderState shares the state of the last load with the followers after the leader wrote its
+// dataset: source statuses, identity conflicts, the load report and the provenance of every user.
+func (app *App) publishLoaderState() {
+	if app.loaderState == nil || app.rulesLoader == nil {
+		return
+	}
+	state, provenance := app.rulesLoader.State()
+	if report, ok := app.userCache.Report(); ok {
+		state.Report = &report
+	}
+	if err := app.loaderState.Publish(state, provenance); err != nil {
+		app.log.Warn().Err(err).Msg(i18n.TWithLang(i18n.LangZH, "log.loader_state_publish_failed"))
+	}
+}
+
+// followLoaderState makes a follower report the leader's last load instead of its own, which it never
+// runs: the admin endpoints (provenance, sources, load report), the source health and the source names
+// in audit records then describe the dataset it serves.
+func (app *App) followLoaderState() {
+	if app.loaderState == nil || app.rulesLoader == nil {
+		return
+	}
+	state, index, err := app.loaderState.Get()
+	if err != nil {
+		app.log.Warn().Err(err).Msg(i18n.TWithLang(i18n.LangZH, "log.loader_state_read_failed"))
+		return
+	}
+	app.rulesLoader.Follow(state, index)
+	app.leaderReport.Store(state.Report)
+}
+
+// Report returns the load report of the served dataset: the leader's on a follower, otherwise the report
+// of the memory cache.
+func (app *App) Report() (define.LoadReport, bool) {
+	if !app.leading() {
+		if report := app.leaderReport.Load(); report != nil {
+			return *report, true
+		}
+	}
+	return app.userCache.Report()
+}
+
+// followLeader is the scheduled task of a follower: it reloads from Redis when the leader wrote a version
+// this instance has not applied, e.g. because the notification was missed. The caller holds reloadMu.
+func (app *App) followLeader() {
+	version, err := app.redisVersion()
+	if err != nil || version == 0 || version == app.dataVersion.Load() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cache.REDIS_OPERATION_TIMEOUT)
+	leader, _ := app.elector.Leader(ctx) //nolint:errcheck // only used for logging
+	cancel()
+	app.reloadFromRedis(cache.CacheUpdate{Instance: leader, Version: version})
+}
+
+// startLeaderElection campaigns for the leadership until ctx is done; a new leader loads the sources at
+// once instead of waiting for the next task run. The returned channel is closed once the leadership has
+// been released on shutdown (immediately without Redis).
+func (app *App) startLeaderElection(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	if app.elector == nil {
+		close(done)
+		return done
+	}
+	app.elector.OnChange(func(leader bool) {
+		if !leader {
+			app.log.Warn().Str("instance", app.elector.Instance()).Msg(i18n.TWithLang(i18n.LangZH, "log.leader_lost"))
+			return
+		}
+		app.log.Info().
+			Str("instance", app.elector.Instance()).
+			Int64("token", app.elector.Token()).
+			Msg(i18n.TWithLang(i18n.LangZH, "log.leader_acquired"))
+		go app.backgroundTask(app.dataFile, app.dataDir)
+	})
+	go func() {
+		defer close(done)
+		app.elector.Run(ctx)
+	}()
+	return done
+}
+
+// status returns the dataset this instance serves.
+func (app *App) status() define.InstanceStatus {
+	hash, users := app.served()
+	st := define.InstanceStatus{
+		Version:   app.dataVersion.Load(),
+		Hash:      hash,
+		Users:     users,
+		Leader:    app.elector != nil && app.elector.IsLeader(),
+		UpdatedAt: time.Now(),
+	}
+	if app.notifier != nil {
+		st.Instance = app.notifier.Instance()
+	} else {
+		st.Instance = cache.DefaultInstanceID()
+	}
+	return st
+}
+
+// reportStatus shares the status of this instance with the others.
+func (app *App) reportStatus(ctx context.Context) {
+	if app.notifier == nil {
+		return
+	}
+	if err := app.notifier.Report(ctx, app.status()); err != nil {
+		app.log.Debug().Err(err).Msg(i18n.TWithLang(i18n.LangZH, "log.cache_notify_failed"))
+	}
+}
+
+// Instances returns the status of every instance sharing the Redis cache (only this one without Redis).
+func (app *App) Instances(ctx context.Context) ([]define.InstanceStatus, error) {
+	if app.notifier == nil {
+		return []define.InstanceStatus{app.status()}, nil
+	}
+	return app.notifier.Instances(ctx)
+}
+
+// startCacheSync subscribes to cache updates of other instances and reports the status of this instance
+// every cache.InstanceHeartbeat until ctx is done. A no-op without Redis.
+func (app *App) startCacheSync(ctx context.Context) {
+	if app.notifier == nil {
+		return
+	}
+	go app.notifier.Subscribe(ctx, app.applyCacheUpdate)
+	go func() {
+		ticker := time.NewTicker(cache.InstanceHeartbeat)
+		defer ticker.Stop()
+		for {
+			reportCtx, cancel := context.WithTimeout(ctx, cache.REDIS_OPERATION_TIMEOUT)
+			app.reportStatus(reportCtx)
+			cancel()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
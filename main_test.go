@@ -2,21 +2,31 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/redis/go-redis/v9"
+	health "github.com/soulteary/health-kit"
 	middlewarekit "github.com/soulteary/middleware-kit"
 	"github.com/soulteary/warden/internal/cache"
 	"github.com/soulteary/warden/internal/cmd"
+	"github.com/soulteary/warden/internal/config"
 	"github.com/soulteary/warden/internal/define"
 	"github.com/soulteary/warden/internal/logger"
+	"github.com/soulteary/warden/internal/router"
+	"github.com/soulteary/warden/internal/testutil"
 )
 
 func newFailingRemoteServer(t *testing.T, expectedAuth string) *httptest.Server {
@@ -1349,3 +1359,505 @@ func TestRegisterRoutes_AllEndpoints(t *testing.T) {
 	// Restore original routes
 	http.DefaultServeMux = originalDefaultMux
 }
+
+// TestApp_startWatcher_ReloadsOnChange tests that editing the data file reloads the cache without waiting for the task interval
+func TestApp_startWatcher_ReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	dataFile := filepath.Join(dir, "data.json")
+	require.NoError(t, os.WriteFile(dataFile, []byte(`[{"phone": "13800138000"}]`), 0o600))
+
+	cfg := &cmd.Config{
+		Port:         "8081",
+		RedisEnabled: false,
+		Mode:         "ONLY_LOCAL",
+		DataFile:     dataFile,
+		TaskInterval: 60,
+		HTTPTimeout:  30,
+		DataWatch:    config.FileWatchConfig{Debounce: 20 * time.Millisecond},
+	}
+	app := NewApp(cfg)
+	require.Equal(t, 1, app.userCache.Len())
+
+	w := app.startWatcher()
+	require.NotNil(t, w)
+	defer func() { _ = w.Close() }()
+
+	// Atomic replace, as done by editors and ConfigMap updates
+	tmp := filepath.Join(dir, ".data.json.tmp")
+	require.NoError(t, os.WriteFile(tmp, []byte(`[{"phone": "13800138000"}, {"phone": "13800138001"}]`), 0o600))
+	require.NoError(t, os.Rename(tmp, dataFile))
+
+	assert.Eventually(t, func() bool { return app.userCache.Len() == 2 }, 3*time.Second, 20*time.Millisecond)
+}
+
+// TestApp_startWatcher_Disabled tests that watching can be turned off and is skipped in ONLY_REMOTE mode
+func TestApp_startWatcher_Disabled(t *testing.T) {
+	cfg := &cmd.Config{
+		Port:         "8081",
+		Mode:         "ONLY_LOCAL",
+		DataFile:     filepath.Join(t.TempDir(), "data.json"),
+		TaskInterval: 60,
+		DataWatch:    config.FileWatchConfig{Disabled: true},
+	}
+	assert.Nil(t, NewApp(cfg).startWatcher())
+
+	cfg.DataWatch.Disabled = false
+	cfg.Mode = "ONLY_REMOTE"
+	assert.Nil(t, NewApp(cfg).startWatcher())
+}
+
+func TestSetupHealthChecker_Sources(t *testing.T) {
+	userCache := cache.NewSafeUserCache()
+	userCache.Set([]define.AllowListUser{{Phone: "13800138000", Status: "active"}})
+	now := time.Now()
+	statuses := []define.SourceStatus{
+		{Name: "https://example.com/users", Type: "remote", LastAttempt: now, LastError: "status 502", Circuit: "open", RetryAt: now.Add(time.Minute)},
+		{Name: "/data/a.json", Type: "file", LastAttempt: now, LastSuccess: now, Records: 1},
+		{Name: "/data/b.json", Type: "file"},
+		{Name: "https://git.example.com/users.git", Type: "git", Version: "0123456789abcdef0123456789abcdef01234567", LastAttempt: now, LastSuccess: now, Records: 2},
+	}
+	agg := setupHealthChecker(nil, userCache.Len, "development", false, "", func() []define.SourceStatus { return statuses })
+
+	res := agg.Check(context.Background())
+	assert.Equal(t, health.StatusDegraded, res.Status, "a failing source only degrades")
+	remote := res.Checks["source:https://example.com/users"]
+	assert.Equal(t, health.StatusUnhealthy, remote.Status)
+	assert.Equal(t, "status 502", remote.Error)
+	assert.Equal(t, "open", remote.Metadata["circuit"])
+	assert.Contains(t, remote.Message, "circuit open")
+	assert.Equal(t, health.StatusHealthy, res.Checks["source:/data/a.json"].Status)
+	assert.Equal(t, "not loaded yet", res.Checks["source:/data/b.json"].Message)
+	assert.Equal(t, "0123456789abcdef0123456789abcdef01234567", res.Checks["source:https://git.example.com/users.git"].Metadata["version"])
+
+	// Sources dropped after startup report disabled.
+	statuses = statuses[1:2]
+	res = agg.Check(context.Background())
+	assert.Equal(t, health.StatusHealthy, res.Status)
+	assert.Equal(t, health.StatusDisabled, res.Checks["source:https://example.com/users"].Status)
+}
+
+// TestApp_UserLookup tests that lookups use the per-user Redis store only when it is configured
+func TestApp_UserLookup(t *testing.T) {
+	app := &App{userCache: cache.NewSafeUserCache()}
+	assert.Same(t, app.userCache, app.userLookup())
+	assert.NoError(t, app.writeRedis([]define.AllowListUser{{Phone: "13800138000"}}), "没有Redis时写入应为空操作")
+
+	app.userStore = cache.NewRedisUserStore(nil, "", -1, 0, app.userCache)
+	assert.Same(t, app.userStore, app.userLookup())
+}
+
+// TestApp_FollowerHashLayout tests that a follower in the hash layout serves the leader's generation from
+// Redis without loading the list into memory
+func TestApp_FollowerHashLayout(t *testing.T) {
+	client, server := testutil.NewFakeRedisClient(t)
+	newApp := func() *App {
+		app := &App{userCache: cache.NewSafeUserCache(), log: logger.GetLoggerKit(), membershipFilter: true}
+		app.userStore = cache.NewRedisUserStore(client, "", 0, 0, app.userCache)
+		app.userStore.EnableMembershipFilter(0.01)
+		return app
+	}
+	leader, follower := newApp(), newApp()
+	server.Mu.Lock()
+	server.Data[cache.DefaultNamespace.Key(cache.REDIS_LEADER_KEY)] = "leader"
+	server.Mu.Unlock()
+	follower.elector = cache.NewLeaderElector(client, "", "follower", 0)
+	follower.elector.Campaign(context.Background())
+	require.False(t, follower.leading())
+
+	// Nothing written yet: the follower waits for the leader
+	require.NoError(t, follower.loadInitialData("/nonexistent/file.json", ""))
+	assert.Zero(t, follower.status().Users)
+
+	users := []define.AllowListUser{{Phone: "13800138000", Mail: "a@example.com"}, {Mail: "b@example.com", UserID: "b"}}
+	leader.userCache.Set(users)
+	require.NoError(t, leader.writeRedis(users))
+	leader.announceWrite()
+	follower.applyCacheUpdate(cache.CacheUpdate{Instance: "leader", Version: leader.dataVersion.Load(), Hash: leader.userCache.GetHash()})
+
+	assert.Zero(t, follower.userCache.Len(), "the list stays in Redis")
+	st := follower.status()
+	assert.Equal(t, 2, st.Users)
+	assert.Equal(t, leader.userCache.GetHash(), st.Hash)
+	assert.Equal(t, leader.dataVersion.Load(), st.Version)
+
+	lookup := follower.userLookup()
+	user, ok := lookup.GetByUserID("b")
+	require.True(t, ok)
+	assert.Equal(t, "b@example.com", user.Mail)
+	_, ok = lookup.GetByMail("c@example.com")
+	assert.False(t, ok)
+	require.NotNil(t, follower.membershipFilters().MembershipFilter(), "filter loaded from Redis")
+	page, total, err := follower.userStore.Page(0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Len(t, page, 2)
+
+	// A restarted follower picks up the current generation at once
+	restarted := newApp()
+	restarted.elector = follower.elector
+	require.NoError(t, restarted.loadInitialData("/nonexistent/file.json", ""))
+	assert.Equal(t, 2, restarted.status().Users)
+	assert.Equal(t, leader.dataVersion.Load(), restarted.dataVersion.Load())
+	assert.Zero(t, restarted.userCache.Len())
+}
+
+// TestApp_FollowerRollbackHashLayout tests that a follower in the hash layout reports a rolled-back
+// version only once it serves it, after the leader applied the pin
+func TestApp_FollowerRollbackHashLayout(t *testing.T) {
+	client, server := testutil.NewFakeRedisClient(t)
+	newApp := func() *App {
+		app := &App{userCache: cache.NewSafeUserCache(), log: logger.GetLoggerKit()}
+		app.userStore = cache.NewRedisUserStore(client, "", 0, 0, app.userCache)
+		app.history = cache.NewDatasetHistory(client, "", 0, "")
+		return app
+	}
+	leader, follower := newApp(), newApp()
+	server.Mu.Lock()
+	server.Data[cache.DefaultNamespace.Key(cache.REDIS_LEADER_KEY)] = "leader"
+	server.Mu.Unlock()
+	follower.elector = cache.NewLeaderElector(client, "", "follower", 0)
+	follower.elector.Campaign(context.Background())
+	require.False(t, follower.leading())
+
+	apply := func(users []define.AllowListUser) {
+		leader.userCache.Set(users)
+		leader.recordDataset(users)
+		require.NoError(t, leader.writeRedis(users))
+		leader.announceWrite()
+		follower.applyCacheUpdate(cache.CacheUpdate{Instance: "leader", Version: leader.dataVersion.Load(), Hash: leader.userCache.GetHash()})
+	}
+	apply([]define.AllowListUser{{Phone: "13800138000", UserID: "a"}})
+	apply([]define.AllowListUser{{Phone: "13900139000", UserID: "b"}})
+	require.Equal(t, int64(2), follower.ActiveVersion())
+
+	// The follower cannot write the store: it keeps reporting what it serves until the leader applied the pin
+	ds, err := follower.Rollback(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), ds.Version)
+	assert.Equal(t, int64(2), follower.ActiveVersion())
+	_, ok := follower.userLookup().GetByUserID("b")
+	assert.True(t, ok)
+
+	require.True(t, leader.applyPinned())
+	assert.Equal(t, int64(1), leader.ActiveVersion())
+	follower.applyCacheUpdate(cache.CacheUpdate{Instance: "leader", Version: leader.dataVersion.Load(), Hash: leader.userCache.GetHash()})
+	assert.Equal(t, int64(1), follower.ActiveVersion())
+	_, ok = follower.userLookup().GetByUserID("a")
+	assert.True(t, ok)
+	_, ok = follower.userLookup().GetByUserID("b")
+	assert.False(t, ok)
+}
+
+// TestApp_FollowerAppliesEmptyList tests that a follower applies an empty list the leader wrote, unlike a
+// missing one
+func TestApp_FollowerAppliesEmptyList(t *testing.T) {
+	client, server := testutil.NewFakeRedisClient(t)
+	writer := cache.NewRedisUserCache(client, "", 0, "")
+	server.Mu.Lock()
+	server.Data[cache.DefaultNamespace.Key(cache.REDIS_LEADER_KEY)] = "leader"
+	server.Mu.Unlock()
+	follower := &App{
+		userCache:      cache.NewSafeUserCache(),
+		redisUserCache: cache.NewRedisUserCache(client, "", 0, ""),
+		elector:        cache.NewLeaderElector(client, "", "follower", 0),
+		log:            logger.GetLoggerKit(),
+	}
+	follower.elector.Campaign(context.Background())
+	require.False(t, follower.leading())
+
+	require.NoError(t, writer.Set([]define.AllowListUser{{Phone: "13800138000"}}))
+	require.NoError(t, follower.loadFromLeader())
+	assert.Equal(t, 1, follower.userCache.Len())
+
+	// The leader publishes an empty list: applied by the scheduled run, which then has nothing left to do
+	require.NoError(t, writer.Set([]define.AllowListUser{}))
+	version, err := writer.GetVersion()
+	require.NoError(t, err)
+	follower.followLeader()
+	assert.Zero(t, follower.userCache.Len())
+	assert.Equal(t, version, follower.dataVersion.Load())
+
+	// A missing list is not applied
+	require.NoError(t, writer.Set([]define.AllowListUser{{Phone: "13800138000"}}))
+	server.Mu.Lock()
+	delete(server.Data, cache.DefaultNamespace.Key(cache.REDIS_CACHE_KEY))
+	server.Mu.Unlock()
+	follower.followLeader()
+	assert.Equal(t, version, follower.dataVersion.Load())
+
+	restarted := &App{userCache: cache.NewSafeUserCache(), redisUserCache: follower.redisUserCache, log: logger.GetLoggerKit()}
+	require.NoError(t, writer.Set([]define.AllowListUser{}))
+	require.NoError(t, restarted.loadFromLeader())
+	assert.Zero(t, restarted.userCache.Len())
+	assert.Equal(t, version+2, restarted.dataVersion.Load())
+}
+
+// TestApp_FollowerAfterListExpired tests that the leader keeps the list in Redis alive while its data does
+// not change, and writes it again once it expired, so a follower started afterwards serves it
+func TestApp_FollowerAfterListExpired(t *testing.T) {
+	client, server := testutil.NewFakeRedisClient(t)
+	dataFile := filepath.Join(t.TempDir(), "data.json")
+	require.NoError(t, os.WriteFile(dataFile, []byte(`[{"phone": "13800138000"}, {"phone": "13900139000"}]`), 0o600))
+	leader := NewApp(&cmd.Config{Port: "8081", Mode: "ONLY_LOCAL", DataFile: dataFile, TaskInterval: 60})
+	leader.redisUserCache = cache.NewRedisUserCache(client, "", time.Minute, "")
+	leader.backgroundTask(dataFile, "")
+	require.Equal(t, 2, leader.userCache.Len())
+	version := leader.dataVersion.Load()
+	require.NotZero(t, version)
+
+	listKey := cache.DefaultNamespace.Key(cache.REDIS_CACHE_KEY)
+	server.Mu.Lock()
+	assert.Zero(t, server.TTLs[cache.DefaultNamespace.Key(cache.REDIS_CACHE_VERSION_KEY)], "the version does not expire")
+	server.TTLs[listKey] = 1
+	server.Mu.Unlock()
+
+	// Unchanged data restarts the expiry without a new version
+	leader.backgroundTask(dataFile, "")
+	server.Mu.Lock()
+	assert.Equal(t, int64(60), server.TTLs[listKey])
+	// The list expires, e.g. while every instance was down
+	delete(server.Data, listKey)
+	server.Mu.Unlock()
+	assert.Equal(t, version, leader.dataVersion.Load())
+
+	newFollower := func() *App {
+		app := &App{userCache: cache.NewSafeUserCache(), redisUserCache: cache.NewRedisUserCache(client, "", time.Minute, ""), log: logger.GetLoggerKit()}
+		app.elector = cache.NewLeaderElector(client, "", "follower", 0)
+		app.elector.Campaign(context.Background())
+		require.False(t, app.leading())
+		return app
+	}
+	server.Mu.Lock()
+	server.Data[cache.DefaultNamespace.Key(cache.REDIS_LEADER_KEY)] = "leader"
+	server.Mu.Unlock()
+	early := newFollower()
+	require.NoError(t, early.loadFromLeader())
+	assert.Zero(t, early.userCache.Len(), "nothing to serve while the list is missing")
+
+	// The next run of the leader writes the unchanged list again, with a version the followers have not seen
+	leader.backgroundTask(dataFile, "")
+	assert.Greater(t, leader.dataVersion.Load(), version)
+	late := newFollower()
+	require.NoError(t, late.loadFromLeader())
+	assert.Equal(t, 2, late.userCache.Len())
+	assert.Equal(t, leader.dataVersion.Load(), late.dataVersion.Load())
+	early.followLeader()
+	assert.Equal(t, 2, early.userCache.Len())
+}
+
+// TestApp_FollowerLoaderState tests that a follower reports the leader's load: provenance, sources,
+// conflicts, load report and the source names of audit records
+func TestApp_FollowerLoaderState(t *testing.T) {
+	for _, layout := range []string{"blob", cache.UserStoreHash} {
+		t.Run(layout, func(t *testing.T) {
+			client, server := testutil.NewFakeRedisClient(t)
+			newApp := func(data string) *App {
+				dir := t.TempDir()
+				dataFile := filepath.Join(dir, "data.json")
+				require.NoError(t, os.WriteFile(dataFile, []byte(data), 0o600))
+				require.NoError(t, os.Mkdir(filepath.Join(dir, "data.d"), 0o700))
+				// DEFAULT merges the data file and the data directory
+				app := NewApp(&cmd.Config{Port: "8081", Mode: "DEFAULT", DataFile: dataFile, DataDir: filepath.Join(dir, "data.d"), TaskInterval: 60})
+				app.dataFile, app.dataDir = dataFile, filepath.Join(dir, "data.d")
+				if layout == cache.UserStoreHash {
+					app.userStore = cache.NewRedisUserStore(client, "", 0, 0, app.userCache)
+				} else {
+					app.redisUserCache = cache.NewRedisUserCache(client, "", 0, "")
+				}
+				app.loaderState = cache.NewLoaderStateStore(client, "")
+				return app
+			}
+			leader := newApp(`[{"phone": "13800138000", "user_id": "a"}]`)
+			follower := newApp(`[{"phone": "13700137000"}]`)
+			server.Mu.Lock()
+			server.Data[cache.DefaultNamespace.Key(cache.REDIS_LEADER_KEY)] = "leader"
+			server.Mu.Unlock()
+			follower.elector = cache.NewLeaderElector(client, "", "follower", 0)
+			follower.elector.Campaign(context.Background())
+			require.False(t, follower.leading())
+
+			// Two sources list user_id a for different phones: an identity conflict of the leader's load
+			require.NoError(t, os.WriteFile(leader.dataFile, []byte(`[{"phone": "13800138000", "user_id": "a"}, {"phone": "13600136000", "mail": "x@example.com"}]`), 0o600))
+			require.NoError(t, os.WriteFile(filepath.Join(leader.dataDir, "b.json"), []byte(`[{"phone": "13900139000", "user_id": "a"}]`), 0o600))
+			leader.backgroundTask(leader.dataFile, leader.dataDir)
+			require.NotEmpty(t, leader.rulesLoader.IdentityConflicts())
+			require.NoError(t, follower.loadFromLeader())
+			assert.Equal(t, leader.rulesLoader.IdentityConflicts(), follower.rulesLoader.IdentityConflicts())
+
+			get := func(handler http.HandlerFunc, target, id string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+				req.SetPathValue("id", id)
+				w := httptest.NewRecorder()
+				handler(w, req)
+				return w
+			}
+			w := get(router.GetUserProvenance(follower.userLookup(), follower.rulesLoader), "/v1/admin/users/x/provenance", "x@example.com")
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			var p router.ProvenanceResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+			require.Len(t, p.Sources, 1)
+			assert.Equal(t, leader.dataFile, p.Sources[0].Name)
+			leaderProvenance, ok := leader.rulesLoader.Provenance(&define.AllowListUser{Phone: "13600136000"})
+			require.True(t, ok)
+			assert.True(t, leaderProvenance.LoadedAt.Equal(p.LoadedAt))
+
+			want := get(router.GetSources(leader.rulesLoader), "/v1/admin/sources", "")
+			got := get(router.GetSources(follower.rulesLoader), "/v1/admin/sources", "")
+			assert.JSONEq(t, want.Body.String(), got.Body.String())
+			assert.Contains(t, got.Body.String(), leader.dataFile)
+
+			want = get(router.GetLoadReport(leader, leader.rulesLoader), "/v1/admin/load-report", "")
+			got = get(router.GetLoadReport(follower, follower.rulesLoader), "/v1/admin/load-report", "")
+			require.Equal(t, http.StatusOK, got.Code)
+			assert.JSONEq(t, want.Body.String(), got.Body.String())
+
+			// Audit records of the follower name the leader's source
+			user, ok := follower.userLookup().GetByMail("x@example.com")
+			require.True(t, ok)
+			p2, ok := follower.rulesLoader.Provenance(&user)
+			require.True(t, ok)
+			assert.Equal(t, []string{leader.dataFile}, p2.Names())
+			_, ok = follower.rulesLoader.Provenance(&define.AllowListUser{Phone: "13700137000"})
+			assert.False(t, ok, "the follower's own sources are not reported")
+
+			// The next load of the leader replaces the provenance of the previous one
+			require.NoError(t, os.WriteFile(leader.dataFile, []byte(`[{"phone": "13500135000"}]`), 0o600))
+			require.NoError(t, os.Remove(filepath.Join(leader.dataDir, "b.json")))
+			leader.backgroundTask(leader.dataFile, leader.dataDir)
+			follower.applyCacheUpdate(cache.CacheUpdate{Instance: "leader", Version: leader.dataVersion.Load()})
+			assert.Empty(t, follower.rulesLoader.IdentityConflicts())
+			_, ok = follower.rulesLoader.Provenance(&define.AllowListUser{Phone: "13600136000"})
+			assert.False(t, ok)
+			_, ok = follower.rulesLoader.Provenance(&define.AllowListUser{Phone: "13500135000"})
+			assert.True(t, ok)
+			server.Mu.Lock()
+			provenanceKeys := 0
+			for key := range server.Hashes {
+				if strings.HasPrefix(key, cache.DefaultNamespace.Key(cache.REDIS_LOADER_PROVENANCE_KEY)) {
+					provenanceKeys++
+				}
+			}
+			server.Mu.Unlock()
+			assert.Equal(t, 1, provenanceKeys, "the previous provenance is deleted")
+		})
+	}
+}
+
+func TestApp_CacheSyncWithoutRedis(t *testing.T) {
+	app := &App{userCache: cache.NewSafeUserCache(), log: logger.GetLoggerKit()}
+	app.userCache.Set([]define.AllowListUser{{Phone: "13800138000"}})
+
+	app.announceWrite()
+	assert.Equal(t, int64(0), app.dataVersion.Load())
+
+	// Without Redis there is nothing to reload, so the version is left unchanged
+	app.applyCacheUpdate(cache.CacheUpdate{Instance: "other", Version: 5, Hash: "h"})
+	assert.Equal(t, int64(0), app.dataVersion.Load())
+
+	instances, err := app.Instances(context.Background())
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, cache.DefaultInstanceID(), instances[0].Instance)
+	assert.Equal(t, app.userCache.GetHash(), instances[0].Hash)
+	assert.Equal(t, 1, instances[0].Users)
+
+	app.startCacheSync(context.Background()) // no-op without Redis
+}
+
+// TestApp_LeaderElectionUnreachable tests that an instance that cannot reach Redis keeps loading its own
+// sources but cannot write to Redis without the leadership
+func TestApp_LeaderElectionUnreachable(t *testing.T) {
+	client := redis.NewClient(&redis.Options{
+		Addr:       "unreachable",
+		MaxRetries: -1,
+		Dialer: func(context.Context, string, string) (net.Conn, error) {
+			return nil, errors.New("connection refused")
+		},
+	})
+	t.Cleanup(func() { _ = client.Close() }) //nolint:errcheck // test cleanup
+	app := &App{
+		userCache:      cache.NewSafeUserCache(),
+		redisUserCache: cache.NewRedisUserCache(client, "", 0, ""),
+		elector:        cache.NewLeaderElector(client, "", "a", 0),
+		log:            logger.GetLoggerKit(),
+	}
+	app.elector.Campaign(context.Background())
+	assert.True(t, app.leading(), "无法访问Redis时应自行加载数据源")
+	assert.False(t, app.status().Leader)
+
+	users := []define.AllowListUser{{Phone: "13800138000"}}
+	require.ErrorIs(t, app.writeRedis(users), cache.ErrNotLeader)
+	require.ErrorIs(t, app.updateRedisCacheWithRetry(users), cache.ErrNotLeader, "不是leader时不应重试")
+
+	res := leaderChecker(app.elector).Check(context.Background())
+	assert.Equal(t, health.StatusUnhealthy, res.Status)
+	assert.Equal(t, "follower", res.Metadata["role"])
+	assert.Equal(t, "a", res.Metadata["instance"])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	select {
+	case <-app.startLeaderElection(ctx):
+	case <-time.After(2 * time.Second):
+		t.Fatal("leader election did not stop after cancel")
+	}
+}
+
+func TestApp_LeaderElectionWithoutRedis(t *testing.T) {
+	app := &App{userCache: cache.NewSafeUserCache(), log: logger.GetLoggerKit()}
+	assert.True(t, app.leading())
+	_, open := <-app.startLeaderElection(context.Background())
+	assert.False(t, open)
+}
+
+// TestApp_RollbackAndPin tests that a rollback applies a kept dataset and keeps it until unpinned
+func TestApp_RollbackAndPin(t *testing.T) {
+	dataFile := filepath.Join(t.TempDir(), "data.json")
+	require.NoError(t, os.WriteFile(dataFile, []byte(`[{"phone": "13800138000"}]`), 0o600))
+
+	app := NewApp(&cmd.Config{
+		Port:         "8081",
+		Mode:         "ONLY_LOCAL",
+		DataFile:     dataFile,
+		TaskInterval: 60,
+	})
+	assert.Equal(t, int64(1), app.ActiveVersion())
+
+	require.NoError(t, os.WriteFile(dataFile, []byte(`[{"phone": "13800138000"}, {"phone": "13900139000"}]`), 0o600))
+	app.backgroundTask(dataFile, "")
+	assert.Equal(t, int64(2), app.ActiveVersion())
+	assert.Equal(t, 2, app.userCache.Len())
+
+	_, err := app.Rollback(context.Background(), 3)
+	require.ErrorIs(t, err, cache.ErrDatasetNotFound)
+
+	ds, err := app.Rollback(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), ds.Version)
+	assert.Equal(t, int64(1), app.ActiveVersion())
+	assert.Equal(t, 1, app.userCache.Len())
+
+	// Pinned: the sources are not applied
+	app.backgroundTask(dataFile, "")
+	assert.Equal(t, 1, app.userCache.Len(), "固定版本时不应加载数据源")
+	datasets, pinned, err := app.Datasets(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pinned)
+	assert.Len(t, datasets, 2)
+
+	require.NoError(t, app.Unpin(context.Background()))
+	assert.Eventually(t, func() bool { return app.ActiveVersion() == 2 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2, app.userCache.Len())
+}
+
+func TestApp_RollbackWithoutHistory(t *testing.T) {
+	app := &App{userCache: cache.NewSafeUserCache(), log: logger.GetLoggerKit()}
+	_, err := app.Rollback(context.Background(), 1)
+	require.ErrorIs(t, err, cache.ErrDatasetNotFound)
+	require.NoError(t, app.Unpin(context.Background()))
+	datasets, pinned, err := app.Datasets(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, datasets)
+	assert.Zero(t, pinned)
+	assert.Zero(t, app.ActiveVersion())
+}
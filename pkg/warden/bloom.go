@@ -0,0 +1,135 @@
+package warden
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"strings"
+)
+
+// Identifier kinds of a MembershipFilter, named like the query parameters of GET /user.
+const (
+	IdentifierPhone  = "phone"
+	IdentifierMail   = "mail"
+	IdentifierUserID = "user_id"
+)
+
+// membershipFilterMagic starts every encoded MembershipFilter; the last byte is the format version.
+var membershipFilterMagic = [4]byte{'W', 'B', 'F', 1}
+
+const membershipFilterHeaderSize = 4 + 4 + 8 + 8 // magic, k, m, n
+
+// MembershipFilter is a Bloom filter over the identifiers (phone, mail, user_id) of the allow list.
+// MayContain never returns false for an identifier that was added, so a false answer means the user is
+// definitely not in the list; a true answer may be a false positive and needs a real lookup.
+//
+// Identifiers are compared trimmed and case-insensitively. A filter must not be modified while it is read;
+// the server publishes a new filter for every dataset, and Client.GetMembershipFilter returns that copy.
+type MembershipFilter struct {
+	bits []uint64
+	m    uint64 // number of bits
+	k    uint32 // number of hash functions
+	n    uint64 // identifiers added
+}
+
+// NewMembershipFilter creates a filter sized for capacity identifiers at the false positive rate fpRate
+// (for example 0.01). Adding more identifiers than capacity raises the false positive rate.
+func NewMembershipFilter(capacity int, fpRate float64) *MembershipFilter {
+	if capacity < 1 {
+		capacity = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	m = max(64, (m+63)/64*64)
+	k := uint32(math.Round(float64(m) / float64(capacity) * math.Ln2))
+	k = min(max(k, 1), 32)
+	return &MembershipFilter{bits: make([]uint64, m/64), m: m, k: k}
+}
+
+// Add adds one identifier of the given kind. Empty values are ignored.
+func (f *MembershipFilter) Add(kind, value string) {
+	h1, h2, ok := membershipHash(kind, value)
+	if !ok {
+		return
+	}
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+	f.n++
+}
+
+// MayContain reports whether the identifier may be in the list; false means it definitely is not.
+func (f *MembershipFilter) MayContain(kind, value string) bool {
+	h1, h2, ok := membershipHash(kind, value)
+	if !ok {
+		return false
+	}
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Count returns the number of identifiers added.
+func (f *MembershipFilter) Count() int {
+	return int(f.n)
+}
+
+// Clone returns a copy that can be modified independently.
+func (f *MembershipFilter) Clone() *MembershipFilter {
+	c := *f
+	c.bits = append([]uint64(nil), f.bits...)
+	return &c
+}
+
+// MarshalBinary encodes the filter in the format served by GET /v1/membership-filter.
+func (f *MembershipFilter) MarshalBinary() ([]byte, error) {
+	out := make([]byte, membershipFilterHeaderSize, membershipFilterHeaderSize+8*len(f.bits))
+	copy(out, membershipFilterMagic[:])
+	binary.BigEndian.PutUint32(out[4:], f.k)
+	binary.BigEndian.PutUint64(out[8:], f.m)
+	binary.BigEndian.PutUint64(out[16:], f.n)
+	for _, word := range f.bits {
+		out = binary.BigEndian.AppendUint64(out, word)
+	}
+	return out, nil
+}
+
+// UnmarshalBinary decodes a filter written by MarshalBinary.
+func (f *MembershipFilter) UnmarshalBinary(data []byte) error {
+	if len(data) < membershipFilterHeaderSize || [4]byte(data[:4]) != membershipFilterMagic {
+		return NewError(ErrCodeInvalidResponse, "invalid membership filter: unknown format", nil)
+	}
+	k := binary.BigEndian.Uint32(data[4:])
+	m := binary.BigEndian.Uint64(data[8:])
+	n := binary.BigEndian.Uint64(data[16:])
+	body := data[membershipFilterHeaderSize:]
+	if k == 0 || m == 0 || m%64 != 0 || uint64(len(body)) != m/8 {
+		return NewError(ErrCodeInvalidResponse, "invalid membership filter: inconsistent size", nil)
+	}
+	bits := make([]uint64, m/64)
+	for i := range bits {
+		bits[i] = binary.BigEndian.Uint64(body[i*8:])
+	}
+	*f = MembershipFilter{bits: bits, m: m, k: k, n: n}
+	return nil
+}
+
+// membershipHash returns the two hashes combined into the k bit positions (Kirsch-Mitzenmacher) of a
+// normalized identifier: FNV-1a 128 of "<kind>\x00<value>", split in halves. ok is false for empty values.
+func membershipHash(kind, value string) (h1, h2 uint64, ok bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return 0, 0, false
+	}
+	h := fnv.New128a()
+	_, _ = h.Write(append(append([]byte(kind), 0), value...)) //nolint:errcheck // hash.Hash never returns an error
+	sum := h.Sum(nil)
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:]) | 1, true
+}
@@ -0,0 +1,65 @@
+package warden
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMembershipFilter_NoFalseNegatives(t *testing.T) {
+	f := NewMembershipFilter(3000, 0.01)
+	for i := 0; i < 1000; i++ {
+		f.Add(IdentifierPhone, fmt.Sprintf("138%08d", i))
+		f.Add(IdentifierMail, fmt.Sprintf("User%d@Example.com", i))
+		f.Add(IdentifierUserID, fmt.Sprintf("u-%d", i))
+	}
+	require.Equal(t, 3000, f.Count())
+
+	for i := 0; i < 1000; i++ {
+		require.True(t, f.MayContain(IdentifierPhone, fmt.Sprintf(" 138%08d ", i)))
+		require.True(t, f.MayContain(IdentifierMail, fmt.Sprintf("user%d@example.COM", i)))
+		require.True(t, f.MayContain(IdentifierUserID, fmt.Sprintf("U-%d", i)))
+	}
+
+	// Identifiers are kept apart by kind, and the false positive rate stays near the target
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if f.MayContain(IdentifierPhone, fmt.Sprintf("139%08d", i)) {
+			falsePositives++
+		}
+	}
+	require.Less(t, falsePositives, 300, "false positive rate far above 1%%")
+	require.False(t, f.MayContain(IdentifierPhone, ""))
+}
+
+func TestMembershipFilter_MarshalRoundTrip(t *testing.T) {
+	f := NewMembershipFilter(10, 0.001)
+	f.Add(IdentifierMail, "a@example.com")
+	f.Add(IdentifierPhone, "13800138000")
+
+	data, err := f.MarshalBinary()
+	require.NoError(t, err)
+
+	var g MembershipFilter
+	require.NoError(t, g.UnmarshalBinary(data))
+	require.Equal(t, 2, g.Count())
+	require.True(t, g.MayContain(IdentifierMail, "A@example.com"))
+	require.True(t, g.MayContain(IdentifierPhone, "13800138000"))
+	require.False(t, g.MayContain(IdentifierMail, "13800138000"))
+
+	require.Error(t, g.UnmarshalBinary(data[:len(data)-1]))
+	require.Error(t, g.UnmarshalBinary([]byte("not a filter at all, really")))
+}
+
+func TestMembershipFilter_Clone(t *testing.T) {
+	f := NewMembershipFilter(10, 0.01)
+	f.Add(IdentifierUserID, "a")
+	c := f.Clone()
+	c.Add(IdentifierUserID, "b")
+
+	require.True(t, c.MayContain(IdentifierUserID, "a"))
+	require.True(t, c.MayContain(IdentifierUserID, "b"))
+	require.Equal(t, 1, f.Count())
+	require.Equal(t, 2, c.Count())
+}
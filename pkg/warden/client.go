@@ -27,6 +27,12 @@ type Client struct {
 	cacheInvalidationChannel <-chan struct{}
 	stopCacheListener        context.CancelFunc
 	cacheListenerWg          sync.WaitGroup
+	useFilter                bool
+	cacheTTL                 time.Duration
+	filterMu                 sync.Mutex
+	filter                   *MembershipFilter
+	filterErr                error // cached "not found" while the server has no filter enabled
+	filterExpiresAt          time.Time
 }
 
 // NewClient creates a new Warden API client with the provided options.
@@ -68,6 +74,8 @@ func NewClient(opts *Options) (*Client, error) {
 		logger:                   opts.Logger,
 		retry:                    retry,
 		cacheInvalidationChannel: opts.CacheInvalidationChannel,
+		useFilter:                opts.MembershipFilter,
+		cacheTTL:                 opts.CacheTTL,
 	}
 
 	// Start cache invalidation listener if channel is provided
@@ -95,6 +103,7 @@ func (c *Client) listenForCacheInvalidation(ctx context.Context) {
 		case <-c.cacheInvalidationChannel:
 			c.logger.Debug("Cache invalidation signal received, clearing cache")
 			c.cache.Clear()
+			c.clearFilter()
 		}
 	}
 }
@@ -308,6 +317,11 @@ func (c *Client) GetUserByIdentifier(ctx context.Context, phone, mail, userID st
 		return nil, NewError(ErrCodeInvalidConfig, "only one identifier (phone, mail, or user_id) should be provided", nil)
 	}
 
+	if c.useFilter && !c.mayContain(ctx, phone, mail, userID) {
+		c.logger.Debugf("User ruled out by membership filter: phone=%s, mail=%s", sanitizePhone(phone), sanitizeEmail(mail))
+		return nil, NewError(ErrCodeNotFound, "not found", nil)
+	}
+
 	// Build request URL
 	reqURL := strings.TrimSuffix(c.baseURL, "/") + "/user"
 	params := url.Values{}
@@ -367,9 +381,87 @@ func (c *Client) GetUserByIdentifier(ctx context.Context, phone, mail, userID st
 	return &user, nil
 }
 
+// GetMembershipFilter fetches the membership filter of the current dataset from Warden API
+// (GET /v1/membership-filter). Returns an ErrCodeNotFound error when the server has no filter enabled.
+// The filter is cached for CacheTTL; the returned filter is shared and must not be modified.
+func (c *Client) GetMembershipFilter(ctx context.Context) (*MembershipFilter, error) {
+	c.filterMu.Lock()
+	defer c.filterMu.Unlock()
+	if (c.filter != nil || c.filterErr != nil) && time.Now().Before(c.filterExpiresAt) {
+		return c.filter, c.filterErr
+	}
+
+	reqURL := strings.TrimSuffix(c.baseURL, "/") + "/v1/membership-filter"
+	c.logger.Debugf("Fetching membership filter from Warden API: %s", reqURL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, http.NoBody)
+	if err != nil {
+		return nil, NewError(ErrCodeRequestFailed, "failed to create request", err)
+	}
+	c.httpClient.InjectTraceContext(ctx, req)
+	c.addAuthHeaders(req)
+
+	resp, err := c.doRequestWithRetry(ctx, req)
+	if err != nil {
+		c.logger.Errorf("Failed to fetch membership filter from Warden API: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close() //nolint:errcheck // Ignoring error in defer is safe
+		}
+	}()
+
+	if err := c.checkResponseStatus(resp); err != nil {
+		if sdkErr, ok := err.(*Error); ok && sdkErr.Code == ErrCodeNotFound {
+			c.filter, c.filterErr, c.filterExpiresAt = nil, err, time.Now().Add(c.cacheTTL)
+		}
+		return nil, err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, NewError(ErrCodeInvalidResponse, "failed to read membership filter", err)
+	}
+	filter := &MembershipFilter{}
+	if err := filter.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	c.filter, c.filterErr, c.filterExpiresAt = filter, nil, time.Now().Add(c.cacheTTL)
+	c.logger.Debugf("Fetched membership filter: %d identifiers, %d bytes", filter.Count(), len(data))
+	return filter, nil
+}
+
+// mayContain checks the one identifier given against the membership filter. When the filter cannot be
+// fetched it returns true, so the lookup goes to the server.
+func (c *Client) mayContain(ctx context.Context, phone, mail, userID string) bool {
+	filter, err := c.GetMembershipFilter(ctx)
+	if err != nil {
+		c.logger.Debugf("Membership filter unavailable, asking Warden API: %v", err)
+		return true
+	}
+	switch {
+	case phone != "":
+		return filter.MayContain(IdentifierPhone, phone)
+	case mail != "":
+		return filter.MayContain(IdentifierMail, mail)
+	default:
+		return filter.MayContain(IdentifierUserID, userID)
+	}
+}
+
+// clearFilter drops the cached membership filter.
+func (c *Client) clearFilter() {
+	c.filterMu.Lock()
+	c.filter, c.filterErr = nil, nil
+	c.filterMu.Unlock()
+}
+
 // ClearCache clears the internal cache.
 func (c *Client) ClearCache() {
 	c.cache.Clear()
+	c.clearFilter()
 	c.logger.Debug("Cache cleared")
 }
 
@@ -45,6 +45,7 @@ type Options struct {
 	Transport                *http.Transport // Custom HTTP transport (optional)
 	Retry                    *RetryOptions   // Retry configuration (optional)
 	CacheInvalidationChannel <-chan struct{} // Channel for event-driven cache invalidation (optional)
+	MembershipFilter         bool            // Skip lookups the server's membership filter rules out (optional, requires CacheTTL > 0)
 }
 
 // DefaultOptions returns default options with sensible defaults.
@@ -80,6 +81,10 @@ func (o *Options) Validate() error {
 		return NewError(ErrCodeInvalidConfig, "CacheTTL must be non-negative", nil)
 	}
 
+	if o.MembershipFilter && o.CacheTTL == 0 {
+		return NewError(ErrCodeInvalidConfig, "MembershipFilter requires CacheTTL greater than 0", nil)
+	}
+
 	// Set default logger if not provided
 	if o.Logger == nil {
 		o.Logger = &NoOpLogger{}
@@ -152,3 +157,12 @@ func (o *Options) WithCacheInvalidationChannel(ch <-chan struct{}) *Options {
 	o.CacheInvalidationChannel = ch
 	return o
 }
+
+// WithMembershipFilter enables the membership filter: GetUserByIdentifier and CheckUserInList answer
+// "not found" without a request for identifiers the server's filter rules out. The filter is fetched
+// from GET /v1/membership-filter and cached like the user list, so users added on the server may be
+// reported as not found for up to CacheTTL.
+func (o *Options) WithMembershipFilter(enabled bool) *Options {
+	o.MembershipFilter = enabled
+	return o
+}
@@ -2035,3 +2035,93 @@ func TestClient_addAuthHeaders(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_MembershipFilter(t *testing.T) {
+	filter := NewMembershipFilter(10, 0.001)
+	filter.Add(IdentifierPhone, "13800138000")
+	filter.Add(IdentifierMail, "user1@example.com")
+	data, err := filter.MarshalBinary()
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/v1/membership-filter":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write(data) //nolint:errcheck // test server
+		case "/user":
+			w.Header().Set("Content-Type", "application/json")
+			require.NoError(t, json.NewEncoder(w).Encode(AllowListUser{Phone: "13800138000", Mail: "user1@example.com", Status: "active"}))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(DefaultOptions().WithBaseURL(server.URL).WithMembershipFilter(true))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Members are looked up on the server
+	require.True(t, client.CheckUserInList(ctx, "13800138000", ""))
+	require.True(t, client.CheckUserInList(ctx, "", "USER1@example.com"))
+
+	// Definite non-members never reach the server
+	require.False(t, client.CheckUserInList(ctx, "13900139000", ""))
+	_, err = client.GetUserByIdentifier(ctx, "", "nobody@example.com", "")
+	var sdkErr *Error
+	require.ErrorAs(t, err, &sdkErr)
+	require.Equal(t, ErrCodeNotFound, sdkErr.Code)
+
+	mu.Lock()
+	require.Equal(t, 2, requests["/user"])
+	require.Equal(t, 1, requests["/v1/membership-filter"], "filter is cached for CacheTTL")
+	mu.Unlock()
+
+	// Clearing the cache drops the filter too
+	client.ClearCache()
+	_, err = client.GetMembershipFilter(ctx)
+	require.NoError(t, err)
+	mu.Lock()
+	require.Equal(t, 2, requests["/v1/membership-filter"])
+	mu.Unlock()
+}
+
+func TestClient_MembershipFilter_Disabled(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		if r.URL.Path == "/v1/membership-filter" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(AllowListUser{Phone: "13800138000", Status: "active"}))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(DefaultOptions().WithBaseURL(server.URL).WithMembershipFilter(true))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Without a filter on the server every lookup asks the server, and the 404 is not refetched each time
+	require.True(t, client.CheckUserInList(ctx, "13800138000", ""))
+	require.True(t, client.CheckUserInList(ctx, "13800138000", ""))
+	mu.Lock()
+	require.Equal(t, 2, requests["/user"])
+	require.Equal(t, 1, requests["/v1/membership-filter"])
+	mu.Unlock()
+}
+
+func TestOptions_Validate_MembershipFilter(t *testing.T) {
+	opts := DefaultOptions().WithBaseURL("http://localhost").WithCacheTTL(0).WithMembershipFilter(true)
+	require.Error(t, opts.Validate())
+	require.NoError(t, opts.WithCacheTTL(time.Minute).Validate())
+}